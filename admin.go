@@ -0,0 +1,115 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrUnknownAdminAction is returned by ApplyAdminAction for an unknown action.
+var ErrUnknownAdminAction = errors.New("unknown action")
+
+// AdminHandler is an http.Handler to inspect and control CircuitBreakers at runtime.
+//
+// GET responds with the snapshots of all registered CircuitBreakers as a JSON array.
+//
+// POST with the form values name and action changes the named CircuitBreaker and
+// responds with its new snapshot. The action is one of:
+//
+//	open:         force the CircuitBreaker into the open state
+//	close:        force the CircuitBreaker into the closed state
+//	reset:        force the CircuitBreaker into the closed state and clear its counts
+//	force-open:   place the CircuitBreaker into StateForcedOpen
+//	force-closed: place the CircuitBreaker into StateForcedClosed
+//	disable:      place the CircuitBreaker into StateDisabled
+//	clear:        return the CircuitBreaker from an administrative state to the closed state
+type AdminHandler struct {
+	registry *Registry
+}
+
+// NewAdminHandler returns a new AdminHandler with the given CircuitBreakers registered.
+func NewAdminHandler(cbs ...*CircuitBreaker) *AdminHandler {
+	h := NewRegistryAdminHandler(NewRegistry())
+	for _, cb := range cbs {
+		h.Register(cb)
+	}
+	return h
+}
+
+// NewRegistryAdminHandler returns a new AdminHandler for the CircuitBreakers in r,
+// including those added to r later.
+func NewRegistryAdminHandler(r *Registry) *AdminHandler {
+	return &AdminHandler{registry: r}
+}
+
+// Register adds cb to the AdminHandler, replacing any CircuitBreaker with the same name.
+func (h *AdminHandler) Register(cb *CircuitBreaker) {
+	h.registry.Register(cb)
+}
+
+func (h *AdminHandler) breaker(name string) *CircuitBreaker {
+	cb, _ := h.registry.Get(name)
+	return cb
+}
+
+func (h *AdminHandler) snapshots() []Snapshot {
+	cbs := h.registry.sorted()
+	snapshots := make([]Snapshot, len(cbs))
+	for i, cb := range cbs {
+		snapshots[i] = cb.Snapshot()
+	}
+	return snapshots
+}
+
+// ServeHTTP implements http.Handler.
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, h.snapshots())
+	case http.MethodPost:
+		cb := h.breaker(r.FormValue("name"))
+		if cb == nil {
+			http.Error(w, "circuit breaker not found", http.StatusNotFound)
+			return
+		}
+
+		if err := ApplyAdminAction(cb, r.FormValue("action")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, cb.Snapshot())
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// ApplyAdminAction applies one of the actions of AdminHandler to cb, e.g. "force-open",
+// for other administrative interfaces. It returns ErrUnknownAdminAction for an unknown action.
+func ApplyAdminAction(cb *CircuitBreaker, action string) error {
+	switch action {
+	case "open":
+		cb.Trip()
+	case "close":
+		cb.forceState(StateClosed, false)
+	case "reset":
+		cb.Reset()
+	case "force-open":
+		cb.ForceOpen()
+	case "force-closed":
+		cb.ForceClose()
+	case "disable":
+		cb.Disable()
+	case "clear":
+		cb.ClearOverride()
+	default:
+		return ErrUnknownAdminAction
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
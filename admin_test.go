@@ -0,0 +1,98 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postAdmin(h http.Handler, name, action string) *httptest.ResponseRecorder {
+	form := url.Values{"name": {name}, "action": {action}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminHandlerList(t *testing.T) {
+	a := NewCircuitBreaker(Settings{Name: "a"})
+	b := NewCircuitBreaker(Settings{Name: "b"})
+	h := NewAdminHandler(b, a)
+
+	assert.Nil(t, fail(a))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var snapshots []Snapshot
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &snapshots))
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, "a", snapshots[0].Name)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, snapshots[0].Counts)
+	assert.Equal(t, "b", snapshots[1].Name)
+}
+
+func TestAdminHandlerControl(t *testing.T) {
+	var changes []StateChange
+	cb := NewCircuitBreaker(Settings{
+		Name: "ctl",
+		OnStateChange: func(name string, from State, to State) {
+			changes = append(changes, StateChange{name, from, to})
+		},
+	})
+	h := NewAdminHandler()
+	h.Register(cb)
+
+	rec := postAdmin(h, "ctl", "open")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, StateOpen, cb.State())
+
+	var s Snapshot
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &s))
+	assert.Equal(t, StateOpen, s.State)
+
+	rec = postAdmin(h, "ctl", "close")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, []StateChange{{"ctl", StateClosed, StateOpen}, {"ctl", StateOpen, StateClosed}}, changes)
+
+	assert.Nil(t, fail(cb))
+	rec = postAdmin(h, "ctl", "close")
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+	rec = postAdmin(h, "ctl", "reset")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.Counts())
+	assert.Len(t, changes, 2)
+
+	assert.Equal(t, http.StatusNotFound, postAdmin(h, "unknown", "open").Code)
+	assert.Equal(t, http.StatusBadRequest, postAdmin(h, "ctl", "explode").Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAdminHandlerOverride(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "ovr"})
+	h := NewAdminHandler(cb)
+
+	for action, state := range map[string]State{
+		"force-open":   StateForcedOpen,
+		"force-closed": StateForcedClosed,
+		"disable":      StateDisabled,
+	} {
+		assert.Equal(t, http.StatusOK, postAdmin(h, "ovr", action).Code)
+		assert.Equal(t, state, cb.State())
+
+		assert.Equal(t, http.StatusOK, postAdmin(h, "ovr", "clear").Code)
+		assert.Equal(t, StateClosed, cb.State())
+	}
+}
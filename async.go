@@ -0,0 +1,47 @@
+package gobreaker
+
+import "context"
+
+// Result is the result and the error of a request, e.g. of a request of a batch.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// Go runs req in a new goroutine through the CircuitBreaker, for callers that submit requests
+// without waiting for them, e.g. worker pools. The request is admitted before Go returns,
+// and its outcome is counted when it completes. The returned channel receives the Result
+// of req, or the rejection if the CircuitBreaker rejected the request, and is closed then.
+//
+// If req panics, the panic counts as a failure and is raised again in the goroutine,
+// unless Settings.PanicHandler recovers it into the error of the Result.
+func (cb *CircuitBreaker) Go(req func() (interface{}, error)) <-chan Result {
+	results := make(chan Result, 1)
+	ctx := context.Background()
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		results <- Result{Err: err}
+		close(results)
+		return results
+	}
+
+	go func() {
+		defer close(results)
+		defer func() {
+			e := recover()
+			if e != nil {
+				outcome, f, perr := cb.recoverPanic(e)
+				cb.finishRequest(ctx, generation, start, outcome, f)
+				if perr == nil {
+					panic(e)
+				}
+				results <- Result{Err: perr}
+			}
+		}()
+
+		value, err := req()
+		cb.finishResult(ctx, generation, start, value, err)
+		results <- Result{Value: value, Err: err}
+	}()
+	return results
+}
@@ -0,0 +1,43 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGo(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{MaxConcurrent: 1, ReadyToTrip: tripAfter(1)})
+
+	release := make(chan struct{})
+	results := cb.Go(func() (interface{}, error) {
+		<-release
+		return "value", nil
+	})
+
+	// the first request is admitted before Go returns
+	r := <-cb.Go(succeedFunc)
+	assert.True(t, errors.Is(r.Err, ErrTooManyConcurrent))
+
+	close(release)
+	assert.Equal(t, Result{Value: "value"}, <-results)
+	_, ok := <-results
+	assert.False(t, ok)
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+
+	r = <-cb.Go(func() (interface{}, error) { return nil, errors.New("fail") })
+	assert.EqualError(t, r.Err, "fail")
+	assert.Equal(t, StateOpen, cb.State())
+
+	r = <-cb.Go(succeedFunc)
+	assert.True(t, errors.Is(r.Err, ErrOpenState))
+}
+
+func TestGoPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{PanicHandler: RecoverPanics})
+	r := <-cb.Go(func() (interface{}, error) { panic("oops") })
+	var panicErr *PanicError
+	assert.True(t, errors.As(r.Err, &panicErr))
+	assert.Equal(t, uint32(1), cb.Counts().FailuresByKind[FailurePanic])
+}
@@ -0,0 +1,68 @@
+package gobreaker
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy lengthens the open-state timeout each time the CircuitBreaker trips again
+// without having closed in between, so that a fleet of clients doesn't probe a dependency
+// that stays down in lockstep.
+//
+// The n-th consecutive trip keeps the CircuitBreaker open for
+// Settings.Timeout * Multiplier^(n-1), limited to MaxTimeout.
+// The count of consecutive trips is cleared when the CircuitBreaker becomes closed.
+//
+// Multiplier is the growth factor of the timeout. If Multiplier is less than or equal to 1,
+// the timeout doesn't grow.
+//
+// MaxTimeout is the upper limit of the timeout. If MaxTimeout is less than or equal to 0,
+// the timeout is not limited.
+//
+// Jitter is the fraction of the timeout, between 0 and MaxJitter, that is randomly subtracted from it,
+// like Settings.Jitter. For example, with a Jitter of 0.2 the CircuitBreaker stays open between 80%
+// and 100% of the timeout.
+type BackoffPolicy struct {
+	Multiplier float64
+	MaxTimeout time.Duration
+	Jitter     float64
+}
+
+// timeout returns the open-state timeout for the given number of consecutive trips.
+func (p *BackoffPolicy) timeout(base time.Duration, trips uint32) time.Duration {
+	d := float64(base)
+	if p.Multiplier > 1 && trips > 1 {
+		d *= math.Pow(p.Multiplier, float64(trips-1))
+	}
+	if p.MaxTimeout > 0 && d > float64(p.MaxTimeout) {
+		d = float64(p.MaxTimeout)
+	}
+	timeout := time.Duration(math.MaxInt64)
+	if d < math.MaxInt64 {
+		timeout = time.Duration(d)
+	}
+	return jitter(timeout, p.Jitter)
+}
+
+// openTimeout returns how long the CircuitBreaker stays open after the current trip.
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.backoff == nil {
+		return jitter(cb.timeout, cb.jitter)
+	}
+	return cb.backoff.timeout(cb.timeout, cb.trips)
+}
+
+// MaxJitter is the largest fraction of Settings.Jitter and BackoffPolicy.Jitter. A larger fraction
+// could shorten an Interval or a Timeout to about 0, so that the CircuitBreaker would clear its Counts
+// on almost every request and never trip, or would barely stay open.
+const MaxJitter = 0.5
+
+// jitter randomly subtracts up to the given fraction of d from it, at most MaxJitter.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	fraction = math.Min(fraction, MaxJitter)
+	return d - time.Duration(float64(d)*fraction*rand.Float64())
+}
@@ -0,0 +1,105 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBackoffCB(policy BackoffPolicy) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clockFunc(func() time.Time { return now }),
+		Timeout:     10 * time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		Backoff:     &policy,
+	})
+	return cb, &now
+}
+
+// reopen lets the open timeout elapse and fails the half-open probe.
+// It returns the open timeout that was in effect.
+func reopen(cb *CircuitBreaker, now *time.Time) time.Duration {
+	timeout := cb.expiry.Sub(*now)
+	*now = cb.expiry.Add(time.Nanosecond)
+	fail(cb)
+	return timeout
+}
+
+func TestBackoffPolicy(t *testing.T) {
+	cb, now := newBackoffCB(BackoffPolicy{Multiplier: 2, MaxTimeout: time.Minute})
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, 10*time.Second, reopen(cb, now))
+	assert.Equal(t, 20*time.Second, reopen(cb, now))
+	assert.Equal(t, 40*time.Second, reopen(cb, now))
+	assert.Equal(t, time.Minute, reopen(cb, now))
+	assert.Equal(t, time.Minute, cb.expiry.Sub(*now))
+
+	// closing the CircuitBreaker starts over from Timeout
+	*now = cb.expiry.Add(time.Nanosecond)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, 10*time.Second, cb.expiry.Sub(*now))
+
+	cb.Reset()
+	cb.Trip()
+	assert.Equal(t, 10*time.Second, cb.expiry.Sub(*now))
+}
+
+func TestBackoffPolicyJitter(t *testing.T) {
+	cb, now := newBackoffCB(BackoffPolicy{Multiplier: 3, Jitter: 0.5})
+
+	assert.Nil(t, fail(cb))
+	for _, base := range []time.Duration{10 * time.Second, 30 * time.Second, 90 * time.Second, 270 * time.Second} {
+		timeout := reopen(cb, now)
+		assert.True(t, timeout > base/2, "%v too short for %v", timeout, base)
+		assert.True(t, timeout <= base, "%v too long for %v", timeout, base)
+	}
+}
+
+func TestBackoffPolicyTimeout(t *testing.T) {
+	p := &BackoffPolicy{}
+	assert.Equal(t, time.Second, p.timeout(time.Second, 5))
+
+	p = &BackoffPolicy{Multiplier: 10}
+	assert.Equal(t, time.Second, p.timeout(time.Second, 1))
+	assert.Equal(t, 100*time.Second, p.timeout(time.Second, 3))
+	assert.True(t, p.timeout(time.Second, 1000) > 0)
+}
+
+func TestBackoffPolicyDefault(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Equal(t, defaultTimeout, cb.openTimeout())
+
+	cb.trips = 3
+	assert.Equal(t, defaultTimeout, cb.openTimeout())
+}
+
+func TestJitter(t *testing.T) {
+	assert.Equal(t, time.Minute, jitter(time.Minute, 0))
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Minute, 2)
+		assert.True(t, d > 30*time.Second && d <= time.Minute)
+		seen[d] = true
+	}
+	assert.True(t, len(seen) > 1)
+
+	clock := newFakeClock()
+	var expiries []time.Time
+	for i := 0; i < 100; i++ {
+		cb := NewCircuitBreaker(Settings{Clock: clock, Interval: time.Minute, Timeout: time.Minute, Jitter: 0.1})
+		assert.True(t, cb.expiry.After(clock.Now().Add(54*time.Second)))
+		assert.False(t, cb.expiry.After(clock.Now().Add(time.Minute)))
+		expiries = append(expiries, cb.expiry)
+
+		cb.Trip()
+		assert.True(t, cb.expiry.After(clock.Now().Add(54*time.Second)))
+		assert.False(t, cb.expiry.After(clock.Now().Add(time.Minute)))
+	}
+	assert.NotEqual(t, expiries[0], expiries[1])
+}
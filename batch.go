@@ -0,0 +1,123 @@
+package gobreaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchPolicy configures ExecuteBatch.
+//
+// MaxParallel is the maximum number of requests of a batch that run at once.
+// If MaxParallel is less than or equal to 0, all of them run at once.
+//
+// FailureRatio is the ratio of failed requests above which the batch fails, e.g. 0.5 for
+// a batch that fails if more than half of its requests fail. The ignored requests don't count.
+// If FailureRatio is less than or equal to 0, any failed request fails the batch.
+type BatchPolicy struct {
+	MaxParallel  int
+	FailureRatio float64
+}
+
+// BatchError is returned by ExecuteBatch when a batch fails: Failures of its Requests failed,
+// and Err is the error of the first of them.
+type BatchError struct {
+	Failures int
+	Requests int
+	Err      error
+}
+
+// Error implements error.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d requests failed: %v", e.Failures, e.Requests, e.Err)
+}
+
+// Unwrap returns the error of the first failed request.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteBatch runs reqs as a single request to the CircuitBreaker, e.g. the fan-out of
+// a logical operation: it is admitted once, its requests run concurrently according to p,
+// and the CircuitBreaker counts one outcome for the whole batch. The batch succeeds unless
+// its failed requests exceed p.FailureRatio, as classified by Settings.Classify, in which case
+// ExecuteBatch returns a *BatchError along with the results. A batch whose requests are all
+// ignored is ignored.
+//
+// If the CircuitBreaker rejects the batch, ExecuteBatch returns no results and the rejection.
+// If a request panics, the batch fails after the other requests returned, and the panic is
+// raised again unless Settings.PanicHandler recovers it into the error of the request.
+func (cb *CircuitBreaker) ExecuteBatch(reqs []func() (interface{}, error), p BatchPolicy) ([]Result, error) {
+	ctx := context.Background()
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(reqs))
+	panics := make([]interface{}, len(reqs))
+	parallel := p.MaxParallel
+	if parallel <= 0 || parallel > len(reqs) {
+		parallel = len(reqs)
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, req func() (interface{}, error)) {
+			defer func() {
+				panics[i] = recover()
+				<-sem
+				wg.Done()
+			}()
+			results[i].Value, results[i].Err = req()
+		}(i, req)
+	}
+	wg.Wait()
+
+	var failures, requests int
+	var first error
+	var f failure
+	var firstPanic interface{}
+	for i := range results {
+		var outcome Outcome
+		var rf failure
+		if v := panics[i]; v != nil {
+			var perr error
+			outcome, rf, perr = cb.recoverPanic(v)
+			if perr == nil && firstPanic == nil {
+				firstPanic = v
+			}
+			results[i] = Result{Err: perr}
+		} else {
+			outcome = cb.classify(results[i].Value, results[i].Err)
+			rf = cb.failureOf(outcome, results[i].Err)
+		}
+
+		if outcome == OutcomeIgnore {
+			continue
+		}
+		requests++
+		if outcome == OutcomeFailure {
+			failures++
+			if failures == 1 {
+				first, f = results[i].Err, rf
+			}
+		}
+	}
+
+	switch {
+	case firstPanic != nil:
+		cb.finishRequest(ctx, generation, start, OutcomeFailure, panicFailure)
+		panic(firstPanic)
+	case requests == 0:
+		cb.finishRequest(ctx, generation, start, OutcomeIgnore, failure{})
+	case failures > 0 && float64(failures) > p.FailureRatio*float64(requests):
+		cb.finishRequest(ctx, generation, start, OutcomeFailure, f)
+		return results, &BatchError{Failures: failures, Requests: requests, Err: first}
+	default:
+		cb.finishRequest(ctx, generation, start, OutcomeSuccess, failure{})
+	}
+	return results, nil
+}
@@ -0,0 +1,102 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func batchOf(errs ...error) []func() (interface{}, error) {
+	reqs := make([]func() (interface{}, error), len(errs))
+	for i, err := range errs {
+		i, err := i, err
+		reqs[i] = func() (interface{}, error) { return i, err }
+	}
+	return reqs
+}
+
+func TestExecuteBatch(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: tripAfter(1)})
+	errFail := errors.New("fail")
+
+	results, err := cb.ExecuteBatch(batchOf(nil, nil, nil), BatchPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{{0, nil}, {1, nil}, {2, nil}}, results)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+
+	// the failures within the ratio don't fail the batch
+	results, err = cb.ExecuteBatch(batchOf(nil, errFail, nil, nil), BatchPolicy{FailureRatio: 0.25})
+	assert.NoError(t, err)
+	assert.Equal(t, errFail, results[1].Err)
+	assert.Equal(t, uint32(2), cb.Counts().TotalSuccesses)
+
+	results, err = cb.ExecuteBatch(batchOf(nil, errFail, errFail), BatchPolicy{FailureRatio: 0.5})
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, &BatchError{Failures: 2, Requests: 3, Err: errFail}, batchErr)
+	assert.True(t, errors.Is(err, errFail))
+	assert.EqualError(t, err, "2 of 3 requests failed: fail")
+	assert.Len(t, results, 3)
+	assert.Equal(t, StateOpen, cb.State())
+
+	results, err = cb.ExecuteBatch(batchOf(nil), BatchPolicy{})
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Nil(t, results)
+}
+
+func TestExecuteBatchIgnored(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	cb := NewCircuitBreaker(Settings{IgnoredErrors: []error{errIgnored}})
+
+	_, err := cb.ExecuteBatch(batchOf(errIgnored, errIgnored), BatchPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), cb.Counts().Requests)
+
+	_, err = cb.ExecuteBatch(batchOf(), BatchPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), cb.Counts().Requests)
+}
+
+func TestExecuteBatchParallel(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	var mutex sync.Mutex
+	running, peak := 0, 0
+	reqs := make([]func() (interface{}, error), 6)
+	for i := range reqs {
+		reqs[i] = func() (interface{}, error) {
+			mutex.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return nil, nil
+		}
+	}
+
+	_, err := cb.ExecuteBatch(reqs, BatchPolicy{MaxParallel: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, peak)
+}
+
+func TestExecuteBatchPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	reqs := append(batchOf(nil), func() (interface{}, error) { panic("oops") })
+	assert.PanicsWithValue(t, "oops", func() { cb.ExecuteBatch(reqs, BatchPolicy{}) })
+	assert.Equal(t, uint32(1), cb.Counts().FailuresByKind[FailurePanic])
+
+	cb = NewCircuitBreaker(Settings{PanicHandler: RecoverPanics})
+	results, err := cb.ExecuteBatch(reqs, BatchPolicy{FailureRatio: 0.5})
+	assert.NoError(t, err)
+	var panicErr *PanicError
+	assert.True(t, errors.As(results[1].Err, &panicErr))
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+}
@@ -0,0 +1,44 @@
+package gobreaker
+
+import "context"
+
+// Breaker is the common interface of the circuit breakers in this package.
+// Libraries should accept a Breaker instead of a *CircuitBreaker,
+// so that their users can pass any implementation, including fakes in tests.
+type Breaker interface {
+	// Name returns the name of the Breaker.
+	Name() string
+	// State returns the current state of the Breaker.
+	State() State
+	// Counts returns the internal counters of the Breaker.
+	Counts() Counts
+	// Execute runs req if the Breaker accepts it, and returns an error instantly otherwise.
+	Execute(req func() (interface{}, error)) (interface{}, error)
+}
+
+// ContextBreaker is a Breaker that passes the context of the request to it, like CircuitBreaker.
+// The adapters of the clients use ExecuteContext when their Breaker is a ContextBreaker,
+// so that e.g. a canceled request is not counted as a failure when IgnoreCanceled is set.
+type ContextBreaker interface {
+	Breaker
+	// ExecuteContext runs req with ctx if the Breaker accepts it, like Execute.
+	ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error)
+}
+
+var (
+	_ ContextBreaker = (*CircuitBreaker)(nil)
+	_ ContextBreaker = (*ChainBreaker)(nil)
+	_ Breaker        = (*TwoStepCircuitBreaker)(nil)
+	_ Breaker        = (*ChildBreaker)(nil)
+)
+
+// Execute runs the given request if the TwoStepCircuitBreaker accepts it, like CircuitBreaker.Execute.
+// It lets a TwoStepCircuitBreaker be used as a Breaker.
+func (tscb *TwoStepCircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	return tscb.cb.Execute(req)
+}
+
+// Counts returns the internal counters of the parent, in which the requests of the ChildBreaker are counted.
+func (c *ChildBreaker) Counts() Counts {
+	return c.parent.Counts()
+}
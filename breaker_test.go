@@ -0,0 +1,29 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "breaker"})
+	for _, b := range []Breaker{
+		cb,
+		&TwoStepCircuitBreaker{cb},
+		cb.NewChild(ChildSettings{}),
+	} {
+		before := b.Counts().Requests
+		result, err := b.Execute(func() (interface{}, error) { return 1, nil })
+		assert.Equal(t, 1, result)
+		assert.Nil(t, err)
+
+		_, err = b.Execute(func() (interface{}, error) { return nil, errors.New("fail") })
+		assert.Error(t, err)
+
+		assert.Equal(t, "breaker", b.Name())
+		assert.Equal(t, StateClosed, b.State())
+		assert.Equal(t, before+2, b.Counts().Requests)
+	}
+}
@@ -0,0 +1,144 @@
+package gobreaker
+
+import (
+	"math"
+	"time"
+)
+
+// ErrorBudgetPolicy trips the closed CircuitBreaker when the error budget of a service level
+// objective burns too fast, like the multiwindow burn rate alerts of the SRE practice.
+//
+// Objective is the target success rate of the requests, e.g. 0.999. Its error budget is
+// 1 - Objective, and the burn rate over a window is the failure rate within the window divided by
+// the error budget: a burn rate of 1 consumes exactly the budget over the period of the objective.
+// If Objective is 0, DefaultErrorBudgetObjective is used.
+//
+// Windows are the pairs of windows on which the burn rate is checked. The CircuitBreaker trips
+// as soon as the burn rates over both the Long and the Short window of any of them reach its BurnRate.
+// If Windows is empty, DefaultBurnRateWindows is used.
+type ErrorBudgetPolicy struct {
+	Objective float64
+	Windows   []BurnRateWindow
+}
+
+// DefaultErrorBudgetObjective is the Objective of an ErrorBudgetPolicy whose Objective is 0.
+const DefaultErrorBudgetObjective = 0.999
+
+// BurnRateWindow is a pair of windows of ErrorBudgetPolicy. The Long window makes sure that
+// a significant part of the budget was consumed, and the Short window that it is still being
+// consumed, so that the CircuitBreaker doesn't trip long after a spike of failures has ended.
+type BurnRateWindow struct {
+	Long     time.Duration
+	Short    time.Duration
+	BurnRate float64
+}
+
+// DefaultBurnRateWindows are the windows recommended for a 30-day objective: 2% of the budget
+// consumed within an hour, or 5% within 6 hours.
+var DefaultBurnRateWindows = []BurnRateWindow{
+	{Long: time.Hour, Short: 5 * time.Minute, BurnRate: 14.4},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, BurnRate: 6},
+}
+
+// burnRule counts the requests of a BurnRateWindow.
+type burnRule struct {
+	long, short *rolling
+	rate        float64
+}
+
+// errorBudget holds the windows of an ErrorBudgetPolicy.
+type errorBudget struct {
+	budget float64 // 1 - Objective
+	rules  []burnRule
+}
+
+func newErrorBudget(p ErrorBudgetPolicy) *errorBudget {
+	windows := p.Windows
+	if len(windows) == 0 {
+		windows = DefaultBurnRateWindows
+	}
+	objective := p.Objective
+	if objective == 0 {
+		objective = DefaultErrorBudgetObjective
+	}
+	b := &errorBudget{budget: 1 - objective}
+	for _, w := range windows {
+		b.rules = append(b.rules, burnRule{
+			long:  newRolling(DetectionWindow{Length: w.Long}),
+			short: newRolling(DetectionWindow{Length: w.Short}),
+			rate:  w.BurnRate,
+		})
+	}
+	return b
+}
+
+func (b *errorBudget) observe(now time.Time, success bool, f failure) {
+	for _, r := range b.rules {
+		r.long.observe(now, success, f)
+		r.short.observe(now, success, f)
+	}
+}
+
+// burnRate returns the burn rate of the budget with counts.
+func (b *errorBudget) burnRate(counts Counts) float64 {
+	if counts.Requests == 0 {
+		return 0
+	}
+	failureRate := float64(counts.TotalFailures) / float64(counts.Requests)
+	if b.budget <= 0 {
+		// any failure exceeds an objective of 100%
+		if failureRate > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return failureRate / b.budget
+}
+
+// exceeded reports whether the burn rates over both windows of any rule reached its rate at now.
+// Each window must hold at least minimumRequests requests.
+func (b *errorBudget) exceeded(now time.Time, minimumRequests uint32) bool {
+	for _, r := range b.rules {
+		long, short := r.long.counts(now), r.short.counts(now)
+		if long.Requests == 0 || short.Requests == 0 ||
+			long.Requests < minimumRequests || short.Requests < minimumRequests {
+			continue
+		}
+		if b.burnRate(long) >= r.rate && b.burnRate(short) >= r.rate {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *errorBudget) reset() {
+	for _, r := range b.rules {
+		r.long.reset()
+		r.short.reset()
+	}
+}
+
+// tripOnErrorBudget opens the closed CircuitBreaker if the error budget of ErrorBudgetPolicy burns too fast.
+func (cb *CircuitBreaker) tripOnErrorBudget(now time.Time) {
+	if cb.budget != nil && cb.throttle == nil && cb.budget.exceeded(now, cb.minimumRequests) {
+		cb.setState(StateOpen, now, ReasonBurnRate)
+	}
+}
+
+// BurnRates returns the burn rates of the error budget over the Long and the Short window
+// of each BurnRateWindow of the CircuitBreaker, or nil if it has no ErrorBudgetPolicy.
+func (cb *CircuitBreaker) BurnRates() [][2]float64 {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.budget == nil {
+		return nil
+	}
+	now := cb.clock.Now()
+	cb.currentState(now)
+	rates := make([][2]float64, len(cb.budget.rules))
+	for i, r := range cb.budget.rules {
+		rates[i] = [2]float64{cb.budget.burnRate(r.long.counts(now)), cb.budget.burnRate(r.short.counts(now))}
+	}
+	return rates
+}
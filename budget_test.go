@@ -0,0 +1,87 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBudgetCB(clock Clock) *CircuitBreaker {
+	return NewCircuitBreaker(Settings{
+		Clock:           clock,
+		ReadyToTrip:     func(counts Counts) bool { return false },
+		MinimumRequests: 20,
+		ErrorBudget: &ErrorBudgetPolicy{
+			Objective: 0.99,
+			Windows:   []BurnRateWindow{{Long: time.Hour, Short: 5 * time.Minute, BurnRate: 10}},
+		},
+	})
+}
+
+func TestErrorBudget(t *testing.T) {
+	clock := newFakeClock()
+	var reason TripReason
+	cb := newBudgetCB(clock)
+	cb.onStateChange2 = func(name string, from State, to State, counts Counts, r TripReason) {
+		reason = r
+	}
+
+	for i := 0; i < 90; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	for i := 0; i < 9; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	rates := cb.BurnRates()
+	assert.InDelta(t, 9.0/99/0.01, rates[0][0], 1e-6)
+	assert.InDelta(t, 9.0/99/0.01, rates[0][1], 1e-6)
+
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, ReasonBurnRate, reason)
+
+	// closing resets the windows
+	cb.Reset()
+	assert.Equal(t, [][2]float64{{0, 0}}, cb.BurnRates())
+}
+
+func TestErrorBudgetShortWindow(t *testing.T) {
+	clock := newFakeClock()
+	cb := newBudgetCB(clock)
+
+	// too few requests to trip
+	for i := 0; i < 15; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+
+	// the failures burned the budget in the long window, but no longer burn it in the short one
+	clock.Advance(10 * time.Minute)
+	for i := 0; i < 30; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestErrorBudgetDefaults(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{ErrorBudget: &ErrorBudgetPolicy{}})
+	assert.Len(t, cb.BurnRates(), len(DefaultBurnRateWindows))
+	assert.Nil(t, NewCircuitBreaker(Settings{}).BurnRates())
+
+	// the default objective trips on a failure rate of 6 times its budget over 6 hours
+	for i := 0; i < 200; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
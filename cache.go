@@ -0,0 +1,120 @@
+package gobreaker
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CachePolicy configures the cache of the last successful results of ExecuteCached,
+// for graceful degradation through stale reads.
+//
+// MaxStaleness is the maximum age of a cached result that ExecuteCached serves.
+// If MaxStaleness is less than or equal to 0, the cached results never expire.
+//
+// Size is the maximum number of keys in the cache. The least recently used key is evicted
+// to make room for a new one. If Size is less than or equal to 0, the cache is not bounded.
+//
+// ServeOnFailure makes ExecuteCached serve the cached result also when the request fails,
+// not only when the CircuitBreaker rejects it.
+type CachePolicy struct {
+	MaxStaleness   time.Duration
+	Size           int
+	ServeOnFailure bool
+}
+
+// Staleness tells whether ExecuteCached served a cached result instead of the result of the request.
+// Age is the time since the cached result was produced, and Err is the rejection or the failure
+// that the cached result replaces.
+type Staleness struct {
+	Stale bool
+	Age   time.Duration
+	Err   error
+}
+
+// resultCache holds the last successful result of each key in LRU order.
+type resultCache struct {
+	policy CachePolicy
+
+	mutex   sync.Mutex
+	lru     *list.List // of *cacheEntry, the most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key    string
+	result interface{}
+	at     time.Time
+}
+
+func newResultCache(p CachePolicy) *resultCache {
+	return &resultCache{policy: p, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// store caches result as the last successful result of key at now.
+func (c *resultCache) store(key string, result interface{}, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		entry.result, entry.at = result, now
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: result, at: now})
+	if c.policy.Size > 0 && c.lru.Len() > c.policy.Size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// load returns the cached result of key and its age at now, if it is not older than MaxStaleness.
+func (c *resultCache) load(key string, now time.Time) (interface{}, time.Duration, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	entry := e.Value.(*cacheEntry)
+	age := now.Sub(entry.at)
+	if c.policy.MaxStaleness > 0 && age > c.policy.MaxStaleness {
+		return nil, 0, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.result, age, true
+}
+
+// ExecuteCached is like Execute, but caches the last successful result of req for key according
+// to Settings.Cache. If the CircuitBreaker rejects the request, or if the request fails and
+// ServeOnFailure is set, ExecuteCached returns the cached result of key instead, with a nil error
+// and a Staleness that tells its age and the error it replaces. If there is no cached result
+// fresh enough, the error is returned as usual.
+//
+// The cached results are shared by the callers, so req should return a value that is not modified.
+func (cb *CircuitBreaker) ExecuteCached(key string, req func() (interface{}, error)) (interface{}, Staleness, error) {
+	result, err := cb.Execute(req)
+	if cb.cache == nil {
+		return result, Staleness{}, err
+	}
+
+	now := cb.clock.Now()
+	if !IsRejection(err) {
+		outcome := cb.classify(result, err)
+		if outcome == OutcomeSuccess {
+			cb.cache.store(key, result, now)
+		}
+		if outcome != OutcomeFailure || !cb.cache.policy.ServeOnFailure {
+			return result, Staleness{}, err
+		}
+	}
+
+	cached, age, ok := cb.cache.load(key, now)
+	if !ok {
+		return result, Staleness{}, err
+	}
+	return cached, Staleness{Stale: true, Age: age, Err: err}, nil
+}
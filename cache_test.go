@@ -0,0 +1,84 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteCached(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clock,
+		ReadyToTrip: tripAfter(1),
+		Cache:       &CachePolicy{MaxStaleness: time.Minute},
+	})
+	value := func(v string) func() (interface{}, error) {
+		return func() (interface{}, error) { return v, nil }
+	}
+
+	result, staleness, err := cb.ExecuteCached("a", value("fresh"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", result)
+	assert.Equal(t, Staleness{}, staleness)
+
+	// failures are returned as is without ServeOnFailure
+	result, staleness, err = cb.ExecuteCached("a", func() (interface{}, error) { return nil, errors.New("fail") })
+	assert.EqualError(t, err, "fail")
+	assert.Nil(t, result)
+	assert.False(t, staleness.Stale)
+	assert.Equal(t, StateOpen, cb.State())
+
+	clock.Advance(10 * time.Second)
+	result, staleness, err = cb.ExecuteCached("a", value("unused"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", result)
+	assert.True(t, staleness.Stale)
+	assert.Equal(t, 10*time.Second, staleness.Age)
+	assert.True(t, errors.Is(staleness.Err, ErrOpenState))
+
+	_, staleness, err = cb.ExecuteCached("b", value("unused"))
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.False(t, staleness.Stale)
+
+	// the cached result expires after MaxStaleness
+	clock.Advance(51 * time.Second)
+	cb.Trip()
+	_, _, err = cb.ExecuteCached("a", value("unused"))
+	assert.True(t, errors.Is(err, ErrOpenState))
+}
+
+func TestExecuteCachedOnFailure(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock: clock,
+		Cache: &CachePolicy{Size: 1, ServeOnFailure: true},
+	})
+	fail := func() (interface{}, error) { return nil, errors.New("fail") }
+
+	_, _, err := cb.ExecuteCached("a", func() (interface{}, error) { return 1, nil })
+	assert.NoError(t, err)
+	clock.Advance(time.Hour)
+	result, staleness, err := cb.ExecuteCached("a", fail)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+	assert.Equal(t, Staleness{Stale: true, Age: time.Hour, Err: errors.New("fail")}, staleness)
+
+	// b evicts a
+	_, _, err = cb.ExecuteCached("b", func() (interface{}, error) { return 2, nil })
+	assert.NoError(t, err)
+	_, _, err = cb.ExecuteCached("a", fail)
+	assert.EqualError(t, err, "fail")
+}
+
+func TestExecuteCachedWithoutCache(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: tripAfter(1)})
+	_, _, err := cb.ExecuteCached("a", func() (interface{}, error) { return 1, nil })
+	assert.NoError(t, err)
+	cb.Trip()
+	_, staleness, err := cb.ExecuteCached("a", succeedFunc)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.False(t, staleness.Stale)
+}
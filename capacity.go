@@ -0,0 +1,33 @@
+package gobreaker
+
+import (
+	"math"
+	"time"
+)
+
+// observeRate records the request rate of the closed state that ends at now with counts,
+// for MaxRequestsRatio. The rate is measured over at least a second so that a trip right
+// after the start of a generation doesn't inflate it.
+func (cb *CircuitBreaker) observeRate(counts Counts, now time.Time) {
+	elapsed := now.Sub(cb.genStart)
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+	cb.tripRate = float64(counts.Requests) / elapsed.Seconds()
+}
+
+// halfOpenMax returns the maximum number of requests in the half-open state,
+// MaxRequests scaled to the traffic observed before the trip if MaxRequestsRatio is set.
+func (cb *CircuitBreaker) halfOpenMax() uint32 {
+	if cb.maxRequestsRatio <= 0 {
+		return cb.maxRequests
+	}
+	n := math.Ceil(cb.maxRequestsRatio * cb.tripRate)
+	if n <= float64(cb.maxRequests) {
+		return cb.maxRequests
+	}
+	if n >= math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(n)
+}
@@ -0,0 +1,55 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxRequestsRatio(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		MaxRequests:      2,
+		MaxRequestsRatio: 0.5,
+		Timeout:          time.Minute,
+		Clock:            clock,
+		ReadyToTrip:      func(counts Counts) bool { return counts.ConsecutiveFailures >= 10 },
+	})
+
+	// 100 requests in 10 seconds before the trip
+	for i := 0; i < 90; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	clock.Advance(10 * time.Second)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, 10.0, cb.tripRate)
+
+	clock.Advance(time.Minute + time.Nanosecond)
+	tscb := &TwoStepCircuitBreaker{cb}
+	for i := 0; i < 5; i++ {
+		_, err := tscb.Allow()
+		assert.Nil(t, err)
+	}
+	_, err := tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+}
+
+func TestHalfOpenMax(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{MaxRequests: 3})
+	cb.tripRate = 100
+	assert.Equal(t, uint32(3), cb.halfOpenMax())
+
+	cb.maxRequestsRatio = 0.01
+	assert.Equal(t, uint32(3), cb.halfOpenMax())
+	cb.maxRequestsRatio = 0.05
+	assert.Equal(t, uint32(5), cb.halfOpenMax())
+
+	// a trip right after the start of a generation is measured over a second
+	cb.observeRate(Counts{Requests: 20}, cb.genStart.Add(time.Millisecond))
+	assert.Equal(t, 20.0, cb.tripRate)
+}
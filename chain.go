@@ -0,0 +1,239 @@
+package gobreaker
+
+import (
+	"context"
+	"strings"
+)
+
+// admission is a request admitted by a CircuitBreaker of a ChainBreaker, whose outcome is yet to be reported.
+type admission struct {
+	cb     *CircuitBreaker // classifies the outcome
+	report func(outcome Outcome, f failure)
+}
+
+func (a admission) finish(result interface{}, err error) {
+	outcome := a.cb.classify(result, err)
+	a.report(outcome, a.cb.failureOf(outcome, err))
+}
+
+func (a admission) finishPanic(v interface{}) {
+	outcome, f, _ := a.cb.recoverPanic(v)
+	a.report(outcome, f)
+}
+
+// cancel releases the request without counting it.
+func (a admission) cancel() {
+	a.report(OutcomeIgnore, failure{})
+}
+
+// admitter is a Breaker that admits a request before it runs, so that a ChainBreaker
+// can ask all of its Breakers before running the request.
+type admitter interface {
+	admit(ctx context.Context) (admission, error)
+}
+
+func (cb *CircuitBreaker) admit(ctx context.Context) (admission, error) {
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return admission{}, err
+	}
+	return admission{cb: cb, report: func(outcome Outcome, f failure) {
+		cb.finishRequest(ctx, generation, start, outcome, f)
+	}}, nil
+}
+
+func (tscb *TwoStepCircuitBreaker) admit(ctx context.Context) (admission, error) {
+	return tscb.cb.admit(ctx)
+}
+
+func (c *ChildBreaker) admit(ctx context.Context) (admission, error) {
+	finish, err := c.allow()
+	if err != nil {
+		return admission{}, err
+	}
+	return admission{cb: c.parent, report: finish}, nil
+}
+
+// ChainBreaker combines several Breakers, e.g. the CircuitBreakers of an endpoint, of its host
+// and of the whole service, so that a request goes through all of them at once.
+// It is created by Chain or ChainAny.
+//
+// The CircuitBreakers, TwoStepCircuitBreakers and ChildBreakers of a ChainBreaker admit
+// a request before it runs, and each of them classifies its outcome with its own Settings.
+// A request that one of them rejects is not counted by the others, unlike when Execute calls are nested.
+// The Breakers of other types, including ChainBreakers, can only admit a request by running it:
+// they run it with Execute after the others admitted it, and a request that they reject
+// is not counted by the others either.
+type ChainBreaker struct {
+	breakers []Breaker
+	any      bool
+}
+
+// Chain returns a ChainBreaker that admits a request only if all of breakers admit it,
+// and reports its outcome to all of them. A rejected request fails with the first rejection.
+func Chain(breakers ...Breaker) *ChainBreaker {
+	return &ChainBreaker{breakers: breakers}
+}
+
+// ChainAny returns a ChainBreaker that admits a request if any of breakers admits it,
+// and reports its outcome to those that admitted it. A request rejected by all of them
+// fails with the first rejection.
+func ChainAny(breakers ...Breaker) *ChainBreaker {
+	return &ChainBreaker{breakers: breakers, any: true}
+}
+
+// Name returns the names of the Breakers, joined by "+" for Chain and by "|" for ChainAny.
+func (c *ChainBreaker) Name() string {
+	sep := "+"
+	if c.any {
+		sep = "|"
+	}
+	names := make([]string, len(c.breakers))
+	for i, b := range c.breakers {
+		names[i] = b.Name()
+	}
+	return strings.Join(names, sep)
+}
+
+// State returns the state in which the ChainBreaker is: for Chain, the state of the Breaker
+// that admits the fewest requests, and for ChainAny, that of the Breaker that admits the most.
+// The open states admit the fewest requests, then the half-open state, then the others.
+func (c *ChainBreaker) State() State {
+	var state State
+	for i, b := range c.breakers {
+		s := b.State()
+		if i == 0 || c.any && admits(s) > admits(state) || !c.any && admits(s) < admits(state) {
+			state = s
+		}
+	}
+	return state
+}
+
+// admits ranks states by how many requests they admit.
+func admits(s State) int {
+	switch s {
+	case StateOpen, StateForcedOpen:
+		return 0
+	case StateHalfOpen:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Counts returns the Counts of the first Breaker, usually the most specific one.
+func (c *ChainBreaker) Counts() Counts {
+	if len(c.breakers) == 0 {
+		return Counts{}
+	}
+	return c.breakers[0].Counts()
+}
+
+// Execute runs req if the ChainBreaker admits it, and returns an error instantly otherwise.
+// If req panics, the panic is reported to the Breakers as a failure and raised again.
+func (c *ChainBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	return c.ExecuteContext(context.Background(), func(context.Context) (interface{}, error) {
+		return req()
+	})
+}
+
+// ExecuteContext is like Execute but passes ctx to the request and to the CircuitBreakers.
+func (c *ChainBreaker) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	result, _, err := c.execute(ctx, req)
+	return result, err
+}
+
+// execute is ExecuteContext, which also returns the index of the Breaker whose admission
+// rejected the request for Chain, or -1.
+func (c *ChainBreaker) execute(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (result interface{}, rejectedBy int, err error) {
+	var admissions []admission
+	var others []Breaker
+	var rejection error
+	for i, b := range c.breakers {
+		a, ok := b.(admitter)
+		if !ok {
+			others = append(others, b)
+			continue
+		}
+		adm, err := a.admit(ctx)
+		if err != nil {
+			if rejection == nil {
+				rejection = err
+			}
+			if c.any {
+				continue
+			}
+			for _, adm := range admissions {
+				adm.cancel()
+			}
+			return nil, i, err
+		}
+		admissions = append(admissions, adm)
+	}
+
+	ran := false
+	run := func() (interface{}, error) {
+		ran = true
+		return req(ctx)
+	}
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		v := recover()
+		for _, adm := range admissions {
+			if v == nil {
+				adm.cancel() // runtime.Goexit
+			} else {
+				adm.finishPanic(v)
+			}
+		}
+		if v != nil {
+			panic(v)
+		}
+	}()
+
+	switch {
+	case !c.any:
+		result, err = runAll(others, run)
+	case len(admissions) > 0 || len(others) == 0 && rejection == nil:
+		result, err = run()
+	default:
+		result, err = runAny(others, run, &ran, rejection)
+	}
+	finished = true
+
+	for _, adm := range admissions {
+		if ran {
+			adm.finish(result, err)
+		} else {
+			adm.cancel()
+		}
+	}
+	return result, -1, err
+}
+
+// runAll runs req through all of others, nesting their Execute calls.
+func runAll(others []Breaker, req func() (interface{}, error)) (interface{}, error) {
+	for i := len(others) - 1; i >= 0; i-- {
+		b, next := others[i], req
+		req = func() (interface{}, error) { return b.Execute(next) }
+	}
+	return req()
+}
+
+// runAny runs req through the first of others that admits it, or returns rejection if none does.
+// ran tells whether req has run.
+func runAny(others []Breaker, req func() (interface{}, error), ran *bool, rejection error) (interface{}, error) {
+	for _, b := range others {
+		result, err := b.Execute(req)
+		if *ran {
+			return result, err
+		}
+		if rejection == nil {
+			rejection = err
+		}
+	}
+	return nil, rejection
+}
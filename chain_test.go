@@ -0,0 +1,162 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tripAfter(failures uint32) func(counts Counts) bool {
+	return func(counts Counts) bool { return counts.ConsecutiveFailures >= failures }
+}
+
+// fakeBreaker is a Breaker of another package, which rejects the requests while open is set.
+type fakeBreaker struct {
+	name     string
+	open     bool
+	requests int
+}
+
+func (b *fakeBreaker) Name() string   { return b.name }
+func (b *fakeBreaker) Counts() Counts { return Counts{Requests: uint32(b.requests)} }
+
+func (b *fakeBreaker) State() State {
+	if b.open {
+		return StateOpen
+	}
+	return StateClosed
+}
+
+func (b *fakeBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	if b.open {
+		return nil, &RejectionError{Name: b.name, State: StateOpen, Err: ErrOpenState}
+	}
+	b.requests++
+	return req()
+}
+
+func TestChain(t *testing.T) {
+	endpoint := NewCircuitBreaker(Settings{Name: "endpoint", ReadyToTrip: tripAfter(1)})
+	host := NewTwoStepCircuitBreaker(Settings{Name: "host", ReadyToTrip: tripAfter(2)})
+	global := NewCircuitBreaker(Settings{Name: "global"})
+	chain := Chain(endpoint, host, global)
+	assert.Equal(t, "endpoint+host+global", chain.Name())
+
+	_, err := chain.Execute(succeedFunc)
+	assert.NoError(t, err)
+	result, err := chain.Execute(func() (interface{}, error) { return errors.New("x"), errors.New("fail") })
+	assert.Equal(t, errors.New("x"), result)
+	assert.EqualError(t, err, "fail")
+	assert.Equal(t, StateOpen, endpoint.State())
+	assert.Equal(t, StateClosed, host.State())
+	assert.Equal(t, StateOpen, chain.State())
+
+	_, err = chain.Execute(succeedFunc)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	for _, counts := range []Counts{host.Counts(), global.Counts()} {
+		assert.Equal(t, uint32(2), counts.Requests)
+		assert.Equal(t, uint32(1), counts.TotalSuccesses)
+		assert.Equal(t, uint32(1), counts.TotalFailures)
+	}
+	assert.Equal(t, endpoint.Counts(), chain.Counts())
+}
+
+func TestChainReleasesRejectedRequests(t *testing.T) {
+	endpoint := NewCircuitBreaker(Settings{Name: "endpoint"})
+	host := NewCircuitBreaker(Settings{Name: "host", ReadyToTrip: tripAfter(1)})
+	fake := &fakeBreaker{name: "fake"}
+	chain := Chain(endpoint, host, fake)
+
+	assert.Nil(t, fail(host))
+	_, err := chain.Execute(succeedFunc)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Equal(t, Counts{}, endpoint.Counts())
+
+	host.Reset()
+	fake.open = true
+	_, err = chain.Execute(succeedFunc)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Equal(t, Counts{}, endpoint.Counts())
+	assert.Equal(t, Counts{}, host.Counts())
+
+	fake.open = false
+	_, err = chain.Execute(succeedFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), endpoint.Counts().TotalSuccesses)
+	assert.Equal(t, 1, fake.requests)
+}
+
+func TestChainChild(t *testing.T) {
+	parent := NewCircuitBreaker(Settings{Name: "parent"})
+	child := parent.NewChild(ChildSettings{Name: "child", ReadyToTrip: func(counts Counts) bool { return true }})
+	other := NewCircuitBreaker(Settings{Name: "other"})
+	chain := Chain(other, child)
+
+	_, err := chain.Execute(func() (interface{}, error) { return nil, errors.New("fail") })
+	assert.EqualError(t, err, "fail")
+	assert.Equal(t, StateOpen, child.State())
+	assert.Equal(t, uint32(1), parent.Counts().TotalFailures)
+
+	_, err = chain.Execute(succeedFunc)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Equal(t, uint32(1), other.Counts().Requests)
+	assert.Equal(t, uint32(1), other.Counts().TotalFailures)
+}
+
+func TestChainPanic(t *testing.T) {
+	a := NewCircuitBreaker(Settings{Name: "a"})
+	b := NewCircuitBreaker(Settings{Name: "b", PanicHandler: RecoverPanics})
+	chain := Chain(a, b)
+
+	assert.PanicsWithValue(t, "oops", func() {
+		chain.Execute(func() (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, uint32(1), a.Counts().TotalFailures)
+	assert.Equal(t, uint32(1), b.Counts().TotalFailures)
+}
+
+func TestChainAny(t *testing.T) {
+	primary := NewCircuitBreaker(Settings{Name: "primary", ReadyToTrip: tripAfter(1)})
+	secondary := NewCircuitBreaker(Settings{Name: "secondary", ReadyToTrip: tripAfter(1)})
+	chain := ChainAny(primary, secondary)
+	assert.Equal(t, "primary|secondary", chain.Name())
+
+	assert.Nil(t, fail(primary))
+	assert.Equal(t, StateClosed, chain.State())
+	_, err := chain.Execute(succeedFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), secondary.Counts().TotalSuccesses)
+	assert.Equal(t, uint32(0), primary.Counts().Requests)
+
+	assert.Nil(t, fail(secondary))
+	assert.Equal(t, StateOpen, chain.State())
+	_, err = chain.Execute(succeedFunc)
+	var rejection *RejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "primary", rejection.Name)
+}
+
+func TestChainAnyOthers(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "cb", ReadyToTrip: tripAfter(1)})
+	closed := &fakeBreaker{name: "closed"}
+	open := &fakeBreaker{name: "open", open: true}
+
+	_, err := ChainAny(cb, open, closed).Execute(succeedFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, closed.requests)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+
+	cb.Trip()
+	_, err = ChainAny(cb, open, closed).Execute(succeedFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed.requests)
+
+	_, err = ChainAny(cb, open).Execute(succeedFunc)
+	var rejection *RejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "cb", rejection.Name)
+
+	_, err = ChainAny().Execute(succeedFunc)
+	assert.NoError(t, err)
+}
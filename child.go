@@ -0,0 +1,248 @@
+package gobreaker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChildSettings configures a ChildBreaker:
+//
+// Name is the name of the ChildBreaker.
+// If Name is empty, the name of the parent CircuitBreaker is used.
+//
+// MaxRequests is the maximum number of requests allowed to pass through
+// when the ChildBreaker is half-open.
+// If MaxRequests is 0, the ChildBreaker allows only 1 request.
+//
+// Timeout is the period of the open state of the ChildBreaker.
+// If Timeout is less than or equal to 0, the Timeout of the parent is used.
+//
+// ReadyToTrip is called with a copy of the parent's Counts, including the failure, whenever a request
+// of the ChildBreaker fails while it is closed, even if that failure trips the parent.
+// If ReadyToTrip returns true, the ChildBreaker will be placed into the open state.
+// If ReadyToTrip is nil, default ReadyToTrip is used.
+//
+// OnStateChange is called whenever the state of the ChildBreaker changes.
+type ChildSettings struct {
+	Name          string
+	MaxRequests   uint32
+	Timeout       time.Duration
+	ReadyToTrip   func(counts Counts) bool
+	OnStateChange func(name string, from State, to State)
+}
+
+// ChildBreaker is a lightweight view of a CircuitBreaker.
+// The requests of a ChildBreaker are counted in the Counts of its parent,
+// but the ChildBreaker trips on its own condition, usually a stricter one than the parent's.
+// A request is rejected when either the ChildBreaker or its parent rejects it.
+type ChildBreaker struct {
+	parent        *CircuitBreaker
+	name          string
+	maxRequests   uint32
+	timeout       time.Duration
+	readyToTrip   func(counts Counts) bool
+	onStateChange func(name string, from State, to State)
+
+	mutex      sync.Mutex
+	state      State
+	generation uint64
+	requests   uint32
+	successes  uint32
+	expiry     time.Time
+}
+
+// NewChild returns a new ChildBreaker of the CircuitBreaker configured with the given ChildSettings.
+func (cb *CircuitBreaker) NewChild(st ChildSettings) *ChildBreaker {
+	c := &ChildBreaker{
+		parent:        cb,
+		name:          st.Name,
+		maxRequests:   st.MaxRequests,
+		timeout:       st.Timeout,
+		readyToTrip:   st.ReadyToTrip,
+		onStateChange: st.OnStateChange,
+	}
+	if c.name == "" {
+		c.name = cb.name
+	}
+	if c.maxRequests == 0 {
+		c.maxRequests = 1
+	}
+	if c.timeout <= 0 {
+		c.timeout = cb.timeout
+	}
+	if c.readyToTrip == nil {
+		c.readyToTrip = defaultReadyToTrip
+	}
+	return c
+}
+
+// Name returns the name of the ChildBreaker.
+func (c *ChildBreaker) Name() string {
+	return c.name
+}
+
+// Parent returns the CircuitBreaker the ChildBreaker was created from.
+func (c *ChildBreaker) Parent() *CircuitBreaker {
+	return c.parent
+}
+
+// State returns the current state of the ChildBreaker itself, regardless of the state of its parent.
+func (c *ChildBreaker) State() State {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	state, _ := c.currentState(c.parent.clock.Now())
+	return state
+}
+
+// Execute runs the given request if both the ChildBreaker and its parent accept it.
+// Execute behaves like CircuitBreaker.Execute otherwise.
+func (c *ChildBreaker) Execute(req func() (interface{}, error)) (result interface{}, err error) {
+	done, err := c.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := recover()
+		if e != nil {
+			outcome, f, perr := c.parent.recoverPanic(e)
+			done(outcome, f)
+			if perr == nil {
+				panic(e)
+			}
+			result, err = nil, perr
+		}
+	}()
+
+	result, err = req()
+	outcome := c.parent.classify(result, err)
+	done(outcome, c.parent.failureOf(outcome, err))
+	return result, err
+}
+
+// Allow checks if a new request can proceed, like TwoStepCircuitBreaker.Allow.
+// Only the first call of the callback counts.
+func (c *ChildBreaker) Allow() (done func(success bool), err error) {
+	finish, err := c.allow()
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) { finish(outcomeOf(success), plainFailure) }, nil
+}
+
+func (c *ChildBreaker) allow() (func(outcome Outcome, f failure), error) {
+	generation, err := c.beforeRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	parentGeneration, start, err := c.parent.beforeRequest(ctx)
+	if err != nil {
+		c.cancelRequest(generation)
+		return nil, err
+	}
+
+	var called uint32
+	return func(outcome Outcome, f failure) {
+		if !atomic.CompareAndSwapUint32(&called, 0, 1) {
+			return
+		}
+		// the parent's Counts with the failure are taken before the parent counts it,
+		// since a failure that trips the parent also resets its Counts.
+		var counts Counts
+		if outcome == OutcomeFailure {
+			counts = c.parent.Counts()
+			counts.onFailure(f)
+		}
+		c.parent.finishRequest(ctx, parentGeneration, start, outcome, f)
+		if outcome == OutcomeIgnore {
+			c.cancelRequest(generation)
+		} else {
+			c.afterRequest(generation, outcome == OutcomeSuccess, counts)
+		}
+	}, nil
+}
+
+func (c *ChildBreaker) beforeRequest() (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.parent.clock.Now()
+	state, generation := c.currentState(now)
+	if state == StateOpen {
+		return generation, &RejectionError{Name: c.name, State: state, RetryAfter: c.expiry.Sub(now), Err: ErrOpenState}
+	} else if state == StateHalfOpen {
+		if c.requests >= c.maxRequests {
+			return generation, &RejectionError{Name: c.name, State: state, Err: ErrTooManyRequests}
+		}
+		c.requests++
+	}
+	return generation, nil
+}
+
+// cancelRequest gives back the half-open slot of a request that the parent rejected or that was ignored.
+func (c *ChildBreaker) cancelRequest(before uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.generation == before && c.state == StateHalfOpen && c.requests > 0 {
+		c.requests--
+	}
+}
+
+func (c *ChildBreaker) afterRequest(before uint64, success bool, counts Counts) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.parent.clock.Now()
+	state, generation := c.currentState(now)
+	if generation != before {
+		return
+	}
+
+	switch state {
+	case StateClosed:
+		if !success && c.readyToTrip(counts) {
+			c.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if !success {
+			c.setState(StateOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= c.maxRequests {
+			c.setState(StateClosed, now)
+		}
+	}
+}
+
+func (c *ChildBreaker) currentState(now time.Time) (State, uint64) {
+	if c.state == StateOpen && c.expiry.Before(now) {
+		c.setState(StateHalfOpen, now)
+	}
+	return c.state, c.generation
+}
+
+func (c *ChildBreaker) setState(state State, now time.Time) {
+	if c.state == state {
+		return
+	}
+
+	prev := c.state
+	c.state = state
+	c.generation++
+	c.requests = 0
+	c.successes = 0
+	if state == StateOpen {
+		c.expiry = now.Add(c.timeout)
+	}
+
+	if c.onStateChange != nil {
+		c.onStateChange(c.name, prev, state)
+	}
+}
@@ -0,0 +1,130 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failChild(c *ChildBreaker) error {
+	msg := "fail"
+	_, err := c.Execute(func() (interface{}, error) { return nil, errors.New(msg) })
+	if err != nil && err.Error() == msg {
+		return nil
+	}
+	return err
+}
+
+func succeedChild(c *ChildBreaker) error {
+	_, err := c.Execute(func() (interface{}, error) { return nil, nil })
+	return err
+}
+
+func TestChildBreaker(t *testing.T) {
+	var changes []StateChange
+	parent := NewCircuitBreaker(Settings{Name: "db"})
+	child := parent.NewChild(ChildSettings{
+		Name:        "db/search",
+		MaxRequests: 2,
+		Timeout:     5 * time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		OnStateChange: func(name string, from State, to State) {
+			changes = append(changes, StateChange{name, from, to})
+		},
+	})
+	assert.Equal(t, "db/search", child.Name())
+	assert.Equal(t, parent, child.Parent())
+
+	// the failures of the parent's other requests count towards the child's condition
+	assert.Nil(t, fail(parent))
+	assert.Equal(t, StateClosed, child.State())
+	assert.Nil(t, failChild(child))
+	assert.Equal(t, StateOpen, child.State())
+	assert.Equal(t, StateClosed, parent.State())
+	assert.Equal(t, Counts{Requests: 2, TotalFailures: 2, ConsecutiveFailures: 2, TotalFailureWeight: 2, ConsecutiveFailureWeight: 2, FailuresByKind: FailureCounts{2}}, parent.Counts())
+
+	// the open child rejects its requests but the parent keeps serving its own
+	assert.True(t, errors.Is(succeedChild(child), ErrOpenState))
+	assert.Nil(t, succeed(parent))
+	assert.Equal(t, uint32(3), parent.Counts().Requests)
+
+	// the child becomes half-open after its own timeout
+	child.expiry = child.expiry.Add(-5 * time.Second)
+	assert.Equal(t, StateHalfOpen, child.State())
+	assert.Nil(t, succeedChild(child))
+	assert.Equal(t, StateHalfOpen, child.State())
+	assert.Nil(t, succeedChild(child))
+	assert.Equal(t, StateClosed, child.State())
+
+	assert.Equal(t, []StateChange{
+		{"db/search", StateClosed, StateOpen},
+		{"db/search", StateOpen, StateHalfOpen},
+		{"db/search", StateHalfOpen, StateClosed},
+	}, changes)
+}
+
+func TestChildBreakerHalfOpen(t *testing.T) {
+	parent := NewCircuitBreaker(Settings{})
+	child := parent.NewChild(ChildSettings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	assert.Equal(t, parent.Name(), child.Name())
+	assert.Equal(t, parent.timeout, child.timeout)
+
+	assert.Nil(t, failChild(child))
+	child.expiry = time.Time{}
+
+	done, err := child.Allow()
+	assert.Nil(t, err)
+	_, err = child.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+	done(false)
+	assert.Equal(t, StateOpen, child.State())
+}
+
+func TestChildBreakerParentRejects(t *testing.T) {
+	parent := NewCircuitBreaker(Settings{})
+	child := parent.NewChild(ChildSettings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+
+	assert.Nil(t, failChild(child))
+	child.expiry = time.Time{}
+	parent.Trip()
+
+	// the parent's rejection doesn't use up the child's half-open request
+	assert.True(t, errors.Is(succeedChild(child), ErrOpenState))
+	assert.Equal(t, StateHalfOpen, child.State())
+	assert.Equal(t, uint32(0), child.requests)
+
+	parent.Reset()
+	assert.Nil(t, succeedChild(child))
+	assert.Equal(t, StateClosed, child.State())
+}
+
+func TestChildBreakerParentTrips(t *testing.T) {
+	parent := NewCircuitBreaker(Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	child := parent.NewChild(ChildSettings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+
+	// the failure that trips the parent trips the child too
+	assert.Nil(t, fail(parent))
+	assert.Nil(t, failChild(child))
+	assert.Equal(t, StateOpen, parent.State())
+	assert.Equal(t, StateOpen, child.State())
+}
+
+func TestChildBreakerPanic(t *testing.T) {
+	parent := NewCircuitBreaker(Settings{})
+	child := parent.NewChild(ChildSettings{})
+
+	assert.Panics(t, func() {
+		child.Execute(func() (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1, TotalFailureWeight: 1, ConsecutiveFailureWeight: 1, FailuresByKind: FailureCounts{FailurePanic: 1}}, parent.Counts())
+}
@@ -0,0 +1,111 @@
+package gobreaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time to a CircuitBreaker.
+// A Clock other than SystemClock is mostly useful to test Interval and Timeout
+// deterministically, without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock that also provides the timers the CircuitBreaker waits on,
+// e.g. for the timeout of QueuePolicy and the interval of ProbePolicy.
+// If the Clock of a CircuitBreaker doesn't implement TimerClock, it uses timers of the time package.
+type TimerClock interface {
+	Clock
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a TimerClock, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the Timer fires.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing, like time.Timer.Stop.
+	Stop() bool
+}
+
+// SystemClock is the Clock that tells the time of the time package.
+var SystemClock TimerClock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// DefaultCoarseResolution is the resolution of a CoarseClock created with a resolution
+// less than or equal to 0.
+const DefaultCoarseResolution = time.Millisecond
+
+// CoarseClock is a TimerClock that reads a cached timestamp updated by a background ticker
+// every resolution, instead of calling time.Now on every request. It trades the precision
+// of Interval, Timeout and the latencies reported to Metrics for cheaper clock reads,
+// which matter for breakers that serve microsecond-level requests.
+// The Now of a CoarseClock carries no monotonic clock reading.
+type CoarseClock struct {
+	now  int64 // UnixNano, first to be 64-bit aligned for the atomic operations
+	done chan struct{}
+	once sync.Once
+}
+
+// NewCoarseClock returns a running CoarseClock. Stop must be called when it is no longer used.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	if resolution <= 0 {
+		resolution = DefaultCoarseResolution
+	}
+
+	c := &CoarseClock{now: time.Now().UnixNano(), done: make(chan struct{})}
+	ticker := time.NewTicker(resolution)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case t := <-ticker.C:
+				atomic.StoreInt64(&c.now, t.UnixNano())
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Now returns the cached time, which lags behind time.Now by up to the resolution.
+func (c *CoarseClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.now))
+}
+
+// NewTimer returns a timer of the time package.
+func (c *CoarseClock) NewTimer(d time.Duration) Timer {
+	return SystemClock.NewTimer(d)
+}
+
+// Stop stops updating the time. Now keeps returning the last cached time.
+func (c *CoarseClock) Stop() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// newTimer returns a Timer of the clock of the CircuitBreaker.
+func (cb *CircuitBreaker) newTimer(d time.Duration) Timer {
+	if clock, ok := cb.clock.(TimerClock); ok {
+		return clock.NewTimer(d)
+	}
+	return SystemClock.NewTimer(d)
+}
@@ -0,0 +1,174 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
+
+// fakeClock is a TimerClock whose time only moves by Advance.
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	c     chan time.Time
+	at    time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the time forward and fires the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.c <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+// Timers returns the number of pending timers.
+func (c *fakeClock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.timers)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func TestClock(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Interval: time.Minute,
+		Timeout:  30 * time.Second,
+		Clock:    clock,
+	})
+
+	assert.Nil(t, fail(cb))
+	clock.Advance(time.Minute)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+	clock.Advance(time.Nanosecond)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1}, cb.Counts())
+
+	cb.Trip()
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateOpen, cb.State())
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+}
+
+func TestClockTimers(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		HalfOpenQueue: &QueuePolicy{Size: 1, Timeout: time.Second},
+		Clock:         clock,
+	})
+	cb.mutex.Lock()
+	cb.setState(StateHalfOpen, clock.Now(), ReasonManual)
+	cb.mutex.Unlock()
+
+	_, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+
+	errs := make(chan error)
+	go func() { errs <- succeed(cb) }()
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Second)
+	assert.True(t, errors.Is(<-errs, ErrTooManyRequests))
+	assert.Equal(t, 0, clock.Timers())
+}
+
+func TestSystemClock(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Equal(t, SystemClock, cb.clock)
+
+	timer := cb.newTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+
+	cb = NewCircuitBreaker(Settings{Clock: clockFunc(time.Now)})
+	_, ok := cb.newTimer(time.Hour).(systemTimer)
+	assert.True(t, ok)
+}
+
+func TestCoarseClock(t *testing.T) {
+	clock := NewCoarseClock(0)
+	defer clock.Stop()
+
+	start := time.Now()
+	now := clock.Now()
+	assert.False(t, now.After(time.Now()))
+	assert.True(t, now.After(start.Add(-time.Second)))
+
+	for !clock.Now().After(now) {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Stop()
+	clock.Stop()
+	time.Sleep(5 * time.Millisecond)
+	now = clock.Now()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, now, clock.Now())
+
+	cb := NewCircuitBreaker(Settings{Clock: clock})
+	_, ok := cb.newTimer(time.Hour).(systemTimer)
+	assert.True(t, ok)
+}
@@ -0,0 +1,111 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// probeCall is a half-open probe of ExecuteCoalesced that other callers of the same key wait for.
+type probeCall struct {
+	done    chan struct{}
+	success bool
+	result  interface{}
+	err     error
+}
+
+// ExecuteCoalesced is like Execute, but coalesces the half-open probes by key, like singleflight:
+// while a probe for key is running in the half-open state, the other callers of key wait for it
+// instead of being rejected with ErrTooManyRequests. If the probe succeeds, they all return
+// its result and error, without running req nor counting a request. Otherwise they are admitted
+// or rejected as usual, e.g. rejected if the failed probe placed the CircuitBreaker back
+// into the open state.
+//
+// The result is shared by the callers, so req should return a value that is not modified,
+// e.g. in the cache-refill and idempotent read patterns. In the other states,
+// ExecuteCoalesced doesn't coalesce the requests. An empty key disables the coalescing.
+func (cb *CircuitBreaker) ExecuteCoalesced(key string, req func() (interface{}, error)) (interface{}, error) {
+	ctx := context.Background()
+	for {
+		call, running := cb.claimSharedProbe(key)
+		if running {
+			<-call.done
+			if call.success {
+				return call.result, call.err
+			}
+			continue
+		}
+
+		generation, start, err := cb.beforeRequest(ctx)
+		if err != nil {
+			cb.endSharedProbe(key, call, false, nil, nil)
+			return nil, err
+		}
+		return cb.runSharedProbe(ctx, key, call, generation, start, req)
+	}
+}
+
+// runSharedProbe runs req admitted in the given generation, and shares its result with
+// the callers waiting for call, if any.
+func (cb *CircuitBreaker) runSharedProbe(ctx context.Context, key string, call *probeCall, generation uint64, start time.Time,
+	req func() (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		e := recover()
+		if e != nil {
+			outcome, f, perr := cb.recoverPanic(e)
+			cb.finishRequest(ctx, generation, start, outcome, f)
+			cb.endSharedProbe(key, call, false, nil, nil)
+			if perr == nil {
+				panic(e)
+			}
+			result, err = nil, perr
+		}
+	}()
+
+	result, err = req()
+	outcome := cb.classify(result, err)
+	cb.finishRequest(ctx, generation, start, outcome, cb.failureOf(outcome, err))
+	cb.endSharedProbe(key, call, outcome == OutcomeSuccess, result, err)
+	return result, err
+}
+
+// claimSharedProbe returns the probe of key running in the half-open state, with running set.
+// If there is none, it registers a new probe of key for the caller and returns it,
+// or returns nil if the CircuitBreaker is not half-open.
+func (cb *CircuitBreaker) claimSharedProbe(key string) (call *probeCall, running bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if state, _ := cb.currentState(cb.clock.Now()); state != StateHalfOpen {
+		return nil, false
+	}
+	if call, ok := cb.probes[key]; ok {
+		return call, true
+	}
+
+	if cb.probes == nil {
+		cb.probes = make(map[string]*probeCall)
+	}
+	call = &probeCall{done: make(chan struct{})}
+	cb.probes[key] = call
+	return call, false
+}
+
+// endSharedProbe wakes the callers waiting for the probe call of key.
+func (cb *CircuitBreaker) endSharedProbe(key string, call *probeCall, success bool, result interface{}, err error) {
+	if call == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	if cb.probes[key] == call {
+		delete(cb.probes, key)
+	}
+	cb.mutex.Unlock()
+
+	call.success, call.result, call.err = success, result, err
+	close(call.done)
+}
@@ -0,0 +1,105 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type coalescedResult struct {
+	result interface{}
+	err    error
+}
+
+// runCoalesced starts a probe of key that blocks until release, then n more callers of key,
+// and returns the results of all of them and the number of requests that ran.
+func runCoalesced(cb *CircuitBreaker, key string, n int, release error) ([]coalescedResult, int) {
+	var mutex sync.Mutex
+	ran := 0
+	unblock := make(chan struct{})
+	req := func() (interface{}, error) {
+		mutex.Lock()
+		ran++
+		mutex.Unlock()
+		<-unblock
+		return "value", release
+	}
+
+	results := make([]coalescedResult, n+1)
+	var wg sync.WaitGroup
+	for i := 0; i <= n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := cb.ExecuteCoalesced(key, req)
+			results[i] = coalescedResult{result, err}
+		}(i)
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(unblock)
+	wg.Wait()
+	return results, ran
+}
+
+func TestExecuteCoalesced(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+	results, ran := runCoalesced(cb, "key", 3, nil)
+	assert.Equal(t, 1, ran)
+	for _, r := range results {
+		assert.Equal(t, coalescedResult{"value", nil}, r)
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, uint32(0), cb.Counts().Requests)
+	assert.Empty(t, cb.probes)
+
+	// no coalescing in the closed state
+	_, ran = runCoalesced(cb, "key", 2, nil)
+	assert.Equal(t, 3, ran)
+}
+
+func TestExecuteCoalescedFailure(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+	results, ran := runCoalesced(cb, "key", 2, errors.New("fail"))
+	assert.Equal(t, 1, ran)
+	assert.EqualError(t, results[0].err, "fail")
+	for _, r := range results[1:] {
+		assert.True(t, errors.Is(r.err, ErrOpenState))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestExecuteCoalescedKeys(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+	unblock := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := cb.ExecuteCoalesced("a", func() (interface{}, error) {
+			<-unblock
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	_, err := cb.ExecuteCoalesced("b", succeedFunc)
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+	_, err = cb.ExecuteCoalesced("", succeedFunc)
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+
+	close(unblock)
+	<-done
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestExecuteCoalescedPanic(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+	assert.Panics(t, func() {
+		cb.ExecuteCoalesced("key", func() (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Empty(t, cb.probes)
+}
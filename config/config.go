@@ -0,0 +1,196 @@
+// Package config builds the Settings of CircuitBreakers from a configuration file,
+// and keeps the CircuitBreakers of a Registry up to date while the file changes.
+//
+// A configuration file holds the defaults and the breakers by name:
+//
+//	{
+//	  "defaults": {"timeout": "30s", "consecutiveFailures": 5},
+//	  "breakers": {
+//	    "payments": {"maxRequests": 3, "failureRatio": 0.5, "minimumRequests": 20}
+//	  }
+//	}
+//
+// JSON is supported out of the box. Other formats are supported by registering their
+// decoder in Unmarshalers, e.g. for YAML:
+//
+//	config.Unmarshalers[".yaml"] = yaml.Unmarshal
+//	config.Unmarshalers[".yml"] = yaml.Unmarshal
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// Unmarshalers are the decoders of the configuration files by their extension.
+// The decoders must honor the json struct tags or encoding.TextUnmarshaler for Duration.
+var Unmarshalers = map[string]func(data []byte, v interface{}) error{
+	".json": json.Unmarshal,
+}
+
+// Duration is a time.Duration written like "1m30s" in a configuration file.
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Breaker is the configuration of a CircuitBreaker. Its fields have the same meaning
+// as the fields of gobreaker.Settings with the same names.
+//
+// ConsecutiveFailures and FailureRatio replace ReadyToTrip: the CircuitBreaker trips
+// once ConsecutiveFailures requests failed in a row, or once the ratio of the failures
+// reached FailureRatio. If both are 0, the default ReadyToTrip is used.
+type Breaker struct {
+	MaxRequests         uint32   `json:"maxRequests,omitempty" yaml:"maxRequests,omitempty"`
+	Interval            Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout             Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	ConsecutiveFailures uint32   `json:"consecutiveFailures,omitempty" yaml:"consecutiveFailures,omitempty"`
+	FailureRatio        float64  `json:"failureRatio,omitempty" yaml:"failureRatio,omitempty"`
+	MinimumRequests     uint32   `json:"minimumRequests,omitempty" yaml:"minimumRequests,omitempty"`
+	MaxConcurrent       int      `json:"maxConcurrent,omitempty" yaml:"maxConcurrent,omitempty"`
+}
+
+// File is the content of a configuration file.
+// The zero fields of a Breaker take their value from Defaults.
+type File struct {
+	Defaults Breaker            `json:"defaults" yaml:"defaults"`
+	Breakers map[string]Breaker `json:"breakers" yaml:"breakers"`
+}
+
+// withDefaults returns b with its zero fields set from d.
+func (b Breaker) withDefaults(d Breaker) Breaker {
+	if b.MaxRequests == 0 {
+		b.MaxRequests = d.MaxRequests
+	}
+	if b.Interval == 0 {
+		b.Interval = d.Interval
+	}
+	if b.Timeout == 0 {
+		b.Timeout = d.Timeout
+	}
+	if b.ConsecutiveFailures == 0 {
+		b.ConsecutiveFailures = d.ConsecutiveFailures
+	}
+	if b.FailureRatio == 0 {
+		b.FailureRatio = d.FailureRatio
+	}
+	if b.MinimumRequests == 0 {
+		b.MinimumRequests = d.MinimumRequests
+	}
+	if b.MaxConcurrent == 0 {
+		b.MaxConcurrent = d.MaxConcurrent
+	}
+	return b
+}
+
+// readyToTrip returns the ReadyToTrip of b, or nil for the default one.
+func (b Breaker) readyToTrip() func(counts gobreaker.Counts) bool {
+	if b.ConsecutiveFailures == 0 && b.FailureRatio == 0 {
+		return nil
+	}
+	return gobreaker.TripOn(b.ConsecutiveFailures, b.FailureRatio)
+}
+
+// Settings returns the Settings of the CircuitBreaker named name configured by b.
+func (b Breaker) Settings(name string) gobreaker.Settings {
+	return gobreaker.Settings{
+		Name:            name,
+		MaxRequests:     b.MaxRequests,
+		Interval:        time.Duration(b.Interval),
+		Timeout:         time.Duration(b.Timeout),
+		ReadyToTrip:     b.readyToTrip(),
+		MinimumRequests: b.MinimumRequests,
+		MaxConcurrent:   b.MaxConcurrent,
+	}
+}
+
+// Validate reports the first invalid Breaker of f.
+func (f *File) Validate() error {
+	for _, name := range f.names() {
+		b := f.Breakers[name].withDefaults(f.Defaults)
+		if b.FailureRatio < 0 || b.FailureRatio > 1 {
+			return fmt.Errorf("gobreaker/config: %s: failureRatio %v out of [0, 1]", name, b.FailureRatio)
+		}
+		if err := b.Settings(name).Validate(); err != nil {
+			return fmt.Errorf("gobreaker/config: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Settings returns the Settings of the breaker named name, and whether f configures it.
+// A breaker not in f is configured by Defaults.
+func (f *File) Settings(name string) (gobreaker.Settings, bool) {
+	b, ok := f.Breakers[name]
+	return b.withDefaults(f.Defaults).Settings(name), ok
+}
+
+func (f *File) names() []string {
+	names := make([]string, 0, len(f.Breakers))
+	for name := range f.Breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply creates the CircuitBreakers of f that are missing from r, and updates the thresholds
+// of the existing ones with UpdateSettings, without resetting their state.
+// Only MaxRequests, Interval, Timeout, the trip condition and MinimumRequests of
+// an existing CircuitBreaker change. The CircuitBreakers of r that f doesn't configure are kept.
+func (f *File) Apply(r *gobreaker.Registry) {
+	for _, name := range f.names() {
+		st, _ := f.Settings(name)
+		if cb, ok := r.Get(name); ok {
+			cb.UpdateSettings(st)
+			continue
+		}
+		r.GetOrCreate(name, st)
+	}
+}
+
+// Parse decodes and validates a configuration file of the given extension, e.g. ".json".
+func Parse(data []byte, ext string) (*File, error) {
+	unmarshal, ok := Unmarshalers[strings.ToLower(ext)]
+	if !ok {
+		return nil, fmt.Errorf("gobreaker/config: no decoder for %q files", ext)
+	}
+
+	f := new(File)
+	if err := unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("gobreaker/config: %v", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Load reads, decodes and validates the configuration file at path.
+// The decoder is chosen by the extension of path.
+func Load(path string) (*File, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gobreaker/config: %v", err)
+	}
+	return Parse(data, filepath.Ext(path))
+}
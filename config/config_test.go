@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `{
+	"defaults": {"timeout": "30s", "consecutiveFailures": 2},
+	"breakers": {
+		"payments": {"maxRequests": 3, "failureRatio": 0.5, "minimumRequests": 4},
+		"search": {"interval": "1m"}
+	}
+}`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(testConfig), ".json")
+	assert.Nil(t, err)
+
+	st, ok := f.Settings("payments")
+	assert.True(t, ok)
+	assert.Equal(t, "payments", st.Name)
+	assert.Equal(t, uint32(3), st.MaxRequests)
+	assert.Equal(t, 30*time.Second, st.Timeout)
+	assert.Equal(t, uint32(4), st.MinimumRequests)
+	assert.True(t, st.ReadyToTrip(gobreaker.Counts{ConsecutiveFailures: 2}))
+	assert.True(t, st.ReadyToTrip(gobreaker.Counts{Requests: 4, TotalFailures: 2, ConsecutiveFailures: 1}))
+	assert.False(t, st.ReadyToTrip(gobreaker.Counts{Requests: 4, TotalFailures: 1, ConsecutiveFailures: 1}))
+
+	st, _ = f.Settings("search")
+	assert.Equal(t, time.Minute, st.Interval)
+	assert.Equal(t, 30*time.Second, st.Timeout)
+
+	st, ok = f.Settings("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, st.Timeout)
+
+	assert.Nil(t, (&File{}).Validate())
+	assert.Nil(t, Breaker{}.Settings("default").ReadyToTrip)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte(testConfig), ".toml")
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"defaults": {"timeout": "soon"}}`), ".json")
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"breakers": {"a": {"failureRatio": 2}}}`), ".json")
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"breakers": {"a": {"maxConcurrent": -1}}}`), ".json")
+	assert.Error(t, err)
+	_, err = Load("testdata/missing.json")
+	assert.Error(t, err)
+}
+
+func TestApply(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	existing := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "payments"})
+	existing.Trip()
+	r.Register(existing)
+
+	f, err := Parse([]byte(testConfig), ".JSON")
+	assert.Nil(t, err)
+	f.Apply(r)
+
+	cb, ok := r.Get("payments")
+	assert.True(t, ok)
+	assert.Equal(t, existing, cb)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	_, ok = r.Get("search")
+	assert.True(t, ok)
+}
+
+func TestDuration(t *testing.T) {
+	text, err := Duration(90 * time.Second).MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "1m30s", string(text))
+}
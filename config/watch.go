@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// DefaultWatchInterval is the period between the checks of Watch if its interval is not positive.
+const DefaultWatchInterval = 5 * time.Second
+
+// Watch applies the configuration file at path to r, and then checks the file every interval
+// and applies it again whenever its content changes, until the returned function is called.
+//
+// Watch returns an error if the file can't be applied at first. Later, a file that can't be
+// read or is invalid is reported to onError, if not nil, and the previous configuration stays in effect.
+func Watch(path string, r *gobreaker.Registry, interval time.Duration, onError func(err error)) (stop func(), err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data, filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+	f.Apply(r)
+
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				data = reload(path, data, r, onError)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}, nil
+}
+
+// reload applies the file at path to r if its content differs from seen, the content
+// read last time, and returns the content read this time. An invalid content is reported once.
+func reload(path string, seen []byte, r *gobreaker.Registry, onError func(err error)) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if onError != nil {
+			onError(err)
+		}
+		return seen
+	}
+	if bytes.Equal(data, seen) {
+		return seen
+	}
+
+	f, err := Parse(data, filepath.Ext(path))
+	if err != nil {
+		if onError != nil {
+			onError(err)
+		}
+		return data
+	}
+	f.Apply(r)
+	return data
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFile replaces the file at path atomically, so that Watch never reads it half-written.
+func writeFile(t *testing.T, path string, data string) {
+	assert.Nil(t, ioutil.WriteFile(path+".tmp", []byte(data), 0600))
+	assert.Nil(t, os.Rename(path+".tmp", path))
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobreaker")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "breakers.json")
+	writeFile(t, path, `{"breakers": {"a": {}}}`)
+
+	r := gobreaker.NewRegistry()
+	var mutex sync.Mutex
+	var errs []error
+	stop, err := Watch(path, r, time.Millisecond, func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, err)
+	})
+	assert.Nil(t, err)
+	defer stop()
+	_, ok := r.Get("a")
+	assert.True(t, ok)
+
+	writeFile(t, path, `{"breakers": {"a": {}, "b": {}}}`)
+	for r.Len() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	writeFile(t, path, `{"breakers": {"c": {"timeout": "never"}}}`)
+	for {
+		mutex.Lock()
+		n := len(errs)
+		mutex.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	stop()
+	stop()
+
+	mutex.Lock()
+	assert.Len(t, errs, 1)
+	mutex.Unlock()
+	assert.Equal(t, 2, r.Len())
+
+	_, err = Watch(filepath.Join(dir, "missing.json"), r, 0, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultConsumerPause is the Pause of a ConsumerPolicy whose Pause is 0.
+const DefaultConsumerPause = time.Second
+
+// MessageHandler handles a message of a queue or a stream, e.g. a *sarama.ConsumerMessage
+// or a *nats.Msg. The consumer acknowledges the message if it returns nil.
+type MessageHandler func(ctx context.Context, msg interface{}) error
+
+// ConsumerPolicy configures Consumer.
+//
+// Pause is how long the consumer waits before offering a message rejected by the CircuitBreaker
+// again, if the rejection doesn't tell when the CircuitBreaker allows a new attempt,
+// e.g. in the half-open state. If Pause is 0, DefaultConsumerPause is used.
+//
+// DeadLetter, if not nil, is called with a message rejected by the CircuitBreaker and the rejection,
+// e.g. to route it to a dead letter queue, instead of waiting for the CircuitBreaker to admit it.
+// The error returned by DeadLetter is returned by the handler of the message.
+type ConsumerPolicy struct {
+	Pause      time.Duration
+	DeadLetter func(ctx context.Context, msg interface{}, err error) error
+}
+
+// Consumer returns a MessageHandler that runs handle through the CircuitBreaker with the context
+// of the message, like ExecuteContext. An error returned by handle counts towards tripping the
+// CircuitBreaker as usual.
+//
+// While the CircuitBreaker rejects the messages, the returned handler blocks, which pauses
+// the consumption of a consumer that handles one message at a time, instead of failing
+// the messages in a tight loop and draining the queue into retries. It offers the message
+// again when the CircuitBreaker allows a new attempt, and returns ctx.Err() if ctx is done first.
+// If p.DeadLetter is set, the rejected messages go to it instead.
+func (cb *CircuitBreaker) Consumer(handle MessageHandler, p ConsumerPolicy) MessageHandler {
+	if p.Pause <= 0 {
+		p.Pause = DefaultConsumerPause
+	}
+
+	return func(ctx context.Context, msg interface{}) error {
+		for {
+			_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+				return nil, handle(ctx, msg)
+			})
+
+			var rejection *RejectionError
+			if !errors.As(err, &rejection) {
+				return err
+			}
+			if p.DeadLetter != nil {
+				return p.DeadLetter(ctx, msg, err)
+			}
+
+			pause := rejection.RetryAfter
+			if pause <= 0 {
+				pause = p.Pause
+			}
+			timer := cb.newTimer(pause)
+			select {
+			case <-timer.C():
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+	}
+}
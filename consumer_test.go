@@ -0,0 +1,88 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newConsumerCB(clock Clock) *CircuitBreaker {
+	return NewCircuitBreaker(Settings{
+		Timeout:     10 * time.Second,
+		Clock:       clock,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+}
+
+func TestConsumerPauses(t *testing.T) {
+	clock := newFakeClock()
+	cb := newConsumerCB(clock)
+	var handled []interface{}
+	handle := cb.Consumer(func(ctx context.Context, msg interface{}) error {
+		handled = append(handled, msg)
+		if msg == "poison" {
+			return errors.New("dependency down")
+		}
+		return nil
+	}, ConsumerPolicy{})
+	ctx := context.Background()
+
+	assert.EqualError(t, handle(ctx, "poison"), "dependency down")
+	assert.Equal(t, StateOpen, cb.State())
+
+	done := make(chan error)
+	go func() { done <- handle(ctx, "next") }()
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("the message was handled while the breaker was open")
+	default:
+	}
+
+	clock.Advance(11 * time.Second)
+	assert.NoError(t, <-done)
+	assert.Equal(t, []interface{}{"poison", "next"}, handled)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestConsumerCanceled(t *testing.T) {
+	clock := newFakeClock()
+	cb := newConsumerCB(clock)
+	cb.Trip()
+	handle := cb.Consumer(func(ctx context.Context, msg interface{}) error {
+		t.Fatal("the message was handled while the breaker was open")
+		return nil
+	}, ConsumerPolicy{Pause: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- handle(ctx, "msg") }()
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestConsumerDeadLetter(t *testing.T) {
+	cb := newConsumerCB(newFakeClock())
+	var dead []interface{}
+	handle := cb.Consumer(func(ctx context.Context, msg interface{}) error {
+		return errors.New("dependency down")
+	}, ConsumerPolicy{DeadLetter: func(ctx context.Context, msg interface{}, err error) error {
+		assert.True(t, errors.Is(err, ErrOpenState))
+		dead = append(dead, msg)
+		return nil
+	}})
+	ctx := context.Background()
+
+	assert.Error(t, handle(ctx, 1))
+	assert.NoError(t, handle(ctx, 2))
+	assert.NoError(t, handle(ctx, 3))
+	assert.Equal(t, []interface{}{2, 3}, dead)
+}
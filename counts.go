@@ -0,0 +1,45 @@
+package gobreaker
+
+// FailureRate returns the ratio of TotalFailures to Requests, or 0 if there are no requests.
+func (c Counts) FailureRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.TotalFailures) / float64(c.Requests)
+}
+
+// SuccessRate returns the ratio of TotalSuccesses to Requests, or 0 if there are no requests.
+// The requests in flight are neither successes nor failures, so SuccessRate and FailureRate
+// may not add up to 1.
+func (c Counts) SuccessRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.TotalSuccesses) / float64(c.Requests)
+}
+
+// HasVolume reports whether there are at least minRequests requests, so that a rate
+// is significant, e.g. not a 100% failure rate out of a single request.
+func (c Counts) HasVolume(minRequests uint32) bool {
+	return c.Requests > 0 && c.Requests >= minRequests
+}
+
+// TripOnFailureRate returns a ReadyToTrip that trips once the FailureRate reaches rate
+// with at least minRequests requests.
+func TripOnFailureRate(rate float64, minRequests uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.HasVolume(minRequests) && counts.FailureRate() >= rate
+	}
+}
+
+// TripOn returns a ReadyToTrip that trips after consecutive failures in a row,
+// or once the FailureRate reaches ratio. A zero threshold is not checked.
+func TripOn(consecutive uint32, ratio float64) func(counts Counts) bool {
+	byRate := TripOnFailureRate(ratio, 0)
+	return func(counts Counts) bool {
+		if consecutive > 0 && counts.ConsecutiveFailures >= consecutive {
+			return true
+		}
+		return ratio > 0 && byRate(counts)
+	}
+}
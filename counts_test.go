@@ -0,0 +1,43 @@
+package gobreaker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountsRates(t *testing.T) {
+	var counts Counts
+	assert.Equal(t, 0.0, counts.FailureRate())
+	assert.Equal(t, 0.0, counts.SuccessRate())
+	assert.False(t, counts.HasVolume(0))
+
+	counts = Counts{Requests: 4, TotalSuccesses: 1, TotalFailures: 2}
+	assert.Equal(t, 0.5, counts.FailureRate())
+	assert.Equal(t, 0.25, counts.SuccessRate())
+	assert.True(t, counts.HasVolume(4))
+	assert.False(t, counts.HasVolume(5))
+}
+
+func TestTripOnFailureRate(t *testing.T) {
+	readyToTrip := TripOnFailureRate(0.5, 4)
+	assert.False(t, readyToTrip(Counts{Requests: 1, TotalFailures: 1}))
+	assert.False(t, readyToTrip(Counts{Requests: 4, TotalFailures: 1}))
+	assert.True(t, readyToTrip(Counts{Requests: 4, TotalFailures: 2}))
+
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: TripOnFailureRate(0.5, 0)})
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestTripOn(t *testing.T) {
+	readyToTrip := TripOn(3, 0.5)
+	assert.False(t, readyToTrip(Counts{}))
+	assert.False(t, readyToTrip(Counts{Requests: 5, TotalFailures: 2, ConsecutiveFailures: 2}))
+	assert.True(t, readyToTrip(Counts{Requests: 10, TotalFailures: 3, ConsecutiveFailures: 3}))
+	assert.True(t, readyToTrip(Counts{Requests: 4, TotalFailures: 2, ConsecutiveFailures: 1}))
+
+	assert.False(t, TripOn(0, 0.5)(Counts{Requests: 10, TotalFailures: 4, ConsecutiveFailures: 4}))
+	assert.False(t, TripOn(3, 0)(Counts{Requests: 2, TotalFailures: 2, ConsecutiveFailures: 2}))
+}
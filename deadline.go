@@ -0,0 +1,122 @@
+package gobreaker
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultDeadlineSamples is the Samples of a DeadlinePolicy whose Samples is 0.
+	DefaultDeadlineSamples = 100
+	// DefaultDeadlineMinSamples is the MinSamples of a DeadlinePolicy whose MinSamples is 0.
+	DefaultDeadlineMinSamples = 10
+)
+
+// DeadlinePolicy rejects upfront the requests of ExecuteContext whose context deadline leaves
+// less time than the requests usually take, since they would likely time out, wasting the capacity
+// of the dependency and counting failures that say nothing about its health. Such a request is
+// rejected with ErrDeadlineTooShort and is not counted, like an ignored request.
+//
+// Quantile is the quantile of the latency of the latest successful requests that the deadline must
+// leave, e.g. 0.5 for the median or 0.95. If Quantile is not between 0 and 1, 0.5 is used.
+//
+// Samples is the number of the latest successful requests whose latency is kept.
+// If Samples is less than or equal to 0, DefaultDeadlineSamples is used.
+//
+// MinSamples is the number of latencies needed before any request is rejected.
+// If MinSamples is less than or equal to 0, DefaultDeadlineMinSamples is used.
+type DeadlinePolicy struct {
+	Quantile   float64
+	Samples    int
+	MinSamples int
+}
+
+// latencySamples holds the latencies of the latest successful requests in a ring.
+type latencySamples struct {
+	quantile   float64
+	minSamples int
+	samples    []time.Duration
+	next       int           // index of the next sample
+	full       bool          // whether samples has wrapped around
+	cached     time.Duration // quantile of the samples, if valid
+	valid      bool
+}
+
+func newLatencySamples(p DeadlinePolicy) *latencySamples {
+	if p.Quantile <= 0 || p.Quantile > 1 {
+		p.Quantile = 0.5
+	}
+	if p.Samples <= 0 {
+		p.Samples = DefaultDeadlineSamples
+	}
+	if p.MinSamples <= 0 {
+		p.MinSamples = DefaultDeadlineMinSamples
+	}
+	return &latencySamples{
+		quantile:   p.Quantile,
+		minSamples: p.MinSamples,
+		samples:    make([]time.Duration, p.Samples),
+	}
+}
+
+func (l *latencySamples) observe(latency time.Duration) {
+	l.samples[l.next] = latency
+	l.next++
+	if l.next == len(l.samples) {
+		l.next, l.full = 0, true
+	}
+	l.valid = false
+}
+
+func (l *latencySamples) len() int {
+	if l.full {
+		return len(l.samples)
+	}
+	return l.next
+}
+
+// value returns the quantile of the samples, or false if there are fewer than minSamples of them.
+func (l *latencySamples) value() (time.Duration, bool) {
+	n := l.len()
+	if n == 0 || n < l.minSamples {
+		return 0, false
+	}
+	if !l.valid {
+		sorted := append([]time.Duration(nil), l.samples[:n]...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		i := int(l.quantile*float64(n)+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		l.cached, l.valid = sorted[i], true
+	}
+	return l.cached, true
+}
+
+// deadlineTooShort reports whether the deadline of ctx leaves less than the latency quantile
+// of the DeadlinePolicy at now. It must be called with cb.mutex held.
+func (cb *CircuitBreaker) deadlineTooShort(ctx context.Context, now time.Time) bool {
+	if cb.latencies == nil {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	latency, ok := cb.latencies.value()
+	return ok && deadline.Sub(now) < latency
+}
+
+// LatencyQuantile returns the quantile of the latency of the latest successful requests
+// according to Settings.Deadline, or false if the CircuitBreaker has no DeadlinePolicy
+// or hasn't observed enough requests yet.
+func (cb *CircuitBreaker) LatencyQuantile() (time.Duration, bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.latencies == nil {
+		return 0, false
+	}
+	return cb.latencies.value()
+}
@@ -0,0 +1,71 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlinePolicy(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Clock: clock, Deadline: &DeadlinePolicy{}})
+	run := func(ctx context.Context, latency time.Duration) error {
+		_, err := cb.ExecuteContext(ctx, func(context.Context) (interface{}, error) {
+			clock.Advance(latency)
+			return nil, nil
+		})
+		return err
+	}
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	withDeadline := func(d time.Duration) context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(d))
+		cancels = append(cancels, cancel)
+		return ctx
+	}
+
+	for i := 1; i <= 9; i++ {
+		assert.NoError(t, run(withDeadline(time.Millisecond), time.Duration(i)*10*time.Millisecond))
+	}
+	_, ok := cb.LatencyQuantile()
+	assert.False(t, ok)
+	assert.NoError(t, run(context.Background(), 100*time.Millisecond))
+	latency, ok := cb.LatencyQuantile()
+	assert.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, latency)
+
+	err := run(withDeadline(40*time.Millisecond), 0)
+	assert.True(t, errors.Is(err, ErrDeadlineTooShort))
+	assert.True(t, IsRejection(err))
+	assert.Equal(t, uint32(10), cb.Counts().Requests)
+
+	assert.NoError(t, run(withDeadline(60*time.Millisecond), 0))
+	assert.NoError(t, run(context.Background(), 0))
+	assert.Equal(t, uint32(12), cb.Counts().Requests)
+}
+
+func TestLatencySamples(t *testing.T) {
+	l := newLatencySamples(DeadlinePolicy{Quantile: 0.95, Samples: 4, MinSamples: 2})
+	l.observe(time.Second)
+	_, ok := l.value()
+	assert.False(t, ok)
+	l.observe(2 * time.Second)
+	v, _ := l.value()
+	assert.Equal(t, 2*time.Second, v)
+
+	for i := 0; i < 4; i++ {
+		l.observe(time.Millisecond)
+	}
+	v, _ = l.value()
+	assert.Equal(t, time.Millisecond, v)
+
+	_, ok = NewCircuitBreaker(Settings{}).LatencyQuantile()
+	assert.False(t, ok)
+}
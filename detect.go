@@ -0,0 +1,152 @@
+package gobreaker
+
+import "time"
+
+// DefaultWindowBuckets is the number of buckets of a DetectionWindow whose Buckets is 0.
+const DefaultWindowBuckets = 10
+
+// DetectionWindow is a sliding window over which the closed CircuitBreaker evaluates
+// a trip condition, independently of Interval, e.g. a 10s window that catches a short spike
+// of failures and a 5m window that catches a slow burn.
+//
+// Length is the length of the window. The window slides by Length / Buckets,
+// so that the requests older than Length are forgotten bucket by bucket.
+// If Buckets is 0, DefaultWindowBuckets is used.
+//
+// ReadyToTrip is called with the Counts of the requests that finished within the window
+// whenever a request fails in the closed state. Requests in these Counts is the number of
+// the finished requests, not including the ignored ones. ConsecutiveSuccesses and
+// ConsecutiveFailures are not forgotten with the buckets.
+// If ReadyToTrip is nil, the window never trips the CircuitBreaker.
+type DetectionWindow struct {
+	Length      time.Duration
+	Buckets     int
+	ReadyToTrip func(counts Counts) bool
+}
+
+// rolling counts the requests of a DetectionWindow in a ring of buckets.
+type rolling struct {
+	window    DetectionWindow
+	width     time.Duration // length of a bucket
+	buckets   []Counts
+	head      int       // index of the current bucket
+	headStart time.Time // start of the current bucket
+	run       Counts    // consecutive counts
+}
+
+func newRolling(w DetectionWindow) *rolling {
+	if w.Buckets <= 0 {
+		w.Buckets = DefaultWindowBuckets
+	}
+	width := w.Length / time.Duration(w.Buckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &rolling{window: w, width: width, buckets: make([]Counts, w.Buckets)}
+}
+
+// advance moves the current bucket to now, clearing the buckets that left the window.
+func (r *rolling) advance(now time.Time) {
+	if r.headStart.IsZero() {
+		r.headStart = now
+		return
+	}
+	n := now.Sub(r.headStart) / r.width
+	if n <= 0 {
+		return
+	}
+	r.headStart = r.headStart.Add(n * r.width)
+	if n > time.Duration(len(r.buckets)) {
+		n = time.Duration(len(r.buckets))
+	}
+	for ; n > 0; n-- {
+		r.head = (r.head + 1) % len(r.buckets)
+		r.buckets[r.head] = Counts{}
+	}
+}
+
+// observe counts a request that finished at now.
+func (r *rolling) observe(now time.Time, success bool, f failure) {
+	r.advance(now)
+	c := &r.buckets[r.head]
+	c.onRequest()
+	r.run.onRequest()
+	if success {
+		c.onSuccess()
+		r.run.onSuccess()
+	} else {
+		c.onFailure(f)
+		r.run.onFailure(f)
+	}
+}
+
+// counts returns the Counts of the window at now.
+func (r *rolling) counts(now time.Time) Counts {
+	r.advance(now)
+	var sum Counts
+	for _, c := range r.buckets {
+		sum.Requests += c.Requests
+		sum.TotalSuccesses += c.TotalSuccesses
+		sum.TotalFailures += c.TotalFailures
+		sum.TotalFailureWeight += c.TotalFailureWeight
+		for k, n := range c.FailuresByKind {
+			sum.FailuresByKind[k] += n
+		}
+	}
+	sum.ConsecutiveSuccesses = r.run.ConsecutiveSuccesses
+	sum.ConsecutiveFailures = r.run.ConsecutiveFailures
+	sum.ConsecutiveFailureWeight = r.run.ConsecutiveFailureWeight
+	return sum
+}
+
+func (r *rolling) reset() {
+	for i := range r.buckets {
+		r.buckets[i] = Counts{}
+	}
+	r.headStart = time.Time{}
+	r.run = Counts{}
+}
+
+// observeWindows counts a request that finished at now in the closed state in every DetectionWindow.
+func (cb *CircuitBreaker) observeWindows(now time.Time, success bool, f failure) {
+	for _, r := range cb.windows {
+		r.observe(now, success, f)
+	}
+}
+
+// windowsReadyToTrip reports whether any DetectionWindow is ready to trip at now.
+func (cb *CircuitBreaker) windowsReadyToTrip(now time.Time) bool {
+	if cb.throttle != nil {
+		return false
+	}
+	for _, r := range cb.windows {
+		if r.window.ReadyToTrip == nil {
+			continue
+		}
+		counts := r.counts(now)
+		if counts.Requests >= cb.minimumRequests && r.window.ReadyToTrip(counts) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cb *CircuitBreaker) resetWindows() {
+	for _, r := range cb.windows {
+		r.reset()
+	}
+}
+
+// WindowCounts returns the Counts of the DetectionWindows of the CircuitBreaker, in the order of Settings.Windows.
+func (cb *CircuitBreaker) WindowCounts() []Counts {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	cb.currentState(now)
+	counts := make([]Counts, len(cb.windows))
+	for i, r := range cb.windows {
+		counts[i] = r.counts(now)
+	}
+	return counts
+}
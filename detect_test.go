@@ -0,0 +1,88 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDualWindowCB(clock Clock) *CircuitBreaker {
+	return NewCircuitBreaker(Settings{
+		Clock:       clock,
+		ReadyToTrip: func(counts Counts) bool { return false },
+		Windows: []DetectionWindow{
+			{Length: 10 * time.Second, ReadyToTrip: func(counts Counts) bool {
+				return counts.TotalFailures >= 5
+			}},
+			{Length: 5 * time.Minute, ReadyToTrip: func(counts Counts) bool {
+				return counts.Requests >= 20 && float64(counts.TotalFailures)/float64(counts.Requests) >= 0.2
+			}},
+		},
+	})
+}
+
+func TestDetectionWindowSpike(t *testing.T) {
+	clock := newFakeClock()
+	cb := newDualWindowCB(clock)
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+
+	// the failures leave the burst window
+	clock.Advance(11 * time.Second)
+	counts := cb.WindowCounts()
+	assert.Equal(t, uint32(0), counts[0].TotalFailures)
+	assert.Equal(t, uint32(4), counts[1].TotalFailures)
+	assert.Equal(t, uint32(4), counts[1].ConsecutiveFailures)
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// closing resets the windows
+	cb.Reset()
+	assert.Equal(t, []Counts{{}, {}}, cb.WindowCounts())
+}
+
+func TestDetectionWindowSlowBurn(t *testing.T) {
+	clock := newFakeClock()
+	cb := newDualWindowCB(clock)
+
+	burn := func() {
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, succeed(cb))
+		}
+		assert.Nil(t, fail(cb))
+		clock.Advance(30 * time.Second)
+	}
+	for i := 0; i < 4; i++ {
+		burn()
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	burn()
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestDetectionWindowExpiry(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock:   clock,
+		Windows: []DetectionWindow{{Length: time.Second, Buckets: 2, ReadyToTrip: func(counts Counts) bool { return false }}},
+	})
+
+	assert.Nil(t, succeed(cb))
+	clock.Advance(600 * time.Millisecond)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, uint32(2), cb.WindowCounts()[0].Requests)
+
+	clock.Advance(600 * time.Millisecond)
+	assert.Equal(t, uint32(1), cb.WindowCounts()[0].Requests)
+	clock.Advance(time.Hour)
+	assert.Equal(t, uint32(0), cb.WindowCounts()[0].Requests)
+}
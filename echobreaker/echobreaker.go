@@ -0,0 +1,104 @@
+// Package echobreaker guards the routes of an Echo server with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on Echo.
+package echobreaker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sony/gobreaker"
+	"github.com/sony/gobreaker/httpbreaker"
+)
+
+// StatusError is the error with which a failed response is reported to the Breaker,
+// so that IsSuccessful and Classify can tell the statuses apart.
+// Err is the error returned by the handler, if any.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("echobreaker: response status %d: %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("echobreaker: response status %d", e.Code)
+}
+
+// Unwrap returns Err.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// classifier returns the Classifier of the middleware: any of classifiers, or FailOn5xx if there are none.
+func classifier(classifiers []httpbreaker.Classifier) httpbreaker.Classifier {
+	if len(classifiers) == 0 {
+		return httpbreaker.FailOn5xx
+	}
+	return httpbreaker.Any(classifiers...)
+}
+
+// Middleware returns an echo.MiddlewareFunc that runs the handlers through cb,
+// e.g. for a group. A response counts as a failure if any of classifiers fails it,
+// or if its status is 500 or more when there are no classifiers. The status of a handler
+// that returns an error is that of an *echo.HTTPError, and 500 for other errors.
+// A request rejected by cb fails with an *echo.HTTPError of 503 Service Unavailable
+// whose Internal is the rejection, and a Retry-After header if the time until the next attempt is known.
+func Middleware(cb gobreaker.Breaker, classifiers ...httpbreaker.Classifier) echo.MiddlewareFunc {
+	classify := classifier(classifiers)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return serve(c, cb, next, classify)
+		}
+	}
+}
+
+// PerRoute returns an echo.MiddlewareFunc like Middleware that runs each route through its own
+// CircuitBreaker, registered in r under the method and the path of the route,
+// e.g. "GET /users/:id", and created with st if it doesn't exist yet.
+// The requests that match no route are not guarded.
+func PerRoute(r *gobreaker.Registry, st gobreaker.Settings, classifiers ...httpbreaker.Classifier) echo.MiddlewareFunc {
+	classify := classifier(classifiers)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Path()
+			if route == "" {
+				return next(c)
+			}
+			return serve(c, r.GetOrCreate(c.Request().Method+" "+route, st), next, classify)
+		}
+	}
+}
+
+func serve(c echo.Context, cb gobreaker.Breaker, next echo.HandlerFunc, classify httpbreaker.Classifier) error {
+	var handlerErr error
+	_, err := cb.Execute(func() (interface{}, error) {
+		handlerErr = next(c)
+		resp := &http.Response{StatusCode: statusOf(c, handlerErr), Header: c.Response().Header(), Request: c.Request()}
+		if classify(resp, nil) {
+			return nil, &StatusError{Code: resp.StatusCode, Err: handlerErr}
+		}
+		return nil, nil
+	})
+	if gobreaker.IsRejection(err) {
+		if retryAfter := gobreaker.RetryAfterHeader(err); retryAfter != "" {
+			c.Response().Header().Set("Retry-After", retryAfter)
+		}
+		return echo.NewHTTPError(http.StatusServiceUnavailable).SetInternal(err)
+	}
+	return handlerErr
+}
+
+// statusOf returns the status of the response to c once the error handler of Echo has handled err.
+func statusOf(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	if he, ok := err.(*echo.HTTPError); ok {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
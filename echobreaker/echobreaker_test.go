@@ -0,0 +1,108 @@
+package echobreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sony/gobreaker"
+	"github.com/sony/gobreaker/httpbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	e := echo.New()
+	api := e.Group("/api", Middleware(cb))
+	api.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	api.GET("/missing", func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound) })
+	api.GET("/broken", func(c echo.Context) error { return c.NoContent(http.StatusBadGateway) })
+	api.GET("/error", func(c echo.Context) error { return errors.New("boom") })
+
+	assert.Equal(t, http.StatusOK, get(e, "/api/ok").Code)
+	assert.Equal(t, http.StatusNotFound, get(e, "/api/missing").Code)
+	assert.Equal(t, gobreaker.Counts{
+		Requests: 2, TotalSuccesses: 2, ConsecutiveSuccesses: 2,
+	}, cb.Counts())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusBadGateway, get(e, "/api/broken").Code)
+		assert.Equal(t, http.StatusInternalServerError, get(e, "/api/error").Code)
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	w := get(e, "/api/ok")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestMiddlewareRejection(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return true },
+	})
+	var err error
+	h := Middleware(cb)(func(c echo.Context) error { return errors.New("boom") })
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		err = h(c)
+		return err
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, get(e, "/").Code)
+	assert.EqualError(t, err, "boom")
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(e, "/").Code)
+	he, ok := err.(*echo.HTTPError)
+	assert.True(t, ok)
+	assert.True(t, errors.Is(he.Internal, gobreaker.ErrOpenState))
+}
+
+func TestPerRoute(t *testing.T) {
+	registry := gobreaker.NewRegistry()
+	e := echo.New()
+	e.Use(PerRoute(registry, gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	}))
+	e.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/broken", func(c echo.Context) error { return echo.NewHTTPError(http.StatusServiceUnavailable) })
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(e, "/broken").Code)
+	w := get(e, "/broken")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, get(e, "/ok").Code)
+	assert.Equal(t, http.StatusNotFound, get(e, "/unknown").Code)
+
+	assert.Equal(t, 2, registry.Len())
+	cb, ok := registry.Get("GET /broken")
+	assert.True(t, ok)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+}
+
+func TestMiddlewareClassifiers(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	e := echo.New()
+	e.GET("/limited", func(c echo.Context) error { return echo.NewHTTPError(http.StatusTooManyRequests) },
+		Middleware(cb, httpbreaker.FailOnStatuses(http.StatusTooManyRequests)))
+
+	assert.Equal(t, http.StatusTooManyRequests, get(e, "/limited").Code)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	assert.Equal(t, http.StatusServiceUnavailable, get(e, "/limited").Code)
+}
+
+func TestStatusError(t *testing.T) {
+	err := &StatusError{Code: 500, Err: errors.New("boom")}
+	assert.Equal(t, "echobreaker: response status 500: boom", err.Error())
+	assert.Equal(t, "boom", errors.Unwrap(err).Error())
+	assert.Equal(t, "echobreaker: response status 502", (&StatusError{Code: 502}).Error())
+}
@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/echobreaker
+
+go 1.13
+
+require (
+	github.com/labstack/echo/v4 v4.1.17
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.4.0
+)
+
+replace github.com/sony/gobreaker => ../
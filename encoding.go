@@ -0,0 +1,188 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalText implements encoding.TextMarshaler, so that a State is encoded by its name,
+// e.g. "half-open", in JSON and other text formats.
+func (s State) MarshalText() ([]byte, error) {
+	switch s {
+	case StateClosed, StateHalfOpen, StateOpen, StateForcedOpen, StateForcedClosed, StateDisabled:
+		return []byte(s.String()), nil
+	default:
+		return nil, fmt.Errorf("gobreaker: unknown state %d", int(s))
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the names returned by String.
+func (s *State) UnmarshalText(text []byte) error {
+	state, err := parseState(string(text))
+	if err != nil || len(text) == 0 {
+		return fmt.Errorf("gobreaker: unknown state %q", text)
+	}
+	*s = state
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so that a FailureKind is encoded by its name.
+func (k FailureKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so that a TripReason is encoded by its name.
+func (r TripReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+type countsJSON struct {
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"totalSuccesses"`
+	TotalFailures        uint32 `json:"totalFailures"`
+	ConsecutiveSuccesses uint32 `json:"consecutiveSuccesses"`
+	ConsecutiveFailures  uint32 `json:"consecutiveFailures"`
+
+	TotalFailureWeight       uint32            `json:"totalFailureWeight,omitempty"`
+	ConsecutiveFailureWeight uint32            `json:"consecutiveFailureWeight,omitempty"`
+	FailuresByKind           map[string]uint32 `json:"failuresByKind,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. FailuresByKind is encoded as an object
+// keyed by the names of the kinds, without the kinds that didn't fail.
+func (c Counts) MarshalJSON() ([]byte, error) {
+	v := countsJSON{
+		Requests:             c.Requests,
+		TotalSuccesses:       c.TotalSuccesses,
+		TotalFailures:        c.TotalFailures,
+		ConsecutiveSuccesses: c.ConsecutiveSuccesses,
+		ConsecutiveFailures:  c.ConsecutiveFailures,
+
+		TotalFailureWeight:       c.TotalFailureWeight,
+		ConsecutiveFailureWeight: c.ConsecutiveFailureWeight,
+	}
+	for kind, n := range c.FailuresByKind {
+		if n > 0 {
+			if v.FailuresByKind == nil {
+				v.FailuresByKind = make(map[string]uint32)
+			}
+			v.FailuresByKind[FailureKind(kind).String()] = n
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Unknown fields are ignored. Without the weights, every failure weighs 1,
+// and without the kinds, every failure is of FailureOther.
+func (c *Counts) UnmarshalJSON(data []byte) error {
+	var v countsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*c = Counts{
+		Requests:             v.Requests,
+		TotalSuccesses:       v.TotalSuccesses,
+		TotalFailures:        v.TotalFailures,
+		ConsecutiveSuccesses: v.ConsecutiveSuccesses,
+		ConsecutiveFailures:  v.ConsecutiveFailures,
+
+		TotalFailureWeight:       v.TotalFailureWeight,
+		ConsecutiveFailureWeight: v.ConsecutiveFailureWeight,
+	}
+	var kinds uint32
+	for kind := FailureKind(0); kind < numFailureKinds; kind++ {
+		c.FailuresByKind[kind] = v.FailuresByKind[kind.String()]
+		kinds += c.FailuresByKind[kind]
+	}
+	if kinds < c.TotalFailures {
+		c.FailuresByKind[FailureOther] += c.TotalFailures - kinds
+	}
+	if c.TotalFailureWeight < c.TotalFailures {
+		c.TotalFailureWeight = c.TotalFailures
+	}
+	if c.ConsecutiveFailureWeight < c.ConsecutiveFailures {
+		c.ConsecutiveFailureWeight = c.ConsecutiveFailures
+	}
+	return nil
+}
+
+// StatusSnapshot is the status of a CircuitBreaker at a point in time, for debug endpoints
+// and logs. It is encoded in JSON with the names of the state and the failure kinds,
+// with TimeInState formatted like time.Duration.String, and without Expiry if it is zero.
+//
+// StateSince is the time of the latest transition, i.e. since when the CircuitBreaker
+// has been in State, and TimeInState is the time elapsed since then.
+type StatusSnapshot struct {
+	Name        string        `json:"name"`
+	State       State         `json:"state"`
+	Counts      Counts        `json:"counts"`
+	Generation  uint64        `json:"generation"`
+	Expiry      time.Time     `json:"expiry"`
+	StateSince  time.Time     `json:"stateSince"`
+	TimeInState time.Duration `json:"timeInState"`
+}
+
+type statusJSON struct {
+	status
+	Expiry      *time.Time `json:"expiry,omitempty"`
+	TimeInState string     `json:"timeInState"`
+}
+
+type status StatusSnapshot
+
+// MarshalJSON implements json.Marshaler.
+func (s StatusSnapshot) MarshalJSON() ([]byte, error) {
+	v := statusJSON{status: status(s), TimeInState: s.TimeInState.String()}
+	if !s.Expiry.IsZero() {
+		v.Expiry = &s.Expiry
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *StatusSnapshot) UnmarshalJSON(data []byte) error {
+	var v statusJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*s = StatusSnapshot(v.status)
+	if v.Expiry != nil {
+		s.Expiry = *v.Expiry
+	}
+	if v.TimeInState != "" {
+		d, err := time.ParseDuration(v.TimeInState)
+		if err != nil {
+			return fmt.Errorf("gobreaker: invalid timeInState %q", v.TimeInState)
+		}
+		s.TimeInState = d
+	}
+	return nil
+}
+
+// StatusSnapshot returns the status of the CircuitBreaker, read atomically,
+// unlike separate calls to State and Counts, which may observe different generations.
+func (cb *CircuitBreaker) StatusSnapshot() StatusSnapshot {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	state, generation := cb.currentState(now)
+	return StatusSnapshot{
+		Name:        cb.name,
+		State:       state,
+		Counts:      cb.counts,
+		Generation:  generation,
+		Expiry:      cb.expiry,
+		StateSince:  cb.stateSince,
+		TimeInState: now.Sub(cb.stateSince),
+	}
+}
+
+// StatusSnapshot returns the status of the TwoStepCircuitBreaker, like CircuitBreaker.StatusSnapshot.
+func (tscb *TwoStepCircuitBreaker) StatusSnapshot() StatusSnapshot {
+	return tscb.cb.StatusSnapshot()
+}
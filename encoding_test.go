@@ -0,0 +1,92 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateText(t *testing.T) {
+	for _, state := range []State{StateClosed, StateHalfOpen, StateOpen, StateForcedOpen, StateForcedClosed, StateDisabled} {
+		data, err := json.Marshal(state)
+		assert.Nil(t, err)
+		assert.Equal(t, `"`+state.String()+`"`, string(data))
+
+		var decoded State
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, state, decoded)
+	}
+
+	_, err := json.Marshal(State(100))
+	assert.Error(t, err)
+	var s State
+	assert.Error(t, json.Unmarshal([]byte(`"ajar"`), &s))
+	assert.Error(t, json.Unmarshal([]byte(`""`), &s))
+
+	data, err := json.Marshal(map[State]int{StateOpen: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"open":1}`, string(data))
+}
+
+func TestCountsJSON(t *testing.T) {
+	counts := Counts{5, 2, 3, 0, 3, 4, 4, FailureCounts{FailureOther: 1, FailureTimeout: 2}}
+	data, err := json.Marshal(counts)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"requests":5,"totalSuccesses":2,"totalFailures":3,"consecutiveSuccesses":0,`+
+		`"consecutiveFailures":3,"totalFailureWeight":4,"consecutiveFailureWeight":4,`+
+		`"failuresByKind":{"other":1,"timeout":2}}`, string(data))
+
+	var decoded Counts
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, counts, decoded)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"requests":2,"totalFailures":2,"consecutiveFailures":2}`), &decoded))
+	assert.Equal(t, Counts{2, 0, 2, 0, 2, 2, 2, FailureCounts{2}}, decoded)
+}
+
+func TestStatusSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	created := clock.Now()
+	cb := NewCircuitBreaker(Settings{Name: "status", Clock: clock})
+	assert.Nil(t, fail(cb))
+	clock.Advance(90 * time.Second)
+
+	s := cb.StatusSnapshot()
+	assert.Equal(t, StatusSnapshot{
+		Name:        "status",
+		State:       StateClosed,
+		Counts:      Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}},
+		Generation:  1,
+		StateSince:  created,
+		TimeInState: 90 * time.Second,
+	}, s)
+
+	data, err := json.Marshal(s)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"name":"status","state":"closed","generation":1,"counts":{"requests":1,`+
+		`"totalSuccesses":0,"totalFailures":1,"consecutiveSuccesses":0,"consecutiveFailures":1,`+
+		`"totalFailureWeight":1,"consecutiveFailureWeight":1,"failuresByKind":{"other":1}},`+
+		`"stateSince":"2020-01-01T00:00:00Z","timeInState":"1m30s"}`, string(data))
+
+	expiry := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Expiry = expiry
+	data, err = json.Marshal(s)
+	assert.Nil(t, err)
+	var decoded StatusSnapshot
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, s, decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"timeInState":"long"}`), &decoded))
+
+	cb.Trip()
+	clock.Advance(time.Second)
+	s = cb.StatusSnapshot()
+	assert.Equal(t, StateOpen, s.State)
+	assert.Equal(t, created.Add(90*time.Second), s.StateSince)
+	assert.Equal(t, time.Second, s.TimeInState)
+
+	tscb := NewTwoStepCircuitBreaker(Settings{Name: "two-step"})
+	assert.Equal(t, "two-step", tscb.StatusSnapshot().Name)
+}
@@ -0,0 +1,147 @@
+package gobreaker
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SettingsFromEnv returns the Settings configured by the environment variables whose names
+// start with prefix, e.g. "PAYMENTS_BREAKER_", see WithEnv. The settings that no variable
+// sets keep their defaults. SettingsFromEnv returns an error for a variable that can't be parsed
+// and for invalid settings, see Settings.Validate.
+func SettingsFromEnv(prefix string) (Settings, error) {
+	var st Settings
+	if err := WithEnv(prefix)(&st); err != nil {
+		return st, fmt.Errorf("gobreaker: %v", err)
+	}
+	if err := st.Validate(); err != nil {
+		return st, fmt.Errorf("gobreaker: %v", err)
+	}
+	return st, nil
+}
+
+// WithEnv overrides the settings with the environment variables named prefix followed by:
+//
+//	MAX_REQUESTS          MaxRequests, e.g. 3
+//	INTERVAL              Interval, e.g. 1m
+//	TIMEOUT               Timeout, e.g. 30s
+//	JITTER                Jitter, e.g. 0.1
+//	CONSECUTIVE_FAILURES  trip after this many consecutive failures
+//	FAILURE_RATIO         trip once the ratio of the failures reaches this, e.g. 0.5
+//	MINIMUM_REQUESTS      MinimumRequests
+//	MAX_CONCURRENT        MaxConcurrent
+//	MAX_REQUESTS_RATIO    MaxRequestsRatio
+//	STRIPES               Stripes
+//
+// The durations are parsed by time.ParseDuration. CONSECUTIVE_FAILURES and FAILURE_RATIO
+// replace ReadyToTrip if any of them is set: the CircuitBreaker trips as soon as either
+// condition holds. Unset and empty variables are ignored.
+func WithEnv(prefix string) Option {
+	return func(st *Settings) error {
+		env := envReader{prefix: prefix}
+		env.uint32("MAX_REQUESTS", &st.MaxRequests)
+		env.duration("INTERVAL", &st.Interval)
+		env.duration("TIMEOUT", &st.Timeout)
+		env.float("JITTER", &st.Jitter)
+		env.uint32("MINIMUM_REQUESTS", &st.MinimumRequests)
+		env.int("MAX_CONCURRENT", &st.MaxConcurrent)
+		env.float("MAX_REQUESTS_RATIO", &st.MaxRequestsRatio)
+		env.int("STRIPES", &st.Stripes)
+
+		var consecutive uint32
+		var ratio float64
+		setConsecutive := env.uint32("CONSECUTIVE_FAILURES", &consecutive)
+		setRatio := env.float("FAILURE_RATIO", &ratio)
+		if env.err != nil {
+			return env.err
+		}
+		if ratio < 0 || ratio > 1 {
+			return fmt.Errorf("%sFAILURE_RATIO %v out of [0, 1]", prefix, ratio)
+		}
+		if setConsecutive || setRatio {
+			st.ReadyToTrip = TripOn(consecutive, ratio)
+		}
+		return nil
+	}
+}
+
+// envReader parses the environment variables named prefix followed by a name,
+// keeping the first error.
+type envReader struct {
+	prefix string
+	err    error
+}
+
+// lookup returns the value of the variable name, and whether it is set and not empty.
+func (e *envReader) lookup(name string) (string, bool) {
+	if e.err != nil {
+		return "", false
+	}
+	v, ok := os.LookupEnv(e.prefix + name)
+	return v, ok && v != ""
+}
+
+func (e *envReader) fail(name string, v string, err error) {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		err = numErr.Err
+	}
+	e.err = fmt.Errorf("invalid %s%s %q: %v", e.prefix, name, v, err)
+}
+
+func (e *envReader) uint32(name string, dst *uint32) bool {
+	v, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		e.fail(name, v, err)
+		return false
+	}
+	*dst = uint32(n)
+	return true
+}
+
+func (e *envReader) int(name string, dst *int) bool {
+	v, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.fail(name, v, err)
+		return false
+	}
+	*dst = n
+	return true
+}
+
+func (e *envReader) float(name string, dst *float64) bool {
+	v, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		e.fail(name, v, err)
+		return false
+	}
+	*dst = f
+	return true
+}
+
+func (e *envReader) duration(name string, dst *time.Duration) bool {
+	v, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.fail(name, v, err)
+		return false
+	}
+	*dst = d
+	return true
+}
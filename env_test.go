@@ -0,0 +1,76 @@
+package gobreaker
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		assert.Nil(t, os.Setenv(k, v))
+	}
+}
+
+func unsetenv(env map[string]string) {
+	for k := range env {
+		os.Unsetenv(k)
+	}
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"TEST_BREAKER_MAX_REQUESTS":         "3",
+		"TEST_BREAKER_INTERVAL":             "1m",
+		"TEST_BREAKER_TIMEOUT":              "30s",
+		"TEST_BREAKER_JITTER":               "0.1",
+		"TEST_BREAKER_CONSECUTIVE_FAILURES": "4",
+		"TEST_BREAKER_FAILURE_RATIO":        "0.5",
+		"TEST_BREAKER_MINIMUM_REQUESTS":     "10",
+		"TEST_BREAKER_MAX_CONCURRENT":       "",
+	}
+	setenv(t, env)
+	defer unsetenv(env)
+
+	st, err := SettingsFromEnv("TEST_BREAKER_")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), st.MaxRequests)
+	assert.Equal(t, time.Minute, st.Interval)
+	assert.Equal(t, 30*time.Second, st.Timeout)
+	assert.Equal(t, 0.1, st.Jitter)
+	assert.Equal(t, uint32(10), st.MinimumRequests)
+	assert.Equal(t, 0, st.MaxConcurrent)
+	assert.True(t, st.ReadyToTrip(Counts{ConsecutiveFailures: 4}))
+	assert.True(t, st.ReadyToTrip(Counts{Requests: 10, TotalFailures: 5, ConsecutiveFailures: 1}))
+	assert.False(t, st.ReadyToTrip(Counts{Requests: 10, TotalFailures: 4, ConsecutiveFailures: 3}))
+
+	st, err = SettingsFromEnv("UNSET_BREAKER_")
+	assert.Nil(t, err)
+	assert.Nil(t, st.ReadyToTrip)
+
+	cb, err := New("env", WithEnv("TEST_BREAKER_"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), cb.maxRequests)
+}
+
+func TestSettingsFromEnvErrors(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"TEST_BREAKER_MAX_REQUESTS": "-1"},
+		{"TEST_BREAKER_TIMEOUT": "30"},
+		{"TEST_BREAKER_FAILURE_RATIO": "half"},
+		{"TEST_BREAKER_FAILURE_RATIO": "1.5"},
+		{"TEST_BREAKER_STRIPES": "-4"},
+	} {
+		setenv(t, env)
+		_, err := SettingsFromEnv("TEST_BREAKER_")
+		assert.Error(t, err, "%v", env)
+		unsetenv(env)
+	}
+
+	setenv(t, map[string]string{"TEST_BREAKER_TIMEOUT": "soon"})
+	defer unsetenv(map[string]string{"TEST_BREAKER_TIMEOUT": ""})
+	_, err := SettingsFromEnv("TEST_BREAKER_")
+	assert.Contains(t, err.Error(), `gobreaker: invalid TEST_BREAKER_TIMEOUT "soon": `)
+}
@@ -0,0 +1,64 @@
+package gobreaker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RejectionError is returned when a CircuitBreaker rejects a request.
+// It wraps ErrOpenState, ErrTooManyRequests, ErrTooManyConcurrent, ErrThrottled or
+// ErrDeadlineTooShort, so errors.Is matches these errors.
+//
+// Name is the name of the CircuitBreaker and State is its state when it rejected the request.
+// RetryAfter is the time until the CircuitBreaker allows a new attempt, e.g. for a Retry-After header.
+// It is 0 if the time is unknown, e.g. in the half-open and forced-open states.
+type RejectionError struct {
+	Name       string
+	State      State
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error implements error.
+func (e *RejectionError) Error() string {
+	if e.Name == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error, such as ErrOpenState.
+func (e *RejectionError) Unwrap() error {
+	return e.Err
+}
+
+// IsRejection reports whether err, or an error it wraps, is a RejectionError.
+func IsRejection(err error) bool {
+	var rejection *RejectionError
+	return errors.As(err, &rejection)
+}
+
+// rejection returns the RejectionError for a request rejected in state at now.
+// The errors of the context of a request are returned as is.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) rejection(state State, now time.Time, err error) error {
+	switch err {
+	case ErrOpenState, ErrTooManyRequests, ErrTooManyConcurrent, ErrThrottled, ErrDeadlineTooShort:
+		return &RejectionError{Name: cb.name, State: state, RetryAfter: cb.remainingTimeout(now), Err: err}
+	}
+	return err
+}
+
+// RetryAfterHeader returns the value of a Retry-After header for err: the RetryAfter of
+// the RejectionError that err wraps, in seconds rounded up. It returns "" if err isn't
+// a rejection or the time until the next attempt is unknown.
+func RetryAfterHeader(err error) string {
+	var rejection *RejectionError
+	if !errors.As(err, &rejection) || rejection.RetryAfter <= 0 {
+		return ""
+	}
+	seconds := (rejection.RetryAfter + time.Second - 1) / time.Second
+	return strconv.FormatInt(int64(seconds), 10)
+}
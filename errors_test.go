@@ -0,0 +1,59 @@
+package gobreaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectionError(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Name:        "rej",
+		Clock:       clockFunc(func() time.Time { return now }),
+		Timeout:     10 * time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+
+	assert.Nil(t, fail(cb))
+	now = now.Add(4 * time.Second)
+
+	err := succeed(cb)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.True(t, IsRejection(err))
+	assert.Equal(t, "rej: circuit breaker is open", err.Error())
+
+	var rejection *RejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "rej", rejection.Name)
+	assert.Equal(t, StateOpen, rejection.State)
+	assert.Equal(t, 6*time.Second, rejection.RetryAfter)
+
+	// the probe in flight makes the next request too many
+	now = now.Add(7 * time.Second)
+	done, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+	err = succeed(cb)
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, StateHalfOpen, rejection.State)
+	assert.Equal(t, time.Duration(0), rejection.RetryAfter)
+	done(true)
+
+	assert.False(t, IsRejection(nil))
+	assert.False(t, IsRejection(ErrOpenState))
+	assert.True(t, IsRejection(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, "too many requests", (&RejectionError{Err: ErrTooManyRequests}).Error())
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	assert.Equal(t, "", RetryAfterHeader(nil))
+	assert.Equal(t, "", RetryAfterHeader(ErrOpenState))
+	assert.Equal(t, "", RetryAfterHeader(&RejectionError{State: StateHalfOpen, Err: ErrTooManyRequests}))
+	assert.Equal(t, "30", RetryAfterHeader(&RejectionError{RetryAfter: 30 * time.Second, Err: ErrOpenState}))
+	assert.Equal(t, "2", RetryAfterHeader(fmt.Errorf("wrapped: %w",
+		&RejectionError{RetryAfter: 1001 * time.Millisecond, Err: ErrOpenState})))
+}
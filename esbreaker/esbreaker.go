@@ -0,0 +1,127 @@
+// Package esbreaker guards the nodes of an Elasticsearch or OpenSearch cluster with the circuit breakers
+// of gobreaker, through the connection pool of elastic-transport-go, so that the requests, such as
+// those of a bulk indexing pipeline, skip the nodes whose CircuitBreaker is open.
+//
+// It lives in its own module so that gobreaker doesn't depend on elastic-transport-go.
+package esbreaker
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/sony/gobreaker"
+)
+
+// ErrNodeFailed is the error with which a request that failed on a node is counted.
+var ErrNodeFailed = errors.New("esbreaker: request to the node failed")
+
+// Pool is an elastictransport.ConnectionPool that selects the nodes of Base whose CircuitBreaker
+// in Group is not open, keyed by the host of the node, e.g. "es1:9200".
+//
+// The transport reports to the pool the requests that succeeded and those that failed on a node,
+// i.e. on a network error or a 502, 503 or 504 response, which Pool counts in the CircuitBreaker
+// of the node before passing them to Base. So the dead nodes are left to Base, which resurrects
+// them on its own schedule, while the CircuitBreakers skip the nodes that keep failing.
+// The half-open CircuitBreakers don't limit the requests to their node: the first reports decide
+// whether they close.
+type Pool struct {
+	Base  elastictransport.ConnectionPool
+	Group *gobreaker.BreakerGroup
+}
+
+// NewPool returns a Pool that selects the nodes of base through the CircuitBreakers in group.
+func NewPool(base elastictransport.ConnectionPool, group *gobreaker.BreakerGroup) *Pool {
+	return &Pool{Base: base, Group: group}
+}
+
+// ConnectionPoolFunc returns a function for the ConnectionPoolFunc of elastictransport.Config,
+// which wraps the default connection pool of the transport in a Pool with group.
+// If the default pool can't be created, e.g. without any node, the requests fail with its error.
+func ConnectionPoolFunc(group *gobreaker.BreakerGroup) func([]*elastictransport.Connection, elastictransport.Selector) elastictransport.ConnectionPool {
+	return func(conns []*elastictransport.Connection, selector elastictransport.Selector) elastictransport.ConnectionPool {
+		base, err := elastictransport.NewConnectionPool(conns, selector)
+		if err != nil {
+			return &errorPool{err: err}
+		}
+		return NewPool(base, group)
+	}
+}
+
+// errorPool is the elastictransport.ConnectionPool of ConnectionPoolFunc when the default pool
+// can't be created, whose Next returns the error of its creation.
+type errorPool struct {
+	err error
+}
+
+func (p *errorPool) Next() (*elastictransport.Connection, error) {
+	return nil, p.err
+}
+
+func (p *errorPool) OnSuccess(c *elastictransport.Connection) error {
+	return nil
+}
+
+func (p *errorPool) OnFailure(c *elastictransport.Connection) error {
+	return nil
+}
+
+func (p *errorPool) URLs() []*url.URL {
+	return nil
+}
+
+// Next implements elastictransport.ConnectionPool. It returns the next node of Base whose
+// CircuitBreaker is not open, or a *gobreaker.RejectionError if all of them are open.
+func (p *Pool) Next() (*elastictransport.Connection, error) {
+	n := len(p.Base.URLs())
+	if n < 1 {
+		n = 1
+	}
+	var rejection error
+	for i := 0; i < n; i++ {
+		c, err := p.Base.Next()
+		if err != nil {
+			return nil, err
+		}
+		if rejection = p.allow(c); rejection == nil {
+			return c, nil
+		}
+	}
+	return nil, rejection
+}
+
+// allow returns a *gobreaker.RejectionError if the CircuitBreaker of c is open.
+func (p *Pool) allow(c *elastictransport.Connection) error {
+	return p.Group.Get(c.URL.Host).Check()
+}
+
+// OnSuccess implements elastictransport.ConnectionPool.
+func (p *Pool) OnSuccess(c *elastictransport.Connection) error {
+	p.count(c, nil)
+	return p.Base.OnSuccess(c)
+}
+
+// OnFailure implements elastictransport.ConnectionPool.
+func (p *Pool) OnFailure(c *elastictransport.Connection) error {
+	p.count(c, ErrNodeFailed)
+	return p.Base.OnFailure(c)
+}
+
+// count reports a request to c, see gobreaker.CircuitBreaker.Report.
+func (p *Pool) count(c *elastictransport.Connection, err error) {
+	p.Group.Get(c.URL.Host).Report(err)
+}
+
+// URLs implements elastictransport.ConnectionPool.
+func (p *Pool) URLs() []*url.URL {
+	return p.Base.URLs()
+}
+
+// Update implements elastictransport.UpdatableConnectionPool, so that the nodes discovered
+// by the transport reach Base, if Base is updatable.
+func (p *Pool) Update(conns []*elastictransport.Connection) error {
+	if base, ok := p.Base.(elastictransport.UpdatableConnectionPool); ok {
+		return base.Update(conns)
+	}
+	return nil
+}
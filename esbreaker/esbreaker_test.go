@@ -0,0 +1,68 @@
+package esbreaker
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func connections(hosts ...string) []*elastictransport.Connection {
+	var conns []*elastictransport.Connection
+	for _, host := range hosts {
+		conns = append(conns, &elastictransport.Connection{URL: &url.URL{Scheme: "http", Host: host}})
+	}
+	return conns
+}
+
+func newGroup() *gobreaker.BreakerGroup {
+	return gobreaker.NewBreakerGroup(0, func(host string) gobreaker.Settings {
+		return gobreaker.Settings{ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 }}
+	})
+}
+
+func TestPool(t *testing.T) {
+	group := newGroup()
+	pool := ConnectionPoolFunc(group)(connections("es1:9200", "es2:9200"), nil)
+	assert.Equal(t, 2, len(pool.URLs()))
+
+	// es1 fails twice and is skipped
+	for i := 0; i < 2; i++ {
+		c, err := pool.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "es1:9200", c.URL.Host)
+		assert.Nil(t, pool.OnFailure(c))
+		c, err = pool.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "es2:9200", c.URL.Host)
+		assert.Nil(t, pool.OnSuccess(c))
+	}
+	assert.Equal(t, gobreaker.StateOpen, group.Get("es1:9200").State())
+	for i := 0; i < 3; i++ {
+		c, err := pool.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "es2:9200", c.URL.Host)
+	}
+
+	// all the nodes are open
+	group.Get("es2:9200").Trip()
+	_, err := pool.Next()
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	// the discovered nodes reach the base pool
+	assert.Nil(t, pool.(elastictransport.UpdatableConnectionPool).Update(connections("es3:9200")))
+	c, err := pool.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "es3:9200", c.URL.Host)
+}
+
+func TestConnectionPoolFuncEmpty(t *testing.T) {
+	pool := ConnectionPoolFunc(newGroup())(nil, nil)
+	assert.NotNil(t, pool)
+	_, err := pool.Next()
+	assert.Error(t, err)
+	assert.Empty(t, pool.URLs())
+}
@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/esbreaker
+
+go 1.13
+
+require (
+	github.com/elastic/elastic-transport-go/v8 v8.3.0
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+)
+
+replace github.com/sony/gobreaker => ../
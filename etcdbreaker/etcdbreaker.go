@@ -0,0 +1,212 @@
+// Package etcdbreaker shares the state of the circuit breakers of gobreaker between
+// the replicas of a service through etcd.
+//
+// It lives in its own module so that gobreaker doesn't depend on etcd.
+package etcdbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sony/gobreaker"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// DefaultPrefix is the Prefix of Options when it is empty.
+	DefaultPrefix = "/gobreaker/"
+	// DefaultTTL is the TTL of Options when it is 0.
+	DefaultTTL = 2 * time.Minute
+)
+
+// retryWatch is how long a Store waits before watching a key again after etcd ended the watch.
+const retryWatch = time.Second
+
+// Client is the part of *clientv3.Client used by a Store.
+type Client interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
+
+// Options configures a Store.
+//
+// Prefix is the prefix of the keys: the state of a breaker is stored under Prefix followed by its name.
+// If Prefix is empty, DefaultPrefix is used.
+//
+// TTL is how long a state stays in etcd after the last state change of its breaker, so that
+// the state left by replicas that stopped expires on its own. It is rounded up to whole seconds,
+// the granularity of the leases of etcd. It should be longer than the Timeout of the breakers,
+// so that a replica that starts while a breaker is open finds it open. If TTL is 0, DefaultTTL is used.
+//
+// Replica identifies the replica in the stored states, so that it ignores its own state changes.
+// If Replica is empty, the host name and the process ID are used.
+//
+// Logger, if not nil, logs the errors of etcd and the states that can't be restored at the warning level.
+type Options struct {
+	Prefix  string
+	TTL     time.Duration
+	Replica string
+	Logger  gobreaker.Logger
+}
+
+// Store shares the state of CircuitBreakers between the replicas of a service through etcd,
+// so that a breaker that trips on one replica opens on all of them within the latency of a watch,
+// instead of every replica sending its own failing requests to the dependency.
+//
+// Every replica changes state on its own and stores its state changes: a Store has no single-writer
+// mode like memcachebreaker.Options.SingleWriter. A Store writes only on the state changes and
+// reads through watches, never on a request, so it doesn't have the cost that mode avoids.
+type Store struct {
+	client  Client
+	prefix  string
+	ttl     int64 // seconds
+	replica string
+	logger  gobreaker.Logger
+}
+
+// NewStore returns a Store that keeps the states in etcd with client, e.g. a *clientv3.Client.
+func NewStore(client Client, o Options) *Store {
+	if o.Prefix == "" {
+		o.Prefix = DefaultPrefix
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.Replica == "" {
+		host, _ := os.Hostname()
+		o.Replica = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return &Store{
+		client:  client,
+		prefix:  o.Prefix,
+		ttl:     int64((o.TTL + time.Second - 1) / time.Second),
+		replica: o.Replica,
+		logger:  o.Logger,
+	}
+}
+
+// record is the value stored under the key of a breaker.
+type record struct {
+	Replica  string             `json:"replica"`
+	Snapshot gobreaker.Snapshot `json:"snapshot"`
+}
+
+// Share shares the state of cb with the CircuitBreakers of the same name on the other replicas,
+// until ctx is done or stop is called. stop waits for the sharing to end.
+//
+// Share restores the state stored in etcd, if any, then stores every state change of cb
+// with a lease of TTL, and restores the state changes stored by the other replicas as soon as
+// etcd delivers them. A restored state is not a state change of cb, so it isn't stored again,
+// and a state that cb is already in is not restored, so that the replicas whose breakers
+// change state at the same time, e.g. from open to half-open, don't reset each other.
+// The expiries of the states are shared as absolute times, so the clocks of the replicas
+// should be synchronized.
+//
+// Share returns an error only if the stored state can't be read.
+func (s *Store) Share(ctx context.Context, cb *gobreaker.CircuitBreaker) (stop func(), err error) {
+	key := s.prefix + cb.Name()
+	ctx, cancel := context.WithCancel(ctx)
+	// watch before reading, so that no state stored in between is missed
+	watch := s.client.Watch(ctx, key)
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for _, kv := range resp.Kvs {
+		s.restore(cb, kv.Value)
+	}
+
+	events, unsubscribe := cb.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer unsubscribe()
+		s.run(ctx, key, cb, events, watch)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+func (s *Store) run(ctx context.Context, key string, cb *gobreaker.CircuitBreaker, events <-chan gobreaker.Event, watch clientv3.WatchChan) {
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			if e.Type == gobreaker.EventStateChange {
+				s.store(ctx, key, cb)
+			}
+		case wr, ok := <-watch:
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+				s.warn("etcd watch ended", key, nil)
+				watch, retry = nil, time.After(retryWatch)
+				continue
+			}
+			if err := wr.Err(); err != nil {
+				s.warn("etcd watch failed", key, err)
+			}
+			for _, ev := range wr.Events {
+				if ev.Type == clientv3.EventTypePut {
+					s.restore(cb, ev.Kv.Value)
+				}
+			}
+		case <-retry:
+			watch, retry = s.client.Watch(ctx, key), nil
+		}
+	}
+}
+
+// store stores the current state of cb under key.
+func (s *Store) store(ctx context.Context, key string, cb *gobreaker.CircuitBreaker) {
+	value, err := json.Marshal(record{Replica: s.replica, Snapshot: cb.Snapshot()})
+	if err != nil {
+		s.warn("can't encode state", key, err)
+		return
+	}
+	lease, err := s.client.Grant(ctx, s.ttl)
+	if err != nil {
+		s.warn("etcd lease failed", key, err)
+		return
+	}
+	if _, err := s.client.Put(ctx, key, string(value), clientv3.WithLease(lease.ID)); err != nil {
+		s.warn("etcd put failed", key, err)
+	}
+}
+
+// restore restores the state stored by another replica in value into cb.
+func (s *Store) restore(cb *gobreaker.CircuitBreaker, value []byte) {
+	var r record
+	if err := json.Unmarshal(value, &r); err != nil {
+		s.warn("can't decode state", cb.Name(), err)
+		return
+	}
+	if r.Replica == s.replica || r.Snapshot.State == cb.State() {
+		return
+	}
+	if err := cb.Restore(r.Snapshot); err != nil {
+		s.warn("can't restore state", cb.Name(), err)
+	}
+}
+
+func (s *Store) warn(msg string, key string, err error) {
+	if s.logger == nil {
+		return
+	}
+	if err == nil {
+		s.logger.Warn(msg, "key", key)
+		return
+	}
+	s.logger.Warn(msg, "key", key, "error", err.Error())
+}
@@ -0,0 +1,208 @@
+package etcdbreaker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd is an in-memory etcd with leases that expire on demand.
+type fakeEtcd struct {
+	mutex    sync.Mutex
+	kvs      map[string]*mvccpb.KeyValue
+	leases   int64
+	ttls     map[clientv3.LeaseID]int64
+	watchers map[string][]chan clientv3.WatchResponse
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		kvs:      make(map[string]*mvccpb.KeyValue),
+		ttls:     make(map[clientv3.LeaseID]int64),
+		watchers: make(map[string][]chan clientv3.WatchResponse),
+	}
+}
+
+func (e *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	resp := &clientv3.GetResponse{}
+	if kv, ok := e.kvs[key]; ok {
+		resp.Kvs, resp.Count = []*mvccpb.KeyValue{kv}, 1
+	}
+	return resp, nil
+}
+
+func (e *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	var op clientv3.Op
+	for _, opt := range opts {
+		opt(&op)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	kv := &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), Lease: int64(op.Lease)}
+	e.kvs[key] = kv
+	e.notify(key, &clientv3.Event{Type: clientv3.EventTypePut, Kv: kv})
+	return &clientv3.PutResponse{}, nil
+}
+
+func (e *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.leases++
+	id := clientv3.LeaseID(e.leases)
+	e.ttls[id] = ttl
+	return &clientv3.LeaseGrantResponse{ID: id, TTL: ttl}, nil
+}
+
+func (e *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ch := make(chan clientv3.WatchResponse, 16)
+	e.mutex.Lock()
+	e.watchers[key] = append(e.watchers[key], ch)
+	e.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		watchers := e.watchers[key]
+		for i, w := range watchers {
+			if w == ch {
+				e.watchers[key] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func (e *fakeEtcd) notify(key string, ev *clientv3.Event) {
+	for _, ch := range e.watchers[key] {
+		ch <- clientv3.WatchResponse{Events: []*clientv3.Event{ev}}
+	}
+}
+
+// expire expires all leases.
+func (e *fakeEtcd) expire() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for key, kv := range e.kvs {
+		if kv.Lease != 0 {
+			delete(e.kvs, key)
+			e.notify(key, &clientv3.Event{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key)}})
+		}
+	}
+}
+
+func (e *fakeEtcd) ttl(key string) int64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	kv, ok := e.kvs[key]
+	if !ok {
+		return 0
+	}
+	return e.ttls[clientv3.LeaseID(kv.Lease)]
+}
+
+func waitForState(t *testing.T, cb *gobreaker.CircuitBreaker, state gobreaker.State) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); cb.State() != state; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %v, want %v", cb.Name(), cb.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func share(t *testing.T, etcd *fakeEtcd, replica string, cb *gobreaker.CircuitBreaker) func() {
+	s := NewStore(etcd, Options{Replica: replica, TTL: 90500 * time.Millisecond})
+	stop, err := s.Share(context.Background(), cb)
+	assert.Nil(t, err)
+	return stop
+}
+
+func TestShare(t *testing.T) {
+	etcd := newFakeEtcd()
+	a := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	b := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, etcd, "a", a)()
+	defer share(t, etcd, "b", b)()
+
+	a.Trip()
+	waitForState(t, b, gobreaker.StateOpen)
+	assert.Equal(t, a.Snapshot().Expiry.UnixNano(), b.Snapshot().Expiry.UnixNano())
+	assert.Equal(t, int64(91), etcd.ttl("/gobreaker/db"))
+
+	b.Reset()
+	waitForState(t, a, gobreaker.StateClosed)
+
+	// a replica that starts later restores the stored state
+	b.ForceOpen()
+	waitForState(t, a, gobreaker.StateForcedOpen)
+	c := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, etcd, "c", c)()
+	assert.Equal(t, gobreaker.StateForcedOpen, c.State())
+}
+
+func TestShareExpiry(t *testing.T) {
+	etcd := newFakeEtcd()
+	a := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	share(t, etcd, "a", a)()
+
+	a.Trip() // not shared anymore
+	b := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, etcd, "b", b)()
+	assert.Equal(t, gobreaker.StateClosed, b.State())
+
+	b.Trip()
+	for etcd.ttl("/gobreaker/db") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	etcd.expire()
+	c := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, etcd, "c", c)()
+	assert.Equal(t, gobreaker.StateClosed, c.State())
+	assert.Equal(t, gobreaker.StateOpen, b.State())
+}
+
+type fakeLogger struct {
+	mutex    sync.Mutex
+	warnings []string
+}
+
+func (l *fakeLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+func (l *fakeLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.warnings = append(l.warnings, msg)
+}
+
+func TestShareInvalidState(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.Put(context.Background(), "/breakers/db", "{")
+	logger := &fakeLogger{}
+	s := NewStore(etcd, Options{Prefix: "/breakers/", Logger: logger})
+	assert.NotEqual(t, "", s.replica)
+
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	stop, err := s.Share(context.Background(), cb)
+	assert.Nil(t, err)
+	stop()
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+	assert.Equal(t, []string{"can't decode state"}, logger.warnings)
+}
@@ -0,0 +1,12 @@
+module github.com/sony/gobreaker/etcdbreaker
+
+go 1.13
+
+require (
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.7.0
+	go.etcd.io/etcd/api/v3 v3.5.0
+	go.etcd.io/etcd/client/v3 v3.5.0
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,124 @@
+package gobreaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventBuffer is the number of events buffered for each subscriber.
+// Events published while the buffer of a subscriber is full are dropped for that subscriber.
+const EventBuffer = 128
+
+// EventType is the type of an Event.
+type EventType int
+
+// These constants are the types of Event.
+const (
+	EventStateChange EventType = iota
+	EventRejection
+	EventSuccess
+	EventFailure
+)
+
+// String implements stringer interface.
+func (t EventType) String() string {
+	switch t {
+	case EventStateChange:
+		return "state-change"
+	case EventRejection:
+		return "rejection"
+	case EventSuccess:
+		return "success"
+	case EventFailure:
+		return "failure"
+	default:
+		return "unknown event"
+	}
+}
+
+// Event is something that happened to a CircuitBreaker, delivered by Subscribe.
+//
+// State is the state of the CircuitBreaker when the event happened,
+// or the new state for EventStateChange, in which case From is the previous state.
+// Counts are the Counts after the event, or the Counts just before they were cleared
+// by the state change for EventStateChange.
+// Reason is the reason of EventStateChange.
+// Err is the error returned for EventRejection.
+type Event struct {
+	Type   EventType
+	Name   string
+	Time   time.Time
+	State  State
+	From   State
+	Counts Counts
+	Reason TripReason
+	Err    error
+}
+
+// Subscribe returns a channel that receives the events of the CircuitBreaker,
+// and a function that ends the subscription and closes the channel.
+//
+// Events are delivered asynchronously on a buffer of EventBuffer events:
+// a subscriber that doesn't keep up misses events rather than slowing down the CircuitBreaker.
+// EventSuccess and EventFailure are published for the outcomes that the CircuitBreaker counts.
+func (cb *CircuitBreaker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, EventBuffer)
+
+	cb.mutex.Lock()
+	cb.listeners = append(cb.listeners, ch)
+	atomic.AddInt32(&cb.fast.listeners, 1)
+	cb.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cb.mutex.Lock()
+			defer cb.mutex.Unlock()
+
+			for i, l := range cb.listeners {
+				if l == ch {
+					cb.listeners = append(cb.listeners[:i], cb.listeners[i+1:]...)
+					atomic.AddInt32(&cb.fast.listeners, -1)
+					break
+				}
+			}
+			close(ch)
+		})
+	}
+}
+
+// Subscribe returns a channel that receives the events of the TwoStepCircuitBreaker.
+func (tscb *TwoStepCircuitBreaker) Subscribe() (<-chan Event, func()) {
+	return tscb.cb.Subscribe()
+}
+
+// publish delivers e to the subscribers without blocking.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) publish(e Event) {
+	e.Name = cb.name
+	for _, ch := range cb.listeners {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (cb *CircuitBreaker) publishOutcome(t EventType, state State, now time.Time) {
+	if len(cb.listeners) > 0 {
+		cb.publish(Event{Type: t, Time: now, State: state, Counts: cb.counts})
+	}
+}
+
+func (cb *CircuitBreaker) publishRejection(state State, now time.Time, err error) {
+	if len(cb.listeners) > 0 {
+		cb.publish(Event{Type: EventRejection, Time: now, State: state, Counts: cb.counts, Err: err})
+	}
+}
+
+func (cb *CircuitBreaker) publishStateChange(from State, to State, now time.Time, reason TripReason) {
+	if len(cb.listeners) > 0 {
+		cb.publish(Event{Type: EventStateChange, Time: now, State: to, From: from, Counts: cb.counts, Reason: reason})
+	}
+}
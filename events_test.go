@@ -0,0 +1,86 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Name:        "sub",
+		Clock:       clock,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+
+	events, cancel := cb.Subscribe()
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+
+	now := clock.Now()
+	assert.Equal(t, []Event{
+		{Type: EventSuccess, Name: "sub", Time: now, State: StateClosed, Counts: Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}},
+		{Type: EventFailure, Name: "sub", Time: now, State: StateClosed, Counts: Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}},
+		{Type: EventFailure, Name: "sub", Time: now, State: StateClosed, Counts: Counts{3, 1, 2, 0, 2, 2, 2, FailureCounts{2}}},
+		{Type: EventStateChange, Name: "sub", Time: now, State: StateOpen, From: StateClosed, Counts: Counts{3, 1, 2, 0, 2, 2, 2, FailureCounts{2}}, Reason: ReasonReadyToTrip},
+		{Type: EventRejection, Name: "sub", Time: now, State: StateOpen, Err: ErrOpenState},
+	}, drain(events))
+
+	clock.Advance(time.Minute + time.Nanosecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, fail(cb))
+	e := drain(events)
+	assert.Len(t, e, 3)
+	assert.Equal(t, EventStateChange, e[0].Type)
+	assert.Equal(t, EventFailure, e[1].Type)
+	assert.Equal(t, StateHalfOpen, e[1].State)
+	assert.Equal(t, EventStateChange, e[2].Type)
+	assert.Equal(t, StateOpen, e[2].State)
+
+	cancel()
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Empty(t, cb.listeners)
+}
+
+func TestSubscribeSlowSubscriber(t *testing.T) {
+	cb := NewTwoStepCircuitBreaker(Settings{})
+	slow, cancelSlow := cb.Subscribe()
+	defer cancelSlow()
+	fast, cancelFast := cb.Subscribe()
+	defer cancelFast()
+
+	received := 0
+	for i := 0; i < 2*EventBuffer; i++ {
+		assert.Nil(t, succeed2Step(cb))
+		received += len(drain(fast))
+	}
+	assert.Equal(t, 2*EventBuffer, received)
+	assert.Len(t, drain(slow), EventBuffer)
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "state-change", EventStateChange.String())
+	assert.Equal(t, "rejection", EventRejection.String())
+	assert.Equal(t, "success", EventSuccess.String())
+	assert.Equal(t, "failure", EventFailure.String())
+	assert.Equal(t, "unknown event", EventType(10).String())
+}
@@ -0,0 +1,116 @@
+package gobreaker
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultEWMAHalfLife is the HalfLife of an EWMAPolicy whose HalfLife is 0.
+const DefaultEWMAHalfLife = 10 * time.Second
+
+// EWMAPolicy trips the closed CircuitBreaker on exponentially weighted moving averages of
+// the failure rate and the latency of the requests, in addition to ReadyToTrip.
+// Unlike Counts, the averages are not reset every Interval: an observation weighs half
+// as much as a new one after HalfLife, so that the CircuitBreaker reacts to a sudden
+// degradation without waiting for the failures to add up in a fixed window.
+// The averages are reset when the CircuitBreaker closes.
+//
+// FailureRate is the average failure rate in (0, 1] at which the CircuitBreaker trips.
+// If FailureRate is 0, the failure rate doesn't trip the CircuitBreaker.
+//
+// Latency is the average latency at which the CircuitBreaker trips.
+// If Latency is 0, the latency doesn't trip the CircuitBreaker.
+//
+// MinimumRequests is the minimum weighted number of the requests in the averages
+// before they trip the CircuitBreaker. With a steady traffic, this number converges
+// to the requests per second times HalfLife in seconds divided by ln 2.
+type EWMAPolicy struct {
+	HalfLife        time.Duration
+	FailureRate     float64
+	Latency         time.Duration
+	MinimumRequests uint32
+}
+
+// ewma holds the moving averages of an EWMAPolicy as decayed sums.
+type ewma struct {
+	policy   EWMAPolicy
+	last     time.Time
+	weight   float64 // weighted number of requests
+	failures float64 // weighted number of failures
+	latency  float64 // weighted sum of the latencies in nanoseconds
+}
+
+func newEWMA(p EWMAPolicy) *ewma {
+	if p.HalfLife <= 0 {
+		p.HalfLife = DefaultEWMAHalfLife
+	}
+	return &ewma{policy: p}
+}
+
+// observe adds a request that finished at now to the averages.
+func (e *ewma) observe(now time.Time, latency time.Duration, failure bool) {
+	if elapsed := now.Sub(e.last); !e.last.IsZero() && elapsed > 0 {
+		decay := math.Exp2(-float64(elapsed) / float64(e.policy.HalfLife))
+		e.weight *= decay
+		e.failures *= decay
+		e.latency *= decay
+	}
+	if now.After(e.last) {
+		e.last = now
+	}
+
+	e.weight++
+	if failure {
+		e.failures++
+	}
+	e.latency += float64(latency)
+}
+
+// failureRate returns the moving average of the failure rate.
+func (e *ewma) failureRate() float64 {
+	if e.weight == 0 {
+		return 0
+	}
+	return e.failures / e.weight
+}
+
+// averageLatency returns the moving average of the latency.
+func (e *ewma) averageLatency() time.Duration {
+	if e.weight == 0 {
+		return 0
+	}
+	return time.Duration(e.latency / e.weight)
+}
+
+// exceeded reports whether the averages reached the thresholds of the EWMAPolicy.
+func (e *ewma) exceeded() bool {
+	p := e.policy
+	if e.weight == 0 || e.weight < float64(p.MinimumRequests) {
+		return false
+	}
+	return p.FailureRate > 0 && e.failureRate() >= p.FailureRate ||
+		p.Latency > 0 && e.averageLatency() >= p.Latency
+}
+
+func (e *ewma) reset() {
+	*e = ewma{policy: e.policy}
+}
+
+// tripOnEWMA opens the closed CircuitBreaker if the moving averages of EWMAPolicy exceed its thresholds.
+func (cb *CircuitBreaker) tripOnEWMA(now time.Time) {
+	if cb.ewma != nil && cb.throttle == nil && cb.ewma.exceeded() {
+		cb.setState(StateOpen, now, ReasonEWMA)
+	}
+}
+
+// EWMA returns the moving averages of the failure rate and the latency of the requests
+// in the closed state, or zeros if the CircuitBreaker has no EWMAPolicy.
+func (cb *CircuitBreaker) EWMA() (failureRate float64, latency time.Duration) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.ewma == nil {
+		return 0, 0
+	}
+	return cb.ewma.failureRate(), cb.ewma.averageLatency()
+}
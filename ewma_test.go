@@ -0,0 +1,77 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEWMAFailureRate(t *testing.T) {
+	clock := newFakeClock()
+	var reason TripReason
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clock,
+		ReadyToTrip: func(counts Counts) bool { return false },
+		EWMA:        &EWMAPolicy{HalfLife: time.Second, FailureRate: 0.5, MinimumRequests: 4},
+		OnStateChange2: func(name string, from State, to State, counts Counts, r TripReason) {
+			reason = r
+		},
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	rate, _ := cb.EWMA()
+	assert.Equal(t, 0.0, rate)
+
+	// the successes weigh 1/8 after 3 half-lives
+	clock.Advance(3 * time.Second)
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	rate, _ = cb.EWMA()
+	assert.InDelta(t, 2/3.25, rate, 1e-9)
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, ReasonEWMA, reason)
+
+	// closing resets the averages
+	cb.Reset()
+	rate, latency := cb.EWMA()
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, time.Duration(0), latency)
+}
+
+func TestEWMALatency(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock: clock,
+		EWMA:  &EWMAPolicy{HalfLife: time.Second, Latency: 100 * time.Millisecond},
+	})
+	request := func(d time.Duration) {
+		_, err := cb.Execute(func() (interface{}, error) {
+			clock.Advance(d)
+			return nil, nil
+		})
+		assert.Nil(t, err)
+	}
+
+	request(50 * time.Millisecond)
+	request(50 * time.Millisecond)
+	_, latency := cb.EWMA()
+	assert.InDelta(t, float64(50*time.Millisecond), float64(latency), 1e3)
+	assert.Equal(t, StateClosed, cb.State())
+
+	request(400 * time.Millisecond)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestEWMADisabled(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Nil(t, fail(cb))
+	rate, latency := cb.EWMA()
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, time.Duration(0), latency)
+}
@@ -0,0 +1,52 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// Exemplar identifies a representative trace of a measurement,
+// e.g. the trace of one of the requests behind a spike of failures.
+type Exemplar struct {
+	TraceID string
+	SpanID  string
+}
+
+// ExemplarSink is implemented by a MetricsSink that can attach exemplars to its measurements.
+// If Settings.Exemplar yields an Exemplar for a request, the CircuitBreaker calls
+// OnFailureExemplar and OnRejectExemplar instead of OnFailure and OnReject,
+// under the same rules, e.g. OnRejectExemplar without the internal lock.
+type ExemplarSink interface {
+	MetricsSink
+	OnFailureExemplar(name string, latency time.Duration, exemplar Exemplar)
+	OnRejectExemplar(name string, state State, exemplar Exemplar)
+}
+
+func (cb *CircuitBreaker) exemplarSink(ctx context.Context) (ExemplarSink, Exemplar, bool) {
+	if cb.exemplar == nil {
+		return nil, Exemplar{}, false
+	}
+	sink, ok := cb.metrics.(ExemplarSink)
+	if !ok {
+		return nil, Exemplar{}, false
+	}
+	exemplar, ok := cb.exemplar(ctx)
+	return sink, exemplar, ok
+}
+
+func (cb *CircuitBreaker) onReject(ctx context.Context, state State) {
+	cb.countRejection()
+	if sink, exemplar, ok := cb.exemplarSink(ctx); ok {
+		sink.OnRejectExemplar(cb.name, state, exemplar)
+		return
+	}
+	cb.metrics.OnReject(cb.name, state)
+}
+
+func (cb *CircuitBreaker) onFailureMetrics(ctx context.Context, latency time.Duration) {
+	if sink, exemplar, ok := cb.exemplarSink(ctx); ok {
+		sink.OnFailureExemplar(cb.name, latency, exemplar)
+		return
+	}
+	cb.metrics.OnFailure(cb.name, latency)
+}
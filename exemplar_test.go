@@ -0,0 +1,66 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type traceKey struct{}
+
+func traceExemplar(ctx context.Context) (Exemplar, bool) {
+	id, ok := ctx.Value(traceKey{}).(string)
+	return Exemplar{TraceID: id}, ok
+}
+
+func TestExemplars(t *testing.T) {
+	sink := &RecordingMetricsSink{}
+	cb := NewCircuitBreaker(Settings{
+		Name:        "ex",
+		Metrics:     sink,
+		Exemplar:    traceExemplar,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+
+	traced := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	failReq := func(ctx context.Context) (interface{}, error) { return nil, errors.New("fail") }
+	okReq := func(ctx context.Context) (interface{}, error) { return ctx.Value(traceKey{}), nil }
+
+	v, err := cb.ExecuteContext(traced, okReq)
+	assert.Nil(t, err)
+	assert.Equal(t, "trace-1", v)
+
+	// requests without a trace are reported without an exemplar
+	cb.ExecuteContext(context.Background(), failReq)
+	cb.ExecuteContext(traced, failReq)
+	assert.Equal(t, StateOpen, cb.State())
+	_, err = cb.ExecuteContext(traced, okReq)
+	assert.True(t, errors.Is(err, ErrOpenState))
+
+	assert.Equal(t, 2, sink.Failures())
+	assert.Equal(t, 1, sink.Rejects(StateOpen))
+	assert.Equal(t, []ExemplarRecord{
+		{"ex", "failure", Exemplar{TraceID: "trace-1"}},
+		{"ex", "reject", Exemplar{TraceID: "trace-1"}},
+	}, sink.Exemplars())
+}
+
+func TestExemplarsWithoutExtractor(t *testing.T) {
+	sink := &RecordingMetricsSink{}
+	cb := NewCircuitBreaker(Settings{Metrics: sink})
+
+	traced := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	cb.ExecuteContext(traced, func(ctx context.Context) (interface{}, error) { return nil, errors.New("fail") })
+	assert.Equal(t, 1, sink.Failures())
+	assert.Empty(t, sink.Exemplars())
+}
+
+func TestExecuteContextPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Panics(t, func() {
+		cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1, TotalFailureWeight: 1, ConsecutiveFailureWeight: 1, FailuresByKind: FailureCounts{FailurePanic: 1}}, cb.Counts())
+}
@@ -0,0 +1,29 @@
+package gobreaker
+
+import (
+	"expvar"
+	"sync"
+)
+
+var (
+	expvarOnce     sync.Once
+	expvarBreakers *expvar.Map
+)
+
+// PublishExpvar publishes the CircuitBreaker in the "gobreaker" expvar map under its name,
+// so that its state, counts, generation and expiry are served by /debug/vars.
+// Publishing another CircuitBreaker with the same name replaces the previous one.
+func (cb *CircuitBreaker) PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvarBreakers = expvar.NewMap("gobreaker")
+	})
+
+	expvarBreakers.Set(cb.name, expvar.Func(func() interface{} {
+		return cb.Snapshot()
+	}))
+}
+
+// PublishExpvar publishes the TwoStepCircuitBreaker in the "gobreaker" expvar map under its name.
+func (tscb *TwoStepCircuitBreaker) PublishExpvar() {
+	tscb.cb.PublishExpvar()
+}
@@ -0,0 +1,39 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "expvar"})
+	cb.PublishExpvar()
+
+	assert.Nil(t, fail(cb))
+
+	v := expvar.Get("gobreaker").(*expvar.Map).Get("expvar")
+	assert.NotNil(t, v)
+
+	var s Snapshot
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &s))
+	assert.Equal(t, "expvar", s.Name)
+	assert.Equal(t, StateClosed, s.State)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, s.Counts)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &s))
+	assert.Equal(t, StateOpen, s.State)
+	assert.False(t, s.Expiry.IsZero())
+
+	tscb := NewTwoStepCircuitBreaker(Settings{Name: "expvar"})
+	tscb.PublishExpvar()
+	assert.Nil(t, fail2Step(tscb))
+	v = expvar.Get("gobreaker").(*expvar.Map).Get("expvar")
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &s))
+	assert.Equal(t, StateClosed, s.State)
+}
@@ -0,0 +1,88 @@
+package gobreaker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// fastPath holds what the CircuitBreaker reads without the mutex:
+// State and the rejections of the open state don't contend with the requests.
+// lastUsed and rejections come first to be 64-bit aligned for the atomic operations on 32-bit platforms.
+type fastPath struct {
+	lastUsed   int64        // UnixNano of the latest request, for the janitor
+	rejections int64        // requests rejected in the current generation, for the Logger
+	listeners  int32        // number of the subscribers to the events
+	draining   int32        // 1 while Drain waits, for the requests finishing in the stripes
+	state      atomic.Value // fastState
+	flags      atomic.Value // *flagBlock, the flags of the callbacks of Allow
+}
+
+// fastState is a consistent copy of the state, the expiry, the next Schedule check,
+// the generation and its stripes of the CircuitBreaker.
+type fastState struct {
+	state      State
+	expiry     time.Time
+	nextCheck  time.Time
+	generation uint64
+	counts     []stripe
+}
+
+// stable reports whether f is still current at now, i.e. currentState wouldn't change it.
+func (f fastState) stable(now time.Time) bool {
+	if !f.nextCheck.IsZero() && !now.Before(f.nextCheck) {
+		return false
+	}
+	switch f.state {
+	case StateClosed:
+		return f.expiry.IsZero() || !f.expiry.Before(now)
+	case StateOpen:
+		return !f.expiry.Before(now)
+	default:
+		return true
+	}
+}
+
+// storeFast publishes the fastState for the lock-free readers.
+// It must be called with cb.mutex held whenever any of its fields changes.
+func (cb *CircuitBreaker) storeFast() {
+	cb.fast.state.Store(fastState{
+		state:      cb.state,
+		expiry:     cb.expiry,
+		nextCheck:  cb.nextCheck,
+		generation: cb.generation,
+		counts:     cb.counted,
+	})
+}
+
+func (cb *CircuitBreaker) loadFast() fastState {
+	f, _ := cb.fast.state.Load().(fastState)
+	return f
+}
+
+func (cb *CircuitBreaker) touch(now time.Time) {
+	atomic.StoreInt64(&cb.fast.lastUsed, now.UnixNano())
+}
+
+func (cb *CircuitBreaker) lastUsed() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&cb.fast.lastUsed))
+}
+
+// rejectFast rejects a request without taking the mutex if the CircuitBreaker is open at now.
+// It returns nil if the request must go through beforeRequest, which is also the case
+// while there are subscribers, as the rejection events carry the Counts.
+func (cb *CircuitBreaker) rejectFast(ctx context.Context, now time.Time) error {
+	f := cb.loadFast()
+	if f.state != StateOpen && f.state != StateForcedOpen || !f.stable(now) ||
+		atomic.LoadInt32(&cb.fast.listeners) > 0 {
+		return nil
+	}
+
+	cb.touch(now)
+	cb.onReject(ctx, f.state)
+	var retryAfter time.Duration
+	if f.state == StateOpen {
+		retryAfter = f.expiry.Sub(now)
+	}
+	return &RejectionError{Name: cb.name, State: f.state, RetryAfter: retryAfter, Err: ErrOpenState}
+}
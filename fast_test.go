@@ -0,0 +1,72 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectFast(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Timeout: time.Minute, Clock: clock})
+	cb.Trip()
+	clock.Advance(10 * time.Second)
+
+	// neither State nor the rejection take the mutex while the CircuitBreaker is open
+	cb.mutex.Lock()
+	assert.Equal(t, StateOpen, cb.State())
+	_, err := cb.Execute(succeedFunc)
+	cb.mutex.Unlock()
+
+	var rejection *RejectionError
+	if assert.True(t, errors.As(err, &rejection)) {
+		assert.Equal(t, StateOpen, rejection.State)
+		assert.Equal(t, 50*time.Second, rejection.RetryAfter)
+		assert.True(t, errors.Is(err, ErrOpenState))
+	}
+	assert.True(t, clock.Now().Equal(cb.lastUsed()))
+
+	clock.Advance(50*time.Second + time.Nanosecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.loadFast().state)
+}
+
+func TestRejectFastSubscribed(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	events, unsubscribe := cb.Subscribe()
+	defer unsubscribe()
+
+	cb.Trip()
+	<-events
+	assert.True(t, errors.Is(fail(cb), ErrOpenState))
+	e := <-events
+	assert.Equal(t, EventRejection, e.Type)
+}
+
+func TestStateFastParallel(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				cb.State()
+				cb.Execute(succeedFunc)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		cb.Trip()
+		cb.Reset()
+	}
+	wg.Wait()
+}
+
+func succeedFunc() (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,58 @@
+package gobreaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// CrossGenerationPolicy is how a CircuitBreaker counts the result of a request that finishes
+// in a later generation than the one it started in, e.g. a slow request spanning the end
+// of an Interval, see Settings.CrossGeneration.
+type CrossGenerationPolicy int
+
+const (
+	// CrossGenerationDrop doesn't count the result, so that a generation only counts
+	// the requests it admitted. The failures of the slowest requests may go uncounted.
+	CrossGenerationDrop CrossGenerationPolicy = iota
+	// CrossGenerationCount counts the result into the current generation as a new request.
+	CrossGenerationCount
+	// CrossGenerationSlowFailure counts the request into the current generation as a failure,
+	// of FailureTimeout if it succeeded, since it was slow enough to outlive its generation.
+	CrossGenerationSlowFailure
+)
+
+// String implements stringer interface.
+func (p CrossGenerationPolicy) String() string {
+	switch p {
+	case CrossGenerationDrop:
+		return "drop"
+	case CrossGenerationCount:
+		return "count"
+	case CrossGenerationSlowFailure:
+		return "slow-failure"
+	default:
+		return fmt.Sprintf("unknown cross-generation policy: %d", p)
+	}
+}
+
+// slowFailure is a successful request counted as a failure by CrossGenerationSlowFailure.
+var slowFailure = failure{kind: FailureTimeout, weight: 1}
+
+// onCrossGeneration counts the result of a request that started in an earlier generation
+// according to the CrossGenerationPolicy. Only the closed states count such a result:
+// it must not decide the probes of the half-open state. It must be called with cb.mutex held.
+func (cb *CircuitBreaker) onCrossGeneration(state State, now time.Time, success bool, f failure) {
+	if cb.crossGeneration == CrossGenerationDrop || (state != StateClosed && state != StateForcedClosed) {
+		return
+	}
+
+	cb.counts.onRequest()
+	if cb.crossGeneration == CrossGenerationSlowFailure && success {
+		success, f = false, slowFailure
+	}
+	if success {
+		cb.onSuccess(state, now)
+	} else {
+		cb.onFailure(state, now, f)
+	}
+}
@@ -0,0 +1,69 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// spanInterval runs a request that returns err after the end of the Interval of cb.
+func spanInterval(cb *CircuitBreaker, clock *fakeClock, err error) {
+	cb.Execute(func() (interface{}, error) {
+		clock.Advance(2 * time.Second)
+		return nil, err
+	})
+}
+
+func TestCrossGeneration(t *testing.T) {
+	newBreaker := func(p CrossGenerationPolicy) (*CircuitBreaker, *fakeClock) {
+		clock := newFakeClock()
+		return NewCircuitBreaker(Settings{
+			Clock:           clock,
+			Interval:        time.Second,
+			CrossGeneration: p,
+			ReadyToTrip:     func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		}), clock
+	}
+
+	cb, clock := newBreaker(CrossGenerationDrop)
+	spanInterval(cb, clock, errors.New("fail"))
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	cb, clock = newBreaker(CrossGenerationCount)
+	spanInterval(cb, clock, nil)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+	spanInterval(cb, clock, errors.New("fail"))
+	assert.Equal(t, uint32(1), cb.Counts().TotalFailures)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	cb, clock = newBreaker(CrossGenerationSlowFailure)
+	spanInterval(cb, clock, nil)
+	counts := cb.Counts()
+	assert.Equal(t, uint32(1), counts.Requests)
+	assert.Equal(t, uint32(1), counts.TotalFailures)
+	assert.Equal(t, uint32(1), counts.FailuresByKind[FailureTimeout])
+	spanInterval(cb, clock, nil)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, uint32(1), cb.Counts().TotalFailures)
+
+	// a result from before a trip doesn't count in the half-open state
+	cb, clock = newBreaker(CrossGenerationSlowFailure)
+	cb.Execute(func() (interface{}, error) {
+		cb.Trip()
+		clock.Advance(time.Minute + time.Second)
+		return nil, nil
+	})
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, Counts{}, cb.Counts())
+}
+
+func TestCrossGenerationPolicyString(t *testing.T) {
+	assert.Equal(t, "drop", CrossGenerationDrop.String())
+	assert.Equal(t, "count", CrossGenerationCount.String())
+	assert.Equal(t, "slow-failure", CrossGenerationSlowFailure.String())
+	assert.Equal(t, "unknown cross-generation policy: 3", CrossGenerationPolicy(3).String())
+}
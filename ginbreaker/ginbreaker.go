@@ -0,0 +1,77 @@
+// Package ginbreaker guards the routes of a Gin engine with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on Gin.
+package ginbreaker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+	"github.com/sony/gobreaker/httpbreaker"
+)
+
+// StatusError is the error with which a failed response is reported to the Breaker,
+// so that IsSuccessful and Classify can tell the statuses apart.
+type StatusError struct {
+	Code int
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("ginbreaker: response status %d", e.Code)
+}
+
+// classifier returns the Classifier of the middleware: any of classifiers, or FailOn5xx if there are none.
+func classifier(classifiers []httpbreaker.Classifier) httpbreaker.Classifier {
+	if len(classifiers) == 0 {
+		return httpbreaker.FailOn5xx
+	}
+	return httpbreaker.Any(classifiers...)
+}
+
+// Middleware returns a gin.HandlerFunc that runs the rest of the handlers through cb,
+// e.g. for a route group. A response counts as a failure if any of classifiers fails it,
+// or if its status is 500 or more when there are no classifiers.
+// A request rejected by cb is aborted with 503 Service Unavailable,
+// and a Retry-After header if the time until the next attempt is known.
+func Middleware(cb gobreaker.Breaker, classifiers ...httpbreaker.Classifier) gin.HandlerFunc {
+	classify := classifier(classifiers)
+	return func(c *gin.Context) {
+		serve(c, cb, classify)
+	}
+}
+
+// PerRoute returns a gin.HandlerFunc like Middleware that runs each route through its own
+// CircuitBreaker, registered in r under the method and the path of the route,
+// e.g. "GET /users/:id", and created with st if it doesn't exist yet.
+// The requests that match no route are not guarded.
+func PerRoute(r *gobreaker.Registry, st gobreaker.Settings, classifiers ...httpbreaker.Classifier) gin.HandlerFunc {
+	classify := classifier(classifiers)
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		serve(c, r.GetOrCreate(c.Request.Method+" "+route, st), classify)
+	}
+}
+
+func serve(c *gin.Context, cb gobreaker.Breaker, classify httpbreaker.Classifier) {
+	_, err := cb.Execute(func() (interface{}, error) {
+		c.Next()
+		resp := &http.Response{StatusCode: c.Writer.Status(), Header: c.Writer.Header(), Request: c.Request}
+		if classify(resp, nil) {
+			return nil, &StatusError{Code: resp.StatusCode}
+		}
+		return nil, nil
+	})
+	if gobreaker.IsRejection(err) {
+		if retryAfter := gobreaker.RetryAfterHeader(err); retryAfter != "" {
+			c.Header("Retry-After", retryAfter)
+		}
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+	}
+}
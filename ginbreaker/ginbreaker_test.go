@@ -0,0 +1,80 @@
+package ginbreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+	"github.com/sony/gobreaker/httpbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	r := gin.New()
+	api := r.Group("/api", Middleware(cb))
+	api.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	api.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	api.GET("/broken", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+
+	assert.Equal(t, http.StatusOK, get(r, "/api/ok").Code)
+	assert.Equal(t, http.StatusNotFound, get(r, "/api/missing").Code)
+	assert.Equal(t, gobreaker.Counts{
+		Requests: 2, TotalSuccesses: 2, ConsecutiveSuccesses: 2,
+	}, cb.Counts())
+
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, http.StatusBadGateway, get(r, "/api/broken").Code)
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	w := get(r, "/api/ok")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestPerRoute(t *testing.T) {
+	registry := gobreaker.NewRegistry()
+	r := gin.New()
+	r.Use(PerRoute(registry, gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	}))
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/broken", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	assert.Equal(t, http.StatusInternalServerError, get(r, "/broken").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get(r, "/broken").Code)
+	assert.Equal(t, http.StatusOK, get(r, "/ok").Code)
+	assert.Equal(t, http.StatusNotFound, get(r, "/unknown").Code)
+
+	assert.Equal(t, 2, registry.Len())
+	cb, ok := registry.Get("GET /broken")
+	assert.True(t, ok)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+}
+
+func TestMiddlewareClassifiers(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	r := gin.New()
+	r.GET("/limited", Middleware(cb, httpbreaker.FailOnStatuses(http.StatusTooManyRequests)),
+		func(c *gin.Context) { c.Status(http.StatusTooManyRequests) })
+
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "/limited").Code)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	assert.Equal(t, http.StatusServiceUnavailable, get(r, "/limited").Code)
+}
+
+func TestStatusError(t *testing.T) {
+	assert.Equal(t, "ginbreaker: response status 503", (&StatusError{Code: 503}).Error())
+}
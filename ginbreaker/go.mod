@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/ginbreaker
+
+go 1.13
+
+require (
+	github.com/gin-gonic/gin v1.6.3
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.4.0
+)
+
+replace github.com/sony/gobreaker => ../
@@ -3,6 +3,7 @@
 package gobreaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -23,6 +24,17 @@ const (
 	StateOpen                  //2	熔断器开启
 )
 
+// These constants are administrative states of CircuitBreaker.
+// They override the automatic state machine until they are explicitly cleared:
+// StateForcedOpen rejects all requests,
+// StateForcedClosed allows all requests and still counts them without ever tripping,
+// StateDisabled allows all requests without counting them.
+const (
+	StateForcedOpen State = iota + 3
+	StateForcedClosed
+	StateDisabled
+)
+
 /*
  		Closed
          /    \
@@ -39,6 +51,12 @@ var (
 	ErrTooManyRequests = errors.New("too many requests")
 	// ErrOpenState is returned when the CB state is open
 	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrTooManyConcurrent is returned when the CB state is closed and the in-flight requests reach MaxConcurrent
+	ErrTooManyConcurrent = errors.New("too many concurrent requests")
+	// ErrThrottled is returned when the CB throttles a request according to its ThrottlePolicy
+	ErrThrottled = errors.New("request throttled")
+	// ErrDeadlineTooShort is returned when the deadline of a request is too short according to the DeadlinePolicy of the CB
+	ErrDeadlineTooShort = errors.New("deadline too short")
 )
 
 // String implements stringer interface.
@@ -50,6 +68,12 @@ func (s State) String() string {
 		return "half-open"
 	case StateOpen:
 		return "open"
+	case StateForcedOpen:
+		return "forced-open"
+	case StateForcedClosed:
+		return "forced-closed"
+	case StateDisabled:
+		return "disabled"
 	default:
 		return fmt.Sprintf("unknown state: %d", s)
 	}
@@ -59,6 +83,12 @@ func (s State) String() string {
 // CircuitBreaker clears the internal Counts either
 // on the change of the state or at the closed-state intervals.
 // Counts ignores the results of the requests sent before clearing.
+//
+// TotalFailureWeight and ConsecutiveFailureWeight are the sums of the weights of
+// the failures, see Settings.FailureWeight. Without FailureWeight every failure
+// weighs 1 and they are equal to TotalFailures and ConsecutiveFailures.
+//
+// FailuresByKind breaks TotalFailures down by FailureKind, see Settings.FailureKindOf.
 
 //范围: Generation周期内
 type Counts struct {
@@ -67,6 +97,10 @@ type Counts struct {
 	TotalFailures        uint32 // 总共失败次数
 	ConsecutiveSuccesses uint32 // 连续成功次数
 	ConsecutiveFailures  uint32 // 连续失败次数
+
+	TotalFailureWeight       uint32
+	ConsecutiveFailureWeight uint32
+	FailuresByKind           FailureCounts
 }
 
 func (c *Counts) onRequest() {
@@ -77,11 +111,15 @@ func (c *Counts) onSuccess() {
 	c.TotalSuccesses++
 	c.ConsecutiveSuccesses++
 	c.ConsecutiveFailures = 0 //连续失败清0
+	c.ConsecutiveFailureWeight = 0
 }
 
-func (c *Counts) onFailure() {
+func (c *Counts) onFailure(f failure) {
 	c.TotalFailures++
 	c.ConsecutiveFailures++
+	c.TotalFailureWeight += f.weight
+	c.ConsecutiveFailureWeight += f.weight
+	c.FailuresByKind[f.kind]++
 	c.ConsecutiveSuccesses = 0 //连续成功清0
 }
 
@@ -91,6 +129,9 @@ func (c *Counts) clear() {
 	c.TotalFailures = 0
 	c.ConsecutiveSuccesses = 0
 	c.ConsecutiveFailures = 0
+	c.TotalFailureWeight = 0
+	c.ConsecutiveFailureWeight = 0
+	c.FailuresByKind = FailureCounts{}
 }
 
 // Settings configures CircuitBreaker:
@@ -120,6 +161,154 @@ func (c *Counts) clear() {
 // If IsSuccessful returns false, the error is considered a failure, and is counted towards tripping the circuit breaker.
 // If IsSuccessful returns true, the error will be returned to the caller without tripping the circuit breaker.
 // If IsSuccessful is nil, default IsSuccessful is used, which returns false for all non-nil errors.
+//
+// Metrics receives request outcomes, rejections and state changes of the CircuitBreaker.
+// If Metrics is nil, the measurements are discarded.
+//
+// Backoff lengthens Timeout on each consecutive trip, see BackoffPolicy.
+// If Backoff is nil, the CircuitBreaker always stays open for Timeout.
+//
+// HalfOpenRamp admits a growing fraction of the traffic in the half-open state, see RampPolicy.
+// MaxRequests is ignored when HalfOpenRamp is set.
+// If HalfOpenRamp is nil or its Period is less than or equal to 0, MaxRequests is used.
+//
+// Exemplar extracts an Exemplar, such as the trace ID, from the context of a request
+// passed to ExecuteContext. If Metrics implements ExemplarSink, failures and rejections
+// are reported with the Exemplar so that metrics can link to representative traces.
+//
+// HalfOpenQueue lets requests beyond MaxRequests in the half-open state wait for the outcome
+// of the probes instead of failing with ErrTooManyRequests, see QueuePolicy.
+// If HalfOpenQueue is nil, such requests are rejected immediately.
+//
+// Probe checks the health of the dependency periodically while the CircuitBreaker is open,
+// and ends the open state early once the dependency recovers, see ProbePolicy.
+// If Probe is nil or has no Probe function, the CircuitBreaker stays open for Timeout.
+//
+// Clock tells the time to the CircuitBreaker, see Clock.
+// If Clock is nil, SystemClock is used.
+//
+// Schedule switches MaxRequests, Interval, Timeout and ReadyToTrip by the time of day, see Schedule.
+// Outside of the windows of the Schedule, the values above apply.
+//
+// HistorySize is the number of the latest state transitions kept for History.
+// If HistorySize is 0, DefaultHistorySize is used. If HistorySize is negative, no history is kept.
+//
+// OnStateChange2 is like OnStateChange but is also called with the Counts just before
+// the state change cleared them and the reason of the state change.
+// If both are set, OnStateChange is called first.
+//
+// AsyncNotify makes the CircuitBreaker call OnStateChange and OnStateChange2 from a separate
+// goroutine instead of while it holds its internal lock, so that a slow callback doesn't stall
+// the requests. The callbacks of a CircuitBreaker are still called one at a time, in the order
+// of the state changes.
+//
+// DoneTimeout is the time within which the done callback returned by TwoStepCircuitBreaker.Allow
+// is expected to be called. If it isn't, the CircuitBreaker counts the request as a FailureTimeout,
+// and logs the leak to the Logger if any, so that a forgotten callback doesn't hold a half-open slot forever.
+// If DoneTimeout is less than or equal to 0, the callbacks are not watched.
+//
+// IgnoreCanceled makes the CircuitBreaker release a request admitted by
+// TwoStepCircuitBreaker.AllowContext without counting it if its context is done
+// before its callback is called. Otherwise such a request is counted as a failure.
+//
+// MaxConcurrent is the maximum number of requests in flight while the CircuitBreaker is closed,
+// like a bulkhead. The requests beyond it are rejected with ErrTooManyConcurrent
+// without being counted. If MaxConcurrent is less than or equal to 0, there is no limit.
+//
+// Throttle replaces the open state with adaptive client-side throttling, see ThrottlePolicy.
+// If Throttle is nil, the CircuitBreaker trips according to ReadyToTrip.
+//
+// Limit adapts the number of requests in flight in the closed state to the observed latency,
+// see Limit. It applies in addition to MaxConcurrent. If Limit is nil, there is no adaptive limit.
+//
+// Classify is called with the error returned from the request, if not nil, and classifies
+// the request as a success, a failure, or neither, see Outcome. An ignored request doesn't
+// count in Counts and gives back its slot in the half-open state.
+// If Classify is set, IsSuccessful is not used.
+//
+// FailureErrors and IgnoredErrors are the errors that are always counted as failures and
+// always ignored, matched with errors.Is, or with errors.As for the errors returned by ErrorType.
+// They are checked in this order before Classify and IsSuccessful.
+//
+// FailureWeight is called with the error of every request counted as a failure and returns
+// its weight in TotalFailureWeight and ConsecutiveFailureWeight of Counts, so that ReadyToTrip
+// can trip on a weighted sum, e.g. a timeout weighing more than a generic error.
+// If FailureWeight is nil or returns 0, the weight is 1.
+//
+// FailureKindOf is called with the error of every request counted as a failure and returns
+// its FailureKind in FailuresByKind of Counts. If FailureKindOf is nil, DefaultFailureKind is used.
+// Panics are always counted as FailurePanic.
+//
+// PanicHandler converts a panic in a request run by Execute and the like into the error
+// the request returns, e.g. RecoverPanics. The error is classified like any other error,
+// so that FailureErrors, IgnoredErrors, Classify or IsSuccessful decide whether the panic
+// counts as a failure. If PanicHandler is nil, a panic counts as a failure and is raised again.
+//
+// IsSuccessfulResult is like IsSuccessful, but is called with the result of the request as well,
+// so that a response that signals a failure with a nil error, e.g. an HTTP 500 response or
+// a partially failed batch, can be counted as a failure. If IsSuccessfulResult is set,
+// IsSuccessful is not used. TwoStepCircuitBreaker.AllowE calls it with a nil result.
+//
+// MinimumRequests is the minimum number of requests in Counts before ReadyToTrip is called
+// in the closed state, so that a single failure in a quiet period doesn't look like a 100%
+// failure rate. If MinimumRequests is 0, ReadyToTrip is called on every failure.
+//
+// Recovery decides when the half-open CircuitBreaker closes, see RecoveryPolicy.
+// If Recovery is nil, the CircuitBreaker closes after MaxRequests consecutive successes.
+// HalfOpenRamp takes precedence over Recovery.
+//
+// MaxRequestsRatio scales the half-open capacity to the traffic: the half-open state admits
+// MaxRequestsRatio of the requests per second observed in the closed state before the trip,
+// e.g. 0.05 for 5% of the prior QPS, but at least MaxRequests.
+// If MaxRequestsRatio is less than or equal to 0, MaxRequests is used as is.
+//
+// Stripes is the number of stripes over which the CircuitBreaker counts the requests and
+// the successes in the closed state without taking its mutex, e.g. runtime.GOMAXPROCS(0),
+// for breakers that serve a very high request rate. The stripes are folded into Counts
+// whenever the CircuitBreaker takes a decision, e.g. on a failure, so that ReadyToTrip
+// sees all of them. OnRequest and OnSuccess of the Metrics are then called without the mutex, see MetricsSink.
+// Stripes has no effect with MaxConcurrent, Limit, Throttle, EWMA, Windows, ErrorBudget, Deadline or
+// LatencyHistogram, or while there are subscribers to the events.
+// If Stripes is less than or equal to 0, every request takes the mutex.
+//
+// EWMA trips the closed CircuitBreaker on moving averages of the failure rate and the latency,
+// see EWMAPolicy. If EWMA is nil, the CircuitBreaker trips according to ReadyToTrip only.
+//
+// Windows are sliding windows over which the closed CircuitBreaker evaluates trip conditions
+// in addition to ReadyToTrip, see DetectionWindow. The CircuitBreaker trips as soon as any of them
+// is ready to trip. MinimumRequests applies to each of them.
+//
+// ErrorBudget trips the closed CircuitBreaker when the error budget of a service level objective
+// burns too fast, see ErrorBudgetPolicy. MinimumRequests applies to each of its windows.
+//
+// Shadow is a candidate configuration evaluated alongside the live one, e.g. to try new thresholds
+// safely. A shadow CircuitBreaker configured with Shadow is fed the outcome of every request that
+// the CircuitBreaker admits, and reports its hypothetical state changes, rejections and outcomes
+// to the Metrics of Shadow, without affecting the admission of the requests, see CircuitBreaker.Shadow.
+// If the Name of Shadow is empty, the name of the CircuitBreaker followed by ".shadow" is used,
+// and if its Metrics is nil, the Metrics of the CircuitBreaker. Its Clock, HalfOpenQueue, Probe
+// and DoneTimeout are ignored. The requests rejected by the CircuitBreaker don't reach the shadow.
+//
+// Logger logs the configuration, the state changes and the rejections of the CircuitBreaker
+// with structured fields, see Logger. If Logger is nil, nothing is logged.
+//
+// Cache keeps the last successful result of ExecuteCached for each key, to serve it stale
+// while the CircuitBreaker is open, see CachePolicy. If Cache is nil, ExecuteCached is like Execute.
+//
+// Deadline rejects upfront the requests whose context deadline is shorter than the latency
+// that the CircuitBreaker observed, see DeadlinePolicy. If Deadline is nil, the deadlines are not checked.
+//
+// Jitter is the fraction, between 0 and MaxJitter, of the Interval of each closed-state generation
+// that is randomly subtracted from it, so that the CircuitBreakers of a fleet don't clear their Counts
+// in lockstep. It shortens Timeout likewise, unless Backoff is set, which has its own Jitter.
+// If Jitter is less than or equal to 0, Interval and Timeout are used as is.
+//
+// LatencyHistogram keeps a histogram of the latencies of the requests of each generation alongside
+// Counts, see CircuitBreaker.LatencyHistogram, and reports it to Metrics when the generation ends
+// if Metrics implements HistogramSink. If LatencyHistogram is false, no histogram is kept.
+//
+// CrossGeneration is how the result of a request that finishes in a later generation than
+// the one it started in is counted, see CrossGenerationPolicy. By default it is dropped.
 
 //breaker 配置
 type Settings struct {
@@ -130,6 +319,45 @@ type Settings struct {
 	ReadyToTrip   func(counts Counts) bool                // Closed状态时,当报错时调用它。当连续错误达到一定数量时，进入Open状态
 	OnStateChange func(name string, from State, to State) // 状态变化时调用
 	IsSuccessful  func(err error) bool
+	Metrics       MetricsSink
+	Backoff       *BackoffPolicy
+	HalfOpenRamp  *RampPolicy
+	Exemplar      func(ctx context.Context) (Exemplar, bool)
+	HalfOpenQueue *QueuePolicy
+	Probe         *ProbePolicy
+	Clock         Clock
+	Schedule      Schedule
+	HistorySize   int
+
+	OnStateChange2 func(name string, from State, to State, counts Counts, reason TripReason)
+	AsyncNotify    bool
+	DoneTimeout    time.Duration
+	IgnoreCanceled bool
+	MaxConcurrent  int
+	Throttle       *ThrottlePolicy
+	Limit          Limit
+	Classify       func(err error) Outcome
+	FailureErrors  []error
+	IgnoredErrors  []error
+	FailureWeight  func(err error) uint32
+	FailureKindOf  func(err error) FailureKind
+	PanicHandler   func(v interface{}) error
+	Recovery       *RecoveryPolicy
+	Stripes        int
+	EWMA           *EWMAPolicy
+	Windows        []DetectionWindow
+	ErrorBudget    *ErrorBudgetPolicy
+	Shadow         *Settings
+	Logger         Logger
+
+	IsSuccessfulResult func(result interface{}, err error) bool
+	MinimumRequests    uint32
+	MaxRequestsRatio   float64
+	Cache              *CachePolicy
+	Deadline           *DeadlinePolicy
+	Jitter             float64
+	LatencyHistogram   bool
+	CrossGeneration    CrossGenerationPolicy
 }
 
 // CircuitBreaker is a state machine to prevent sending requests that are likely to fail.
@@ -141,12 +369,71 @@ type CircuitBreaker struct {
 	readyToTrip   func(counts Counts) bool
 	isSuccessful  func(err error) bool
 	onStateChange func(name string, from State, to State)
+	metrics       MetricsSink
+	backoff       *BackoffPolicy
+	ramp          *RampPolicy
+	schedule      Schedule
+	basePolicy    Policy
+	exemplar      func(ctx context.Context) (Exemplar, bool)
+	queue         *QueuePolicy
+	probe         *ProbePolicy
+	clock         Clock
+
+	onStateChange2 func(name string, from State, to State, counts Counts, reason TripReason)
+	notifier       *notifier
+	doneTimeout    time.Duration
+	ignoreCanceled bool
+	maxConcurrent  int
+	throttle       *throttle
+	limit          Limit
+	classifier     func(err error) Outcome
+	failureErrors  []error
+	ignoredErrors  []error
+	weigher        func(err error) uint32
+	failureKind    func(err error) FailureKind
+	panicHandler   func(v interface{}) error
+	recovery       *RecoveryPolicy
+	stripes        *stripes
+	ewma           *ewma
+	windows        []*rolling
+	budget         *errorBudget
+	cache          *resultCache
+	latencies      *latencySamples
+	histogram      *LatencyHistogram
+	jitter         float64
+	shadow         *CircuitBreaker
+	logger         Logger
+
+	isSuccessfulResult func(result interface{}, err error) bool
+	minimumRequests    uint32
+	maxRequestsRatio   float64
+	crossGeneration    CrossGenerationPolicy
 
 	mutex      sync.Mutex
 	state      State  //熔断器的当前状态，初始化为0（关闭状态）
 	generation uint64 //当前的代数，从0开始
 	counts     Counts
 	expiry     time.Time
+	stateSince time.Time
+	trips      uint32 // consecutive trips since the CircuitBreaker was last closed
+	activeRule int    // index of the active ScheduleRule, -1 for basePolicy
+	nextCheck  time.Time
+	waiters    int           // requests waiting in the half-open queue
+	stateDone  chan struct{} // closed on the next state change to wake the waiters
+	drained    chan struct{} // closed when no request is in flight to wake Drain
+	reprobed   map[string]struct{}
+	probes     map[string]*probeCall // half-open probes of ExecuteCoalesced by key
+	listeners  []chan Event
+	history    []Transition // ring buffer of the latest transitions
+	historyPos int          // index of the next transition in history
+	lastErr    error        // error of the latest failure
+	lastTrip   TripInfo     // latest trip, see LastTrip
+	inflight   int          // requests admitted and not finished yet
+	genStart   time.Time    // start of the current generation
+	genState   State        // state of the current generation
+	tripRate   float64      // requests per second in the closed state before the latest trip
+	fast       *fastPath
+	counted    []stripe // stripes of the current generation, see Settings.Stripes
 }
 
 // TwoStepCircuitBreaker is like CircuitBreaker but instead of surrounding a function
@@ -160,42 +447,127 @@ type TwoStepCircuitBreaker struct {
 //初始化对象
 func NewCircuitBreaker(st Settings) *CircuitBreaker {
 	cb := new(CircuitBreaker)
+	cb.fast = new(fastPath)
 
 	cb.name = st.Name
 	cb.onStateChange = st.OnStateChange //onStateChange为用户传入的自定义函数
+	cb.onStateChange2 = st.OnStateChange2
+	cb.doneTimeout = st.DoneTimeout
+	cb.ignoreCanceled = st.IgnoreCanceled
+	cb.maxConcurrent = st.MaxConcurrent
+	if st.Throttle != nil {
+		cb.throttle = newThrottle(st.Throttle)
+	}
+	cb.limit = st.Limit
+	cb.classifier = st.Classify
+	cb.failureErrors = append([]error(nil), st.FailureErrors...)
+	cb.ignoredErrors = append([]error(nil), st.IgnoredErrors...)
+	cb.weigher = st.FailureWeight
+	cb.failureKind = DefaultFailureKind
+	if st.FailureKindOf != nil {
+		cb.failureKind = st.FailureKindOf
+	}
+	if st.AsyncNotify {
+		cb.notifier = new(notifier)
+	}
 
-	if st.MaxRequests == 0 {
-		cb.maxRequests = 1
+	cb.basePolicy = st.policy()
+	cb.applyPolicy(cb.basePolicy)
+
+	if st.IsSuccessful == nil {
+		cb.isSuccessful = defaultIsSuccessful
 	} else {
-		cb.maxRequests = st.MaxRequests
+		cb.isSuccessful = st.IsSuccessful
+	}
+	cb.isSuccessfulResult = st.IsSuccessfulResult
+	cb.panicHandler = st.PanicHandler
+	cb.maxRequestsRatio = st.MaxRequestsRatio
+	cb.crossGeneration = st.CrossGeneration
+	if st.Stripes > 0 && st.MaxConcurrent <= 0 && st.Limit == nil && st.Throttle == nil &&
+		st.EWMA == nil && len(st.Windows) == 0 && st.ErrorBudget == nil && st.Deadline == nil && !st.LatencyHistogram {
+		cb.stripes = newStripes(st.Stripes)
+	}
+	if st.Recovery != nil {
+		recovery := *st.Recovery
+		cb.recovery = &recovery
+	}
+	if st.EWMA != nil {
+		cb.ewma = newEWMA(*st.EWMA)
+	}
+	for _, w := range st.Windows {
+		cb.windows = append(cb.windows, newRolling(w))
+	}
+	if st.ErrorBudget != nil {
+		cb.budget = newErrorBudget(*st.ErrorBudget)
+	}
+	if st.Cache != nil {
+		cb.cache = newResultCache(*st.Cache)
+	}
+	if st.Deadline != nil {
+		cb.latencies = newLatencySamples(*st.Deadline)
+	}
+	if st.LatencyHistogram {
+		cb.histogram = new(LatencyHistogram)
 	}
+	cb.jitter = st.Jitter
 
-	if st.Interval <= 0 {
-		cb.interval = defaultInterval
+	if st.Metrics == nil {
+		cb.metrics = noopMetricsSink{}
 	} else {
-		cb.interval = st.Interval
+		cb.metrics = st.Metrics
 	}
 
-	if st.Timeout <= 0 {
-		cb.timeout = defaultTimeout
-	} else {
-		cb.timeout = st.Timeout
+	if st.Backoff != nil {
+		backoff := *st.Backoff
+		cb.backoff = &backoff
 	}
 
-	if st.ReadyToTrip == nil {
-		cb.readyToTrip = defaultReadyToTrip
-	} else {
-		cb.readyToTrip = st.ReadyToTrip
+	if st.HalfOpenRamp != nil && st.HalfOpenRamp.Period > 0 {
+		ramp := *st.HalfOpenRamp
+		cb.ramp = &ramp
 	}
 
-	if st.IsSuccessful == nil {
-		cb.isSuccessful = defaultIsSuccessful
+	cb.exemplar = st.Exemplar
+	cb.logger = st.Logger
+
+	if st.HistorySize == 0 {
+		cb.history = make([]Transition, 0, DefaultHistorySize)
+	} else if st.HistorySize > 0 {
+		cb.history = make([]Transition, 0, st.HistorySize)
+	}
+
+	if st.HalfOpenQueue != nil && st.HalfOpenQueue.Size > 0 {
+		queue := *st.HalfOpenQueue
+		cb.queue = &queue
+	}
+
+	if st.Probe != nil && st.Probe.Probe != nil {
+		probe := *st.Probe
+		cb.probe = &probe
+	}
+
+	if len(st.Schedule) > 0 {
+		cb.schedule = append(Schedule(nil), st.Schedule...)
+		cb.activeRule = -1
+	}
+
+	if st.Clock == nil {
+		cb.clock = SystemClock
 	} else {
-		cb.isSuccessful = st.IsSuccessful
+		cb.clock = st.Clock
 	}
 
 	//初始化cb的expiry时间
-	cb.toNewGeneration(time.Now())
+	now := cb.clock.Now()
+	cb.stateSince = now
+	cb.touch(now)
+	cb.applySchedule(now)
+	cb.toNewGeneration(now)
+
+	if st.Shadow != nil {
+		cb.shadow = cb.newShadow(*st.Shadow)
+	}
+	cb.logCreated()
 
 	return cb
 }
@@ -226,10 +598,14 @@ func (cb *CircuitBreaker) Name() string {
 // State returns the current state of the CircuitBreaker.
 //获取当前的熔断器状态，需要原子操作
 func (cb *CircuitBreaker) State() State {
+	now := cb.clock.Now()
+	if f := cb.loadFast(); f.stable(now) {
+		return f.state
+	}
+
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	now := time.Now()
 	//获取当前的状态
 	state, _ := cb.currentState(now)
 	return state
@@ -241,17 +617,41 @@ func (cb *CircuitBreaker) Counts() Counts {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
+	cb.foldStripes()
 	return cb.counts
 }
 
+// RemainingTimeout returns how long the CircuitBreaker remains open.
+// RemainingTimeout returns 0 if the CircuitBreaker is not open.
+func (cb *CircuitBreaker) RemainingTimeout() time.Duration {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.remainingTimeout(cb.clock.Now())
+}
+
+// NextAttemptAt returns the time at which the CircuitBreaker becomes half-open and allows a new attempt.
+// NextAttemptAt returns the zero time if the CircuitBreaker is not open.
+func (cb *CircuitBreaker) NextAttemptAt() time.Time {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, _ := cb.currentState(cb.clock.Now())
+	if state != StateOpen {
+		return time.Time{}
+	}
+	return cb.expiry
+}
+
 // Execute runs the given request if the CircuitBreaker accepts it.
 // Execute returns an error instantly if the CircuitBreaker rejects the request.
 // Otherwise, Execute returns the result of the request.
 // If a panic occurs in the request, the CircuitBreaker handles it as an error
-// and causes the same panic again.
+// and causes the same panic again, unless PanicHandler is set.
 //核心执行函数Execute： 该函数分为三步 beforeRequest、 执行请求、 afterRequest
-func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	generation, err := cb.beforeRequest()
+func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (result interface{}, err error) {
+	ctx := context.Background()
+	generation, start, err := cb.beforeRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -259,19 +659,91 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
-			panic(e) //if panic，继续panic给上层调用者去recover，有趣
+			outcome, f, perr := cb.recoverPanic(e)
+			cb.finishRequest(ctx, generation, start, outcome, f)
+			if perr == nil {
+				panic(e) //if panic，继续panic给上层调用者去recover，有趣
+			}
+			result, err = nil, perr
 		}
 	}()
 
 	//执行真正的用户调用
-	result, err := req()
+	result, err = req()
 
 	//调用后更新熔断器状态
-	cb.afterRequest(generation, cb.isSuccessful(err))
+	cb.finishResult(ctx, generation, start, result, err)
+	return result, err
+}
+
+// ExecuteContext is like Execute but passes ctx to the request.
+// The CircuitBreaker also uses ctx for the request, e.g. to extract an Exemplar.
+func (cb *CircuitBreaker) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := recover()
+		if e != nil {
+			outcome, f, perr := cb.recoverPanic(e)
+			cb.finishRequest(ctx, generation, start, outcome, f)
+			if perr == nil {
+				panic(e)
+			}
+			result, err = nil, perr
+		}
+	}()
+
+	result, err = req(ctx)
+	cb.finishResult(ctx, generation, start, result, err)
 	return result, err
 }
 
+// Trip forces the CircuitBreaker into the open state.
+// The CircuitBreaker becomes half-open after Timeout as usual.
+// Trip does nothing if the CircuitBreaker is already open.
+func (cb *CircuitBreaker) Trip() {
+	cb.forceState(StateOpen, false)
+}
+
+// Reset forces the CircuitBreaker into the closed state and clears its Counts.
+func (cb *CircuitBreaker) Reset() {
+	cb.forceState(StateClosed, true)
+}
+
+// ForceOpen places the CircuitBreaker into StateForcedOpen, e.g. during maintenance.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.forceState(StateForcedOpen, false)
+}
+
+// ForceClose places the CircuitBreaker into StateForcedClosed.
+func (cb *CircuitBreaker) ForceClose() {
+	cb.forceState(StateForcedClosed, false)
+}
+
+// Disable places the CircuitBreaker into StateDisabled.
+func (cb *CircuitBreaker) Disable() {
+	cb.forceState(StateDisabled, false)
+}
+
+// ClearOverride returns the CircuitBreaker from an administrative state
+// to the automatic state machine, starting in the closed state.
+// ClearOverride does nothing if the CircuitBreaker is not in an administrative state.
+func (cb *CircuitBreaker) ClearOverride() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state.overridden() {
+		cb.setState(StateClosed, cb.clock.Now(), ReasonManual)
+	}
+}
+
+func (s State) overridden() bool {
+	return s == StateForcedOpen || s == StateForcedClosed || s == StateDisabled
+}
+
 // Name returns the name of the TwoStepCircuitBreaker.
 func (tscb *TwoStepCircuitBreaker) Name() string {
 	return tscb.cb.Name()
@@ -287,18 +759,46 @@ func (tscb *TwoStepCircuitBreaker) Counts() Counts {
 	return tscb.cb.Counts()
 }
 
+// RemainingTimeout returns how long the TwoStepCircuitBreaker remains open.
+func (tscb *TwoStepCircuitBreaker) RemainingTimeout() time.Duration {
+	return tscb.cb.RemainingTimeout()
+}
+
+// NextAttemptAt returns the time at which the TwoStepCircuitBreaker allows a new attempt.
+func (tscb *TwoStepCircuitBreaker) NextAttemptAt() time.Time {
+	return tscb.cb.NextAttemptAt()
+}
+
+// Trip forces the TwoStepCircuitBreaker into the open state.
+func (tscb *TwoStepCircuitBreaker) Trip() {
+	tscb.cb.Trip()
+}
+
+// Reset forces the TwoStepCircuitBreaker into the closed state and clears its Counts.
+func (tscb *TwoStepCircuitBreaker) Reset() {
+	tscb.cb.Reset()
+}
+
 // Allow checks if a new request can proceed. It returns a callback that should be used to
 // register the success or failure in a separate step. If the circuit breaker doesn't allow
 // requests, it returns an error.
+// Only the first call of the callback counts; the later calls are ignored.
 func (tscb *TwoStepCircuitBreaker) Allow() (done func(success bool), err error) {
-	generation, err := tscb.cb.beforeRequest()
+	d, err := tscb.cb.allowDone(true)
 	if err != nil {
 		return nil, err
 	}
+	return d.Report, nil
+}
 
-	return func(success bool) {
-		tscb.cb.afterRequest(generation, success)
-	}, nil
+// AllowE is like Allow, but the returned callback takes the error of the request
+// and classifies it with Classify or IsSuccessful, as Execute does.
+func (tscb *TwoStepCircuitBreaker) AllowE() (done func(err error), err error) {
+	d, err := tscb.cb.allowDone(true)
+	if err != nil {
+		return nil, err
+	}
+	return d.ReportError, nil
 }
 
 /*
@@ -310,25 +810,75 @@ beforeRequest函数的核心功能：判断是否放行请求，计数或达到
 3. 如果是half-open状态，则判断是否已放行MaxRequests个请求，如未达到刚放行；否则返回:ErrTooManyRequests。
 4. 此函数一旦放行请求，就会对请求计数加1（conut.onRequest())，请求后到另一个关键函数 : afterRequest()。
 */
-func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
+func (cb *CircuitBreaker) beforeRequest(ctx context.Context) (uint64, time.Time, error) {
+	now := cb.clock.Now()
+	if err := cb.rejectFast(ctx, now); err != nil {
+		return 0, now, err
+	}
+	if cb.stripes != nil {
+		if generation, ok := cb.beforeRequestStriped(now); ok {
+			return generation, now, nil
+		}
+	}
+
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	now := time.Now()
+	var deadline time.Time
+	cb.touch(now)
 	//获取当前熔断器的状态和generation
 	state, generation := cb.currentState(now)
 
-	if state == StateOpen {
+	if state == StateHalfOpen && cb.shedHalfOpen(ctx, now) {
+		cb.onReject(ctx, state)
+		cb.publishRejection(state, now, ErrTooManyRequests)
+		return generation, now, cb.rejection(state, now, ErrTooManyRequests)
+	}
+
+	for state == StateHalfOpen && !cb.admitHalfOpen(now) {
+		//half-open状态 && 请求超量，排队等待探测结果，否则拒绝请求
+		if err := cb.waitHalfOpen(ctx, &deadline); err != nil {
+			cb.onReject(ctx, state)
+			cb.publishRejection(state, now, err)
+			return generation, now, cb.rejection(state, now, err)
+		}
+		now = cb.clock.Now()
+		state, generation = cb.currentState(now)
+	}
+
+	if state == StateOpen || state == StateForcedOpen {
 		//若打开，禁止请求
-		return generation, ErrOpenState
-	} else if state == StateHalfOpen && cb.counts.Requests >= cb.maxRequests {
-		//half-open状态 && 请求超量，也拒绝请求
-		return generation, ErrTooManyRequests
+		cb.onReject(ctx, state)
+		cb.publishRejection(state, now, ErrOpenState)
+		return generation, now, cb.rejection(state, now, ErrOpenState)
+	} else if state == StateDisabled {
+		cb.inflight++
+		return generation, now, nil
+	}
+
+	if state == StateClosed && cb.concurrencyExceeded() {
+		cb.onReject(ctx, state)
+		cb.publishRejection(state, now, ErrTooManyConcurrent)
+		return generation, now, cb.rejection(state, now, ErrTooManyConcurrent)
+	}
+
+	if cb.deadlineTooShort(ctx, now) {
+		cb.onReject(ctx, state)
+		cb.publishRejection(state, now, ErrDeadlineTooShort)
+		return generation, now, cb.rejection(state, now, ErrDeadlineTooShort)
+	}
+
+	if state == StateClosed && cb.throttle != nil && !cb.throttle.allow(now, PriorityFrom(ctx)) {
+		cb.onReject(ctx, state)
+		cb.publishRejection(state, now, ErrThrottled)
+		return generation, now, cb.rejection(state, now, ErrThrottled)
 	}
 
 	//其他情况，放行请求，走到afterRequest逻辑
+	cb.inflight++
 	cb.counts.onRequest()
-	return generation, nil
+	cb.metrics.OnRequest(cb.name)
+	return generation, now, nil
 }
 
 /*
@@ -337,14 +887,52 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 currentState(now) 先判断是否进入一个先的计数时间周期(Interval), 是则重置计数，改变熔断器状态，并返回新一代。
 如果request耗时大于Interval, 几本每次都会进入新的计数周期，熔断器就没什么意义了
 */
-func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+func (cb *CircuitBreaker) afterRequest(ctx context.Context, before uint64, start time.Time, success bool, f failure) {
+	if success && cb.stripes != nil && cb.afterSuccessStriped(before, start, cb.clock.Now()) {
+		return
+	}
+
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	now := time.Now()
+	cb.finishInflight()
+	now := cb.clock.Now()
 	state, generation := cb.currentState(now)
+	if state == StateDisabled {
+		return
+	}
+
+	cb.observeHistogram(now.Sub(start))
+	if success {
+		cb.metrics.OnSuccess(cb.name, now.Sub(start))
+		if cb.latencies != nil {
+			cb.latencies.observe(now.Sub(start))
+		}
+		if state == StateClosed && cb.throttle != nil {
+			cb.throttle.onSuccess(now)
+		}
+	} else {
+		cb.onFailureMetrics(ctx, now.Sub(start))
+		if f.err != nil {
+			cb.lastErr = f.err
+		}
+	}
+	if state == StateClosed && cb.limit != nil {
+		cb.limit.Update(now.Sub(start), cb.inflightCount()+1, !success)
+	}
+	if state == StateClosed && cb.ewma != nil {
+		cb.ewma.observe(now, now.Sub(start), !success)
+	}
+	if state == StateClosed {
+		cb.observeWindows(now, success, f)
+		if cb.budget != nil {
+			cb.budget.observe(now, success, f)
+		}
+	}
+
 	if generation != before {
-		//说明，在currentState已经更新了代数，直接返回吧
+		//说明，在currentState已经更新了代数，按CrossGeneration处理
+		cb.onCrossGeneration(state, now, success, f)
 		return
 	}
 
@@ -353,37 +941,60 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 		//更新succ
 		cb.onSuccess(state, now)
 	} else {
-		cb.onFailure(state, now)
+		cb.onFailure(state, now, f)
 	}
 }
 
 func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 	switch state {
-	case StateClosed:
+	case StateClosed, StateForcedClosed:
 		cb.counts.onSuccess()
+		cb.publishOutcome(EventSuccess, state, now)
+		if state == StateClosed {
+			cb.tripOnEWMA(now)
+		}
 	case StateHalfOpen:
 		//在half-open状态下，如果（当前这代counts中）连续succ的数目超过maxRequests，那么则重置当前熔断器的状态为closed（关闭）
 		cb.counts.onSuccess()
-		if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
-			cb.setState(StateClosed, now)
+		cb.publishOutcome(EventSuccess, state, now)
+		if cb.ramp == nil && cb.recovery != nil {
+			cb.judgeProbes(now)
+		} else if cb.readyToClose(now) {
+			cb.setState(StateClosed, now, ReasonHalfOpenSuccess)
 		}
 		//这里不可能出现stateOpen状态
 	}
 }
 
 // 调用失败情况下的处理
-func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
+func (cb *CircuitBreaker) onFailure(state State, now time.Time, f failure) {
 	switch state {
 	case StateClosed:
-		cb.counts.onFailure() //失败计数++
-		if cb.readyToTrip(cb.counts) {
+		cb.counts.onFailure(f) //失败计数++
+		cb.publishOutcome(EventFailure, state, now)
+		if cb.throttle == nil && cb.counts.Requests >= cb.minimumRequests && cb.readyToTrip(cb.counts) {
 			//调用触发熔断器由关闭=>打开的判断方法（可由用户传入，默认方法defaultReadyToTrip是连续的错误次数>5）
 			//设置熔断器为打开状态
-			cb.setState(StateOpen, now)
+			cb.setState(StateOpen, now, ReasonReadyToTrip)
+		} else if cb.windowsReadyToTrip(now) {
+			cb.setState(StateOpen, now, ReasonReadyToTrip)
+		} else {
+			cb.tripOnEWMA(now)
+			cb.tripOnErrorBudget(now)
 		}
 	case StateHalfOpen:
 		//在half-open情况下，如果仍然调用失败，那么继续把熔断器设置为打开状态
-		cb.setState(StateOpen, now)
+		if cb.ramp == nil && cb.recovery != nil {
+			cb.counts.onFailure(f)
+			cb.publishOutcome(EventFailure, state, now)
+			cb.judgeProbes(now)
+			return
+		}
+		cb.publishOutcome(EventFailure, state, now)
+		cb.setState(StateOpen, now, ReasonHalfOpenFailure)
+	case StateForcedClosed:
+		cb.counts.onFailure(f)
+		cb.publishOutcome(EventFailure, state, now)
 	}
 }
 
@@ -391,6 +1002,9 @@ func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
 //1、当Closed时且expiry过期，调用toNewGeneration生成新的generation
 //2、当Open时且expiry过期，设为halfOpen
 func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
+	cb.foldStripes()
+	cb.applySchedule(now)
+
 	switch cb.state {
 	//熔断器关闭时
 	case StateClosed:
@@ -404,28 +1018,88 @@ func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
 		if cb.expiry.Before(now) {
 			//如果打开时，cb.expiry过期，那么熔断器需要进入half-open状态
 			//注意：在此来完成从熔断器打开=>熔断器半打开的触发逻辑！！！！！
-			cb.setState(StateHalfOpen, now)
+			cb.setState(StateHalfOpen, now, ReasonTimeout)
 		}
 	}
 	return cb.state, cb.generation
 }
 
+// remainingTimeout returns how long the CircuitBreaker remains open after now.
+func (cb *CircuitBreaker) remainingTimeout(now time.Time) time.Duration {
+	state, _ := cb.currentState(now)
+	if state != StateOpen {
+		return 0
+	}
+	return cb.expiry.Sub(now)
+}
+
 //设置当前熔断器状态
-func (cb *CircuitBreaker) setState(state State, now time.Time) {
+func (cb *CircuitBreaker) setState(state State, now time.Time, reason TripReason) {
 	if cb.state == state {
 		//无需设置
 		return
 	}
 
 	prev := cb.state
+	counts := cb.counts
+	cb.recordTransition(prev, state, now, reason)
+	cb.recordTrip(prev, state, now, reason)
+	cb.logRejections(now)
+	cb.logStateChange(prev, state, counts, reason)
+	cb.publishStateChange(prev, state, now, reason)
 	cb.state = state
+	switch state {
+	case StateOpen:
+		cb.trips++
+		if prev == StateClosed {
+			cb.observeRate(counts, now)
+		}
+	case StateClosed:
+		cb.trips = 0
+		if cb.ewma != nil {
+			cb.ewma.reset()
+		}
+		cb.resetWindows()
+		if cb.budget != nil {
+			cb.budget.reset()
+		}
+	}
 	//每当设置新状态时，需要重置当前的generation
 	cb.toNewGeneration(now)
 
+	cb.metrics.OnStateChange(cb.name, prev, state, now.Sub(cb.stateSince))
+	cb.stateSince = now
+	cb.wakeWaiters()
+	cb.startProbe()
+
 	//如果用户设置了状态变迁回调，那么就调用
-	if cb.onStateChange != nil {
-		cb.onStateChange(cb.name, prev, state)
+	if cb.onStateChange != nil || cb.onStateChange2 != nil {
+		cb.notify(func() {
+			if cb.onStateChange != nil {
+				cb.onStateChange(cb.name, prev, state)
+			}
+			if cb.onStateChange2 != nil {
+				cb.onStateChange2(cb.name, prev, state, counts, reason)
+			}
+		})
+	}
+}
+
+// forceState moves the CircuitBreaker into state regardless of its counts.
+// If reset is true, a new generation is started even when the state doesn't change.
+func (cb *CircuitBreaker) forceState(state State, reset bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	cb.currentState(now)
+	if cb.state == state {
+		if reset {
+			cb.toNewGeneration(now)
+		}
+		return
 	}
+	cb.setState(state, now, ReasonManual)
 }
 
 //toNewGeneration: 生成新的generation。 主要是清空counts和设置expiry（过期时间）
@@ -433,9 +1107,14 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 //2. 当状态为Open时expiry为Open的过期时间（当前时间 + timeout）
 
 func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
+	cb.logRejections(now)
+	cb.endHistogram()
 	cb.generation++
 	//清空单个周期内的计数结构
 	cb.counts.clear()
+	cb.reprobed = nil
+	cb.genStart = now
+	cb.genState = cb.state
 
 	var zero time.Time
 	switch cb.state {
@@ -446,11 +1125,16 @@ func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
 			cb.expiry = zero
 		} else {
 			//
-			cb.expiry = now.Add(cb.interval)
+			cb.expiry = now.Add(jitter(cb.interval, cb.jitter))
 		}
 	case StateOpen:
-		cb.expiry = now.Add(cb.timeout)
+		cb.expiry = now.Add(cb.openTimeout())
 	default: // StateHalfOpen
 		cb.expiry = zero
 	}
+	cb.counted = nil
+	if cb.stripes != nil && cb.state == StateClosed {
+		cb.counted = cb.stripes.newGeneration()
+	}
+	cb.storeFast()
 }
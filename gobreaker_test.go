@@ -1,6 +1,7 @@
 package gobreaker
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"testing"
@@ -24,6 +25,7 @@ var stateChange StateChange
 func pseudoSleep(cb *CircuitBreaker, period time.Duration) {
 	if !cb.expiry.IsZero() {
 		cb.expiry = cb.expiry.Add(-period)
+		cb.storeFast()
 	}
 }
 
@@ -74,7 +76,7 @@ func fail2Step(cb *TwoStepCircuitBreaker) error {
 }
 
 func causePanic(cb *CircuitBreaker) error {
-	_, err := cb.Execute(func() (interface{}, error) { panic("oops"); return nil, nil })
+	_, err := cb.Execute(func() (interface{}, error) { panic("oops") })
 	return err
 }
 
@@ -134,7 +136,7 @@ func TestNewCircuitBreaker(t *testing.T) {
 	assert.NotNil(t, defaultCB.readyToTrip)
 	assert.Nil(t, defaultCB.onStateChange)
 	assert.Equal(t, StateClosed, defaultCB.state)
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, defaultCB.counts)
 	assert.True(t, defaultCB.expiry.IsZero())
 
 	customCB := newCustom()
@@ -145,7 +147,7 @@ func TestNewCircuitBreaker(t *testing.T) {
 	assert.NotNil(t, customCB.readyToTrip)
 	assert.NotNil(t, customCB.onStateChange)
 	assert.Equal(t, StateClosed, customCB.state)
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, customCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, customCB.counts)
 	assert.False(t, customCB.expiry.IsZero())
 
 	negativeDurationCB := newNegativeDurationCB()
@@ -156,7 +158,7 @@ func TestNewCircuitBreaker(t *testing.T) {
 	assert.NotNil(t, negativeDurationCB.readyToTrip)
 	assert.Nil(t, negativeDurationCB.onStateChange)
 	assert.Equal(t, StateClosed, negativeDurationCB.state)
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, negativeDurationCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, negativeDurationCB.counts)
 	assert.True(t, negativeDurationCB.expiry.IsZero())
 }
 
@@ -167,27 +169,27 @@ func TestDefaultCircuitBreaker(t *testing.T) {
 		assert.Nil(t, fail(defaultCB))
 	}
 	assert.Equal(t, StateClosed, defaultCB.State())
-	assert.Equal(t, Counts{5, 0, 5, 0, 5}, defaultCB.counts)
+	assert.Equal(t, Counts{5, 0, 5, 0, 5, 5, 5, FailureCounts{5}}, defaultCB.counts)
 
 	assert.Nil(t, succeed(defaultCB))
 	assert.Equal(t, StateClosed, defaultCB.State())
-	assert.Equal(t, Counts{6, 1, 5, 1, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{6, 1, 5, 1, 0, 5, 0, FailureCounts{5}}, defaultCB.counts)
 
 	assert.Nil(t, fail(defaultCB))
 	assert.Equal(t, StateClosed, defaultCB.State())
-	assert.Equal(t, Counts{7, 1, 6, 0, 1}, defaultCB.counts)
+	assert.Equal(t, Counts{7, 1, 6, 0, 1, 6, 1, FailureCounts{6}}, defaultCB.counts)
 
 	// StateClosed to StateOpen
 	for i := 0; i < 5; i++ {
 		assert.Nil(t, fail(defaultCB)) // 6 consecutive failures
 	}
 	assert.Equal(t, StateOpen, defaultCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, defaultCB.counts)
 	assert.False(t, defaultCB.expiry.IsZero())
 
 	assert.Error(t, succeed(defaultCB))
 	assert.Error(t, fail(defaultCB))
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, defaultCB.counts)
 
 	pseudoSleep(defaultCB, time.Duration(59)*time.Second)
 	assert.Equal(t, StateOpen, defaultCB.State())
@@ -200,7 +202,7 @@ func TestDefaultCircuitBreaker(t *testing.T) {
 	// StateHalfOpen to StateOpen
 	assert.Nil(t, fail(defaultCB))
 	assert.Equal(t, StateOpen, defaultCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, defaultCB.counts)
 	assert.False(t, defaultCB.expiry.IsZero())
 
 	// StateOpen to StateHalfOpen
@@ -211,7 +213,7 @@ func TestDefaultCircuitBreaker(t *testing.T) {
 	// StateHalfOpen to StateClosed
 	assert.Nil(t, succeed(defaultCB))
 	assert.Equal(t, StateClosed, defaultCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, defaultCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, defaultCB.counts)
 	assert.True(t, defaultCB.expiry.IsZero())
 }
 
@@ -223,23 +225,23 @@ func TestCustomCircuitBreaker(t *testing.T) {
 		assert.Nil(t, fail(customCB))
 	}
 	assert.Equal(t, StateClosed, customCB.State())
-	assert.Equal(t, Counts{10, 5, 5, 0, 1}, customCB.counts)
+	assert.Equal(t, Counts{10, 5, 5, 0, 1, 5, 1, FailureCounts{5}}, customCB.counts)
 
 	pseudoSleep(customCB, time.Duration(29)*time.Second)
 	assert.Nil(t, succeed(customCB))
 	assert.Equal(t, StateClosed, customCB.State())
-	assert.Equal(t, Counts{11, 6, 5, 1, 0}, customCB.counts)
+	assert.Equal(t, Counts{11, 6, 5, 1, 0, 5, 0, FailureCounts{5}}, customCB.counts)
 
 	pseudoSleep(customCB, time.Duration(1)*time.Second) // over Interval
 	assert.Nil(t, fail(customCB))
 	assert.Equal(t, StateClosed, customCB.State())
-	assert.Equal(t, Counts{1, 0, 1, 0, 1}, customCB.counts)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, customCB.counts)
 
 	// StateClosed to StateOpen
 	assert.Nil(t, succeed(customCB))
 	assert.Nil(t, fail(customCB)) // failure ratio: 2/3 >= 0.6
 	assert.Equal(t, StateOpen, customCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, customCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, customCB.counts)
 	assert.False(t, customCB.expiry.IsZero())
 	assert.Equal(t, StateChange{"cb", StateClosed, StateOpen}, stateChange)
 
@@ -252,16 +254,16 @@ func TestCustomCircuitBreaker(t *testing.T) {
 	assert.Nil(t, succeed(customCB))
 	assert.Nil(t, succeed(customCB))
 	assert.Equal(t, StateHalfOpen, customCB.State())
-	assert.Equal(t, Counts{2, 2, 0, 2, 0}, customCB.counts)
+	assert.Equal(t, Counts{2, 2, 0, 2, 0, 0, 0, FailureCounts{}}, customCB.counts)
 
 	// StateHalfOpen to StateClosed
 	ch := succeedLater(customCB, time.Duration(100)*time.Millisecond) // 3 consecutive successes
 	time.Sleep(time.Duration(50) * time.Millisecond)
-	assert.Equal(t, Counts{3, 2, 0, 2, 0}, customCB.counts)
+	assert.Equal(t, Counts{3, 2, 0, 2, 0, 0, 0, FailureCounts{}}, customCB.counts)
 	assert.Error(t, succeed(customCB)) // over MaxRequests
 	assert.Nil(t, <-ch)
 	assert.Equal(t, StateClosed, customCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, customCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, customCB.counts)
 	assert.False(t, customCB.expiry.IsZero())
 	assert.Equal(t, StateChange{"cb", StateHalfOpen, StateClosed}, stateChange)
 }
@@ -275,27 +277,27 @@ func TestTwoStepCircuitBreaker(t *testing.T) {
 	}
 
 	assert.Equal(t, StateClosed, tscb.State())
-	assert.Equal(t, Counts{5, 0, 5, 0, 5}, tscb.cb.counts)
+	assert.Equal(t, Counts{5, 0, 5, 0, 5, 5, 5, FailureCounts{5}}, tscb.cb.counts)
 
 	assert.Nil(t, succeed2Step(tscb))
 	assert.Equal(t, StateClosed, tscb.State())
-	assert.Equal(t, Counts{6, 1, 5, 1, 0}, tscb.cb.counts)
+	assert.Equal(t, Counts{6, 1, 5, 1, 0, 5, 0, FailureCounts{5}}, tscb.cb.counts)
 
 	assert.Nil(t, fail2Step(tscb))
 	assert.Equal(t, StateClosed, tscb.State())
-	assert.Equal(t, Counts{7, 1, 6, 0, 1}, tscb.cb.counts)
+	assert.Equal(t, Counts{7, 1, 6, 0, 1, 6, 1, FailureCounts{6}}, tscb.cb.counts)
 
 	// StateClosed to StateOpen
 	for i := 0; i < 5; i++ {
 		assert.Nil(t, fail2Step(tscb)) // 6 consecutive failures
 	}
 	assert.Equal(t, StateOpen, tscb.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, tscb.cb.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, tscb.cb.counts)
 	assert.False(t, tscb.cb.expiry.IsZero())
 
 	assert.Error(t, succeed2Step(tscb))
 	assert.Error(t, fail2Step(tscb))
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, tscb.cb.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, tscb.cb.counts)
 
 	pseudoSleep(tscb.cb, time.Duration(59)*time.Second)
 	assert.Equal(t, StateOpen, tscb.State())
@@ -308,7 +310,7 @@ func TestTwoStepCircuitBreaker(t *testing.T) {
 	// StateHalfOpen to StateOpen
 	assert.Nil(t, fail2Step(tscb))
 	assert.Equal(t, StateOpen, tscb.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, tscb.cb.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, tscb.cb.counts)
 	assert.False(t, tscb.cb.expiry.IsZero())
 
 	// StateOpen to StateHalfOpen
@@ -319,13 +321,13 @@ func TestTwoStepCircuitBreaker(t *testing.T) {
 	// StateHalfOpen to StateClosed
 	assert.Nil(t, succeed2Step(tscb))
 	assert.Equal(t, StateClosed, tscb.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, tscb.cb.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, tscb.cb.counts)
 	assert.True(t, tscb.cb.expiry.IsZero())
 }
 
 func TestPanicInRequest(t *testing.T) {
 	assert.Panics(t, func() { causePanic(defaultCB) })
-	assert.Equal(t, Counts{1, 0, 1, 0, 1}, defaultCB.counts)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{FailurePanic: 1}}, defaultCB.counts)
 }
 
 func TestGeneration(t *testing.T) {
@@ -333,15 +335,15 @@ func TestGeneration(t *testing.T) {
 	assert.Nil(t, succeed(customCB))
 	ch := succeedLater(customCB, time.Duration(1500)*time.Millisecond)
 	time.Sleep(time.Duration(500) * time.Millisecond)
-	assert.Equal(t, Counts{2, 1, 0, 1, 0}, customCB.counts)
+	assert.Equal(t, Counts{2, 1, 0, 1, 0, 0, 0, FailureCounts{}}, customCB.counts)
 
 	time.Sleep(time.Duration(500) * time.Millisecond) // over Interval
 	assert.Equal(t, StateClosed, customCB.State())
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, customCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, customCB.counts)
 
 	// the request from the previous generation has no effect on customCB.counts
 	assert.Nil(t, <-ch)
-	assert.Equal(t, Counts{0, 0, 0, 0, 0}, customCB.counts)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, customCB.counts)
 }
 
 func TestCustomIsSuccessful(t *testing.T) {
@@ -354,7 +356,7 @@ func TestCustomIsSuccessful(t *testing.T) {
 		assert.Nil(t, fail(cb))
 	}
 	assert.Equal(t, StateClosed, cb.State())
-	assert.Equal(t, Counts{5, 5, 0, 5, 0}, cb.counts)
+	assert.Equal(t, Counts{5, 5, 0, 5, 0, 0, 0, FailureCounts{}}, cb.counts)
 
 	cb.counts.clear()
 
@@ -390,5 +392,157 @@ func TestCircuitBreakerInParallel(t *testing.T) {
 		err := <-ch
 		assert.Nil(t, err)
 	}
-	assert.Equal(t, Counts{total, total, 0, total, 0}, customCB.counts)
+	assert.Equal(t, Counts{total, total, 0, total, 0, 0, 0, FailureCounts{}}, customCB.counts)
+}
+
+func TestTripAndReset(t *testing.T) {
+	var changes []StateChange
+	cb := NewCircuitBreaker(Settings{
+		Name: "manual",
+		OnStateChange: func(name string, from State, to State) {
+			changes = append(changes, StateChange{name, from, to})
+		},
+	})
+
+	assert.Nil(t, fail(cb))
+	cb.Trip()
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.counts)
+	assert.False(t, cb.expiry.IsZero())
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+
+	expiry := cb.expiry
+	cb.Trip() // already open
+	assert.Equal(t, expiry, cb.expiry)
+
+	pseudoSleep(cb, time.Duration(60)*time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	cb.Reset()
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, []StateChange{
+		{"manual", StateClosed, StateOpen},
+		{"manual", StateOpen, StateHalfOpen},
+		{"manual", StateHalfOpen, StateClosed},
+	}, changes)
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.counts)
+	cb.Reset() // already closed
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.counts)
+	assert.Len(t, changes, 3)
+
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+	tscb.Trip()
+	assert.Equal(t, StateOpen, tscb.State())
+	tscb.Reset()
+	assert.Equal(t, StateClosed, tscb.State())
+}
+
+func TestRemainingTimeout(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clockFunc(func() time.Time { return now }),
+		Timeout:     10 * time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	assert.Equal(t, time.Duration(0), cb.RemainingTimeout())
+	assert.True(t, cb.NextAttemptAt().IsZero())
+
+	assert.Nil(t, fail(cb))
+	expiry := now.Add(10 * time.Second)
+	assert.Equal(t, 10*time.Second, cb.RemainingTimeout())
+	assert.Equal(t, expiry, cb.NextAttemptAt())
+
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, 7*time.Second, tscb.RemainingTimeout())
+	assert.Equal(t, expiry, tscb.NextAttemptAt())
+
+	now = expiry.Add(time.Nanosecond)
+	assert.Equal(t, time.Duration(0), cb.RemainingTimeout())
+	assert.True(t, cb.NextAttemptAt().IsZero())
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	cb.ForceOpen()
+	assert.Equal(t, time.Duration(0), cb.RemainingTimeout())
+}
+
+func TestTwoStepAllowE(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	tscb := NewTwoStepCircuitBreaker(Settings{
+		IsSuccessful: func(err error) bool { return err == nil || err == errIgnored },
+		ReadyToTrip:  func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+
+	done, err := tscb.AllowE()
+	assert.Nil(t, err)
+	done(nil)
+	done, _ = tscb.AllowE()
+	done(errIgnored)
+	assert.Equal(t, Counts{2, 2, 0, 2, 0, 0, 0, FailureCounts{}}, tscb.Counts())
+
+	done, _ = tscb.AllowE()
+	done(errors.New("fail"))
+	done, _ = tscb.AllowE()
+	done(errors.New("fail"))
+	assert.Equal(t, StateOpen, tscb.State())
+
+	done, err = tscb.AllowE()
+	assert.Nil(t, done)
+	assert.True(t, errors.Is(err, ErrOpenState))
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{MaxConcurrent: 2})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	done1, err := tscb.Allow()
+	assert.Nil(t, err)
+	done2, err := tscb.Allow()
+	assert.Nil(t, err)
+
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyConcurrent))
+	assert.True(t, IsRejection(err))
+	assert.True(t, errors.Is(succeed(cb), ErrTooManyConcurrent))
+	assert.Equal(t, Counts{2, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.Counts())
+
+	done1(true)
+	assert.Nil(t, succeed(cb))
+	done2(false)
+	assert.Equal(t, 0, cb.inflight)
+
+	// the limit doesn't apply in the other states
+	cb.Disable()
+	done1, err = tscb.Allow()
+	assert.Nil(t, err)
+	done2, err = tscb.Allow()
+	assert.Nil(t, err)
+	assert.Nil(t, succeed(cb))
+	cb.ClearOverride()
+	done1(true)
+	done2(true)
+	assert.Equal(t, 0, cb.inflight)
+}
+
+func TestMinimumRequests(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		MinimumRequests: 4,
+		ReadyToTrip: func(counts Counts) bool {
+			return float64(counts.TotalFailures)/float64(counts.Requests) >= 0.5
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	cb.Reset()
+	cb.UpdateSettings(Settings{MinimumRequests: 1})
+	assert.Equal(t, uint32(1), cb.minimumRequests)
 }
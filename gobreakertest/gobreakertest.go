@@ -0,0 +1,49 @@
+// Package gobreakertest provides helpers to test code that uses gobreaker.
+//
+// The helpers put a CircuitBreaker directly into the state that a test needs,
+// instead of generating fake failures until the CircuitBreaker trips,
+// which would couple the test to the thresholds in its Settings.
+package gobreakertest
+
+import (
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// The helpers restore a snapshot of the CircuitBreaker, so the results of
+// requests that are in flight when a helper is called are ignored.
+
+// ForceState puts cb into state with empty Counts.
+// An open CircuitBreaker becomes half-open after its Timeout as usual.
+// Unlike Trip and Reset, ForceState doesn't call OnStateChange.
+// ForceState panics if state is not a valid State.
+func ForceState(cb *gobreaker.CircuitBreaker, state gobreaker.State) {
+	s := cb.Snapshot()
+	s.State = state
+	s.Counts = gobreaker.Counts{}
+	s.Expiry = time.Time{}
+	restore(cb, s)
+}
+
+// SetCounts replaces the Counts of cb, keeping its state.
+// The CircuitBreaker doesn't evaluate ReadyToTrip until the next request fails.
+func SetCounts(cb *gobreaker.CircuitBreaker, counts gobreaker.Counts) {
+	s := cb.Snapshot()
+	s.Counts = counts
+	restore(cb, s)
+}
+
+// SetExpiry sets the time at which the closed cb clears its Counts,
+// or at which the open cb becomes half-open. The expiry of other states is ignored.
+func SetExpiry(cb *gobreaker.CircuitBreaker, expiry time.Time) {
+	s := cb.Snapshot()
+	s.Expiry = expiry
+	restore(cb, s)
+}
+
+func restore(cb *gobreaker.CircuitBreaker, s gobreaker.Snapshot) {
+	if err := cb.Restore(s); err != nil {
+		panic(err)
+	}
+}
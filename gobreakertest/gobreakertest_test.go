@@ -0,0 +1,79 @@
+package gobreakertest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForceState(t *testing.T) {
+	var changes int
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) { changes++ },
+	})
+
+	ForceState(cb, gobreaker.StateOpen)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	ForceState(cb, gobreaker.StateHalfOpen)
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+	_, err = cb.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	assert.Equal(t, 1, changes)
+	assert.Panics(t, func() { ForceState(cb, gobreaker.State(42)) })
+}
+
+func TestSetCounts(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+
+	counts := gobreaker.Counts{Requests: 5, TotalFailures: 5, ConsecutiveFailures: 5}
+	SetCounts(cb, counts)
+	assert.Equal(t, counts, cb.Counts())
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	// the next failure trips the default ReadyToTrip
+	cb.Execute(func() (interface{}, error) { return nil, errors.New("fail") })
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+}
+
+func TestSetExpiry(t *testing.T) {
+	clock := NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Clock: clock})
+
+	ForceState(cb, gobreaker.StateOpen)
+	assert.Equal(t, clock.Now().Add(time.Minute), cb.Snapshot().Expiry)
+
+	SetExpiry(cb, clock.Now().Add(time.Second))
+	clock.Advance(time.Second)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	t1 := clock.NewTimer(time.Second)
+	t2 := clock.NewTimer(2 * time.Second)
+	assert.Equal(t, 2, clock.Timers())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-t1.C())
+	assert.False(t, t1.Stop())
+	assert.True(t, t2.Stop())
+	assert.Equal(t, 0, clock.Timers())
+
+	select {
+	case <-clock.NewTimer(0).C():
+	default:
+		t.Fatal("an expired timer didn't fire")
+	}
+}
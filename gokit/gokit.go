@@ -0,0 +1,46 @@
+// Package gokit wraps go-kit endpoints with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on go-kit.
+package gokit
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/sony/gobreaker"
+)
+
+// Middleware returns an endpoint.Middleware that runs the endpoint through cb.
+// If cb is a gobreaker.ContextBreaker, like *gobreaker.CircuitBreaker, the context of
+// the request is passed to it, so that a canceled request is not counted as a failure
+// of the dependency when IgnoreCanceled is set. A request rejected by cb returns its error,
+// e.g. a *gobreaker.RejectionError, without calling the endpoint.
+func Middleware(cb gobreaker.Breaker) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if c, ok := cb.(gobreaker.ContextBreaker); ok {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				return c.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+					return next(ctx, request)
+				})
+			}
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return cb.Execute(func() (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
+
+// Named returns a function that returns the Middleware of the CircuitBreaker registered
+// under the name of an endpoint in r, created with st if it doesn't exist yet,
+// so that every endpoint of a service gets its own CircuitBreaker:
+//
+//	breaker := gokit.Named(registry, settings)
+//	getUser = breaker("getUser")(getUser)
+//	listUsers = breaker("listUsers")(listUsers)
+func Named(r *gobreaker.Registry, st gobreaker.Settings) func(name string) endpoint.Middleware {
+	return func(name string) endpoint.Middleware {
+		return Middleware(r.GetOrCreate(name, st))
+	}
+}
@@ -0,0 +1,77 @@
+package gokit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var errEndpoint = errors.New("endpoint failed")
+
+func failing(ctx context.Context, request interface{}) (interface{}, error) {
+	return nil, errEndpoint
+}
+
+func echo(ctx context.Context, request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	var e endpoint.Endpoint = Middleware(cb)(echo)
+	response, err := e(context.Background(), "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, "ping", response)
+
+	e = Middleware(cb)(failing)
+	for i := 0; i < 6; i++ {
+		_, err = e(context.Background(), nil)
+		assert.Equal(t, errEndpoint, err)
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	_, err = Middleware(cb)(echo)(context.Background(), "ping")
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	e := Middleware(cb)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return ctx.Value(key{}), nil
+	})
+	response, err := e(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "value", response)
+
+	// a Breaker without ExecuteContext
+	child := cb.NewChild(gobreaker.ChildSettings{Name: "child"})
+	e = Middleware(child)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return ctx.Value(key{}), nil
+	})
+	response, err = e(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "value", response)
+}
+
+func TestNamed(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	breaker := Named(r, gobreaker.Settings{MaxRequests: 3})
+
+	_, err := breaker("getUser")(echo)(context.Background(), nil)
+	assert.Nil(t, err)
+	_, err = breaker("listUsers")(failing)(context.Background(), nil)
+	assert.Equal(t, errEndpoint, err)
+
+	cb, ok := r.Get("getUser")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+	cb, ok = r.Get("listUsers")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), cb.Counts().TotalFailures)
+}
@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/gossipbreaker
+
+go 1.13
+
+require (
+	github.com/hashicorp/memberlist v0.2.2
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,306 @@
+// Package gossipbreaker propagates the trips of the circuit breakers of gobreaker between
+// the replicas of a service by gossip, with hashicorp/memberlist, without an external datastore.
+//
+// It lives in its own module so that gobreaker doesn't depend on memberlist.
+package gossipbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/sony/gobreaker"
+)
+
+// DefaultRetransmitMult is the RetransmitMult of Options when it is 0.
+const DefaultRetransmitMult = 4
+
+// Options configures a Gossip.
+//
+// Quorum is the fraction of the other members that must report a dependency as down for the local
+// CircuitBreaker of the dependency to open, e.g. 0.5 for half of them. If Quorum is 0, the reports
+// of the peers never open the local CircuitBreakers, which only report their own state.
+//
+// MinPeers is the minimum number of other members for Quorum to apply, so that a replica doesn't
+// follow a single peer in a small cluster. If MinPeers is 0, 1 is used.
+//
+// RetransmitMult is the RetransmitMult of the memberlist.TransmitLimitedQueue of the reports.
+// If RetransmitMult is 0, DefaultRetransmitMult is used.
+type Options struct {
+	Quorum         float64
+	MinPeers       int
+	RetransmitMult int
+}
+
+// report is the state of a dependency reported by a member.
+// Seq orders the reports of a member, so that a report delivered late doesn't override a newer one.
+type report struct {
+	Node    string `json:"node"`
+	Breaker string `json:"breaker"`
+	Down    bool   `json:"down"`
+	Seq     int64  `json:"seq"`
+}
+
+// Gossip broadcasts the trips and the recoveries of CircuitBreakers to the other members
+// of a memberlist cluster, and opens the CircuitBreakers of the dependencies that a quorum
+// of the other members report as down.
+//
+// A CircuitBreaker reports its dependency as down when it opens and as up when it closes,
+// on its own: the state changes forced by an operator or by Gossip itself are not reported.
+// A CircuitBreaker opened by the quorum recovers through its half-open state as usual.
+type Gossip struct {
+	name     string
+	quorum   float64
+	minPeers int
+	queue    *memberlist.TransmitLimitedQueue
+
+	mutex    sync.Mutex
+	seq      int64
+	members  map[string]bool
+	reports  map[string]map[string]report // by breaker, then by node
+	breakers map[string]*gobreaker.CircuitBreaker
+}
+
+// New returns a Gossip for the memberlist configured by conf, and sets itself as
+// the Delegate and the Events of conf, which is then passed to memberlist.Create.
+func New(conf *memberlist.Config, o Options) *Gossip {
+	if o.MinPeers <= 0 {
+		o.MinPeers = 1
+	}
+	if o.RetransmitMult <= 0 {
+		o.RetransmitMult = DefaultRetransmitMult
+	}
+	g := &Gossip{
+		name:     conf.Name,
+		quorum:   o.Quorum,
+		minPeers: o.MinPeers,
+		seq:      time.Now().UnixNano(),
+		members:  map[string]bool{conf.Name: true},
+		reports:  make(map[string]map[string]report),
+		breakers: make(map[string]*gobreaker.CircuitBreaker),
+	}
+	g.queue = &memberlist.TransmitLimitedQueue{NumNodes: g.numMembers, RetransmitMult: o.RetransmitMult}
+	conf.Delegate = g
+	conf.Events = g
+	return g
+}
+
+func (g *Gossip) numMembers() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return len(g.members)
+}
+
+// Add reports the state changes of cb to the other members, and opens cb when a quorum of them
+// report the CircuitBreakers of the same name as down, until ctx is done or stop is called.
+func (g *Gossip) Add(ctx context.Context, cb *gobreaker.CircuitBreaker) (stop func()) {
+	g.mutex.Lock()
+	g.breakers[cb.Name()] = cb
+	g.checkQuorum(cb.Name())
+	g.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	events, unsubscribe := cb.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-events:
+				if e.Type != gobreaker.EventStateChange || e.Reason == gobreaker.ReasonManual {
+					continue
+				}
+				switch e.State {
+				case gobreaker.StateOpen:
+					g.broadcast(cb.Name(), true)
+				case gobreaker.StateClosed:
+					g.broadcast(cb.Name(), false)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+
+		if g.breakers[cb.Name()] == cb {
+			delete(g.breakers, cb.Name())
+		}
+	}
+}
+
+// broadcast reports the dependency of the named breaker as down or up.
+func (g *Gossip) broadcast(breaker string, down bool) {
+	g.mutex.Lock()
+	g.seq++
+	r := report{Node: g.name, Breaker: breaker, Down: down, Seq: g.seq}
+	g.merge(r)
+	g.mutex.Unlock()
+
+	msg, _ := json.Marshal(r)
+	g.queue.QueueBroadcast(&broadcast{breaker: breaker, msg: msg})
+}
+
+// merge records r unless a newer report of its node is known, and reports whether it did.
+// It must be called with g.mutex held.
+func (g *Gossip) merge(r report) bool {
+	reports := g.reports[r.Breaker]
+	if reports == nil {
+		reports = make(map[string]report)
+		g.reports[r.Breaker] = reports
+	}
+	if prev, ok := reports[r.Node]; ok && prev.Seq >= r.Seq {
+		return false
+	}
+	reports[r.Node] = r
+	return true
+}
+
+// checkQuorum opens the named CircuitBreaker if a quorum of the other members report it as down.
+// It must be called with g.mutex held.
+func (g *Gossip) checkQuorum(breaker string) {
+	cb := g.breakers[breaker]
+	if cb == nil || g.quorum <= 0 {
+		return
+	}
+	peers, down := len(g.members)-1, g.down(breaker)
+	if peers < g.minPeers || float64(down) < g.quorum*float64(peers) {
+		return
+	}
+	if cb.State() == gobreaker.StateClosed {
+		cb.Trip()
+	}
+}
+
+// Down returns the number of the other members that report the named breaker as down.
+func (g *Gossip) Down(breaker string) int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.down(breaker)
+}
+
+// down must be called with g.mutex held.
+func (g *Gossip) down(breaker string) int {
+	down := 0
+	for node, r := range g.reports[breaker] {
+		if node != g.name && g.members[node] && r.Down {
+			down++
+		}
+	}
+	return down
+}
+
+// NodeMeta implements memberlist.Delegate.
+func (g *Gossip) NodeMeta(limit int) []byte {
+	return nil
+}
+
+// NotifyMsg implements memberlist.Delegate. It receives the reports of the other members.
+func (g *Gossip) NotifyMsg(msg []byte) {
+	var r report
+	if json.Unmarshal(msg, &r) != nil || r.Node == g.name {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.merge(r) {
+		g.checkQuorum(r.Breaker)
+	}
+}
+
+// GetBroadcasts implements memberlist.Delegate.
+func (g *Gossip) GetBroadcasts(overhead, limit int) [][]byte {
+	return g.queue.GetBroadcasts(overhead, limit)
+}
+
+// LocalState implements memberlist.Delegate. It sends all known reports, so that
+// a member that joins learns the state of the cluster.
+func (g *Gossip) LocalState(join bool) []byte {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var reports []report
+	for _, byNode := range g.reports {
+		for _, r := range byNode {
+			reports = append(reports, r)
+		}
+	}
+	state, _ := json.Marshal(reports)
+	return state
+}
+
+// MergeRemoteState implements memberlist.Delegate.
+func (g *Gossip) MergeRemoteState(buf []byte, join bool) {
+	var reports []report
+	if json.Unmarshal(buf, &reports) != nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	changed := make(map[string]bool)
+	for _, r := range reports {
+		if r.Node != g.name && g.merge(r) {
+			changed[r.Breaker] = true
+		}
+	}
+	for breaker := range changed {
+		g.checkQuorum(breaker)
+	}
+}
+
+// NotifyJoin implements memberlist.EventDelegate.
+func (g *Gossip) NotifyJoin(node *memberlist.Node) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.members[node.Name] = true
+}
+
+// NotifyLeave implements memberlist.EventDelegate. The reports of the member are forgotten,
+// and the quorums are checked against the remaining members.
+func (g *Gossip) NotifyLeave(node *memberlist.Node) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.members, node.Name)
+	for _, reports := range g.reports {
+		delete(reports, node.Name)
+	}
+	for breaker := range g.breakers {
+		g.checkQuorum(breaker)
+	}
+}
+
+// NotifyUpdate implements memberlist.EventDelegate.
+func (g *Gossip) NotifyUpdate(node *memberlist.Node) {}
+
+// broadcast is a report queued for gossip. It invalidates the previous reports of the same breaker.
+type broadcast struct {
+	breaker string
+	msg     []byte
+}
+
+func (b *broadcast) Invalidates(other memberlist.Broadcast) bool {
+	o, ok := other.(*broadcast)
+	return ok && o.breaker == b.breaker
+}
+
+func (b *broadcast) Message() []byte {
+	return b.msg
+}
+
+func (b *broadcast) Finished() {}
@@ -0,0 +1,148 @@
+package gossipbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// cluster connects the Gossips of its members directly, delivering their broadcasts on demand.
+type cluster []*Gossip
+
+func newCluster(o Options, names ...string) cluster {
+	var c cluster
+	for _, name := range names {
+		conf := memberlist.DefaultLANConfig()
+		conf.Name = name
+		g := New(conf, o)
+		c = append(c, g)
+	}
+	for _, g := range c {
+		for _, name := range names {
+			g.NotifyJoin(&memberlist.Node{Name: name})
+		}
+	}
+	return c
+}
+
+// deliver waits for the broadcasts of g and delivers them to the other members.
+func (c cluster) deliver(t *testing.T, g *Gossip) {
+	var msgs [][]byte
+	for deadline := time.Now().Add(time.Second); len(msgs) == 0; msgs = g.GetBroadcasts(0, 1400) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s broadcast nothing", g.name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	g.queue.Reset()
+	for _, other := range c {
+		if other != g {
+			for _, msg := range msgs {
+				other.NotifyMsg(msg)
+			}
+		}
+	}
+}
+
+func newBreaker() *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "db",
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+}
+
+func fail(cb *gobreaker.CircuitBreaker) {
+	cb.Execute(func() (interface{}, error) { return nil, errors.New("fail") })
+}
+
+func TestGossipQuorum(t *testing.T) {
+	c := newCluster(Options{Quorum: 0.5}, "a", "b", "c")
+	a, b, cb := newBreaker(), newBreaker(), newBreaker()
+	defer c[0].Add(context.Background(), a)()
+	defer c[1].Add(context.Background(), b)()
+	defer c[2].Add(context.Background(), cb)()
+
+	fail(a)
+	assert.Equal(t, gobreaker.StateOpen, a.State())
+	c.deliver(t, c[0])
+	assert.Equal(t, 1, c[2].Down("db"))
+	// 1 of the 2 peers of b and c is down
+	assert.Equal(t, gobreaker.StateOpen, b.State())
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	// the breakers opened by the quorum don't report
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, c[1].queue.NumQueued())
+	assert.Equal(t, 0, c[0].Down("db"))
+
+	// the trips of the other members add up
+	a.Reset()
+	b.Reset()
+	fail(b)
+	c.deliver(t, c[1])
+	assert.Equal(t, 2, c[2].Down("db"))
+}
+
+func TestGossipQuorumNotReached(t *testing.T) {
+	c := newCluster(Options{Quorum: 1}, "a", "b", "c")
+	a, b := newBreaker(), newBreaker()
+	defer c[0].Add(context.Background(), a)()
+	defer c[1].Add(context.Background(), b)()
+
+	fail(a)
+	c.deliver(t, c[0])
+	assert.Equal(t, gobreaker.StateClosed, b.State())
+
+	// c leaves: a is all the peers of b
+	c[1].NotifyLeave(&memberlist.Node{Name: "c"})
+	assert.Equal(t, gobreaker.StateOpen, b.State())
+}
+
+func TestGossipRecovery(t *testing.T) {
+	c := newCluster(Options{Quorum: 1}, "a", "b")
+	a := newBreaker()
+	defer c[0].Add(context.Background(), a)()
+
+	fail(a)
+	c.deliver(t, c[0])
+	assert.Equal(t, 1, c[1].Down("db"))
+
+	a.Reset() // manual, not reported
+	a.Restore(gobreaker.Snapshot{State: gobreaker.StateHalfOpen})
+	a.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Equal(t, gobreaker.StateClosed, a.State())
+	c.deliver(t, c[0])
+	assert.Equal(t, 0, c[1].Down("db"))
+}
+
+func TestGossipState(t *testing.T) {
+	c := newCluster(Options{Quorum: 0.5}, "a", "b")
+	a := newBreaker()
+	defer c[0].Add(context.Background(), a)()
+	fail(a)
+	c.deliver(t, c[0])
+
+	// c joins and learns the reports of a from b
+	conf := memberlist.DefaultLANConfig()
+	conf.Name = "c"
+	g := New(conf, Options{Quorum: 0.5})
+	assert.Equal(t, g, conf.Delegate)
+	assert.Equal(t, g, conf.Events)
+	for _, name := range []string{"a", "b", "c"} {
+		g.NotifyJoin(&memberlist.Node{Name: name})
+	}
+	cb := newBreaker()
+	defer g.Add(context.Background(), cb)()
+	g.MergeRemoteState(c[1].LocalState(true), true)
+	assert.Equal(t, 1, g.Down("db"))
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	// a late report doesn't override a newer one
+	g.MergeRemoteState([]byte(`[{"node":"a","breaker":"db","down":false,"seq":1}]`), false)
+	assert.Equal(t, 1, g.Down("db"))
+}
@@ -0,0 +1,107 @@
+package gobreaker
+
+import (
+	"container/list"
+	"sync"
+)
+
+// BreakerGroup lazily creates one CircuitBreaker per key, such as a host, an endpoint or a tenant.
+// A BreakerGroup holds at most a fixed number of CircuitBreakers and evicts the least recently used
+// one to make room for a new key. An evicted key gets a new CircuitBreaker when it is used again.
+// A BreakerGroup is safe for concurrent use.
+type BreakerGroup struct {
+	mutex    sync.Mutex
+	size     int
+	settings func(key string) Settings
+	lru      *list.List // of *groupEntry, the most recently used first
+	entries  map[string]*list.Element
+}
+
+type groupEntry struct {
+	key string
+	cb  *CircuitBreaker
+}
+
+// NewBreakerGroup returns a new BreakerGroup that holds at most size CircuitBreakers.
+// If size is less than or equal to 0, the BreakerGroup never evicts a CircuitBreaker.
+// settings returns the Settings for the CircuitBreaker of a key.
+// If settings is nil or returns Settings without Name, the key is used as the Name.
+func NewBreakerGroup(size int, settings func(key string) Settings) *BreakerGroup {
+	return &BreakerGroup{
+		size:     size,
+		settings: settings,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the CircuitBreaker of key, creating it if needed.
+func (g *BreakerGroup) Get(key string) *CircuitBreaker {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if e, ok := g.entries[key]; ok {
+		g.lru.MoveToFront(e)
+		return e.Value.(*groupEntry).cb
+	}
+
+	var st Settings
+	if g.settings != nil {
+		st = g.settings(key)
+	}
+	if st.Name == "" {
+		st.Name = key
+	}
+	cb := NewCircuitBreaker(st)
+	g.entries[key] = g.lru.PushFront(&groupEntry{key: key, cb: cb})
+
+	if g.size > 0 && g.lru.Len() > g.size {
+		g.remove(g.lru.Back())
+	}
+	return cb
+}
+
+// Execute runs req through the CircuitBreaker of key.
+func (g *BreakerGroup) Execute(key string, req func() (interface{}, error)) (interface{}, error) {
+	return g.Get(key).Execute(req)
+}
+
+// Remove removes the CircuitBreaker of key, if any.
+func (g *BreakerGroup) Remove(key string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if e, ok := g.entries[key]; ok {
+		g.remove(e)
+	}
+}
+
+// Len returns the number of CircuitBreakers in the BreakerGroup.
+func (g *BreakerGroup) Len() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.lru.Len()
+}
+
+// Range calls fn for each key and its CircuitBreaker, from the most to the least recently used.
+// If fn returns false, Range stops the iteration. Range doesn't change the order of use.
+func (g *BreakerGroup) Range(fn func(key string, cb *CircuitBreaker) bool) {
+	g.mutex.Lock()
+	entries := make([]*groupEntry, 0, g.lru.Len())
+	for e := g.lru.Front(); e != nil; e = e.Next() {
+		entries = append(entries, e.Value.(*groupEntry))
+	}
+	g.mutex.Unlock()
+
+	for _, entry := range entries {
+		if !fn(entry.key, entry.cb) {
+			return
+		}
+	}
+}
+
+func (g *BreakerGroup) remove(e *list.Element) {
+	g.lru.Remove(e)
+	delete(g.entries, e.Value.(*groupEntry).key)
+}
@@ -0,0 +1,66 @@
+package gobreaker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func groupKeys(g *BreakerGroup) []string {
+	var keys []string
+	g.Range(func(key string, cb *CircuitBreaker) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestBreakerGroup(t *testing.T) {
+	g := NewBreakerGroup(2, func(key string) Settings {
+		return Settings{Timeout: time.Second}
+	})
+
+	a := g.Get("a")
+	assert.Equal(t, "a", a.Name())
+	assert.Equal(t, time.Second, a.timeout)
+	assert.Equal(t, a, g.Get("a"))
+
+	b := g.Get("b")
+	assert.Equal(t, []string{"b", "a"}, groupKeys(g))
+
+	// using a makes b the least recently used
+	_, err := g.Execute("a", func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), a.Counts().Requests)
+
+	g.Get("c")
+	assert.Equal(t, 2, g.Len())
+	assert.Equal(t, []string{"c", "a"}, groupKeys(g))
+	assert.NotEqual(t, b, g.Get("b"))
+	assert.Equal(t, []string{"b", "c"}, groupKeys(g))
+
+	g.Remove("b")
+	g.Remove("x")
+	assert.Equal(t, []string{"c"}, groupKeys(g))
+}
+
+func TestBreakerGroupUnlimited(t *testing.T) {
+	g := NewBreakerGroup(0, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g.Get(fmt.Sprint(i % 50))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 50, g.Len())
+
+	g = NewBreakerGroup(0, func(key string) Settings { return Settings{Name: "upstream " + key} })
+	assert.Equal(t, "upstream x", g.Get("x").Name())
+}
@@ -0,0 +1,288 @@
+package grpcbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerCounts is the message of gobreaker.Counts in admin.proto.
+type BreakerCounts struct {
+	Requests             uint32 `protobuf:"varint,1,opt,name=requests,proto3" json:"requests,omitempty"`
+	TotalSuccesses       uint32 `protobuf:"varint,2,opt,name=total_successes,json=totalSuccesses,proto3" json:"total_successes,omitempty"`
+	TotalFailures        uint32 `protobuf:"varint,3,opt,name=total_failures,json=totalFailures,proto3" json:"total_failures,omitempty"`
+	ConsecutiveSuccesses uint32 `protobuf:"varint,4,opt,name=consecutive_successes,json=consecutiveSuccesses,proto3" json:"consecutive_successes,omitempty"`
+	ConsecutiveFailures  uint32 `protobuf:"varint,5,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"`
+}
+
+// BreakerInfo is the message of a gobreaker.Snapshot in admin.proto.
+type BreakerInfo struct {
+	Name         string         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	State        string         `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Generation   uint64         `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	Counts       *BreakerCounts `protobuf:"bytes,4,opt,name=counts,proto3" json:"counts,omitempty"`
+	ExpiryUnixMs int64          `protobuf:"varint,5,opt,name=expiry_unix_ms,json=expiryUnixMs,proto3" json:"expiry_unix_ms,omitempty"`
+}
+
+// ListBreakersRequest is the request of BreakerAdmin.ListBreakers.
+type ListBreakersRequest struct{}
+
+// ListBreakersResponse is the response of BreakerAdmin.ListBreakers.
+type ListBreakersResponse struct {
+	Breakers []*BreakerInfo `protobuf:"bytes,1,rep,name=breakers,proto3" json:"breakers,omitempty"`
+}
+
+// GetBreakerRequest is the request of BreakerAdmin.GetBreaker.
+type GetBreakerRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+// ForceStateRequest is the request of BreakerAdmin.ForceState.
+// Action is one of the actions of gobreaker.AdminHandler, e.g. "force-open".
+type ForceStateRequest struct {
+	Name   string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Action string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+}
+
+// ResetRequest is the request of BreakerAdmin.Reset.
+type ResetRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+// UpdateSettingsRequest is the request of BreakerAdmin.UpdateSettings.
+// Its fields have the meaning of the fields of config.Breaker with the same names.
+// The fields left at 0 keep the current thresholds of the breaker. If ConsecutiveFailures
+// or FailureRatio is set, both replace its ReadyToTrip, otherwise ReadyToTrip is kept.
+type UpdateSettingsRequest struct {
+	Name                string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	MaxRequests         uint32  `protobuf:"varint,2,opt,name=max_requests,json=maxRequests,proto3" json:"max_requests,omitempty"`
+	IntervalMs          int64   `protobuf:"varint,3,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	TimeoutMs           int64   `protobuf:"varint,4,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	ConsecutiveFailures uint32  `protobuf:"varint,5,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"`
+	FailureRatio        float64 `protobuf:"fixed64,6,opt,name=failure_ratio,json=failureRatio,proto3" json:"failure_ratio,omitempty"`
+	MinimumRequests     uint32  `protobuf:"varint,7,opt,name=minimum_requests,json=minimumRequests,proto3" json:"minimum_requests,omitempty"`
+}
+
+func (m *BreakerCounts) Reset()         { *m = BreakerCounts{} }
+func (m *BreakerCounts) String() string { return fmt.Sprintf("%+v", *m) }
+func (*BreakerCounts) ProtoMessage()    {}
+
+func (m *BreakerInfo) Reset()         { *m = BreakerInfo{} }
+func (m *BreakerInfo) String() string { return fmt.Sprintf("%+v", *m) }
+func (*BreakerInfo) ProtoMessage()    {}
+
+func (m *ListBreakersRequest) Reset()         { *m = ListBreakersRequest{} }
+func (m *ListBreakersRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ListBreakersRequest) ProtoMessage()    {}
+
+func (m *ListBreakersResponse) Reset()         { *m = ListBreakersResponse{} }
+func (m *ListBreakersResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ListBreakersResponse) ProtoMessage()    {}
+
+func (m *GetBreakerRequest) Reset()         { *m = GetBreakerRequest{} }
+func (m *GetBreakerRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*GetBreakerRequest) ProtoMessage()    {}
+
+func (m *ForceStateRequest) Reset()         { *m = ForceStateRequest{} }
+func (m *ForceStateRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ForceStateRequest) ProtoMessage()    {}
+
+func (m *ResetRequest) Reset()         { *m = ResetRequest{} }
+func (m *ResetRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ResetRequest) ProtoMessage()    {}
+
+func (m *UpdateSettingsRequest) Reset()         { *m = UpdateSettingsRequest{} }
+func (m *UpdateSettingsRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*UpdateSettingsRequest) ProtoMessage()    {}
+
+// AdminServer is the server of the BreakerAdmin service of admin.proto.
+type AdminServer interface {
+	ListBreakers(ctx context.Context, req *ListBreakersRequest) (*ListBreakersResponse, error)
+	GetBreaker(ctx context.Context, req *GetBreakerRequest) (*BreakerInfo, error)
+	ForceState(ctx context.Context, req *ForceStateRequest) (*BreakerInfo, error)
+	Reset(ctx context.Context, req *ResetRequest) (*BreakerInfo, error)
+	UpdateSettings(ctx context.Context, req *UpdateSettingsRequest) (*BreakerInfo, error)
+}
+
+// NewAdminServer returns an AdminServer for the CircuitBreakers in r, including those added
+// to r later, like gobreaker.NewRegistryAdminHandler for gRPC-only environments.
+// An unknown breaker fails with codes.NotFound, and an invalid action or invalid settings
+// with codes.InvalidArgument.
+//
+// The service lets its callers open every breaker of the process: it should only be
+// registered on a server reachable by the operators, or behind an authorizing interceptor.
+func NewAdminServer(r *gobreaker.Registry) AdminServer {
+	return &adminServer{registry: r}
+}
+
+type adminServer struct {
+	registry *gobreaker.Registry
+}
+
+func (s *adminServer) breaker(name string) (*gobreaker.CircuitBreaker, error) {
+	cb, ok := s.registry.Get(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "circuit breaker %q not found", name)
+	}
+	return cb, nil
+}
+
+func (s *adminServer) ListBreakers(ctx context.Context, req *ListBreakersRequest) (*ListBreakersResponse, error) {
+	var cbs []*gobreaker.CircuitBreaker
+	s.registry.Range(func(cb *gobreaker.CircuitBreaker) bool {
+		cbs = append(cbs, cb)
+		return true
+	})
+	resp := &ListBreakersResponse{Breakers: make([]*BreakerInfo, len(cbs))}
+	for i, cb := range cbs {
+		resp.Breakers[i] = breakerInfo(cb.Snapshot())
+	}
+	return resp, nil
+}
+
+func (s *adminServer) GetBreaker(ctx context.Context, req *GetBreakerRequest) (*BreakerInfo, error) {
+	cb, err := s.breaker(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return breakerInfo(cb.Snapshot()), nil
+}
+
+func (s *adminServer) ForceState(ctx context.Context, req *ForceStateRequest) (*BreakerInfo, error) {
+	cb, err := s.breaker(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := gobreaker.ApplyAdminAction(cb, req.Action); err != nil {
+		if errors.Is(err, gobreaker.ErrUnknownAdminAction) {
+			return nil, status.Errorf(codes.InvalidArgument, "%v %q", err, req.Action)
+		}
+		return nil, err
+	}
+	return breakerInfo(cb.Snapshot()), nil
+}
+
+func (s *adminServer) Reset(ctx context.Context, req *ResetRequest) (*BreakerInfo, error) {
+	cb, err := s.breaker(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	cb.Reset()
+	return breakerInfo(cb.Snapshot()), nil
+}
+
+func (s *adminServer) UpdateSettings(ctx context.Context, req *UpdateSettingsRequest) (*BreakerInfo, error) {
+	cb, err := s.breaker(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.FailureRatio < 0 || req.FailureRatio > 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "failure_ratio %v out of [0, 1]", req.FailureRatio)
+	}
+	if req.IntervalMs < 0 || req.TimeoutMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "negative interval_ms or timeout_ms")
+	}
+	cb.UpdatePolicy(req.merge)
+	return breakerInfo(cb.Snapshot()), nil
+}
+
+// merge sets the fields of p that are present in the request, i.e. not 0.
+func (m *UpdateSettingsRequest) merge(p gobreaker.Policy) gobreaker.Policy {
+	if m.MaxRequests != 0 {
+		p.MaxRequests = m.MaxRequests
+	}
+	if m.IntervalMs != 0 {
+		p.Interval = time.Duration(m.IntervalMs) * time.Millisecond
+	}
+	if m.TimeoutMs != 0 {
+		p.Timeout = time.Duration(m.TimeoutMs) * time.Millisecond
+	}
+	if m.ConsecutiveFailures != 0 || m.FailureRatio != 0 {
+		p.ReadyToTrip = gobreaker.TripOn(m.ConsecutiveFailures, m.FailureRatio)
+	}
+	if m.MinimumRequests != 0 {
+		p.MinimumRequests = m.MinimumRequests
+	}
+	return p
+}
+
+func breakerInfo(s gobreaker.Snapshot) *BreakerInfo {
+	info := &BreakerInfo{
+		Name:       s.Name,
+		State:      s.State.String(),
+		Generation: s.Generation,
+		Counts: &BreakerCounts{
+			Requests:             s.Counts.Requests,
+			TotalSuccesses:       s.Counts.TotalSuccesses,
+			TotalFailures:        s.Counts.TotalFailures,
+			ConsecutiveSuccesses: s.Counts.ConsecutiveSuccesses,
+			ConsecutiveFailures:  s.Counts.ConsecutiveFailures,
+		},
+	}
+	if !s.Expiry.IsZero() {
+		info.ExpiryUnixMs = s.Expiry.UnixNano() / int64(time.Millisecond)
+	}
+	return info
+}
+
+// RegisterAdminServer registers srv as the BreakerAdmin service on s, e.g. a *grpc.Server.
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&adminServiceDesc, srv)
+}
+
+const adminServiceName = "gobreaker.admin.v1.BreakerAdmin"
+
+// adminHandler returns the grpc method handler of the BreakerAdmin method named method,
+// which decodes a request with newReq and passes it to call.
+func adminHandler(method string, newReq func() interface{}, call func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newReq()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(AdminServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + adminServiceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(AdminServer), ctx, req)
+			})
+		},
+	}
+}
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: adminServiceName,
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		adminHandler("ListBreakers", func() interface{} { return new(ListBreakersRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ListBreakers(ctx, req.(*ListBreakersRequest))
+			}),
+		adminHandler("GetBreaker", func() interface{} { return new(GetBreakerRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetBreaker(ctx, req.(*GetBreakerRequest))
+			}),
+		adminHandler("ForceState", func() interface{} { return new(ForceStateRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ForceState(ctx, req.(*ForceStateRequest))
+			}),
+		adminHandler("Reset", func() interface{} { return new(ResetRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.Reset(ctx, req.(*ResetRequest))
+			}),
+		adminHandler("UpdateSettings", func() interface{} { return new(UpdateSettingsRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.UpdateSettings(ctx, req.(*UpdateSettingsRequest))
+			}),
+	},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,123 @@
+package grpcbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminServer(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	db := r.GetOrCreate("db", gobreaker.Settings{})
+	r.GetOrCreate("cache", gobreaker.Settings{})
+	srv := NewAdminServer(r)
+	ctx := context.Background()
+
+	list, err := srv.ListBreakers(ctx, &ListBreakersRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list.Breakers))
+	assert.Equal(t, "cache", list.Breakers[0].Name)
+	assert.Equal(t, "db", list.Breakers[1].Name)
+
+	db.Execute(func() (interface{}, error) { return nil, errors.New("fail") })
+	info, err := srv.GetBreaker(ctx, &GetBreakerRequest{Name: "db"})
+	assert.Nil(t, err)
+	assert.Equal(t, "closed", info.State)
+	assert.Equal(t, uint32(1), info.Counts.TotalFailures)
+
+	_, err = srv.GetBreaker(ctx, &GetBreakerRequest{Name: "queue"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	info, err = srv.ForceState(ctx, &ForceStateRequest{Name: "db", Action: "open"})
+	assert.Nil(t, err)
+	assert.Equal(t, "open", info.State)
+	assert.Equal(t, gobreaker.StateOpen, db.State())
+	assert.NotEqual(t, int64(0), info.ExpiryUnixMs)
+
+	_, err = srv.ForceState(ctx, &ForceStateRequest{Name: "db", Action: "explode"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = srv.ForceState(ctx, &ForceStateRequest{Name: "queue", Action: "open"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	info, err = srv.Reset(ctx, &ResetRequest{Name: "db"})
+	assert.Nil(t, err)
+	assert.Equal(t, "closed", info.State)
+	assert.Equal(t, uint32(0), info.Counts.Requests)
+}
+
+func TestAdminServerUpdateSettings(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	db := r.GetOrCreate("db", gobreaker.Settings{})
+	srv := NewAdminServer(r)
+	ctx := context.Background()
+
+	_, err := srv.UpdateSettings(ctx, &UpdateSettingsRequest{Name: "db", FailureRatio: 2})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = srv.UpdateSettings(ctx, &UpdateSettingsRequest{Name: "queue"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = srv.UpdateSettings(ctx, &UpdateSettingsRequest{Name: "db", TimeoutMs: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = srv.UpdateSettings(ctx, &UpdateSettingsRequest{Name: "db", ConsecutiveFailures: 2, TimeoutMs: 1000})
+	assert.Nil(t, err)
+
+	// a partial update keeps the other thresholds
+	_, err = srv.UpdateSettings(ctx, &UpdateSettingsRequest{Name: "db", MaxRequests: 3})
+	assert.Nil(t, err)
+	p := db.Policy()
+	assert.Equal(t, uint32(3), p.MaxRequests)
+	assert.Equal(t, time.Second, p.Timeout)
+
+	fail := func() { db.Execute(func() (interface{}, error) { return nil, errors.New("fail") }) }
+	fail()
+	assert.Equal(t, gobreaker.StateClosed, db.State())
+	fail()
+	assert.Equal(t, gobreaker.StateOpen, db.State())
+	assert.True(t, time.Until(db.Snapshot().Expiry) <= time.Second)
+}
+
+type fakeRegistrar struct {
+	desc *grpc.ServiceDesc
+	impl interface{}
+}
+
+func (r *fakeRegistrar) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	r.desc, r.impl = desc, impl
+}
+
+func TestRegisterAdminServer(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	db := r.GetOrCreate("db", gobreaker.Settings{})
+	var s fakeRegistrar
+	RegisterAdminServer(&s, NewAdminServer(r))
+	assert.Equal(t, "gobreaker.admin.v1.BreakerAdmin", s.desc.ServiceName)
+
+	handlers := make(map[string]grpc.MethodDesc)
+	for _, m := range s.desc.Methods {
+		handlers[m.MethodName] = m
+	}
+	assert.Equal(t, 5, len(handlers))
+
+	dec := func(v interface{}) error {
+		*v.(*ForceStateRequest) = ForceStateRequest{Name: "db", Action: "force-open"}
+		return nil
+	}
+	var method string
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method = info.FullMethod
+		return handler(ctx, req)
+	}
+	resp, err := handlers["ForceState"].Handler(s.impl, context.Background(), dec, interceptor)
+	assert.Nil(t, err)
+	assert.Equal(t, "/gobreaker.admin.v1.BreakerAdmin/ForceState", method)
+	assert.Equal(t, "forced-open", resp.(*BreakerInfo).State)
+	assert.Equal(t, gobreaker.StateForcedOpen, db.State())
+}
@@ -0,0 +1,70 @@
+package grpcbreaker
+
+import (
+	"context"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+)
+
+// KeyFunc returns the key of the CircuitBreaker of an RPC in a BreakerGroup.
+type KeyFunc func(ctx context.Context, method string, cc *grpc.ClientConn) string
+
+// FullMethod is the KeyFunc that returns the full method name of an RPC, e.g. "/example.Users/Get",
+// so that each method gets its own CircuitBreaker.
+func FullMethod(ctx context.Context, method string, cc *grpc.ClientConn) string {
+	return method
+}
+
+// UnaryClientInterceptor returns a grpc.UnaryClientInterceptor that runs the RPCs through cb.
+// cb should be created with Classify as Settings.Classify, so that the errors of invalid requests
+// don't trip it. An RPC rejected by cb returns its error, e.g. a *gobreaker.RejectionError,
+// without reaching the server.
+func UnaryClientInterceptor(cb gobreaker.Breaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, err := execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a grpc.StreamClientInterceptor like UnaryClientInterceptor.
+// Only the establishment of a stream goes through cb.
+func StreamClientInterceptor(cb gobreaker.Breaker) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return stream.(grpc.ClientStream), nil
+	}
+}
+
+// GroupUnaryClientInterceptor returns a grpc.UnaryClientInterceptor like UnaryClientInterceptor
+// that runs each RPC through the CircuitBreaker of its key in g, so that a failing method
+// doesn't open the breaker of the others. The settings function of g gives each key
+// its own thresholds. If key is nil, FullMethod is used.
+func GroupUnaryClientInterceptor(g *gobreaker.BreakerGroup, key KeyFunc) grpc.UnaryClientInterceptor {
+	if key == nil {
+		key = FullMethod
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		cb := g.Get(key(ctx, method, cc))
+		return UnaryClientInterceptor(cb)(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+// GroupStreamClientInterceptor returns a grpc.StreamClientInterceptor like StreamClientInterceptor
+// that runs each stream through the CircuitBreaker of its key in g, see GroupUnaryClientInterceptor.
+func GroupStreamClientInterceptor(g *gobreaker.BreakerGroup, key KeyFunc) grpc.StreamClientInterceptor {
+	if key == nil {
+		key = FullMethod
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cb := g.Get(key(ctx, method, cc))
+		return StreamClientInterceptor(cb)(ctx, desc, cc, method, streamer, opts...)
+	}
+}
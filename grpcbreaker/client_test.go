@@ -0,0 +1,124 @@
+package grpcbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// invoker returns a grpc.UnaryInvoker that fails the methods in errs and counts the calls.
+func invoker(errs map[string]error, calls *int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		return errs[method]
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Classify: Classify})
+	intercept := UnaryClientInterceptor(cb)
+	ctx := context.Background()
+	calls := 0
+	errs := map[string]error{
+		"/example.Users/Create": status.Error(codes.InvalidArgument, "bad"),
+		"/example.Users/Get":    status.Error(codes.Unavailable, "down"),
+	}
+
+	for i := 0; i < 10; i++ {
+		err := intercept(ctx, "/example.Users/Create", nil, nil, nil, invoker(errs, &calls))
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	for i := 0; i < 6; i++ {
+		assert.Error(t, intercept(ctx, "/example.Users/Get", nil, nil, nil, invoker(errs, &calls)))
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	err := intercept(ctx, "/example.Users/List", nil, nil, nil, invoker(errs, &calls))
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.Equal(t, 16, calls)
+}
+
+func TestGroupUnaryClientInterceptor(t *testing.T) {
+	g := gobreaker.NewBreakerGroup(0, func(key string) gobreaker.Settings {
+		failures := uint32(5)
+		if key == "/example.Admin/Reindex" {
+			failures = 1
+		}
+		return gobreaker.Settings{
+			Classify:    Classify,
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= failures },
+		}
+	})
+	intercept := GroupUnaryClientInterceptor(g, nil)
+	ctx := context.Background()
+	calls := 0
+	errs := map[string]error{"/example.Admin/Reindex": status.Error(codes.Internal, "bug")}
+
+	assert.Error(t, intercept(ctx, "/example.Admin/Reindex", nil, nil, nil, invoker(errs, &calls)))
+	err := intercept(ctx, "/example.Admin/Reindex", nil, nil, nil, invoker(errs, &calls))
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.NoError(t, intercept(ctx, "/example.Users/Get", nil, nil, nil, invoker(errs, &calls)))
+
+	assert.Equal(t, 2, g.Len())
+	assert.Equal(t, gobreaker.StateOpen, g.Get("/example.Admin/Reindex").State())
+	assert.Equal(t, gobreaker.StateClosed, g.Get("/example.Users/Get").State())
+}
+
+func TestGroupUnaryClientInterceptorKey(t *testing.T) {
+	g := gobreaker.NewBreakerGroup(0, nil)
+	target := func(ctx context.Context, method string, cc *grpc.ClientConn) string {
+		return cc.Target
+	}
+	intercept := GroupUnaryClientInterceptor(g, target)
+	calls := 0
+
+	cc := &grpc.ClientConn{Target: "users:443"}
+	assert.NoError(t, intercept(context.Background(), "/example.Users/Get", nil, nil, cc, invoker(nil, &calls)))
+	assert.NoError(t, intercept(context.Background(), "/example.Users/List", nil, nil, cc, invoker(nil, &calls)))
+	assert.Equal(t, 1, g.Len())
+	assert.Equal(t, uint32(2), g.Get("users:443").Counts().Requests)
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+}
+
+func TestGroupStreamClientInterceptor(t *testing.T) {
+	g := gobreaker.NewBreakerGroup(0, func(key string) gobreaker.Settings {
+		return gobreaker.Settings{
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}
+	})
+	intercept := GroupStreamClientInterceptor(g, nil)
+	ctx := context.Background()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream := &fakeClientStream{}
+	streamer := func(err error) grpc.Streamer {
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if err != nil {
+				return nil, err
+			}
+			return stream, nil
+		}
+	}
+
+	cs, err := intercept(ctx, desc, nil, "/example.Users/Watch", streamer(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, stream, cs)
+
+	_, err = intercept(ctx, desc, nil, "/example.Users/Tail", streamer(status.Error(codes.Unavailable, "down")))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = intercept(ctx, desc, nil, "/example.Users/Tail", streamer(nil))
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	_, err = intercept(ctx, desc, nil, "/example.Users/Watch", streamer(nil))
+	assert.NoError(t, err)
+}
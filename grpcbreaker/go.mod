@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/grpcbreaker
+
+go 1.13
+
+require (
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+	google.golang.org/grpc v1.33.2
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,49 @@
+// Package grpcbreaker guards gRPC servers and clients with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on gRPC.
+package grpcbreaker
+
+import (
+	"context"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failureCodes are the status codes that say the server or its dependencies are unhealthy.
+// The other codes, e.g. InvalidArgument or NotFound, are answers of a healthy server.
+var failureCodes = map[codes.Code]bool{
+	codes.Unknown:           true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Internal:          true,
+	codes.Unavailable:       true,
+	codes.DataLoss:          true,
+}
+
+// Classify classifies the error of an RPC for Settings.Classify by its status code.
+// The codes Unavailable, DeadlineExceeded, ResourceExhausted, Internal, Unknown and DataLoss
+// count as failures, Canceled is ignored, as the caller gave up on the RPC,
+// and the other codes count as successes. An error without a status, e.g. the rejection
+// of a downstream breaker returned by a handler, has the code Unknown and counts as a failure.
+func Classify(err error) gobreaker.Outcome {
+	switch code := status.Code(err); {
+	case code == codes.Canceled:
+		return gobreaker.OutcomeIgnore
+	case failureCodes[code]:
+		return gobreaker.OutcomeFailure
+	default:
+		return gobreaker.OutcomeSuccess
+	}
+}
+
+// execute runs req through cb with ctx if cb is a gobreaker.ContextBreaker.
+func execute(ctx context.Context, cb gobreaker.Breaker, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if c, ok := cb.(gobreaker.ContextBreaker); ok {
+		return c.ExecuteContext(ctx, req)
+	}
+	return cb.Execute(func() (interface{}, error) {
+		return req(ctx)
+	})
+}
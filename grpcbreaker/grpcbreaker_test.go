@@ -0,0 +1,42 @@
+package grpcbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		outcome gobreaker.Outcome
+	}{
+		{status.Error(codes.Unavailable, "down"), gobreaker.OutcomeFailure},
+		{status.Error(codes.DeadlineExceeded, "slow"), gobreaker.OutcomeFailure},
+		{status.Error(codes.Internal, "bug"), gobreaker.OutcomeFailure},
+		{status.Error(codes.InvalidArgument, "bad"), gobreaker.OutcomeSuccess},
+		{status.Error(codes.NotFound, "missing"), gobreaker.OutcomeSuccess},
+		{status.Error(codes.Canceled, "gave up"), gobreaker.OutcomeIgnore},
+		{fmt.Errorf("call: %w", status.Error(codes.Unavailable, "down")), gobreaker.OutcomeFailure},
+		{errors.New("connection refused"), gobreaker.OutcomeFailure},
+		{&gobreaker.RejectionError{Name: "db", Err: gobreaker.ErrOpenState}, gobreaker.OutcomeFailure},
+	} {
+		assert.Equal(t, test.outcome, Classify(test.err), test.err.Error())
+	}
+}
+
+func TestExecuteWithoutContext(t *testing.T) {
+	cb := gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{})
+	ctx := context.WithValue(context.Background(), struct{}{}, "value")
+	result, err := execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(struct{}{}), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "value", result)
+}
@@ -0,0 +1,47 @@
+package grpcbreaker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sony/gobreaker"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DefaultHealthInterval is the interval of UpdateHealth when it is called with an interval
+// less than or equal to 0.
+const DefaultHealthInterval = 5 * time.Second
+
+// StatusSetter sets the serving status of a service, like *health.Server of gRPC.
+type StatusSetter interface {
+	SetServingStatus(service string, servingStatus healthpb.HealthCheckResponse_ServingStatus)
+}
+
+// ServingStatus returns the serving status of grpc_health_v1 for h:
+// NOT_SERVING if the service is unhealthy, SERVING if it is healthy or degraded.
+func ServingStatus(h gobreaker.Health) healthpb.HealthCheckResponse_ServingStatus {
+	if h == gobreaker.HealthUnhealthy {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// UpdateHealth sets the serving status of service in srv to the ServingStatus of the Health
+// reported by checker, right away and then every interval, until ctx is done.
+// The empty service is the overall health of the server.
+func UpdateHealth(ctx context.Context, srv StatusSetter, service string, checker *gobreaker.HealthChecker, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		srv.SetServingStatus(service, ServingStatus(checker.Report().Health))
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
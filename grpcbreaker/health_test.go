@@ -0,0 +1,67 @@
+package grpcbreaker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestServingStatus(t *testing.T) {
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, ServingStatus(gobreaker.HealthHealthy))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, ServingStatus(gobreaker.HealthDegraded))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, ServingStatus(gobreaker.HealthUnhealthy))
+}
+
+type fakeHealthServer struct {
+	mutex    sync.Mutex
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (s *fakeHealthServer) SetServingStatus(service string, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.statuses[service] = servingStatus
+}
+
+func (s *fakeHealthServer) status(service string) healthpb.HealthCheckResponse_ServingStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.statuses[service]
+}
+
+func (s *fakeHealthServer) waitFor(t *testing.T, service string, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	for deadline := time.Now().Add(time.Second); s.status(service) != servingStatus; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %v, want %v", service, s.status(service), servingStatus)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUpdateHealth(t *testing.T) {
+	r := gobreaker.NewRegistry()
+	db := r.GetOrCreate("db", gobreaker.Settings{})
+	checker := gobreaker.NewHealthChecker(r, "db")
+	srv := &fakeHealthServer{statuses: make(map[string]healthpb.HealthCheckResponse_ServingStatus)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		UpdateHealth(ctx, srv, "example.Users", checker, time.Millisecond)
+		close(done)
+	}()
+
+	srv.waitFor(t, "example.Users", healthpb.HealthCheckResponse_SERVING)
+	db.Trip()
+	srv.waitFor(t, "example.Users", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	cancel()
+	<-done
+}
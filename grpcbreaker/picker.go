@@ -0,0 +1,112 @@
+package grpcbreaker
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AddressBreakers holds a TwoStepCircuitBreaker per backend address of a gRPC client,
+// so that a picker ejects the backends whose breakers are open, like outlier detection,
+// and selects them again once their breakers are half-open.
+// Custom pickers call Allow for the backend they are about to pick; PickerBuilder
+// provides a round-robin picker that does so.
+//
+// The breakers are kept when their backends leave the ready set, so that a backend
+// that reconnects doesn't come back with a closed breaker.
+type AddressBreakers struct {
+	st       gobreaker.Settings
+	mutex    sync.Mutex
+	breakers map[string]*gobreaker.TwoStepCircuitBreaker
+}
+
+// NewAddressBreakers returns an AddressBreakers whose breakers are created with st,
+// named after their addresses. If st.Classify is nil, Classify is used.
+func NewAddressBreakers(st gobreaker.Settings) *AddressBreakers {
+	if st.Classify == nil {
+		st.Classify = Classify
+	}
+	return &AddressBreakers{st: st, breakers: make(map[string]*gobreaker.TwoStepCircuitBreaker)}
+}
+
+// Get returns the breaker of addr, creating it if it doesn't exist yet.
+func (b *AddressBreakers) Get(addr string) *gobreaker.TwoStepCircuitBreaker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	cb, ok := b.breakers[addr]
+	if !ok {
+		st := b.st
+		st.Name = addr
+		cb = gobreaker.NewTwoStepCircuitBreaker(st)
+		b.breakers[addr] = cb
+	}
+	return cb
+}
+
+// Allow reports whether the breaker of addr admits an RPC. If it does, done must be set
+// as the Done of the balancer.PickResult, so that the outcome of the RPC feeds the breaker.
+// Otherwise, Allow returns the rejection and the picker should select another backend.
+func (b *AddressBreakers) Allow(addr string) (done func(info balancer.DoneInfo), err error) {
+	report, err := b.Get(addr).AllowE()
+	if err != nil {
+		return nil, err
+	}
+	return func(info balancer.DoneInfo) { report(info.Err) }, nil
+}
+
+// PickerBuilder returns a base.PickerBuilder, e.g. for base.NewBalancerBuilder, whose pickers
+// select the ready backends in turn, skipping those whose breakers reject the RPC.
+// If every breaker rejects it, the RPC fails with codes.Unavailable, unless it waits for ready.
+func (b *AddressBreakers) PickerBuilder() base.PickerBuilder {
+	return pickerBuilder{b}
+}
+
+type pickerBuilder struct {
+	breakers *AddressBreakers
+}
+
+func (pb pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &picker{breakers: pb.breakers}
+	for sc, sci := range info.ReadySCs {
+		p.subConns = append(p.subConns, subConn{sc: sc, addr: sci.Address.Addr})
+	}
+	sort.Slice(p.subConns, func(i, j int) bool { return p.subConns[i].addr < p.subConns[j].addr })
+	return p
+}
+
+type subConn struct {
+	sc   balancer.SubConn
+	addr string
+}
+
+// picker is a round-robin picker that skips the backends rejected by their breakers.
+type picker struct {
+	breakers *AddressBreakers
+	subConns []subConn
+	next     uint32
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	n := uint32(len(p.subConns))
+	start := atomic.AddUint32(&p.next, 1) - 1
+	var err error
+	for i := uint32(0); i < n; i++ {
+		sc := p.subConns[(start+i)%n]
+		var done func(balancer.DoneInfo)
+		if done, err = p.breakers.Allow(sc.addr); err == nil {
+			return balancer.PickResult{SubConn: sc.sc, Done: done}, nil
+		}
+	}
+	return balancer.PickResult{}, status.Errorf(codes.Unavailable, "every backend is rejected by its breaker, last: %v", err)
+}
@@ -0,0 +1,78 @@
+package grpcbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+func buildPicker(b *AddressBreakers, addrs ...string) balancer.Picker {
+	info := base.PickerBuildInfo{ReadySCs: make(map[balancer.SubConn]base.SubConnInfo)}
+	for _, addr := range addrs {
+		info.ReadySCs[&fakeSubConn{addr: addr}] = base.SubConnInfo{Address: resolver.Address{Addr: addr}}
+	}
+	return b.PickerBuilder().Build(info)
+}
+
+// pick picks a backend for an RPC that fails with err and returns its address.
+func pick(t *testing.T, p balancer.Picker, err error) string {
+	result, pickErr := p.Pick(balancer.PickInfo{FullMethodName: "/example.Users/Get", Ctx: context.Background()})
+	if !assert.NoError(t, pickErr) {
+		return ""
+	}
+	result.Done(balancer.DoneInfo{Err: err})
+	return result.SubConn.(*fakeSubConn).addr
+}
+
+func TestPicker(t *testing.T) {
+	b := NewAddressBreakers(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	p := buildPicker(b, "10.0.0.2:443", "10.0.0.1:443")
+
+	assert.Equal(t, "10.0.0.1:443", pick(t, p, nil))
+	assert.Equal(t, "10.0.0.2:443", pick(t, p, status.Error(codes.Unavailable, "down")))
+	assert.Equal(t, gobreaker.StateOpen, b.Get("10.0.0.2:443").State())
+	assert.Equal(t, "10.0.0.2:443", b.Get("10.0.0.2:443").Name())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "10.0.0.1:443", pick(t, p, status.Error(codes.NotFound, "missing")))
+	}
+	assert.Equal(t, gobreaker.StateClosed, b.Get("10.0.0.1:443").State())
+
+	assert.Equal(t, "10.0.0.1:443", pick(t, p, status.Error(codes.Internal, "bug")))
+	_, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestPickerWithoutReadySubConns(t *testing.T) {
+	p := buildPicker(NewAddressBreakers(gobreaker.Settings{}))
+	_, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.Equal(t, balancer.ErrNoSubConnAvailable, err)
+}
+
+func TestAddressBreakersAllow(t *testing.T) {
+	b := NewAddressBreakers(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	done, err := b.Allow("10.0.0.1:443")
+	assert.NoError(t, err)
+	done(balancer.DoneInfo{Err: status.Error(codes.DeadlineExceeded, "slow")})
+
+	_, err = b.Allow("10.0.0.1:443")
+	assert.True(t, gobreaker.IsRejection(err))
+	_, err = b.Allow("10.0.0.2:443")
+	assert.NoError(t, err)
+}
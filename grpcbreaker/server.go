@@ -0,0 +1,68 @@
+package grpcbreaker
+
+import (
+	"context"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HealthMethods are the methods of the health checking and reflection services,
+// which a load-shedding server usually keeps serving while its breaker is open.
+var HealthMethods = []string{
+	"/grpc.health.v1.Health/Check",
+	"/grpc.health.v1.Health/Watch",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that sheds the load of a server:
+// it runs the handlers through cb, and fails the RPCs with codes.Unavailable while cb rejects them.
+// cb should be created with Classify as Settings.Classify, so that it is fed by the errors
+// of the handlers that say the server is unhealthy, including the rejections of the breakers
+// of its downstream dependencies. The excluded methods, e.g. HealthMethods, bypass cb.
+func UnaryServerInterceptor(cb gobreaker.Breaker, excluded ...string) grpc.UnaryServerInterceptor {
+	skip := methodSet(excluded)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		resp, err := execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+			return handler(ctx, req)
+		})
+		return resp, unavailable(err)
+	}
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor like UnaryServerInterceptor.
+// A stream counts as a single request that finishes when its handler returns.
+func StreamServerInterceptor(cb gobreaker.Breaker, excluded ...string) grpc.StreamServerInterceptor {
+	skip := methodSet(excluded)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		_, err := execute(ss.Context(), cb, func(ctx context.Context) (interface{}, error) {
+			return nil, handler(srv, ss)
+		})
+		return unavailable(err)
+	}
+}
+
+// unavailable converts a rejection into an error with codes.Unavailable.
+func unavailable(err error) error {
+	if gobreaker.IsRejection(err) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[m] = true
+	}
+	return set
+}
@@ -0,0 +1,94 @@
+package grpcbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newServerCB() *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Classify:    Classify,
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	cb := newServerCB()
+	intercept := UnaryServerInterceptor(cb, HealthMethods...)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/example.Users/Get"}
+	health := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	respond := func(err error) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, err
+		}
+	}
+
+	resp, err := intercept(ctx, "req", info, respond(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "req", resp)
+
+	_, err = intercept(ctx, "req", info, respond(status.Error(codes.InvalidArgument, "bad")))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = intercept(ctx, "req", info, respond(&gobreaker.RejectionError{Name: "db", Err: gobreaker.ErrOpenState}))
+	assert.Error(t, err)
+	_, err = intercept(ctx, "req", info, respond(status.Error(codes.Unavailable, "db down")))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	_, err = intercept(ctx, "req", info, respond(nil))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "circuit breaker is open")
+
+	resp, err = intercept(ctx, "req", health, respond(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "req", resp)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetTrailer(metadata.MD) {}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	cb := newServerCB()
+	intercept := StreamServerInterceptor(cb, HealthMethods...)
+	ss := &fakeServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/example.Users/List", IsServerStream: true}
+	watch := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch", IsServerStream: true}
+	calls := 0
+	handler := func(err error) grpc.StreamHandler {
+		return func(srv interface{}, stream grpc.ServerStream) error {
+			calls++
+			assert.Equal(t, ss, stream)
+			return err
+		}
+	}
+
+	assert.NoError(t, intercept(nil, ss, info, handler(nil)))
+	for i := 0; i < 2; i++ {
+		err := intercept(nil, ss, info, handler(status.Error(codes.Internal, "bug")))
+		assert.Equal(t, codes.Internal, status.Code(err))
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	err := intercept(nil, ss, info, handler(nil))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, calls)
+
+	assert.NoError(t, intercept(nil, ss, watch, handler(nil)))
+	assert.Equal(t, 4, calls)
+}
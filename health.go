@@ -0,0 +1,143 @@
+package gobreaker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Health is the health of a service as seen through its CircuitBreakers.
+type Health int
+
+const (
+	// HealthHealthy means that every CircuitBreaker admits requests.
+	HealthHealthy Health = iota
+	// HealthDegraded means that a CircuitBreaker of a non-critical dependency is open,
+	// or any CircuitBreaker is probing its dependency in the half-open state.
+	HealthDegraded
+	// HealthUnhealthy means that a CircuitBreaker of a critical dependency is open.
+	HealthUnhealthy
+)
+
+// String implements stringer interface.
+func (h Health) String() string {
+	switch h {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnhealthy:
+		return "unhealthy"
+	default:
+		return fmt.Sprintf("unknown health: %d", h)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that a Health is encoded by its name.
+func (h Health) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the names returned by String.
+func (h *Health) UnmarshalText(text []byte) error {
+	for health := HealthHealthy; health <= HealthUnhealthy; health++ {
+		if string(text) == health.String() {
+			*h = health
+			return nil
+		}
+	}
+	return fmt.Errorf("gobreaker: unknown health %q", text)
+}
+
+// BreakerHealth is the health of a dependency in a HealthReport.
+type BreakerHealth struct {
+	Name     string `json:"name"`
+	State    State  `json:"state"`
+	Critical bool   `json:"critical"`
+	Health   Health `json:"health"`
+}
+
+// HealthReport is the health of a service: the worst Health of its dependencies.
+type HealthReport struct {
+	Health   Health          `json:"health"`
+	Breakers []BreakerHealth `json:"breakers"`
+}
+
+// HealthChecker aggregates CircuitBreakers into a HealthReport for readiness checks,
+// e.g. a /healthz handler or a gRPC health service. The CircuitBreaker of a critical
+// dependency makes the service unhealthy when it is open; any other open CircuitBreaker
+// only degrades it. The administrative states count as the states they behave like.
+//
+// A HealthChecker should not back a liveness check: restarting a service doesn't
+// repair its dependencies.
+type HealthChecker struct {
+	registry *Registry
+	critical map[string]bool
+
+	mutex    sync.Mutex
+	breakers []Breaker
+}
+
+// NewHealthChecker returns a HealthChecker of the CircuitBreakers in r, including those added
+// to r later, and of those added with Add. r may be nil. The Breakers named in critical
+// are the critical dependencies of the service.
+func NewHealthChecker(r *Registry, critical ...string) *HealthChecker {
+	h := &HealthChecker{registry: r, critical: make(map[string]bool, len(critical))}
+	for _, name := range critical {
+		h.critical[name] = true
+	}
+	return h
+}
+
+// Add adds b to the Breakers of the HealthChecker.
+func (h *HealthChecker) Add(b Breaker) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.breakers = append(h.breakers, b)
+}
+
+// Report returns the HealthReport of the Breakers: those of the Registry sorted by name,
+// then those added with Add in their order.
+func (h *HealthChecker) Report() HealthReport {
+	var breakers []Breaker
+	if h.registry != nil {
+		for _, cb := range h.registry.sorted() {
+			breakers = append(breakers, cb)
+		}
+	}
+	h.mutex.Lock()
+	breakers = append(breakers, h.breakers...)
+	h.mutex.Unlock()
+
+	report := HealthReport{Breakers: make([]BreakerHealth, len(breakers))}
+	for i, b := range breakers {
+		name := b.Name()
+		bh := BreakerHealth{Name: name, State: b.State(), Critical: h.critical[name]}
+		switch bh.State {
+		case StateOpen, StateForcedOpen:
+			bh.Health = HealthDegraded
+			if bh.Critical {
+				bh.Health = HealthUnhealthy
+			}
+		case StateHalfOpen:
+			bh.Health = HealthDegraded
+		}
+		if bh.Health > report.Health {
+			report.Health = bh.Health
+		}
+		report.Breakers[i] = bh
+	}
+	return report
+}
+
+// ServeHTTP implements http.Handler for a readiness check. It responds with the HealthReport
+// as JSON, with 503 Service Unavailable if the service is unhealthy and 200 OK otherwise.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Report()
+	code := http.StatusOK
+	if report.Health == HealthUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
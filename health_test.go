@@ -0,0 +1,86 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthString(t *testing.T) {
+	assert.Equal(t, "healthy", HealthHealthy.String())
+	assert.Equal(t, "degraded", HealthDegraded.String())
+	assert.Equal(t, "unhealthy", HealthUnhealthy.String())
+	assert.Equal(t, "unknown health: 100", Health(100).String())
+
+	var h Health
+	assert.NoError(t, h.UnmarshalText([]byte("degraded")))
+	assert.Equal(t, HealthDegraded, h)
+	assert.EqualError(t, h.UnmarshalText([]byte("sick")), `gobreaker: unknown health "sick"`)
+}
+
+func TestHealthChecker(t *testing.T) {
+	r := NewRegistry()
+	db := r.GetOrCreate("db", Settings{})
+	cache := r.GetOrCreate("cache", Settings{})
+	search := NewTwoStepCircuitBreaker(Settings{Name: "search"})
+	h := NewHealthChecker(r, "db")
+	h.Add(search)
+
+	report := h.Report()
+	assert.Equal(t, HealthHealthy, report.Health)
+	assert.Equal(t, []BreakerHealth{
+		{Name: "cache", State: StateClosed},
+		{Name: "db", State: StateClosed, Critical: true},
+		{Name: "search", State: StateClosed},
+	}, report.Breakers)
+
+	cache.Trip()
+	report = h.Report()
+	assert.Equal(t, HealthDegraded, report.Health)
+	assert.Equal(t, HealthDegraded, report.Breakers[0].Health)
+
+	db.ForceOpen()
+	report = h.Report()
+	assert.Equal(t, HealthUnhealthy, report.Health)
+	assert.Equal(t, HealthUnhealthy, report.Breakers[1].Health)
+
+	db.ClearOverride()
+	cache.Reset()
+	search.Trip()
+	assert.Equal(t, HealthDegraded, h.Report().Health)
+}
+
+func TestHealthCheckerHalfOpen(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{Name: "db"})
+	h := NewHealthChecker(nil, "db")
+	h.Add(cb)
+
+	report := h.Report()
+	assert.Equal(t, HealthDegraded, report.Health)
+	assert.Equal(t, StateHalfOpen, report.Breakers[0].State)
+}
+
+func TestHealthCheckerServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	db := r.GetOrCreate("db", Settings{})
+	h := NewHealthChecker(r, "db")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"health":"healthy","breakers":[{"name":"db","state":"closed","critical":true,"health":"healthy"}]}`, w.Body.String())
+
+	db.Trip()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report HealthReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, HealthReport{
+		Health:   HealthUnhealthy,
+		Breakers: []BreakerHealth{{Name: "db", State: StateOpen, Critical: true, Health: HealthUnhealthy}},
+	}, report)
+}
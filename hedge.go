@@ -0,0 +1,84 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// hedgedResult is the outcome of an attempt of a hedged request.
+type hedgedResult struct {
+	value interface{}
+	err   error
+	panic interface{}
+}
+
+// ExecuteHedged runs req like ExecuteContext, and if it hasn't returned after delay,
+// runs a second, hedged attempt of req concurrently. The first successful attempt wins
+// and the context of the other attempt is cancelled. If an attempt fails while the other
+// is still running, ExecuteHedged waits for the other one. An attempt whose error is
+// ignored according to Classify wins like a successful one.
+//
+// Both attempts together are a single request to the CircuitBreaker, which counts
+// exactly one outcome. The hedged attempt is launched only in the closed state,
+// so that it doesn't add load to a dependency that is recovering in the half-open state.
+func (cb *CircuitBreaker) ExecuteHedged(ctx context.Context, delay time.Duration, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgedResult, 2)
+	attempt := func() {
+		var r hedgedResult
+		defer func() {
+			r.panic = recover()
+			results <- r
+		}()
+		r.value, r.err = req(ctx)
+	}
+
+	go attempt()
+	running := 1
+
+	timer := cb.newTimer(delay)
+	defer timer.Stop()
+	hedge := timer.C()
+
+	var r hedgedResult
+	var outcome Outcome
+	var f failure
+	for running > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			if state := cb.State(); state == StateClosed || state == StateForcedClosed {
+				go attempt()
+				running++
+			}
+			continue
+		case r = <-results:
+		}
+		running--
+
+		if r.panic != nil {
+			outcome, f, r.err = cb.recoverPanic(r.panic)
+			if r.err == nil {
+				cb.finishRequest(ctx, generation, start, outcome, f)
+				panic(r.panic)
+			}
+			r.value = nil
+		} else {
+			outcome = cb.classify(r.value, r.err)
+			f = cb.failureOf(outcome, r.err)
+		}
+		if outcome != OutcomeFailure {
+			break
+		}
+	}
+
+	cb.finishRequest(ctx, generation, start, outcome, f)
+	return r.value, r.err
+}
@@ -0,0 +1,106 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteHedged(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Clock: clock})
+
+	var calls int32
+	cancelled := make(chan struct{})
+	req := func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// the slow first attempt loses
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+		return "hedged", nil
+	}
+
+	go func() {
+		for clock.Timers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(time.Second)
+	}()
+
+	result, err := cb.ExecuteHedged(context.Background(), time.Second, req)
+	assert.Nil(t, err)
+	assert.Equal(t, "hedged", result)
+	<-cancelled
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}, cb.Counts())
+}
+
+func TestExecuteHedgedFast(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	errFail := errors.New("fail")
+
+	result, err := cb.ExecuteHedged(context.Background(), time.Hour, func(ctx context.Context) (interface{}, error) {
+		return "fast", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fast", result)
+
+	// a failure before the delay isn't hedged
+	calls := 0
+	_, err = cb.ExecuteHedged(context.Background(), time.Hour, func(ctx context.Context) (interface{}, error) {
+		calls++
+		return nil, errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+}
+
+func TestExecuteHedgedBothFail(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+
+	var calls int32
+	release := make(chan struct{})
+	_, err := cb.ExecuteHedged(context.Background(), time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return nil, errors.New("first")
+		}
+		close(release)
+		return nil, errors.New("second")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+}
+
+func TestExecuteHedgedHalfOpen(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+
+	var calls int32
+	result, err := cb.ExecuteHedged(context.Background(), time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "probe", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "probe", result)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestExecuteHedgedPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Panics(t, func() {
+		cb.ExecuteHedged(context.Background(), time.Hour, func(ctx context.Context) (interface{}, error) {
+			panic("oops")
+		})
+	})
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{FailurePanic: 1}}, cb.Counts())
+}
@@ -0,0 +1,179 @@
+package gobreaker
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramSubBits is the number of bits of the sub-buckets of each power of 2 of a LatencyHistogram:
+// 8 sub-buckets bound the relative error of the quantiles to 12.5%.
+const histogramSubBits = 3
+
+const (
+	histogramSubBuckets = 1 << histogramSubBits
+	histogramBuckets    = (63 - histogramSubBits + 1) * histogramSubBuckets
+)
+
+// LatencyHistogram is a histogram of the latencies of the requests of a generation,
+// see Settings.LatencyHistogram.
+//
+// Its buckets are log-linear, like those of an HDR histogram: every power of 2 of nanoseconds
+// is split into 8 buckets, so that the quantiles are within 12.5% of the actual latencies
+// from nanoseconds to hours, in a fixed size and without allocating.
+//
+// Count is the number of latencies, Sum their sum, and Min and Max the extremes.
+type LatencyHistogram struct {
+	Count uint64
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+
+	counts [histogramBuckets]uint32
+}
+
+// HistogramBucket is a non-empty bucket of a LatencyHistogram:
+// Count latencies are less than UpperBound and at least the UpperBound of the previous bucket.
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint32
+}
+
+// HistogramSink is implemented by a MetricsSink that receives the latency histogram of every generation.
+// If Settings.LatencyHistogram is true, the CircuitBreaker calls OnHistogram when a generation
+// ends, with the generation, the state it was in and its LatencyHistogram, unless it is empty.
+type HistogramSink interface {
+	MetricsSink
+	OnHistogram(name string, generation uint64, state State, histogram LatencyHistogram)
+}
+
+// histogramBucket returns the index of the bucket of latency.
+func histogramBucket(latency time.Duration) int {
+	v := uint64(latency)
+	if latency < 0 {
+		v = 0
+	}
+	if v < histogramSubBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - 1
+	sub := int(v>>uint(e-histogramSubBits)) & (histogramSubBuckets - 1)
+	return (e-histogramSubBits+1)*histogramSubBuckets + sub
+}
+
+// histogramLowerBound returns the smallest latency in the bucket of index i.
+func histogramLowerBound(i int) time.Duration {
+	if i < histogramSubBuckets {
+		return time.Duration(i)
+	}
+	e := i/histogramSubBuckets + histogramSubBits - 1
+	sub := i % histogramSubBuckets
+	return time.Duration(uint64(histogramSubBuckets+sub) << uint(e-histogramSubBits))
+}
+
+// histogramUpperBound returns the latency just above the bucket of index i.
+func histogramUpperBound(i int) time.Duration {
+	if i == histogramBuckets-1 {
+		return time.Duration(1<<63 - 1)
+	}
+	return histogramLowerBound(i + 1)
+}
+
+func (h *LatencyHistogram) observe(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	if h.Count == 0 || latency < h.Min {
+		h.Min = latency
+	}
+	if latency > h.Max {
+		h.Max = latency
+	}
+	h.Count++
+	h.Sum += latency
+	h.counts[histogramBucket(latency)]++
+}
+
+// Mean returns the mean latency, or 0 if the LatencyHistogram is empty.
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the q quantile of the latencies, e.g. 0.99 for the 99th percentile,
+// as the upper bound of its bucket, within Min and Max. It returns 0 if the LatencyHistogram is empty.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return h.Min
+	}
+	if q >= 1 {
+		return h.Max
+	}
+	rank := uint64(q*float64(h.Count) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range h.counts {
+		seen += uint64(n)
+		if seen >= rank {
+			latency := histogramUpperBound(i) - 1
+			if latency > h.Max {
+				latency = h.Max
+			}
+			if latency < h.Min {
+				latency = h.Min
+			}
+			return latency
+		}
+	}
+	return h.Max
+}
+
+// Buckets returns the non-empty buckets of the LatencyHistogram in increasing order.
+func (h *LatencyHistogram) Buckets() []HistogramBucket {
+	var buckets []HistogramBucket
+	for i, n := range h.counts {
+		if n > 0 {
+			buckets = append(buckets, HistogramBucket{UpperBound: histogramUpperBound(i), Count: n})
+		}
+	}
+	return buckets
+}
+
+// observeHistogram adds latency to the histogram of the current generation, if any.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) observeHistogram(latency time.Duration) {
+	if cb.histogram != nil {
+		cb.histogram.observe(latency)
+	}
+}
+
+// endHistogram reports the histogram of the ending generation to the Metrics and clears it.
+// It must be called with cb.mutex held, before the generation changes.
+func (cb *CircuitBreaker) endHistogram() {
+	if cb.histogram == nil || cb.histogram.Count == 0 {
+		return
+	}
+	if sink, ok := cb.metrics.(HistogramSink); ok {
+		sink.OnHistogram(cb.name, cb.generation, cb.genState, *cb.histogram)
+	}
+	*cb.histogram = LatencyHistogram{}
+}
+
+// LatencyHistogram returns the histogram of the latencies of the requests of the current generation,
+// alongside Counts, or false if Settings.LatencyHistogram is false.
+func (cb *CircuitBreaker) LatencyHistogram() (LatencyHistogram, bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.histogram == nil {
+		return LatencyHistogram{}, false
+	}
+	cb.currentState(cb.clock.Now())
+	return *cb.histogram, true
+}
@@ -0,0 +1,100 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	for _, latency := range []time.Duration{0, 1, 7, 8, 9, 15, 16, 17, 1000, time.Millisecond, 3 * time.Second, time.Duration(1<<63 - 1)} {
+		i := histogramBucket(latency)
+		assert.True(t, histogramLowerBound(i) <= latency, "%v", latency)
+		assert.True(t, latency < histogramUpperBound(i) || i == histogramBuckets-1, "%v", latency)
+	}
+	assert.Equal(t, 16, histogramBucket(16))
+	assert.Equal(t, 16, histogramBucket(17))
+	assert.Equal(t, histogramBuckets-1, histogramBucket(time.Duration(1<<63-1)))
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	assert.Equal(t, time.Duration(0), h.Quantile(0.99))
+	assert.Equal(t, time.Duration(0), h.Mean())
+
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, uint64(100), h.Count)
+	assert.Equal(t, time.Millisecond, h.Min)
+	assert.Equal(t, 100*time.Millisecond, h.Max)
+	assert.Equal(t, 50500*time.Microsecond, h.Mean())
+	assert.Equal(t, time.Millisecond, h.Quantile(0))
+	assert.Equal(t, 100*time.Millisecond, h.Quantile(1))
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		want := time.Duration(q*100) * time.Millisecond
+		got := h.Quantile(q)
+		assert.True(t, got >= want && float64(got) <= 1.125*float64(want), "p%v = %v", q*100, got)
+	}
+
+	var total uint32
+	buckets := h.Buckets()
+	for i, b := range buckets {
+		assert.True(t, b.Count > 0)
+		if i > 0 {
+			assert.True(t, b.UpperBound > buckets[i-1].UpperBound)
+		}
+		total += b.Count
+	}
+	assert.Equal(t, uint32(100), total)
+}
+
+func TestCircuitBreakerLatencyHistogram(t *testing.T) {
+	_, ok := NewCircuitBreaker(Settings{}).LatencyHistogram()
+	assert.False(t, ok)
+
+	clock := newFakeClock()
+	sink := &RecordingMetricsSink{}
+	cb := NewCircuitBreaker(Settings{
+		Name:             "hist",
+		Clock:            clock,
+		Metrics:          sink,
+		LatencyHistogram: true,
+		Stripes:          4,
+		ReadyToTrip:      func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	wait := func(d time.Duration, err error) {
+		cb.Execute(func() (interface{}, error) {
+			clock.Advance(d)
+			return nil, err
+		})
+	}
+	wait(10*time.Millisecond, nil)
+	wait(30*time.Millisecond, errors.New("fail"))
+	h, ok := cb.LatencyHistogram()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), h.Count)
+	assert.Equal(t, 40*time.Millisecond, h.Sum)
+	assert.Equal(t, 30*time.Millisecond, h.Max)
+	assert.Empty(t, sink.Histograms())
+
+	// the histogram of the generation is reported when the breaker trips
+	wait(20*time.Millisecond, errors.New("fail"))
+	assert.Equal(t, StateOpen, cb.State())
+	records := sink.Histograms()
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "hist", records[0].Name)
+	assert.Equal(t, uint64(1), records[0].Generation)
+	assert.Equal(t, StateClosed, records[0].State)
+	assert.Equal(t, uint64(3), records[0].Histogram.Count)
+	assert.Equal(t, 10*time.Millisecond, records[0].Histogram.Min)
+
+	h, _ = cb.LatencyHistogram()
+	assert.Equal(t, uint64(0), h.Count)
+
+	// empty generations are not reported
+	cb.Reset()
+	assert.Equal(t, 1, len(sink.Histograms()))
+}
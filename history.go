@@ -0,0 +1,140 @@
+package gobreaker
+
+import "time"
+
+// DefaultHistorySize is the number of transitions kept by default for History.
+const DefaultHistorySize = 16
+
+// TripReason is the reason why the state of a CircuitBreaker changed.
+type TripReason int
+
+// These constants are the reasons of state changes.
+const (
+	// ReasonReadyToTrip means ReadyToTrip returned true in the closed state.
+	ReasonReadyToTrip TripReason = iota
+	// ReasonHalfOpenFailure means a request failed in the half-open state.
+	ReasonHalfOpenFailure
+	// ReasonHalfOpenSuccess means enough requests succeeded in the half-open state.
+	ReasonHalfOpenSuccess
+	// ReasonTimeout means the open state timed out.
+	ReasonTimeout
+	// ReasonHealthCheck means the active health probe of ProbePolicy succeeded.
+	ReasonHealthCheck
+	// ReasonManual means the state was changed by a method such as Trip, Reset or ForceOpen.
+	ReasonManual
+	// ReasonEWMA means the moving averages of EWMAPolicy exceeded its thresholds in the closed state.
+	ReasonEWMA
+	// ReasonBurnRate means the error budget of ErrorBudgetPolicy burned too fast in the closed state.
+	ReasonBurnRate
+)
+
+// String implements stringer interface.
+func (r TripReason) String() string {
+	switch r {
+	case ReasonReadyToTrip:
+		return "ready-to-trip"
+	case ReasonHalfOpenFailure:
+		return "half-open-failure"
+	case ReasonHalfOpenSuccess:
+		return "half-open-success"
+	case ReasonTimeout:
+		return "timeout"
+	case ReasonHealthCheck:
+		return "health-check"
+	case ReasonManual:
+		return "manual"
+	case ReasonEWMA:
+		return "ewma"
+	case ReasonBurnRate:
+		return "burn-rate"
+	default:
+		return "unknown reason"
+	}
+}
+
+// Transition is a state change of a CircuitBreaker recorded in its History.
+// Counts are the Counts just before they were cleared by the transition.
+type Transition struct {
+	Time   time.Time
+	From   State
+	To     State
+	Counts Counts
+	Reason TripReason
+}
+
+// History returns the latest state transitions of the CircuitBreaker, oldest first.
+// The number of transitions kept is limited by Settings.HistorySize.
+func (cb *CircuitBreaker) History() []Transition {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.currentState(cb.clock.Now())
+	history := make([]Transition, 0, len(cb.history))
+	if len(cb.history) == cap(cb.history) {
+		history = append(history, cb.history[cb.historyPos:]...)
+		return append(history, cb.history[:cb.historyPos]...)
+	}
+	return append(history, cb.history...)
+}
+
+// History returns the latest state transitions of the TwoStepCircuitBreaker, oldest first.
+func (tscb *TwoStepCircuitBreaker) History() []Transition {
+	return tscb.cb.History()
+}
+
+// recordTransition adds a transition to the history. It must be called before the Counts are cleared.
+func (cb *CircuitBreaker) recordTransition(from State, to State, now time.Time, reason TripReason) {
+	if cap(cb.history) == 0 {
+		return
+	}
+
+	t := Transition{Time: now, From: from, To: to, Counts: cb.counts, Reason: reason}
+	if len(cb.history) < cap(cb.history) {
+		cb.history = append(cb.history, t)
+		return
+	}
+	cb.history[cb.historyPos] = t
+	cb.historyPos = (cb.historyPos + 1) % len(cb.history)
+}
+
+// TripInfo describes the latest time a CircuitBreaker opened, returned by LastTrip.
+//
+// Reason tells which strategy opened it, e.g. ReasonReadyToTrip for ReadyToTrip or a DetectionWindow,
+// ReasonEWMA or ReasonBurnRate. Counts are the Counts of the generation that ended with the trip.
+// Err is the error of the latest failure before the trip, or nil if the failures didn't return
+// an error or the CircuitBreaker was opened manually.
+type TripInfo struct {
+	Time   time.Time
+	From   State
+	To     State
+	Reason TripReason
+	Counts Counts
+	Err    error
+}
+
+// recordTrip records the transition as the latest trip if the CircuitBreaker opens.
+// It must be called before the Counts are cleared.
+func (cb *CircuitBreaker) recordTrip(from State, to State, now time.Time, reason TripReason) {
+	if to != StateOpen && to != StateForcedOpen {
+		return
+	}
+	cb.lastTrip = TripInfo{Time: now, From: from, To: to, Reason: reason, Counts: cb.counts}
+	if reason != ReasonManual {
+		cb.lastTrip.Err = cb.lastErr
+	}
+}
+
+// LastTrip returns why the CircuitBreaker opened the latest time, in StateOpen or StateForcedOpen.
+// Its Time is zero if the CircuitBreaker has never opened.
+func (cb *CircuitBreaker) LastTrip() TripInfo {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.currentState(cb.clock.Now())
+	return cb.lastTrip
+}
+
+// LastTrip returns why the TwoStepCircuitBreaker opened the latest time.
+func (tscb *TwoStepCircuitBreaker) LastTrip() TripInfo {
+	return tscb.cb.LastTrip()
+}
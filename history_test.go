@@ -0,0 +1,146 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clock,
+		Timeout:     time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	assert.Empty(t, cb.History())
+
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	clock.Advance(2 * time.Second)
+	assert.Nil(t, fail(cb))
+	clock.Advance(2 * time.Second)
+	assert.Nil(t, succeed(cb))
+	cb.ForceOpen()
+
+	assert.Equal(t, []Transition{
+		{start, StateClosed, StateOpen, Counts{2, 0, 2, 0, 2, 2, 2, FailureCounts{2}}, ReasonReadyToTrip},
+		{start.Add(2 * time.Second), StateOpen, StateHalfOpen, Counts{}, ReasonTimeout},
+		{start.Add(2 * time.Second), StateHalfOpen, StateOpen, Counts{1, 0, 0, 0, 0, 0, 0, FailureCounts{}}, ReasonHalfOpenFailure},
+		{start.Add(4 * time.Second), StateOpen, StateHalfOpen, Counts{}, ReasonTimeout},
+		{start.Add(4 * time.Second), StateHalfOpen, StateClosed, Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}, ReasonHalfOpenSuccess},
+		{start.Add(4 * time.Second), StateClosed, StateForcedOpen, Counts{}, ReasonManual},
+	}, cb.History())
+}
+
+func TestHistoryRing(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{HistorySize: 3})
+	for i := 0; i < 4; i++ {
+		tscb.Trip()
+		tscb.Reset()
+	}
+
+	history := tscb.History()
+	assert.Len(t, history, 3)
+	assert.Equal(t, StateClosed, history[0].To)
+	assert.Equal(t, StateOpen, history[1].To)
+	assert.Equal(t, StateClosed, history[2].To)
+
+	cb := NewCircuitBreaker(Settings{HistorySize: -1})
+	cb.Trip()
+	assert.Empty(t, cb.History())
+
+	cb = NewCircuitBreaker(Settings{})
+	assert.Equal(t, DefaultHistorySize, cap(cb.history))
+}
+
+func TestLastTrip(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clock,
+		Timeout:     time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	assert.True(t, cb.LastTrip().Time.IsZero())
+
+	timeout := errors.New("timeout")
+	cb.Execute(func() (interface{}, error) { return nil, errors.New("refused") })
+	cb.Execute(func() (interface{}, error) { return nil, timeout })
+	trip := cb.LastTrip()
+	assert.Equal(t, start, trip.Time)
+	assert.Equal(t, StateClosed, trip.From)
+	assert.Equal(t, StateOpen, trip.To)
+	assert.Equal(t, ReasonReadyToTrip, trip.Reason)
+	assert.Equal(t, uint32(2), trip.Counts.ConsecutiveFailures)
+	assert.Equal(t, timeout, trip.Err)
+
+	// the trip outlives the recovery
+	clock.Advance(2 * time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, start, cb.LastTrip().Time)
+
+	// a panic in the half-open state
+	cb.Trip()
+	assert.Equal(t, ReasonManual, cb.LastTrip().Reason)
+	assert.Nil(t, cb.LastTrip().Err)
+	clock.Advance(2 * time.Second)
+	assert.Panics(t, func() {
+		cb.Execute(func() (interface{}, error) { panic("boom") })
+	})
+	trip = NewTwoStepCircuitBreaker(Settings{}).LastTrip()
+	assert.True(t, trip.Time.IsZero())
+	trip = cb.LastTrip()
+	assert.Equal(t, ReasonHalfOpenFailure, trip.Reason)
+	assert.Equal(t, StateHalfOpen, trip.From)
+	assert.Equal(t, &PanicError{Value: "boom"}, trip.Err)
+}
+
+func TestTripReasonString(t *testing.T) {
+	assert.Equal(t, "ready-to-trip", ReasonReadyToTrip.String())
+	assert.Equal(t, "half-open-failure", ReasonHalfOpenFailure.String())
+	assert.Equal(t, "half-open-success", ReasonHalfOpenSuccess.String())
+	assert.Equal(t, "timeout", ReasonTimeout.String())
+	assert.Equal(t, "health-check", ReasonHealthCheck.String())
+	assert.Equal(t, "manual", ReasonManual.String())
+	assert.Equal(t, "ewma", ReasonEWMA.String())
+	assert.Equal(t, "burn-rate", ReasonBurnRate.String())
+	assert.Equal(t, "unknown reason", TripReason(10).String())
+}
+
+type stateChange2 struct {
+	from   State
+	to     State
+	counts Counts
+	reason TripReason
+}
+
+func TestOnStateChange2(t *testing.T) {
+	var calls []string
+	var changes []stateChange2
+	cb := NewCircuitBreaker(Settings{
+		OnStateChange: func(name string, from State, to State) {
+			calls = append(calls, "OnStateChange")
+		},
+		OnStateChange2: func(name string, from State, to State, counts Counts, reason TripReason) {
+			assert.Equal(t, "", name)
+			calls = append(calls, "OnStateChange2")
+			changes = append(changes, stateChange2{from, to, counts, reason})
+		},
+	})
+
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	cb.Reset()
+
+	assert.Equal(t, []string{"OnStateChange", "OnStateChange2", "OnStateChange", "OnStateChange2"}, calls)
+	assert.Equal(t, []stateChange2{
+		{StateClosed, StateOpen, Counts{6, 0, 6, 0, 6, 6, 6, FailureCounts{6}}, ReasonReadyToTrip},
+		{StateOpen, StateClosed, Counts{}, ReasonManual},
+	}, changes)
+}
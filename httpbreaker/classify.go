@@ -0,0 +1,61 @@
+package httpbreaker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Classifier reports whether an HTTP request failed, i.e. counts as a failure of its CircuitBreaker.
+// resp is the response to the request, or nil if err is the error of the transport.
+// The Classifiers compose with Any, e.g. Any(FailOnTransportError, FailOnStatuses(429)).
+type Classifier func(resp *http.Response, err error) bool
+
+// DefaultClassifier fails the requests on the errors of the transport and the responses of 5xx statuses.
+var DefaultClassifier = Any(FailOnTransportError, FailOn5xx)
+
+// Any returns a Classifier that fails a request if any of classifiers fails it.
+func Any(classifiers ...Classifier) Classifier {
+	return func(resp *http.Response, err error) bool {
+		for _, c := range classifiers {
+			if c(resp, err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FailOn5xx fails the requests whose response has a server error status, 500 or more.
+func FailOn5xx(resp *http.Response, err error) bool {
+	return resp != nil && resp.StatusCode >= http.StatusInternalServerError
+}
+
+// FailOnStatuses returns a Classifier that fails the requests whose response has one of codes,
+// e.g. 429 Too Many Requests.
+func FailOnStatuses(codes ...int) Classifier {
+	set := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return func(resp *http.Response, err error) bool {
+		return resp != nil && set[resp.StatusCode]
+	}
+}
+
+// FailOnTimeout fails the requests that timed out: the errors of the transport that are timeouts,
+// including context.DeadlineExceeded, and the responses of 504 Gateway Timeout.
+func FailOnTimeout(resp *http.Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode == http.StatusGatewayTimeout
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// FailOnTransportError fails the requests that got no response, e.g. because the connection
+// was refused or reset, except those canceled by the caller.
+func FailOnTransportError(resp *http.Response, err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
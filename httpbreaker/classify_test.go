@@ -0,0 +1,56 @@
+package httpbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func status(code int) *http.Response {
+	return &http.Response{StatusCode: code}
+}
+
+func TestClassifiers(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+	for _, test := range []struct {
+		name     string
+		classify Classifier
+		resp     *http.Response
+		err      error
+		failed   bool
+	}{
+		{"5xx", FailOn5xx, status(502), nil, true},
+		{"5xx", FailOn5xx, status(404), nil, false},
+		{"5xx", FailOn5xx, nil, refused, false},
+		{"statuses", FailOnStatuses(429, 409), status(429), nil, true},
+		{"statuses", FailOnStatuses(429, 409), status(500), nil, false},
+		{"timeout", FailOnTimeout, status(504), nil, true},
+		{"timeout", FailOnTimeout, status(503), nil, false},
+		{"timeout", FailOnTimeout, nil, timeout, true},
+		{"timeout", FailOnTimeout, nil, fmt.Errorf("get: %w", context.DeadlineExceeded), true},
+		{"timeout", FailOnTimeout, nil, refused, false},
+		{"transport", FailOnTransportError, nil, refused, true},
+		{"transport", FailOnTransportError, nil, context.Canceled, false},
+		{"transport", FailOnTransportError, status(500), nil, false},
+		{"default", DefaultClassifier, status(500), nil, true},
+		{"default", DefaultClassifier, nil, refused, true},
+		{"default", DefaultClassifier, status(429), nil, false},
+		{"any", Any(FailOnTimeout, FailOnStatuses(429)), status(429), nil, true},
+		{"any", Any(FailOnTimeout, FailOnStatuses(429)), nil, refused, false},
+		{"any", Any(), status(500), nil, false},
+	} {
+		assert.Equal(t, test.failed, test.classify(test.resp, test.err), "%s %v %v", test.name, test.resp, test.err)
+	}
+}
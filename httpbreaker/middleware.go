@@ -0,0 +1,69 @@
+package httpbreaker
+
+import (
+	"net/http"
+
+	"github.com/sony/gobreaker"
+)
+
+// Middleware returns a function that wraps an http.Handler so that it serves the requests through cb.
+// classify is called with a response that holds the status and the header written by the handler,
+// and a nil error. If classify is nil, DefaultClassifier is used.
+// A request rejected by cb gets 503 Service Unavailable, and a Retry-After header
+// if the time until the next attempt is known.
+func Middleware(cb gobreaker.Breaker, classify Classifier) func(http.Handler) http.Handler {
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := cb.Execute(func() (interface{}, error) {
+				rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+				next.ServeHTTP(rec, r)
+				resp := &http.Response{StatusCode: rec.status, Header: w.Header(), Request: r}
+				if classify(resp, nil) {
+					return nil, &StatusError{Code: rec.status}
+				}
+				return nil, nil
+			})
+			if gobreaker.IsRejection(err) {
+				if retryAfter := gobreaker.RetryAfterHeader(err); retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// statusRecorder records the status written to a http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying http.ResponseWriter does.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,52 @@
+package httpbreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	code := http.StatusOK
+	h := Middleware(cb, Any(FailOn5xx, FailOnStatuses(http.StatusTooManyRequests)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+	code = http.StatusNotFound
+	assert.Equal(t, http.StatusNotFound, serve().Code)
+	assert.Equal(t, uint32(2), cb.Counts().TotalSuccesses)
+
+	code = http.StatusTooManyRequests
+	assert.Equal(t, http.StatusTooManyRequests, serve().Code)
+	code = http.StatusInternalServerError
+	assert.Equal(t, http.StatusInternalServerError, serve().Code)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	w := serve()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestMiddlewareDefaultClassifier(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	h := Middleware(cb, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+}
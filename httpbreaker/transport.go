@@ -0,0 +1,113 @@
+// Package httpbreaker guards HTTP clients with the circuit breakers of gobreaker.
+package httpbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/sony/gobreaker"
+)
+
+// StatusError is the error with which a response that a Classifier fails is reported
+// to the CircuitBreaker, so that IsSuccessful and Classify can tell the statuses apart.
+// The response itself is returned to the caller without an error.
+type StatusError struct {
+	Code int
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpbreaker: response status %d", e.Code)
+}
+
+// ErrNoGroup is the error of the requests sent through a Transport without a Group.
+var ErrNoGroup = errors.New("httpbreaker: Transport has no Group")
+
+// KeyFunc returns the key of the CircuitBreaker of a request in a BreakerGroup.
+type KeyFunc func(req *http.Request) string
+
+// HostKey is the KeyFunc that returns the host and port of a request, e.g. "example.com:443",
+// so that each upstream gets its own CircuitBreaker.
+func HostKey(req *http.Request) string {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
+
+// Transport is an http.RoundTripper that sends each request through the CircuitBreaker of its key
+// in Group, so that a failing upstream doesn't block the requests to the healthy ones
+// through the same client. A request rejected by its CircuitBreaker fails with the rejection,
+// e.g. a *gobreaker.RejectionError, without being sent.
+type Transport struct {
+	// Base sends the requests. If Base is nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Group holds the CircuitBreakers of the keys. Group is required: without it,
+	// the requests fail with ErrNoGroup without being sent.
+	Group *gobreaker.BreakerGroup
+	// Key returns the key of a request. If Key is nil, HostKey is used,
+	// while e.g. a path or a service name can be used instead.
+	Key KeyFunc
+	// Classify reports whether a request failed. If Classify is nil, DefaultClassifier is used.
+	// The errors of the transport that Classify doesn't fail are ignored, see
+	// gobreaker.CircuitBreaker.ExecuteClassified.
+	Classify Classifier
+}
+
+// NewTransport returns a Transport that sends the requests with base through the CircuitBreakers
+// of the hosts in a BreakerGroup of size, created with settings, see gobreaker.NewBreakerGroup.
+func NewTransport(base http.RoundTripper, size int, settings func(key string) gobreaker.Settings) *Transport {
+	return &Transport{Base: base, Group: gobreaker.NewBreakerGroup(size, settings)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Group == nil {
+		closeBody(req)
+		return nil, ErrNoGroup
+	}
+	key := t.Key
+	if key == nil {
+		key = HostKey
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	classify := t.Classify
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	var resp *http.Response
+	var roundTripErr error
+	err := t.Group.Get(key(req)).ExecuteClassified(req.Context(), func(ctx context.Context) error {
+		resp, roundTripErr = base.RoundTrip(req)
+		if roundTripErr == nil && classify(resp, nil) {
+			return &StatusError{Code: resp.StatusCode}
+		}
+		return roundTripErr
+	}, func(err error) bool {
+		var status *StatusError
+		return errors.As(err, &status) || classify(nil, err)
+	})
+	if gobreaker.IsRejection(err) {
+		closeBody(req)
+		return nil, err
+	}
+	return resp, roundTripErr
+}
+
+// closeBody closes the body of a request that is not sent, as http.RoundTripper requires.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
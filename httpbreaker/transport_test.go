@@ -0,0 +1,186 @@
+package httpbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostKey(t *testing.T) {
+	for _, test := range []struct {
+		url string
+		key string
+	}{
+		{"http://example.com/users", "example.com:80"},
+		{"https://example.com/users", "example.com:443"},
+		{"http://example.com:8080/users", "example.com:8080"},
+		{"https://[::1]/users", "[::1]:443"},
+	} {
+		u, err := url.Parse(test.url)
+		assert.NoError(t, err)
+		assert.Equal(t, test.key, HostKey(&http.Request{URL: u}))
+	}
+}
+
+func statusServer(code int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+	}))
+}
+
+func TestTransport(t *testing.T) {
+	healthy := statusServer(http.StatusOK)
+	defer healthy.Close()
+	failing := statusServer(http.StatusBadGateway)
+	defer failing.Close()
+
+	transport := NewTransport(nil, 0, func(key string) gobreaker.Settings {
+		return gobreaker.Settings{
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		}
+	})
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(failing.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "Bad Gateway", string(body))
+	}
+
+	_, err := client.Get(failing.URL)
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	resp, err := client.Get(healthy.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, 2, transport.Group.Len())
+	assert.Equal(t, gobreaker.StateOpen, transport.Group.Get(strings.TrimPrefix(failing.URL, "http://")).State())
+}
+
+type bodyCloser struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *bodyCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTransportClassify(t *testing.T) {
+	limited := statusServer(http.StatusTooManyRequests)
+	defer limited.Close()
+
+	transport := NewTransport(nil, 0, func(key string) gobreaker.Settings {
+		return gobreaker.Settings{
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}
+	})
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(limited.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	_, err = client.Get("http://127.0.0.1:1/")
+	assert.Error(t, err)
+	assert.Equal(t, gobreaker.StateClosed, transport.Group.Get(strings.TrimPrefix(limited.URL, "http://")).State())
+	assert.Equal(t, gobreaker.StateOpen, transport.Group.Get("127.0.0.1:1").State())
+
+	transport.Classify = FailOnStatuses(http.StatusTooManyRequests)
+	resp, err = client.Get(limited.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, gobreaker.StateOpen, transport.Group.Get(strings.TrimPrefix(limited.URL, "http://")).State())
+}
+
+func TestTransportKey(t *testing.T) {
+	srv := statusServer(http.StatusInternalServerError)
+	defer srv.Close()
+
+	transport := &Transport{
+		Group: gobreaker.NewBreakerGroup(0, func(key string) gobreaker.Settings {
+			return gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+			}
+		}),
+		Key: func(req *http.Request) string { return req.URL.Path },
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(srv.URL + "/reports")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	body := &bodyCloser{Reader: strings.NewReader("report")}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/reports", body)
+	_, err = transport.RoundTrip(req)
+	assert.True(t, gobreaker.IsRejection(err))
+	assert.True(t, body.closed)
+
+	resp, err = client.Get(srv.URL + "/users")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, gobreaker.StateOpen, transport.Group.Get("/users").State())
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportIgnoresCanceled(t *testing.T) {
+	var err error
+	transport := NewTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, err
+	}), 0, func(key string) gobreaker.Settings {
+		return gobreaker.Settings{
+			Timeout:     time.Millisecond,
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}
+	})
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	cb := transport.Group.Get("example.com:80")
+
+	err = errors.New("connection refused")
+	_, rtErr := transport.RoundTrip(req)
+	assert.Equal(t, err, rtErr)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+
+	// a request canceled by its caller doesn't close the half-open breaker
+	err = fmt.Errorf("roundtrip: %w", context.Canceled)
+	_, rtErr = transport.RoundTrip(req)
+	assert.Equal(t, err, rtErr)
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+	assert.Equal(t, gobreaker.Counts{}, cb.Counts())
+}
+
+func TestTransportNoGroup(t *testing.T) {
+	sent := false
+	transport := &Transport{Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return nil, nil
+	})}
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("body"))
+	_, err := transport.RoundTrip(req)
+	assert.Equal(t, ErrNoGroup, err)
+	assert.False(t, sent)
+}
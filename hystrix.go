@@ -0,0 +1,315 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HystrixWindow is the rolling window of the counters reported by HystrixStream,
+// the default metrics.rollingStats.timeInMilliseconds of Hystrix.
+const HystrixWindow = 10 * time.Second
+
+// hystrixLatencies is the number of the latest latencies kept per CircuitBreaker for the percentiles.
+const hystrixLatencies = 1024
+
+// HystrixStream is an http.Handler that streams the metrics of the CircuitBreakers of a Registry
+// as server-sent events in the format of hystrix-metrics-event-stream, so that the Hystrix
+// dashboard and Turbine can visualize them.
+//
+// HystrixStream is also a MetricsSink that counts the successes, the failures and the rejections
+// of the CircuitBreakers over HystrixWindow, and their latencies. It must be set as
+// Settings.Metrics, possibly with MultiMetricsSink, for the counters to be reported;
+// otherwise only the states and the settings of the CircuitBreakers are.
+type HystrixStream struct {
+	registry *Registry
+	interval time.Duration
+	now      func() time.Time
+
+	mutex    sync.Mutex
+	counters map[string]*hystrixCounters
+}
+
+// hystrixBucket holds the counters of a second.
+type hystrixBucket struct {
+	second    int64
+	successes uint64
+	failures  uint64
+	rejects   uint64
+}
+
+type hystrixCounters struct {
+	buckets   [HystrixWindow / time.Second]hystrixBucket
+	latencies []time.Duration // ring buffer of the latest latencies
+	next      int             // index of the next latency in latencies
+}
+
+// NewHystrixStream returns a HystrixStream for the CircuitBreakers in r, including those
+// added to r later, that sends their metrics every interval. If interval is less than or equal
+// to 0, it is 500ms like the stream of Hystrix.
+func NewHystrixStream(r *Registry, interval time.Duration) *HystrixStream {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	return &HystrixStream{
+		registry: r,
+		interval: interval,
+		now:      time.Now,
+		counters: make(map[string]*hystrixCounters),
+	}
+}
+
+// bucket returns the bucket of the current second of the counters of name.
+// It must be called with s.mutex held.
+func (s *HystrixStream) bucket(name string) (*hystrixCounters, *hystrixBucket) {
+	c, ok := s.counters[name]
+	if !ok {
+		c = new(hystrixCounters)
+		s.counters[name] = c
+	}
+	second := s.now().Unix()
+	b := &c.buckets[second%int64(len(c.buckets))]
+	if b.second != second {
+		*b = hystrixBucket{second: second}
+	}
+	return c, b
+}
+
+func (c *hystrixCounters) observe(latency time.Duration) {
+	if len(c.latencies) < hystrixLatencies {
+		c.latencies = append(c.latencies, latency)
+		return
+	}
+	c.latencies[c.next] = latency
+	c.next = (c.next + 1) % hystrixLatencies
+}
+
+// OnRequest implements MetricsSink.
+func (s *HystrixStream) OnRequest(name string) {}
+
+// OnSuccess implements MetricsSink.
+func (s *HystrixStream) OnSuccess(name string, latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, b := s.bucket(name)
+	b.successes++
+	c.observe(latency)
+}
+
+// OnFailure implements MetricsSink.
+func (s *HystrixStream) OnFailure(name string, latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, b := s.bucket(name)
+	b.failures++
+	c.observe(latency)
+}
+
+// OnReject implements MetricsSink.
+func (s *HystrixStream) OnReject(name string, state State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, b := s.bucket(name)
+	b.rejects++
+}
+
+// OnStateChange implements MetricsSink.
+func (s *HystrixStream) OnStateChange(name string, from State, to State, elapsed time.Duration) {}
+
+// hystrixCommand is a HystrixCommand event of hystrix-metrics-event-stream.
+type hystrixCommand struct {
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	Group          string `json:"group"`
+	CurrentTime    int64  `json:"currentTime"`
+	ReportingHosts int    `json:"reportingHosts"`
+
+	IsCircuitBreakerOpen bool   `json:"isCircuitBreakerOpen"`
+	ErrorPercentage      int    `json:"errorPercentage"`
+	ErrorCount           uint64 `json:"errorCount"`
+	RequestCount         uint64 `json:"requestCount"`
+
+	RollingCountSuccess            uint64 `json:"rollingCountSuccess"`
+	RollingCountFailure            uint64 `json:"rollingCountFailure"`
+	RollingCountShortCircuited     uint64 `json:"rollingCountShortCircuited"`
+	RollingCountTimeout            uint64 `json:"rollingCountTimeout"`
+	RollingCountSemaphoreRejected  uint64 `json:"rollingCountSemaphoreRejected"`
+	RollingCountThreadPoolRejected uint64 `json:"rollingCountThreadPoolRejected"`
+	RollingCountBadRequests        uint64 `json:"rollingCountBadRequests"`
+	RollingCountExceptionsThrown   uint64 `json:"rollingCountExceptionsThrown"`
+	RollingCountFallbackSuccess    uint64 `json:"rollingCountFallbackSuccess"`
+	RollingCountFallbackFailure    uint64 `json:"rollingCountFallbackFailure"`
+	RollingCountFallbackRejection  uint64 `json:"rollingCountFallbackRejection"`
+	RollingCountResponsesFromCache uint64 `json:"rollingCountResponsesFromCache"`
+	RollingCountCollapsedRequests  uint64 `json:"rollingCountCollapsedRequests"`
+
+	CurrentConcurrentExecutionCount int `json:"currentConcurrentExecutionCount"`
+
+	LatencyExecuteMean int64            `json:"latencyExecute_mean"`
+	LatencyExecute     map[string]int64 `json:"latencyExecute"`
+	LatencyTotalMean   int64            `json:"latencyTotal_mean"`
+	LatencyTotal       map[string]int64 `json:"latencyTotal"`
+
+	RequestVolumeThreshold            uint32 `json:"propertyValue_circuitBreakerRequestVolumeThreshold"`
+	SleepWindowInMilliseconds         int64  `json:"propertyValue_circuitBreakerSleepWindowInMilliseconds"`
+	ErrorThresholdPercentage          int    `json:"propertyValue_circuitBreakerErrorThresholdPercentage"`
+	ForceOpen                         bool   `json:"propertyValue_circuitBreakerForceOpen"`
+	ForceClosed                       bool   `json:"propertyValue_circuitBreakerForceClosed"`
+	Enabled                           bool   `json:"propertyValue_circuitBreakerEnabled"`
+	IsolationStrategy                 string `json:"propertyValue_executionIsolationStrategy"`
+	IsolationTimeoutInMilliseconds    int64  `json:"propertyValue_executionIsolationThreadTimeoutInMilliseconds"`
+	SemaphoreMaxConcurrentRequests    int    `json:"propertyValue_executionIsolationSemaphoreMaxConcurrentRequests"`
+	RollingStatisticalWindowInMillis  int64  `json:"propertyValue_metricsRollingStatisticalWindowInMilliseconds"`
+	RequestCacheEnabled               bool   `json:"propertyValue_requestCacheEnabled"`
+	RequestLogEnabled                 bool   `json:"propertyValue_requestLogEnabled"`
+	FallbackIsolationSemaphoreMaxReqs int    `json:"propertyValue_fallbackIsolationSemaphoreMaxConcurrentRequests"`
+}
+
+// hystrixPercentiles are the latency percentiles of hystrix-metrics-event-stream.
+var hystrixPercentiles = []struct {
+	key string
+	p   float64
+}{
+	{"0", 0}, {"25", 25}, {"50", 50}, {"75", 75}, {"90", 90},
+	{"95", 95}, {"99", 99}, {"99.5", 99.5}, {"100", 100},
+}
+
+// hystrixStatus is what HystrixStream reads from a CircuitBreaker.
+type hystrixStatus struct {
+	state           State
+	inflight        int
+	timeout         time.Duration
+	minimumRequests uint32
+	maxConcurrent   int
+}
+
+func (cb *CircuitBreaker) hystrixStatus() hystrixStatus {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, _ := cb.currentState(cb.clock.Now())
+	return hystrixStatus{
+		state:           state,
+		inflight:        cb.inflightCount(),
+		timeout:         cb.timeout,
+		minimumRequests: cb.minimumRequests,
+		maxConcurrent:   cb.maxConcurrent,
+	}
+}
+
+// command returns the HystrixCommand event of cb.
+func (s *HystrixStream) command(cb *CircuitBreaker) hystrixCommand {
+	st := cb.hystrixStatus()
+	now := s.now()
+	e := hystrixCommand{
+		Type:           "HystrixCommand",
+		Name:           cb.name,
+		Group:          cb.name,
+		CurrentTime:    now.UnixNano() / int64(time.Millisecond),
+		ReportingHosts: 1,
+
+		IsCircuitBreakerOpen:            st.state == StateOpen || st.state == StateForcedOpen,
+		CurrentConcurrentExecutionCount: st.inflight,
+
+		RequestVolumeThreshold:           st.minimumRequests,
+		SleepWindowInMilliseconds:        int64(st.timeout / time.Millisecond),
+		ForceOpen:                        st.state == StateForcedOpen,
+		ForceClosed:                      st.state == StateForcedClosed,
+		Enabled:                          st.state != StateDisabled,
+		IsolationStrategy:                "SEMAPHORE",
+		SemaphoreMaxConcurrentRequests:   st.maxConcurrent,
+		RollingStatisticalWindowInMillis: int64(HystrixWindow / time.Millisecond),
+	}
+
+	var latencies []time.Duration
+	s.mutex.Lock()
+	if c, ok := s.counters[cb.name]; ok {
+		oldest := now.Unix() - int64(len(c.buckets)) + 1
+		for _, b := range c.buckets {
+			if b.second >= oldest {
+				e.RollingCountSuccess += b.successes
+				e.RollingCountFailure += b.failures
+				e.RollingCountShortCircuited += b.rejects
+			}
+		}
+		latencies = append(latencies, c.latencies...)
+	}
+	s.mutex.Unlock()
+
+	// like the health counts of Hystrix, the short-circuited requests are not counted
+	e.ErrorCount = e.RollingCountFailure
+	e.RequestCount = e.RollingCountSuccess + e.RollingCountFailure
+	if e.RequestCount > 0 {
+		e.ErrorPercentage = int(e.ErrorCount * 100 / e.RequestCount)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	e.LatencyExecute = make(map[string]int64, len(hystrixPercentiles))
+	for _, p := range hystrixPercentiles {
+		e.LatencyExecute[p.key] = millis(percentile(latencies, p.p))
+	}
+	if len(latencies) > 0 {
+		var sum time.Duration
+		for _, l := range latencies {
+			sum += l
+		}
+		e.LatencyExecuteMean = millis(sum / time.Duration(len(latencies)))
+	}
+	e.LatencyTotal = e.LatencyExecute
+	e.LatencyTotalMean = e.LatencyExecuteMean
+	return e
+}
+
+// percentile returns the p-th percentile of sorted, or 0 if it is empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+func millis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// ServeHTTP implements http.Handler. It streams the events until the client disconnects.
+func (s *HystrixStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		for _, cb := range s.registry.sorted() {
+			data, err := json.Marshal(s.command(cb))
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
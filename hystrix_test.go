@@ -0,0 +1,91 @@
+package gobreaker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHystrixStream(t *testing.T) {
+	r := NewRegistry()
+	stream := NewHystrixStream(r, 10*time.Millisecond)
+	now := time.Unix(1000, 0)
+	stream.now = func() time.Time { return now }
+
+	sink := new(RecordingMetricsSink)
+	cb := r.GetOrCreate("payments", Settings{Metrics: MultiMetricsSink(stream, sink), MinimumRequests: 5})
+	assert.Nil(t, succeed(cb))
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+	assert.Equal(t, 7, sink.Requests())
+	assert.Equal(t, 1, sink.Rejects(StateOpen))
+	assert.Len(t, sink.StateChanges(), 1)
+
+	e := stream.command(cb)
+	assert.Equal(t, "HystrixCommand", e.Type)
+	assert.Equal(t, "payments", e.Name)
+	assert.True(t, e.IsCircuitBreakerOpen)
+	assert.Equal(t, uint64(1), e.RollingCountSuccess)
+	assert.Equal(t, uint64(6), e.RollingCountFailure)
+	assert.Equal(t, uint64(1), e.RollingCountShortCircuited)
+	assert.Equal(t, uint64(7), e.RequestCount)
+	assert.Equal(t, 85, e.ErrorPercentage)
+	assert.Equal(t, uint32(5), e.RequestVolumeThreshold)
+	assert.Equal(t, int64(60000), e.SleepWindowInMilliseconds)
+	assert.Len(t, e.LatencyExecute, len(hystrixPercentiles))
+
+	// the counters roll out of the window
+	now = now.Add(HystrixWindow)
+	e = stream.command(cb)
+	assert.Equal(t, uint64(0), e.RequestCount)
+	assert.Equal(t, 0, e.ErrorPercentage)
+}
+
+func TestHystrixStreamHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.GetOrCreate("a", Settings{})
+	r.GetOrCreate("b", Settings{})
+	server := httptest.NewServer(NewHystrixStream(r, time.Millisecond))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	var names []string
+	for len(names) < 4 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+		assert.Equal(t, false, e["isCircuitBreakerOpen"])
+		names = append(names, e["name"].(string))
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, names)
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+	sorted := []time.Duration{1, 2, 3, 4, 5}
+	assert.Equal(t, time.Duration(1), percentile(sorted, 0))
+	assert.Equal(t, time.Duration(3), percentile(sorted, 50))
+	assert.Equal(t, time.Duration(5), percentile(sorted, 100))
+}
@@ -0,0 +1,60 @@
+package gobreaker
+
+import "context"
+
+// ExecuteIdempotent is like Execute, but tags the request with an idempotency key.
+// If the request is a half-open probe and fails, the CircuitBreaker re-issues it once more
+// for the same key before counting the failure, so that a single transient error during
+// probation doesn't reopen the CircuitBreaker. The re-issued request doesn't count towards
+// MaxRequests. Each key is re-issued at most once per half-open period.
+// An empty key disables the re-issue.
+func (cb *CircuitBreaker) ExecuteIdempotent(key string, req func() (interface{}, error)) (result interface{}, err error) {
+	ctx := context.Background()
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := recover()
+		if e != nil {
+			outcome, f, perr := cb.recoverPanic(e)
+			cb.finishRequest(ctx, generation, start, outcome, f)
+			if perr == nil {
+				panic(e)
+			}
+			result, err = nil, perr
+		}
+	}()
+
+	result, err = req()
+	outcome := cb.classify(result, err)
+	if outcome == OutcomeFailure && key != "" && cb.allowReprobe(generation, key) {
+		result, err = req()
+		outcome = cb.classify(result, err)
+	}
+
+	cb.finishRequest(ctx, generation, start, outcome, cb.failureOf(outcome, err))
+	return result, err
+}
+
+// allowReprobe reports whether a failed half-open probe of the given generation
+// may be re-issued for key, and marks key as re-issued if so.
+func (cb *CircuitBreaker) allowReprobe(before uint64, key string) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, generation := cb.currentState(cb.clock.Now())
+	if state != StateHalfOpen || generation != before {
+		return false
+	}
+	if _, ok := cb.reprobed[key]; ok {
+		return false
+	}
+
+	if cb.reprobed == nil {
+		cb.reprobed = make(map[string]struct{})
+	}
+	cb.reprobed[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,86 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHalfOpenCB(t *testing.T, st Settings) *CircuitBreaker {
+	cb := NewCircuitBreaker(st)
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	pseudoSleep(cb, time.Duration(60)*time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	return cb
+}
+
+func flaky(failures int) (func() (interface{}, error), *int) {
+	calls := 0
+	return func() (interface{}, error) {
+		calls++
+		if calls <= failures {
+			return nil, errors.New("blip")
+		}
+		return "ok", nil
+	}, &calls
+}
+
+func TestExecuteIdempotentReprobe(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+
+	req, calls := flaky(1)
+	result, err := cb.ExecuteIdempotent("k", req)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestExecuteIdempotentGivesUp(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+
+	req, calls := flaky(2)
+	_, err := cb.ExecuteIdempotent("k", req)
+	assert.EqualError(t, err, "blip")
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestExecuteIdempotentOncePerKey(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{MaxRequests: 3})
+
+	req, calls := flaky(1)
+	_, err := cb.ExecuteIdempotent("k", req)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}, cb.Counts())
+
+	// the same key is not re-issued twice in the same half-open period
+	req, calls = flaky(1)
+	_, err = cb.ExecuteIdempotent("k", req)
+	assert.EqualError(t, err, "blip")
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestExecuteIdempotentClosed(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+
+	req, calls := flaky(1)
+	_, err := cb.ExecuteIdempotent("k", req)
+	assert.EqualError(t, err, "blip")
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+
+	req, calls = flaky(1)
+	cb = newHalfOpenCB(t, Settings{})
+	_, err = cb.ExecuteIdempotent("", req)
+	assert.EqualError(t, err, "blip")
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, StateOpen, cb.State())
+}
@@ -0,0 +1,75 @@
+package gobreaker
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// InFlight returns the number of requests admitted by the CircuitBreaker and not finished yet,
+// across state changes: a request admitted in the closed state is in flight until it finishes,
+// even if the CircuitBreaker opened meanwhile.
+func (cb *CircuitBreaker) InFlight() int {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.inflightCount()
+}
+
+// InFlight returns the number of requests allowed by the TwoStepCircuitBreaker
+// whose callback hasn't been called yet.
+func (tscb *TwoStepCircuitBreaker) InFlight() int {
+	return tscb.cb.InFlight()
+}
+
+// finishInflight releases a request in flight, and wakes Drain if it was the last one.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) finishInflight() {
+	cb.inflight--
+	cb.wakeDrain()
+}
+
+// wakeDrain wakes Drain if no request is in flight.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) wakeDrain() {
+	if cb.drained != nil && cb.inflightCount() <= 0 {
+		close(cb.drained)
+		cb.drained = nil
+		atomic.StoreInt32(&cb.fast.draining, 0)
+	}
+}
+
+// Drain places the CircuitBreaker into StateForcedOpen, so that it rejects new requests,
+// and waits until the requests in flight finish, e.g. before a maintenance of the dependency.
+// Drain returns the error of ctx if ctx is done first; the CircuitBreaker stays forced open either way.
+func (cb *CircuitBreaker) Drain(ctx context.Context) error {
+	cb.ForceOpen()
+
+	cb.mutex.Lock()
+	for cb.inflightCount() > 0 {
+		if cb.drained == nil {
+			cb.drained = make(chan struct{})
+			// The requests counted in the stripes finish without the mutex and check draining
+			// after leaving, so the requests in flight are counted again once it is set.
+			atomic.StoreInt32(&cb.fast.draining, 1)
+			continue
+		}
+		drained := cb.drained
+		cb.mutex.Unlock()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		cb.mutex.Lock()
+	}
+	cb.wakeDrain()
+	cb.mutex.Unlock()
+	return nil
+}
+
+// Drain places the TwoStepCircuitBreaker into StateForcedOpen and waits until
+// the callbacks of the allowed requests are called, see CircuitBreaker.Drain.
+func (tscb *TwoStepCircuitBreaker) Drain(ctx context.Context) error {
+	return tscb.cb.Drain(ctx)
+}
@@ -0,0 +1,56 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+	assert.Equal(t, 0, tscb.InFlight())
+
+	done1, err := tscb.Allow()
+	assert.Nil(t, err)
+	done2, err := tscb.Allow()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, tscb.InFlight())
+
+	// the requests stay in flight across the state change
+	tscb.Trip()
+	assert.Equal(t, 2, tscb.InFlight())
+	done1(true)
+	assert.Equal(t, 1, tscb.InFlight())
+	done2(false)
+	assert.Equal(t, 0, tscb.InFlight())
+}
+
+func TestDrain(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+	assert.Nil(t, tscb.Drain(context.Background()))
+	assert.Equal(t, StateForcedOpen, tscb.State())
+
+	tscb = NewTwoStepCircuitBreaker(Settings{})
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, tscb.Drain(ctx))
+	assert.Equal(t, StateForcedOpen, tscb.State())
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrOpenState))
+
+	drained := make(chan error)
+	go func() { drained <- tscb.Drain(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("drained with a request in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	done(true)
+	assert.Nil(t, <-drained)
+}
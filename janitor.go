@@ -0,0 +1,122 @@
+package gobreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// JanitorPolicy configures the janitor of a Registry or a BreakerGroup,
+// which removes the CircuitBreakers that have been idle for a while,
+// e.g. the CircuitBreakers of the hosts a gateway no longer proxies to.
+//
+// Idle is the time since the latest request after which a CircuitBreaker is removed.
+// A CircuitBreaker that has never run a request is idle since its creation.
+// If Idle is less than or equal to 0, DefaultJanitorIdle is used.
+//
+// Interval is the period between the sweeps of the janitor.
+// If Interval is less than or equal to 0, Idle is used.
+//
+// OnEvict is called with the name or key and the CircuitBreaker that the janitor removed.
+//
+// Clock provides the timers of the sweeps, usually the Clock of the CircuitBreakers.
+// If Clock is nil or doesn't implement TimerClock, SystemClock is used.
+type JanitorPolicy struct {
+	Idle     time.Duration
+	Interval time.Duration
+	OnEvict  func(key string, cb *CircuitBreaker)
+	Clock    Clock
+}
+
+// DefaultJanitorIdle is the Idle of a JanitorPolicy whose Idle is less than or equal to 0.
+const DefaultJanitorIdle = 10 * time.Minute
+
+// idle reports whether the CircuitBreaker has run no request for d.
+func (cb *CircuitBreaker) idle(d time.Duration) bool {
+	return cb.clock.Now().Sub(cb.lastUsed()) >= d
+}
+
+// startJanitor calls sweep every Interval of p until the returned function is called.
+func startJanitor(p JanitorPolicy, sweep func(p JanitorPolicy)) (stop func()) {
+	if p.Idle <= 0 {
+		p.Idle = DefaultJanitorIdle
+	}
+	interval := p.Interval
+	if interval <= 0 {
+		interval = p.Idle
+	}
+	clock, ok := p.Clock.(TimerClock)
+	if !ok {
+		clock = SystemClock
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		for {
+			timer := clock.NewTimer(interval)
+			select {
+			case <-timer.C():
+				sweep(p)
+			case <-done:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// StartJanitor starts removing the CircuitBreakers of the Registry that are idle according to p.
+// It returns a function to stop the janitor.
+func (r *Registry) StartJanitor(p JanitorPolicy) (stop func()) {
+	return startJanitor(p, r.removeIdle)
+}
+
+func (r *Registry) removeIdle(p JanitorPolicy) {
+	for _, cb := range r.sorted() {
+		if !cb.idle(p.Idle) {
+			continue
+		}
+
+		r.mutex.Lock()
+		evict := r.breakers[cb.Name()] == cb
+		if evict {
+			delete(r.breakers, cb.Name())
+		}
+		r.mutex.Unlock()
+
+		if evict && p.OnEvict != nil {
+			p.OnEvict(cb.Name(), cb)
+		}
+	}
+}
+
+// StartJanitor starts removing the CircuitBreakers of the BreakerGroup that are idle according to p.
+// It returns a function to stop the janitor.
+func (g *BreakerGroup) StartJanitor(p JanitorPolicy) (stop func()) {
+	return startJanitor(p, g.removeIdle)
+}
+
+func (g *BreakerGroup) removeIdle(p JanitorPolicy) {
+	g.Range(func(key string, cb *CircuitBreaker) bool {
+		if !cb.idle(p.Idle) {
+			return true
+		}
+
+		g.mutex.Lock()
+		e, evict := g.entries[key]
+		evict = evict && e.Value.(*groupEntry).cb == cb
+		if evict {
+			g.remove(e)
+		}
+		g.mutex.Unlock()
+
+		if evict && p.OnEvict != nil {
+			p.OnEvict(key, cb)
+		}
+		return true
+	})
+}
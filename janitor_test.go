@@ -0,0 +1,90 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryJanitor(t *testing.T) {
+	clock := newFakeClock()
+	r := NewRegistry()
+	a := r.GetOrCreate("a", Settings{Clock: clock})
+	r.GetOrCreate("b", Settings{Clock: clock})
+
+	var evicted []string
+	p := JanitorPolicy{
+		Idle:    time.Minute,
+		OnEvict: func(key string, cb *CircuitBreaker) { evicted = append(evicted, key) },
+	}
+
+	clock.Advance(30 * time.Second)
+	assert.Nil(t, succeed(a))
+	r.removeIdle(p)
+	assert.Equal(t, 2, r.Len())
+
+	clock.Advance(30 * time.Second)
+	r.removeIdle(p)
+	assert.Equal(t, []string{"b"}, evicted)
+	_, ok := r.Get("a")
+	assert.True(t, ok)
+
+	clock.Advance(30 * time.Second)
+	r.removeIdle(p)
+	assert.Equal(t, []string{"b", "a"}, evicted)
+	assert.Equal(t, 0, r.Len())
+}
+
+func TestBreakerGroupJanitor(t *testing.T) {
+	clock := newFakeClock()
+	g := NewBreakerGroup(0, func(key string) Settings { return Settings{Clock: clock} })
+	g.Get("a")
+	g.Get("b")
+
+	evicted := make(chan string, 2)
+	stop := g.StartJanitor(JanitorPolicy{
+		Idle:    time.Minute,
+		OnEvict: func(key string, cb *CircuitBreaker) { evicted <- key },
+		Clock:   clock,
+	})
+	defer stop()
+
+	_, err := g.Execute("a", func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+	g.Get("c")
+
+	keys := []string{<-evicted, <-evicted}
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+	assert.Equal(t, []string{"c"}, groupKeys(g))
+
+	stop()
+	stop()
+}
+
+func TestJanitorDefaults(t *testing.T) {
+	clock := newFakeClock()
+	r := NewRegistry()
+	r.GetOrCreate("a", Settings{Clock: clock})
+
+	evicted := make(chan string, 1)
+	stop := r.StartJanitor(JanitorPolicy{
+		OnEvict: func(key string, cb *CircuitBreaker) { evicted <- key },
+		Clock:   clock,
+	})
+	defer stop()
+
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(DefaultJanitorIdle - time.Second)
+	assert.Equal(t, 1, r.Len())
+	clock.Advance(time.Second)
+	assert.Equal(t, "a", <-evicted)
+
+	(&Registry{}).StartJanitor(JanitorPolicy{})()
+}
@@ -0,0 +1,19 @@
+module github.com/sony/gobreaker/kgobreaker
+
+go 1.21
+
+require (
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+	github.com/twmb/franz-go v1.17.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/klauspost/compress v1.17.8 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/twmb/franz-go/pkg/kmsg v1.8.0 // indirect
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,93 @@
+// Package kgobreaker guards the Kafka producers of franz-go with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on franz-go.
+package kgobreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sony/gobreaker"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Classify classifies the error of a franz-go producer for Settings.Classify.
+// The non-retriable Kafka errors other than kerr.UnknownServerError are caused by a record,
+// e.g. kerr.MessageTooLarge, and count as successes. context.Canceled is ignored,
+// as the caller gave up on the record. The other errors, e.g. the retriable Kafka errors
+// or kgo.ErrRecordTimeout, count as failures.
+func Classify(err error) gobreaker.Outcome {
+	if errors.Is(err, context.Canceled) {
+		return gobreaker.OutcomeIgnore
+	}
+	var ke *kerr.Error
+	if errors.As(err, &ke) && !ke.Retriable && ke != kerr.UnknownServerError {
+		return gobreaker.OutcomeSuccess
+	}
+	return gobreaker.OutcomeFailure
+}
+
+// Client is the producing part of *kgo.Client.
+type Client interface {
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+}
+
+// OverflowFunc handles a record that a Producer didn't produce because its breaker rejected it,
+// e.g. by writing it to a local spool to be produced later. err is the rejection.
+// The error returned by OverflowFunc is the error of the record instead of err.
+type OverflowFunc func(ctx context.Context, r *kgo.Record, err error) error
+
+// Producer produces records with a Client through a Breaker.
+type Producer struct {
+	client   Client
+	cb       gobreaker.Breaker
+	overflow OverflowFunc
+}
+
+// NewProducer returns a Producer that produces the records with client through cb.
+// cb should be created with Classify as Settings.Classify, so that invalid records don't trip it.
+// While cb rejects the records, they are passed to overflow, if not nil, and fail fast otherwise,
+// so that the producers don't block on unavailable brokers.
+func NewProducer(client Client, cb gobreaker.Breaker, overflow OverflowFunc) *Producer {
+	return &Producer{client: client, cb: cb, overflow: overflow}
+}
+
+// ProduceSync produces rs through the Breaker as a single request, like kgo.Client.ProduceSync.
+// The request fails if any record fails, with the first error that Classify counts as a failure.
+func (p *Producer) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	var results kgo.ProduceResults
+	produce := func(ctx context.Context) (interface{}, error) {
+		results = p.client.ProduceSync(ctx, rs...)
+		return nil, firstErr(results)
+	}
+
+	var err error
+	if c, ok := p.cb.(gobreaker.ContextBreaker); ok {
+		_, err = c.ExecuteContext(ctx, produce)
+	} else {
+		_, err = p.cb.Execute(func() (interface{}, error) { return produce(ctx) })
+	}
+	if !gobreaker.IsRejection(err) {
+		return results
+	}
+
+	results = make(kgo.ProduceResults, len(rs))
+	for i, r := range rs {
+		results[i] = kgo.ProduceResult{Record: r, Err: err}
+		if p.overflow != nil {
+			results[i].Err = p.overflow(ctx, r, err)
+		}
+	}
+	return results
+}
+
+// firstErr returns the first error of results that counts as a failure, or their first error.
+func firstErr(results kgo.ProduceResults) error {
+	for _, r := range results {
+		if r.Err != nil && Classify(r.Err) == gobreaker.OutcomeFailure {
+			return r.Err
+		}
+	}
+	return results.FirstErr()
+}
@@ -0,0 +1,98 @@
+package kgobreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		outcome gobreaker.Outcome
+	}{
+		{kerr.NotLeaderForPartition, gobreaker.OutcomeFailure},
+		{kerr.BrokerNotAvailable, gobreaker.OutcomeFailure},
+		{kerr.UnknownServerError, gobreaker.OutcomeFailure},
+		{kgo.ErrRecordTimeout, gobreaker.OutcomeFailure},
+		{kerr.MessageTooLarge, gobreaker.OutcomeSuccess},
+		{fmt.Errorf("produce: %w", kerr.TopicAuthorizationFailed), gobreaker.OutcomeSuccess},
+		{context.Canceled, gobreaker.OutcomeIgnore},
+	} {
+		assert.Equal(t, test.outcome, Classify(test.err), test.err.Error())
+	}
+}
+
+// fakeClient fails the records whose value is in errs.
+type fakeClient struct {
+	errs     map[string]error
+	produced []*kgo.Record
+}
+
+func (c *fakeClient) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	var results kgo.ProduceResults
+	for _, r := range rs {
+		err := c.errs[string(r.Value)]
+		if err == nil {
+			c.produced = append(c.produced, r)
+		}
+		results = append(results, kgo.ProduceResult{Record: r, Err: err})
+	}
+	return results
+}
+
+func TestProducer(t *testing.T) {
+	client := &fakeClient{errs: map[string]error{
+		"large": kerr.MessageTooLarge,
+		"down":  kerr.BrokerNotAvailable,
+	}}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Classify: Classify})
+	var overflow []*kgo.Record
+	p := NewProducer(client, cb, func(ctx context.Context, r *kgo.Record, err error) error {
+		assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+		overflow = append(overflow, r)
+		return nil
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, p.ProduceSync(ctx, kgo.StringRecord("ok")).FirstErr())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, kerr.MessageTooLarge, p.ProduceSync(ctx, kgo.StringRecord("large")).FirstErr())
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	for i := 0; i < 6; i++ {
+		results := p.ProduceSync(ctx, kgo.StringRecord("large"), kgo.StringRecord("down"))
+		assert.Equal(t, kerr.MessageTooLarge, results.FirstErr())
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	results := p.ProduceSync(ctx, kgo.StringRecord("ok"), kgo.StringRecord("ok"))
+	assert.Len(t, results, 2)
+	assert.NoError(t, results.FirstErr())
+	assert.Len(t, overflow, 2)
+	assert.Len(t, client.produced, 1)
+}
+
+func TestProducerWithoutOverflow(t *testing.T) {
+	client := &fakeClient{errs: map[string]error{"down": kgo.ErrRecordTimeout}}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	p := NewProducer(client, cb, nil)
+	ctx := context.Background()
+
+	assert.Equal(t, kgo.ErrRecordTimeout, p.ProduceSync(ctx, kgo.StringRecord("down")).FirstErr())
+
+	results := p.ProduceSync(ctx, kgo.StringRecord("a"), kgo.StringRecord("b"))
+	assert.Len(t, results, 2)
+	assert.Equal(t, "b", string(results[1].Record.Value))
+	assert.True(t, errors.Is(results[1].Err, gobreaker.ErrOpenState))
+	assert.Empty(t, client.produced)
+}
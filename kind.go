@@ -0,0 +1,94 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// FailureKind is the category of a failure, counted in Counts.FailuresByKind.
+type FailureKind int
+
+const (
+	// FailureOther is an application error, e.g. a 5xx response.
+	FailureOther FailureKind = iota
+	// FailureTimeout is a timed out request.
+	FailureTimeout
+	// FailureConnection is a network error other than a timeout, e.g. a refused connection.
+	FailureConnection
+	// FailurePanic is a request that panicked.
+	FailurePanic
+
+	numFailureKinds
+)
+
+// String implements stringer interface.
+func (k FailureKind) String() string {
+	switch k {
+	case FailureOther:
+		return "other"
+	case FailureTimeout:
+		return "timeout"
+	case FailureConnection:
+		return "connection"
+	case FailurePanic:
+		return "panic"
+	default:
+		return fmt.Sprintf("unknown failure kind: %d", k)
+	}
+}
+
+// FailureCounts holds the numbers of failures indexed by FailureKind.
+type FailureCounts [numFailureKinds]uint32
+
+// DefaultFailureKind returns FailurePanic for a *PanicError, FailureTimeout for
+// context.DeadlineExceeded and the net.Error timeouts, FailureConnection for the other
+// net.Errors, and FailureOther otherwise.
+func DefaultFailureKind(err error) FailureKind {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return FailurePanic
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureConnection
+	}
+	return FailureOther
+}
+
+// failure describes a request counted as a failure.
+type failure struct {
+	kind   FailureKind
+	weight uint32
+	err    error // error of the request, if any
+}
+
+var (
+	// plainFailure is a failure without an error, e.g. reported by a bool callback.
+	plainFailure = failure{kind: FailureOther, weight: 1}
+	panicFailure = failure{kind: FailurePanic, weight: 1}
+)
+
+// failureOf describes a request that returned err if outcome is a failure.
+func (cb *CircuitBreaker) failureOf(outcome Outcome, err error) failure {
+	if outcome != OutcomeFailure {
+		return failure{}
+	}
+	f := failure{kind: cb.failureKind(err), weight: 1, err: err}
+	if f.kind < 0 || f.kind >= numFailureKinds {
+		f.kind = FailureOther
+	}
+	if cb.weigher != nil {
+		if w := cb.weigher(err); w > 0 {
+			f.weight = w
+		}
+	}
+	return f
+}
@@ -0,0 +1,69 @@
+package gobreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestFailureKindString(t *testing.T) {
+	assert.Equal(t, "other", FailureOther.String())
+	assert.Equal(t, "timeout", FailureTimeout.String())
+	assert.Equal(t, "connection", FailureConnection.String())
+	assert.Equal(t, "panic", FailurePanic.String())
+	assert.Equal(t, "unknown failure kind: 4", FailureKind(4).String())
+}
+
+func TestDefaultFailureKind(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	assert.Equal(t, FailureOther, DefaultFailureKind(errors.New("500")))
+	assert.Equal(t, FailureTimeout, DefaultFailureKind(context.DeadlineExceeded))
+	assert.Equal(t, FailureTimeout, DefaultFailureKind(fmt.Errorf("wrapped: %w", timeoutError{})))
+	assert.Equal(t, FailureConnection, DefaultFailureKind(refused))
+}
+
+func TestFailuresByKind(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.FailuresByKind[FailureTimeout] >= 2 },
+	})
+
+	timeout := func() (interface{}, error) { return nil, timeoutError{} }
+	_, err := cb.Execute(timeout)
+	assert.Equal(t, timeoutError{}, err)
+	assert.Nil(t, fail(cb))
+	assert.Panics(t, func() { causePanic(cb) })
+	assert.Equal(t, FailureCounts{1, 1, 0, 1}, cb.Counts().FailuresByKind)
+
+	snapshot := cb.Snapshot()
+	data, err := json.Marshal(snapshot)
+	assert.Nil(t, err)
+	var s Snapshot
+	assert.Nil(t, json.Unmarshal(data, &s))
+	assert.Equal(t, snapshot.Counts, s.Counts)
+
+	// snapshots without kinds count every failure as FailureOther
+	assert.Nil(t, json.Unmarshal([]byte(`{"counts":{"totalFailures":3}}`), &s))
+	assert.Equal(t, FailureCounts{3}, s.Counts.FailuresByKind)
+
+	_, err = cb.Execute(timeout)
+	assert.Equal(t, timeoutError{}, err)
+	assert.Equal(t, StateOpen, cb.State())
+
+	cb = NewCircuitBreaker(Settings{
+		FailureKindOf: func(err error) FailureKind { return FailureConnection },
+	})
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, FailureCounts{FailureConnection: 1}, cb.Counts().FailuresByKind)
+}
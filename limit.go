@@ -0,0 +1,155 @@
+package gobreaker
+
+import (
+	"math"
+	"time"
+)
+
+// Limit is an adaptive concurrency limit, which adjusts the number of requests the
+// CircuitBreaker lets in flight in the closed state to the observed latency,
+// like the Netflix concurrency-limits library. The requests beyond the limit are
+// rejected with ErrTooManyConcurrent.
+//
+// The CircuitBreaker calls a Limit with its internal lock held,
+// so a Limit must not be shared among CircuitBreakers.
+type Limit interface {
+	// Limit returns the current limit.
+	Limit() int
+	// Update is called when a request completes, with its latency, the number of
+	// requests in flight when it completed, including itself, and whether it failed.
+	Update(latency time.Duration, inflight int, failed bool)
+}
+
+// AIMDPolicy configures an AIMD Limit, which increases the limit by 1 after a success while
+// the limit is being used, and multiplies it by Backoff after a failure or a slow request.
+//
+// Initial, Min and Max bound the limit. They default to 20, 1 and 200.
+// Backoff is the factor between 0 and 1 applied on a failure. It defaults to 0.9.
+// Timeout is the latency beyond which a request is handled like a failure.
+// If Timeout is less than or equal to 0, only failures decrease the limit.
+type AIMDPolicy struct {
+	Initial int
+	Min     int
+	Max     int
+	Backoff float64
+	Timeout time.Duration
+}
+
+type aimdLimit struct {
+	policy AIMDPolicy
+	limit  int
+}
+
+// NewAIMDLimit returns a new AIMD Limit configured with p.
+func NewAIMDLimit(p AIMDPolicy) Limit {
+	p.Min, p.Max, p.Initial = limitBounds(p.Min, p.Max, p.Initial)
+	if p.Backoff <= 0 || p.Backoff >= 1 {
+		p.Backoff = 0.9
+	}
+	return &aimdLimit{policy: p, limit: p.Initial}
+}
+
+func (l *aimdLimit) Limit() int {
+	return l.limit
+}
+
+func (l *aimdLimit) Update(latency time.Duration, inflight int, failed bool) {
+	if failed || (l.policy.Timeout > 0 && latency > l.policy.Timeout) {
+		l.limit = int(float64(l.limit) * l.policy.Backoff)
+		if l.limit < l.policy.Min {
+			l.limit = l.policy.Min
+		}
+	} else if inflight*2 >= l.limit && l.limit < l.policy.Max {
+		l.limit++
+	}
+}
+
+// GradientPolicy configures a gradient Limit, which compares the latency of recent requests
+// with a long-term average and shrinks the limit as the requests queue up and slow down.
+// A failure shrinks the limit like a request twice as slow as usual.
+//
+// Initial, Min and Max bound the limit. They default to 20, 1 and 200.
+// Smoothing is the weight between 0 and 1 of a new limit estimate. It defaults to 0.2.
+// Window is the number of requests over which the long-term latency is averaged. It defaults to 600.
+type GradientPolicy struct {
+	Initial   int
+	Min       int
+	Max       int
+	Smoothing float64
+	Window    int
+}
+
+type gradientLimit struct {
+	policy   GradientPolicy
+	limit    float64
+	longRTT  float64 // moving average of the latency over Window requests
+	shortRTT float64 // moving average of the latency over the last few requests
+}
+
+// NewGradientLimit returns a new gradient Limit configured with p.
+func NewGradientLimit(p GradientPolicy) Limit {
+	p.Min, p.Max, p.Initial = limitBounds(p.Min, p.Max, p.Initial)
+	if p.Smoothing <= 0 || p.Smoothing > 1 {
+		p.Smoothing = 0.2
+	}
+	if p.Window <= 0 {
+		p.Window = 600
+	}
+	return &gradientLimit{policy: p, limit: float64(p.Initial)}
+}
+
+func (l *gradientLimit) Limit() int {
+	return int(l.limit)
+}
+
+func (l *gradientLimit) Update(latency time.Duration, inflight int, failed bool) {
+	rtt := float64(latency)
+	if l.longRTT == 0 {
+		l.longRTT, l.shortRTT = rtt, rtt
+	} else {
+		l.longRTT += (rtt - l.longRTT) / float64(l.policy.Window)
+		l.shortRTT += (rtt - l.shortRTT) / 10
+	}
+
+	gradient := 0.5
+	if !failed {
+		// don't grow the limit while it isn't used
+		if float64(inflight) < l.limit/2 {
+			return
+		}
+		gradient = math.Max(0.5, math.Min(1, l.longRTT/l.shortRTT))
+	}
+	estimate := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.limit*(1-l.policy.Smoothing) + estimate*l.policy.Smoothing
+	l.limit = math.Max(float64(l.policy.Min), math.Min(float64(l.policy.Max), l.limit))
+}
+
+// limitBounds applies the defaults to the bounds of a Limit.
+func limitBounds(min, max, initial int) (int, int, int) {
+	if min <= 0 {
+		min = 1
+	}
+	if max <= 0 {
+		max = 200
+	}
+	if max < min {
+		max = min
+	}
+	if initial <= 0 {
+		initial = 20
+	}
+	if initial < min {
+		initial = min
+	} else if initial > max {
+		initial = max
+	}
+	return min, max, initial
+}
+
+// concurrencyExceeded reports whether the requests in flight reach MaxConcurrent or the Limit.
+func (cb *CircuitBreaker) concurrencyExceeded() bool {
+	if cb.maxConcurrent > 0 && cb.inflight >= cb.maxConcurrent {
+		return true
+	}
+	return cb.limit != nil && cb.inflight >= cb.limit.Limit()
+}
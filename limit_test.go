@@ -0,0 +1,94 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMDLimit(t *testing.T) {
+	l := NewAIMDLimit(AIMDPolicy{Initial: 10, Max: 12, Timeout: time.Second})
+	assert.Equal(t, 10, l.Limit())
+
+	// an unused limit doesn't grow
+	l.Update(time.Millisecond, 1, false)
+	assert.Equal(t, 10, l.Limit())
+
+	l.Update(time.Millisecond, 6, false)
+	l.Update(time.Millisecond, 6, false)
+	l.Update(time.Millisecond, 6, false)
+	assert.Equal(t, 12, l.Limit())
+
+	l.Update(time.Millisecond, 6, true)
+	assert.Equal(t, 10, l.Limit())
+	l.Update(2*time.Second, 6, false)
+	assert.Equal(t, 9, l.Limit())
+
+	for i := 0; i < 50; i++ {
+		l.Update(time.Millisecond, 1, true)
+	}
+	assert.Equal(t, 1, l.Limit())
+}
+
+func TestGradientLimit(t *testing.T) {
+	l := NewGradientLimit(GradientPolicy{Initial: 10, Max: 50})
+	assert.Equal(t, 10, l.Limit())
+
+	// a steady latency grows a used limit
+	for i := 0; i < 20; i++ {
+		l.Update(10*time.Millisecond, l.Limit(), false)
+	}
+	grown := l.Limit()
+	assert.True(t, grown > 10, "%d", grown)
+	assert.True(t, grown <= 50, "%d", grown)
+
+	// a rising latency shrinks it
+	for i := 0; i < 20; i++ {
+		l.Update(100*time.Millisecond, l.Limit(), false)
+	}
+	assert.True(t, l.Limit() < grown, "%d", l.Limit())
+
+	shrunk := l.(*gradientLimit).limit
+	l.Update(10*time.Millisecond, l.Limit(), true)
+	assert.True(t, l.(*gradientLimit).limit < shrunk)
+
+	// the limit keeps room for a small queue on the way down
+	for i := 0; i < 100; i++ {
+		l.Update(time.Second, 1, true)
+	}
+	assert.Equal(t, 4, l.Limit())
+}
+
+func TestLimitBounds(t *testing.T) {
+	min, max, initial := limitBounds(0, 0, 0)
+	assert.Equal(t, []int{1, 200, 20}, []int{min, max, initial})
+	min, max, initial = limitBounds(5, 3, 1)
+	assert.Equal(t, []int{5, 5, 5}, []int{min, max, initial})
+}
+
+func TestCircuitBreakerLimit(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock: clock,
+		Limit: NewAIMDLimit(AIMDPolicy{Initial: 2, Timeout: time.Second}),
+	})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	done1, err := tscb.Allow()
+	assert.Nil(t, err)
+	done2, err := tscb.Allow()
+	assert.Nil(t, err)
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyConcurrent))
+
+	// a fast success under load raises the limit
+	done1(true)
+	assert.Equal(t, 3, cb.limit.Limit())
+
+	// a slow one lowers it
+	clock.Advance(2 * time.Second)
+	done2(true)
+	assert.Equal(t, 2, cb.limit.Limit())
+}
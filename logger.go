@@ -0,0 +1,80 @@
+package gobreaker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Logger receives structured log records from a CircuitBreaker, as a message followed by
+// alternating keys and values, like log/slog. A *slog.Logger implements Logger as is,
+// and a *zap.SugaredLogger through a wrapper calling its Infow and Warnw.
+//
+// The CircuitBreaker logs its configuration when it is created, its state changes,
+// the trips at the warning level, and a summary of the requests it rejected when a generation ends.
+// The Logger is called while the CircuitBreaker holds its internal lock,
+// so it must not call the CircuitBreaker.
+type Logger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+}
+
+// logCreated logs the configuration of a new CircuitBreaker.
+func (cb *CircuitBreaker) logCreated() {
+	if cb.logger == nil {
+		return
+	}
+	cb.logger.Info("circuit breaker created",
+		"name", cb.name,
+		"state", cb.state.String(),
+		"maxRequests", cb.maxRequests,
+		"interval", cb.interval.String(),
+		"timeout", cb.timeout.String(),
+	)
+}
+
+// logStateChange logs a state change from prev to state, with the counts of the generation it ended.
+func (cb *CircuitBreaker) logStateChange(prev State, state State, counts Counts, reason TripReason) {
+	if cb.logger == nil {
+		return
+	}
+	log := cb.logger.Info
+	msg := "circuit breaker state changed"
+	if state == StateOpen && prev != StateOpen {
+		log = cb.logger.Warn
+		msg = "circuit breaker tripped"
+	}
+	log(msg,
+		"name", cb.name,
+		"from", prev.String(),
+		"to", state.String(),
+		"reason", reason.String(),
+		"requests", counts.Requests,
+		"failures", counts.TotalFailures,
+		"consecutiveFailures", counts.ConsecutiveFailures,
+	)
+}
+
+// countRejection counts a rejected request for the summary logged at the end of the generation.
+func (cb *CircuitBreaker) countRejection() {
+	if cb.logger != nil {
+		atomic.AddInt64(&cb.fast.rejections, 1)
+	}
+}
+
+// logRejections logs the number of the requests rejected during the generation that ends at now, if any.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) logRejections(now time.Time) {
+	if cb.logger == nil {
+		return
+	}
+	rejected := atomic.SwapInt64(&cb.fast.rejections, 0)
+	if rejected == 0 {
+		return
+	}
+	cb.logger.Info("circuit breaker rejected requests",
+		"name", cb.name,
+		"state", cb.genState.String(),
+		"rejected", rejected,
+		"duration", now.Sub(cb.genStart).String(),
+	)
+}
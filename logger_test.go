@@ -0,0 +1,108 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logRecord struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	mutex   sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) log(level string, msg string, keysAndValues []interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.records = append(l.records, logRecord{level, msg, fields})
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log("warn", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Records() []logRecord {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return append([]logRecord(nil), l.records...)
+}
+
+func TestLogger(t *testing.T) {
+	logger := new(recordingLogger)
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Name: "logged", Clock: clock, Logger: logger})
+
+	records := logger.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, "circuit breaker created", records[0].msg)
+	assert.Equal(t, "logged", records[0].fields["name"])
+	assert.Equal(t, "1m0s", records[0].fields["timeout"])
+
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	records = logger.Records()
+	assert.Len(t, records, 2)
+	assert.Equal(t, logRecord{"warn", "circuit breaker tripped", map[string]interface{}{
+		"name":                "logged",
+		"from":                "closed",
+		"to":                  "open",
+		"reason":              "ready-to-trip",
+		"requests":            uint32(6),
+		"failures":            uint32(6),
+		"consecutiveFailures": uint32(6),
+	}}, records[1])
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+	}
+	clock.Advance(61 * time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	records = logger.Records()
+	assert.Len(t, records, 5)
+	assert.Equal(t, logRecord{"info", "circuit breaker rejected requests", map[string]interface{}{
+		"name":     "logged",
+		"state":    "open",
+		"rejected": int64(3),
+		"duration": "1m1s",
+	}}, records[2])
+	assert.Equal(t, "circuit breaker state changed", records[3].msg)
+	assert.Equal(t, "half-open", records[3].fields["to"])
+	assert.Equal(t, "closed", records[4].fields["to"])
+}
+
+func TestLoggerDoneTimeout(t *testing.T) {
+	logger := new(recordingLogger)
+	tscb := NewTwoStepCircuitBreaker(Settings{Logger: logger, DoneTimeout: time.Millisecond})
+	_, err := tscb.Allow()
+	assert.Nil(t, err)
+
+	for len(logger.Records()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, logRecord{"warn", "done callback not called, counted as a failure", map[string]interface{}{
+		"name":        "",
+		"doneTimeout": "1ms",
+	}}, logger.Records()[1])
+}
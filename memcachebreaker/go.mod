@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/memcachebreaker
+
+go 1.13
+
+require (
+	github.com/bradfitz/gomemcache v0.0.0-20190913173617-a41fca850d0b
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+)
+
+replace github.com/sony/gobreaker => ../
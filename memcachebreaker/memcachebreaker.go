@@ -0,0 +1,326 @@
+// Package memcachebreaker shares the state of the circuit breakers of gobreaker between
+// the replicas of a service through memcached, on a best-effort basis.
+//
+// It lives in its own module so that gobreaker doesn't depend on memcached.
+package memcachebreaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/sony/gobreaker"
+)
+
+const (
+	// DefaultPrefix is the Prefix of Options when it is empty.
+	DefaultPrefix = "gobreaker:"
+	// DefaultTTL is the TTL of Options when it is 0.
+	DefaultTTL = 2 * time.Minute
+	// DefaultPollInterval is the PollInterval of Options when it is 0.
+	DefaultPollInterval = time.Second
+	// DefaultLeaseTTL is the LeaseTTL of Options when it is 0.
+	DefaultLeaseTTL = 10 * time.Second
+)
+
+// casAttempts is how many times a Store tries to update a state that other replicas keep updating.
+const casAttempts = 3
+
+// Client is the part of *memcache.Client used by a Store.
+type Client interface {
+	Get(key string) (*memcache.Item, error)
+	Add(item *memcache.Item) error
+	CompareAndSwap(item *memcache.Item) error
+}
+
+// Options configures a Store.
+//
+// Prefix is the prefix of the keys: the state of a breaker is stored under Prefix followed by its name.
+// If Prefix is empty, DefaultPrefix is used.
+//
+// TTL is how long a state stays in memcached after the last state change of its breaker, so that
+// the state left by replicas that stopped expires on its own. It is rounded up to whole seconds.
+// It should be longer than the Timeout of the breakers. If TTL is 0, DefaultTTL is used.
+//
+// PollInterval is how often the stored states are read, as memcached doesn't notify changes.
+// If PollInterval is 0, DefaultPollInterval is used.
+//
+// Replica identifies the replica in the stored states, so that it ignores its own state changes.
+// If Replica is empty, the host name and the process ID are used.
+//
+// SingleWriter, if true, elects a leader among the replicas sharing a breaker: only the leader
+// stores its state changes, and the followers mirror the stored state instead of changing
+// state on their own, see Share. The leader holds
+// a lock in memcached under the key of the breaker followed by ":leader", which it renews
+// at every poll, and the followers take it over when it expires after LeaseTTL.
+// If LeaseTTL is 0, DefaultLeaseTTL is used. It is rounded up to whole seconds,
+// and should be a few times longer than PollInterval.
+//
+// Logger, if not nil, logs the errors of memcached and the states that can't be restored at the warning level.
+type Options struct {
+	Prefix       string
+	TTL          time.Duration
+	PollInterval time.Duration
+	Replica      string
+	SingleWriter bool
+	LeaseTTL     time.Duration
+	Logger       gobreaker.Logger
+}
+
+// Store shares the state of CircuitBreakers between the replicas of a service through memcached.
+//
+// Unlike a store with watches, a Store propagates a state change within PollInterval,
+// and memcached may evict a state before its TTL: it suits the environments that already
+// run memcached and can do with a best-effort coordination of their replicas.
+type Store struct {
+	client   Client
+	prefix   string
+	ttl      int32 // seconds
+	interval time.Duration
+	replica  string
+	single   bool
+	leaseTTL int32 // seconds
+	logger   gobreaker.Logger
+}
+
+// NewStore returns a Store that keeps the states in memcached with client, e.g. a *memcache.Client.
+func NewStore(client Client, o Options) *Store {
+	if o.Prefix == "" {
+		o.Prefix = DefaultPrefix
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	if o.LeaseTTL <= 0 {
+		o.LeaseTTL = DefaultLeaseTTL
+	}
+	if o.Replica == "" {
+		host, _ := os.Hostname()
+		o.Replica = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return &Store{
+		client:   client,
+		prefix:   o.Prefix,
+		ttl:      int32((o.TTL + time.Second - 1) / time.Second),
+		interval: o.PollInterval,
+		replica:  o.Replica,
+		single:   o.SingleWriter,
+		leaseTTL: int32((o.LeaseTTL + time.Second - 1) / time.Second),
+		logger:   o.Logger,
+	}
+}
+
+// record is the value stored under the key of a breaker.
+// Since is when the replica changed the state of its breaker.
+type record struct {
+	Replica  string             `json:"replica"`
+	Since    time.Time          `json:"since"`
+	Snapshot gobreaker.Snapshot `json:"snapshot"`
+}
+
+// sharing is a CircuitBreaker shared by Share.
+type sharing struct {
+	*Store
+	key       string
+	cb        *gobreaker.CircuitBreaker
+	seen      []byte  // last value read or written
+	published *record // last record read or written, nil if none is stored
+	leader    bool    // holds the lock of SingleWriter
+}
+
+// Share shares the state of cb with the CircuitBreakers of the same name on the other replicas,
+// until ctx is done or stop is called. stop waits for the sharing to end.
+//
+// Share restores the state stored in memcached, if any, then stores every state change of cb,
+// and restores the state changes stored by the other replicas when it reads them.
+// The states are updated with compare-and-swap, so that a state change doesn't overwrite
+// a later one stored by another replica meanwhile. A restored state is not a state change of cb,
+// so it isn't stored again, and a state that cb is already in is not restored.
+// The expiries of the states are shared as absolute times, so the clocks of the replicas
+// should be synchronized.
+//
+// With SingleWriter, only the leader changes state and stores its state changes. A follower
+// mirrors the stored state: a state change of its breaker, e.g. a trip caused by its own requests,
+// is undone as soon as its event is received by restoring the stored state, or the closed state
+// if none is stored, and the state is checked again at every poll. The state changes that
+// the leader makes at the same time, like the end of the open state, are kept.
+//
+// Share returns an error only if the stored state can't be read.
+func (s *Store) Share(ctx context.Context, cb *gobreaker.CircuitBreaker) (stop func(), err error) {
+	sh := &sharing{Store: s, key: s.prefix + cb.Name(), cb: cb}
+	sh.elect()
+	if err := sh.poll(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events, unsubscribe := cb.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer unsubscribe()
+		sh.run(ctx, events)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+func (sh *sharing) run(ctx context.Context, events <-chan gobreaker.Event) {
+	ticker := time.NewTicker(sh.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			if e.Type != gobreaker.EventStateChange {
+				continue
+			}
+			if sh.following() {
+				sh.mirror()
+			} else {
+				sh.store(e.Time)
+			}
+		case <-ticker.C:
+			sh.elect()
+			if err := sh.poll(); err != nil {
+				sh.warn("memcached get failed", sh.key, err)
+			}
+		}
+	}
+}
+
+// elect takes or renews the lock of the leader with SingleWriter.
+func (sh *sharing) elect() {
+	if !sh.single {
+		return
+	}
+	lock := sh.key + ":leader"
+	item, err := sh.client.Get(lock)
+	switch err {
+	case memcache.ErrCacheMiss:
+		err = sh.client.Add(&memcache.Item{Key: lock, Value: []byte(sh.replica), Expiration: sh.leaseTTL})
+	case nil:
+		if string(item.Value) != sh.replica {
+			sh.leader = false
+			return
+		}
+		item.Expiration = sh.leaseTTL
+		err = sh.client.CompareAndSwap(item)
+	}
+	if err != nil && err != memcache.ErrNotStored && err != memcache.ErrCASConflict {
+		sh.warn("memcached lock failed", lock, err)
+	}
+	sh.leader = err == nil
+}
+
+// following reports whether the CircuitBreaker is a follower of SingleWriter.
+func (sh *sharing) following() bool {
+	return sh.single && !sh.leader
+}
+
+// poll restores the stored state if it changed since it was last seen,
+// and keeps a follower in the stored state.
+func (sh *sharing) poll() error {
+	item, err := sh.client.Get(sh.key)
+	switch {
+	case err == memcache.ErrCacheMiss:
+		sh.seen, sh.published = nil, nil
+	case err != nil:
+		return err
+	case !bytes.Equal(item.Value, sh.seen):
+		sh.seen = item.Value
+		var r record
+		if err := json.Unmarshal(item.Value, &r); err != nil {
+			sh.warn("can't decode state", sh.key, err)
+			break
+		}
+		sh.published = &r
+		if !sh.following() && r.Replica != sh.replica && r.Snapshot.State != sh.cb.State() {
+			sh.restore(r.Snapshot)
+		}
+	}
+	if sh.following() {
+		sh.mirror()
+	}
+	return nil
+}
+
+// mirror puts a follower into the published state, or the closed state if none is published.
+// A published open state whose expiry passed is half-open, as on the leader.
+func (sh *sharing) mirror() {
+	s := gobreaker.Snapshot{Version: gobreaker.SnapshotVersion, Name: sh.cb.Name(), State: gobreaker.StateClosed}
+	if sh.published != nil {
+		s = sh.published.Snapshot
+	}
+	want := s.State
+	if want == gobreaker.StateOpen && !s.Expiry.IsZero() && s.Expiry.Before(time.Now()) {
+		want = gobreaker.StateHalfOpen
+	}
+	if sh.cb.State() != want {
+		sh.restore(s)
+	}
+}
+
+func (sh *sharing) restore(s gobreaker.Snapshot) {
+	if err := sh.cb.Restore(s); err != nil {
+		sh.warn("can't restore state", sh.key, err)
+	}
+}
+
+// store stores the current state of the CircuitBreaker, which changed at since,
+// unless a later state change is stored.
+func (sh *sharing) store(since time.Time) {
+	r := record{Replica: sh.replica, Since: since, Snapshot: sh.cb.Snapshot()}
+	value, err := json.Marshal(r)
+	if err != nil {
+		sh.warn("can't encode state", sh.key, err)
+		return
+	}
+
+	for i := 0; i < casAttempts; i++ {
+		item, err := sh.client.Get(sh.key)
+		switch err {
+		case memcache.ErrCacheMiss:
+			err = sh.client.Add(&memcache.Item{Key: sh.key, Value: value, Expiration: sh.ttl})
+		case nil:
+			var stored record
+			if json.Unmarshal(item.Value, &stored) == nil && stored.Since.After(since) {
+				return
+			}
+			item.Value, item.Expiration = value, sh.ttl
+			err = sh.client.CompareAndSwap(item)
+		}
+		switch err {
+		case nil:
+			sh.seen, sh.published = value, &r
+			return
+		case memcache.ErrCASConflict, memcache.ErrNotStored, memcache.ErrCacheMiss:
+			continue
+		default:
+			sh.warn("memcached update failed", sh.key, err)
+			return
+		}
+	}
+	sh.warn("memcached update conflicted", sh.key, nil)
+}
+
+func (s *Store) warn(msg string, key string, err error) {
+	if s.logger == nil {
+		return
+	}
+	if err == nil {
+		s.logger.Warn(msg, "key", key)
+		return
+	}
+	s.logger.Warn(msg, "key", key, "error", err.Error())
+}
@@ -0,0 +1,260 @@
+package memcachebreaker
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeItem struct {
+	value      []byte
+	expiration int32
+	cas        uint64
+}
+
+// fakeMemcache is an in-memory memcached. beforeCAS, if set, is called before a compare-and-swap.
+type fakeMemcache struct {
+	mutex     sync.Mutex
+	items     map[string]fakeItem
+	cas       uint64
+	casids    map[*memcache.Item]uint64
+	beforeCAS func()
+}
+
+func newFakeMemcache() *fakeMemcache {
+	return &fakeMemcache{items: make(map[string]fakeItem), casids: make(map[*memcache.Item]uint64)}
+}
+
+func (m *fakeMemcache) Get(key string) (*memcache.Item, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	it, ok := m.items[key]
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	item := &memcache.Item{Key: key, Value: it.value, Expiration: it.expiration}
+	m.casids[item] = it.cas
+	return item, nil
+}
+
+func (m *fakeMemcache) set(item *memcache.Item) {
+	m.cas++
+	m.items[item.Key] = fakeItem{value: item.Value, expiration: item.Expiration, cas: m.cas}
+}
+
+func (m *fakeMemcache) Add(item *memcache.Item) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.items[item.Key]; ok {
+		return memcache.ErrNotStored
+	}
+	m.set(item)
+	return nil
+}
+
+func (m *fakeMemcache) CompareAndSwap(item *memcache.Item) error {
+	if m.beforeCAS != nil {
+		m.beforeCAS()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	it, ok := m.items[item.Key]
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	if it.cas != m.casids[item] {
+		return memcache.ErrCASConflict
+	}
+	m.set(item)
+	return nil
+}
+
+func (m *fakeMemcache) record(key string) (record, int32) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var r record
+	json.Unmarshal(m.items[key].value, &r)
+	return r, m.items[key].expiration
+}
+
+func (m *fakeMemcache) delete(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.items, key)
+}
+
+func (m *fakeMemcache) has(key string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.items[key]
+	return ok
+}
+
+func waitForState(t *testing.T, cb *gobreaker.CircuitBreaker, state gobreaker.State) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); cb.State() != state; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %v, want %v", cb.Name(), cb.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func share(t *testing.T, mc *fakeMemcache, replica string, cb *gobreaker.CircuitBreaker) func() {
+	s := NewStore(mc, Options{Replica: replica, TTL: 90500 * time.Millisecond, PollInterval: time.Millisecond})
+	stop, err := s.Share(context.Background(), cb)
+	assert.Nil(t, err)
+	return stop
+}
+
+func TestShare(t *testing.T) {
+	mc := newFakeMemcache()
+	a := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	b := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, mc, "a", a)()
+	defer share(t, mc, "b", b)()
+
+	a.Trip()
+	waitForState(t, b, gobreaker.StateOpen)
+	assert.Equal(t, a.Snapshot().Expiry.UnixNano(), b.Snapshot().Expiry.UnixNano())
+	r, expiration := mc.record("gobreaker:db")
+	assert.Equal(t, "a", r.Replica)
+	assert.Equal(t, int32(91), expiration)
+
+	b.Reset()
+	waitForState(t, a, gobreaker.StateClosed)
+
+	// a replica that starts later restores the stored state
+	b.ForceOpen()
+	waitForState(t, a, gobreaker.StateForcedOpen)
+	c := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	defer share(t, mc, "c", c)()
+	assert.Equal(t, gobreaker.StateForcedOpen, c.State())
+}
+
+func TestShareConflict(t *testing.T) {
+	mc := newFakeMemcache()
+	later, _ := json.Marshal(record{
+		Replica:  "b",
+		Since:    time.Now().Add(time.Minute),
+		Snapshot: gobreaker.Snapshot{Version: gobreaker.SnapshotVersion, Name: "db", State: gobreaker.StateForcedOpen},
+	})
+	mc.Add(&memcache.Item{Key: "gobreaker:db", Value: []byte(`{"replica":"b"}`)})
+	mc.beforeCAS = func() {
+		mc.mutex.Lock()
+		defer mc.mutex.Unlock()
+
+		mc.set(&memcache.Item{Key: "gobreaker:db", Value: later})
+	}
+
+	sh := &sharing{Store: NewStore(mc, Options{Replica: "a"}), key: "gobreaker:db", cb: gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})}
+	sh.cb.Trip()
+	sh.store(time.Now())
+
+	r, _ := mc.record("gobreaker:db")
+	assert.Equal(t, "b", r.Replica)
+	assert.Equal(t, gobreaker.StateForcedOpen, r.Snapshot.State)
+}
+
+func TestShareCacheMiss(t *testing.T) {
+	mc := newFakeMemcache()
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	stop := share(t, mc, "a", cb)
+	stop()
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	sh := &sharing{Store: NewStore(mc, Options{}), key: "gobreaker:db", cb: cb}
+	assert.NotEqual(t, "", sh.replica)
+	cb.Trip()
+	sh.store(time.Now())
+	r, expiration := mc.record("gobreaker:db")
+	assert.Equal(t, gobreaker.StateOpen, r.Snapshot.State)
+	assert.Equal(t, int32(DefaultTTL/time.Second), expiration)
+}
+
+func TestShareSingleWriter(t *testing.T) {
+	mc := newFakeMemcache()
+	o := Options{SingleWriter: true, LeaseTTL: 1500 * time.Millisecond, PollInterval: time.Millisecond}
+	a := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	b := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	o.Replica = "a"
+	stopA, err := NewStore(mc, o).Share(context.Background(), a)
+	assert.Nil(t, err)
+	o.Replica = "b"
+	stopB, err := NewStore(mc, o).Share(context.Background(), b)
+	assert.Nil(t, err)
+	defer stopB()
+
+	// the follower doesn't change state on its own: it mirrors the leader
+	b.Trip()
+	waitForState(t, b, gobreaker.StateClosed)
+	assert.False(t, mc.has("gobreaker:db"))
+	assert.Equal(t, gobreaker.StateClosed, a.State())
+
+	a.Trip()
+	waitForState(t, b, gobreaker.StateOpen)
+	b.Reset()
+	waitForState(t, b, gobreaker.StateOpen)
+	r, _ := mc.record("gobreaker:db")
+	assert.Equal(t, "a", r.Replica)
+	_, expiration := mc.record("gobreaker:db:leader")
+	assert.Equal(t, int32(2), expiration)
+
+	// the follower takes over when the lock of the leader expires
+	stopA()
+	mc.delete("gobreaker:db:leader")
+	for deadline := time.Now().Add(time.Second); !mc.has("gobreaker:db:leader"); {
+		if time.Now().After(deadline) {
+			t.Fatal("no leader")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	b.Reset()
+	for deadline := time.Now().Add(time.Second); ; {
+		if r, _ := mc.record("gobreaker:db"); r.Replica == "b" {
+			assert.Equal(t, gobreaker.StateClosed, r.Snapshot.State)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("state of the new leader not stored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShareSingleWriterMirror(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+	sh := &sharing{Store: NewStore(newFakeMemcache(), Options{SingleWriter: true}), key: "gobreaker:db", cb: cb}
+
+	// a published open state that timed out is half-open on the follower too
+	sh.published = &record{Replica: "a", Snapshot: gobreaker.Snapshot{
+		Version: gobreaker.SnapshotVersion,
+		Name:    "db",
+		State:   gobreaker.StateOpen,
+		Expiry:  time.Now().Add(-time.Second),
+	}}
+	sh.mirror()
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+	sh.mirror()
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+
+	// a probe of the follower doesn't close it
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+	sh.mirror()
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+}
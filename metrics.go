@@ -0,0 +1,285 @@
+package gobreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// MetricsSink receives measurements from a CircuitBreaker so that any metrics backend
+// (statsd, Datadog, Prometheus, ...) can be plugged in without gobreaker importing it.
+//
+// OnRequest is called when a request is allowed to proceed.
+// OnSuccess and OnFailure are called with the latency of the request when its outcome is known.
+// OnReject is called with the current state when a request is rejected.
+// OnStateChange is called with the time spent in the previous state when the state changes.
+//
+// A MetricsSink must be safe for concurrent use: OnReject is called without the internal lock
+// of the CircuitBreaker for the requests rejected while it is open, and so are OnRequest and OnSuccess
+// for the requests counted in the Stripes. The other calls are made while the CircuitBreaker holds
+// its internal lock, so the methods must return quickly and must not call back into the CircuitBreaker.
+type MetricsSink interface {
+	OnRequest(name string)
+	OnSuccess(name string, latency time.Duration)
+	OnFailure(name string, latency time.Duration)
+	OnReject(name string, state State)
+	OnStateChange(name string, from State, to State, elapsed time.Duration)
+}
+
+// NoopMetricsSink is a MetricsSink that discards all measurements.
+var NoopMetricsSink MetricsSink = noopMetricsSink{}
+
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) OnRequest(string)                                  {}
+func (noopMetricsSink) OnSuccess(string, time.Duration)                   {}
+func (noopMetricsSink) OnFailure(string, time.Duration)                   {}
+func (noopMetricsSink) OnReject(string, State)                            {}
+func (noopMetricsSink) OnStateChange(string, State, State, time.Duration) {}
+
+// StateChangeRecord is a state change recorded by RecordingMetricsSink.
+type StateChangeRecord struct {
+	Name    string
+	From    State
+	To      State
+	Elapsed time.Duration
+}
+
+// RecordingMetricsSink is a MetricsSink that keeps every measurement in memory.
+// It is intended for tests. The zero value is ready to use.
+type RecordingMetricsSink struct {
+	mutex        sync.Mutex
+	requests     int
+	successes    int
+	failures     int
+	rejects      map[State]int
+	latencies    []time.Duration
+	stateChanges []StateChangeRecord
+	exemplars    []ExemplarRecord
+	histograms   []HistogramRecord
+}
+
+// ExemplarRecord is an exemplar recorded by RecordingMetricsSink.
+// Outcome is either "failure" or "reject".
+type ExemplarRecord struct {
+	Name     string
+	Outcome  string
+	Exemplar Exemplar
+}
+
+// OnRequest implements MetricsSink.
+func (s *RecordingMetricsSink) OnRequest(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests++
+}
+
+// OnSuccess implements MetricsSink.
+func (s *RecordingMetricsSink) OnSuccess(name string, latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.successes++
+	s.latencies = append(s.latencies, latency)
+}
+
+// OnFailure implements MetricsSink.
+func (s *RecordingMetricsSink) OnFailure(name string, latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures++
+	s.latencies = append(s.latencies, latency)
+}
+
+// HistogramRecord is a LatencyHistogram recorded by RecordingMetricsSink.
+type HistogramRecord struct {
+	Name       string
+	Generation uint64
+	State      State
+	Histogram  LatencyHistogram
+}
+
+// OnReject implements MetricsSink.
+func (s *RecordingMetricsSink) OnReject(name string, state State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.rejects == nil {
+		s.rejects = make(map[State]int)
+	}
+	s.rejects[state]++
+}
+
+// OnFailureExemplar implements ExemplarSink.
+func (s *RecordingMetricsSink) OnFailureExemplar(name string, latency time.Duration, exemplar Exemplar) {
+	s.OnFailure(name, latency)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.exemplars = append(s.exemplars, ExemplarRecord{name, "failure", exemplar})
+}
+
+// OnRejectExemplar implements ExemplarSink.
+func (s *RecordingMetricsSink) OnRejectExemplar(name string, state State, exemplar Exemplar) {
+	s.OnReject(name, state)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.exemplars = append(s.exemplars, ExemplarRecord{name, "reject", exemplar})
+}
+
+// OnHistogram implements HistogramSink.
+func (s *RecordingMetricsSink) OnHistogram(name string, generation uint64, state State, histogram LatencyHistogram) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.histograms = append(s.histograms, HistogramRecord{name, generation, state, histogram})
+}
+
+// OnStateChange implements MetricsSink.
+func (s *RecordingMetricsSink) OnStateChange(name string, from State, to State, elapsed time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stateChanges = append(s.stateChanges, StateChangeRecord{name, from, to, elapsed})
+}
+
+// Requests returns the number of allowed requests.
+func (s *RecordingMetricsSink) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests
+}
+
+// Successes returns the number of successful requests.
+func (s *RecordingMetricsSink) Successes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.successes
+}
+
+// Failures returns the number of failed requests.
+func (s *RecordingMetricsSink) Failures() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.failures
+}
+
+// Rejects returns the number of requests rejected in the given state.
+func (s *RecordingMetricsSink) Rejects(state State) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rejects[state]
+}
+
+// Latencies returns the latencies of all completed requests in the order they were reported.
+func (s *RecordingMetricsSink) Latencies() []time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]time.Duration(nil), s.latencies...)
+}
+
+// StateChanges returns the recorded state changes in the order they happened.
+func (s *RecordingMetricsSink) StateChanges() []StateChangeRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]StateChangeRecord(nil), s.stateChanges...)
+}
+
+// Exemplars returns the recorded exemplars in the order they were reported.
+func (s *RecordingMetricsSink) Exemplars() []ExemplarRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]ExemplarRecord(nil), s.exemplars...)
+}
+
+// Histograms returns the recorded histograms in the order the generations ended.
+func (s *RecordingMetricsSink) Histograms() []HistogramRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]HistogramRecord(nil), s.histograms...)
+}
+
+// MultiMetricsSink returns a MetricsSink that passes every measurement to all of sinks in order.
+// The MetricsSink is also an ExemplarSink and a HistogramSink: the exemplars are passed to the sinks
+// that are ExemplarSinks, the others get the measurements without them, and the histograms
+// are passed to the sinks that are HistogramSinks.
+func MultiMetricsSink(sinks ...MetricsSink) MetricsSink {
+	return multiMetricsSink(append([]MetricsSink(nil), sinks...))
+}
+
+type multiMetricsSink []MetricsSink
+
+var (
+	_ ExemplarSink  = multiMetricsSink(nil)
+	_ HistogramSink = multiMetricsSink(nil)
+)
+
+func (m multiMetricsSink) OnRequest(name string) {
+	for _, s := range m {
+		s.OnRequest(name)
+	}
+}
+
+func (m multiMetricsSink) OnSuccess(name string, latency time.Duration) {
+	for _, s := range m {
+		s.OnSuccess(name, latency)
+	}
+}
+
+func (m multiMetricsSink) OnFailure(name string, latency time.Duration) {
+	for _, s := range m {
+		s.OnFailure(name, latency)
+	}
+}
+
+func (m multiMetricsSink) OnReject(name string, state State) {
+	for _, s := range m {
+		s.OnReject(name, state)
+	}
+}
+
+func (m multiMetricsSink) OnStateChange(name string, from State, to State, elapsed time.Duration) {
+	for _, s := range m {
+		s.OnStateChange(name, from, to, elapsed)
+	}
+}
+
+func (m multiMetricsSink) OnFailureExemplar(name string, latency time.Duration, exemplar Exemplar) {
+	for _, s := range m {
+		if e, ok := s.(ExemplarSink); ok {
+			e.OnFailureExemplar(name, latency, exemplar)
+		} else {
+			s.OnFailure(name, latency)
+		}
+	}
+}
+
+func (m multiMetricsSink) OnRejectExemplar(name string, state State, exemplar Exemplar) {
+	for _, s := range m {
+		if e, ok := s.(ExemplarSink); ok {
+			e.OnRejectExemplar(name, state, exemplar)
+		} else {
+			s.OnReject(name, state)
+		}
+	}
+}
+
+func (m multiMetricsSink) OnHistogram(name string, generation uint64, state State, histogram LatencyHistogram) {
+	for _, s := range m {
+		if h, ok := s.(HistogramSink); ok {
+			h.OnHistogram(name, generation, state, histogram)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsSink(t *testing.T) {
+	sink := new(RecordingMetricsSink)
+	cb := NewCircuitBreaker(Settings{Name: "metrics", Metrics: sink})
+
+	assert.Nil(t, succeed(cb))
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+	assert.Equal(t, 7, sink.Requests())
+	assert.Equal(t, 1, sink.Successes())
+	assert.Equal(t, 6, sink.Failures())
+	assert.Equal(t, 1, sink.Rejects(StateOpen))
+	assert.Len(t, sink.Latencies(), 7)
+
+	pseudoSleep(cb, time.Duration(60)*time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	changes := sink.StateChanges()
+	assert.Len(t, changes, 3)
+	assert.Equal(t, StateChangeRecord{"metrics", StateClosed, StateOpen, changes[0].Elapsed}, changes[0])
+	assert.Equal(t, StateOpen, changes[1].From)
+	assert.Equal(t, StateHalfOpen, changes[1].To)
+	assert.Equal(t, StateHalfOpen, changes[2].From)
+	assert.Equal(t, StateClosed, changes[2].To)
+}
+
+func TestMetricsSinkTwoStep(t *testing.T) {
+	sink := new(RecordingMetricsSink)
+	tscb := NewTwoStepCircuitBreaker(Settings{Name: "metrics", Metrics: sink})
+
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	done(true)
+
+	assert.Equal(t, 1, sink.Requests())
+	assert.Equal(t, 1, sink.Successes())
+	assert.True(t, sink.Latencies()[0] >= time.Duration(10)*time.Millisecond)
+}
+
+func TestNoopMetricsSink(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Metrics: NoopMetricsSink})
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+}
+
+func TestMultiMetricsSink(t *testing.T) {
+	recording := new(RecordingMetricsSink)
+	plain := new(RecordingMetricsSink)
+	cb := NewCircuitBreaker(Settings{
+		Name:             "multi",
+		Metrics:          MultiMetricsSink(recording, struct{ MetricsSink }{plain}),
+		Exemplar:         traceExemplar,
+		LatencyHistogram: true,
+		ReadyToTrip:      func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+
+	traced := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	cb.ExecuteContext(traced, func(ctx context.Context) (interface{}, error) { return nil, errors.New("fail") })
+	cb.ExecuteContext(traced, func(ctx context.Context) (interface{}, error) { return nil, nil })
+
+	// the sinks that are not ExemplarSinks nor HistogramSinks get the plain measurements
+	for _, sink := range []*RecordingMetricsSink{recording, plain} {
+		assert.Equal(t, 1, sink.Failures())
+		assert.Equal(t, 1, sink.Rejects(StateOpen))
+	}
+	assert.Equal(t, []ExemplarRecord{
+		{"multi", "failure", Exemplar{TraceID: "trace-1"}},
+		{"multi", "reject", Exemplar{TraceID: "trace-1"}},
+	}, recording.Exemplars())
+	assert.Len(t, recording.Histograms(), 1)
+	assert.Empty(t, plain.Exemplars())
+	assert.Empty(t, plain.Histograms())
+}
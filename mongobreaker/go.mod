@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/mongobreaker
+
+go 1.13
+
+require (
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+	go.mongodb.org/mongo-driver v1.12.1
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,135 @@
+// Package mongobreaker feeds the circuit breakers of gobreaker, one per MongoDB server,
+// with the command and heartbeat events of the mongo-go-driver, so that the breakers
+// follow the topology without being placed around every call by hand.
+//
+// It lives in its own module so that gobreaker doesn't depend on the mongo-go-driver.
+package mongobreaker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Options configures a Monitor.
+//
+// SlowThreshold, if positive, counts the successful commands that took longer as failures,
+// like timeouts, so that a server that slows down trips before the commands time out.
+//
+// IsFailure reports whether a failed command counts as a failure of its server, e.g. not for
+// the errors of the application such as duplicate keys, which then count as successes since the server
+// answered. If IsFailure is nil, every failed command counts as a failure.
+type Options struct {
+	SlowThreshold time.Duration
+	IsFailure     func(e *event.CommandFailedEvent) bool
+}
+
+// Monitor holds the CircuitBreakers of the MongoDB servers, keyed by their address, e.g. "db1:27017",
+// and counts the commands sent to each server and its failed heartbeats.
+//
+// Its CommandMonitor and ServerMonitor are set in the options of the client:
+//
+//	m := mongobreaker.New(gobreaker.NewBreakerGroup(0, settings), mongobreaker.Options{})
+//	opts := options.Client().ApplyURI(uri).
+//		SetMonitor(m.CommandMonitor(nil)).
+//		SetServerMonitor(m.ServerMonitor(nil))
+//
+// The driver selects the server of a command on its own, so the CircuitBreakers don't reject
+// the commands: Allow tells whether a server is broken before a command is issued to it.
+type Monitor struct {
+	group     *gobreaker.BreakerGroup
+	slow      time.Duration
+	isFailure func(e *event.CommandFailedEvent) bool
+}
+
+// New returns a Monitor with the CircuitBreakers of the servers in group.
+func New(group *gobreaker.BreakerGroup, o Options) *Monitor {
+	return &Monitor{group: group, slow: o.SlowThreshold, isFailure: o.IsFailure}
+}
+
+// Breaker returns the CircuitBreaker of the server at address.
+func (m *Monitor) Breaker(address string) *gobreaker.CircuitBreaker {
+	return m.group.Get(address)
+}
+
+// Allow returns a *gobreaker.RejectionError if the CircuitBreaker of the server at address is open,
+// so that a command can error out fast instead of being issued to the broken server, and nil otherwise.
+func (m *Monitor) Allow(address string) error {
+	return m.group.Get(address).Check()
+}
+
+// CommandMonitor returns an event.CommandMonitor that counts the commands, then passes
+// the events to next, if not nil.
+func (m *Monitor) CommandMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	monitor := &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			if m.slow > 0 && time.Duration(e.DurationNanos) > m.slow {
+				m.count(e.ConnectionID, context.DeadlineExceeded)
+			} else {
+				m.count(e.ConnectionID, nil)
+			}
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			if m.isFailure == nil || m.isFailure(e) {
+				m.count(e.ConnectionID, &CommandError{Command: e.CommandName, Failure: e.Failure})
+			} else {
+				m.count(e.ConnectionID, nil)
+			}
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, e)
+			}
+		},
+	}
+	if next != nil {
+		monitor.Started = next.Started
+	}
+	return monitor
+}
+
+// ServerMonitor returns an event.ServerMonitor that counts the failed heartbeats of the servers
+// as failures, then passes the events to next, if not nil. The successful heartbeats are not
+// counted, since a server may answer them while failing the commands.
+func (m *Monitor) ServerMonitor(next *event.ServerMonitor) *event.ServerMonitor {
+	monitor := &event.ServerMonitor{}
+	if next != nil {
+		*monitor = *next
+	}
+	monitor.ServerHeartbeatFailed = func(e *event.ServerHeartbeatFailedEvent) {
+		m.count(e.ConnectionID, e.Failure)
+		if next != nil && next.ServerHeartbeatFailed != nil {
+			next.ServerHeartbeatFailed(e)
+		}
+	}
+	return monitor
+}
+
+// count reports a command of the connection as a failure with err, or as a success if err is nil,
+// see gobreaker.CircuitBreaker.Report.
+func (m *Monitor) count(connectionID string, err error) {
+	m.group.Get(address(connectionID)).Report(err)
+}
+
+// address returns the address of the server of a connection ID, e.g. "db1:27017" for "db1:27017[-12]".
+func address(connectionID string) string {
+	if i := strings.IndexByte(connectionID, '['); i >= 0 {
+		return connectionID[:i]
+	}
+	return connectionID
+}
+
+// CommandError is the error with which a failed command is counted.
+type CommandError struct {
+	Command string
+	Failure string
+}
+
+// Error implements error.
+func (e *CommandError) Error() string {
+	return "mongobreaker: " + e.Command + ": " + e.Failure
+}
@@ -0,0 +1,86 @@
+package mongobreaker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func newMonitor(o Options) *Monitor {
+	return New(gobreaker.NewBreakerGroup(0, func(address string) gobreaker.Settings {
+		return gobreaker.Settings{ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 }}
+	}), o)
+}
+
+func succeeded(connectionID string, d time.Duration) *event.CommandSucceededEvent {
+	return &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{
+		CommandName: "find", ConnectionID: connectionID, DurationNanos: d.Nanoseconds(),
+	}}
+}
+
+func failed(connectionID string, failure string) *event.CommandFailedEvent {
+	return &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", ConnectionID: connectionID},
+		Failure:              failure,
+	}
+}
+
+func TestCommandMonitor(t *testing.T) {
+	var passed []string
+	m := newMonitor(Options{
+		SlowThreshold: time.Second,
+		IsFailure:     func(e *event.CommandFailedEvent) bool { return !strings.Contains(e.Failure, "duplicate key") },
+	})
+	monitor := m.CommandMonitor(&event.CommandMonitor{
+		Started:   func(ctx context.Context, e *event.CommandStartedEvent) { passed = append(passed, "started") },
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) { passed = append(passed, "succeeded") },
+		Failed:    func(ctx context.Context, e *event.CommandFailedEvent) { passed = append(passed, "failed") },
+	})
+	ctx := context.Background()
+
+	monitor.Started(ctx, &event.CommandStartedEvent{ConnectionID: "db1:27017[-1]"})
+	monitor.Succeeded(ctx, succeeded("db1:27017[-1]", time.Millisecond))
+	monitor.Failed(ctx, failed("db1:27017[-2]", "E11000 duplicate key error"))
+	assert.Equal(t, []string{"started", "succeeded", "failed"}, passed)
+	assert.Equal(t, gobreaker.Counts{Requests: 2, TotalSuccesses: 2, ConsecutiveSuccesses: 2}, m.Breaker("db1:27017").Counts())
+	assert.Nil(t, m.Allow("db1:27017"))
+
+	// a slow command and a failed one trip the server
+	monitor.Succeeded(ctx, succeeded("db1:27017[-1]", 2*time.Second))
+	monitor.Failed(ctx, failed("db1:27017[-3]", "connection reset"))
+	err := m.Allow("db1:27017")
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.True(t, gobreaker.IsRejection(err))
+
+	// the other servers are not affected
+	assert.Nil(t, m.Allow("db2:27017"))
+	assert.NotNil(t, m.CommandMonitor(nil).Succeeded)
+}
+
+func TestServerMonitor(t *testing.T) {
+	m := newMonitor(Options{})
+	started := 0
+	monitor := m.ServerMonitor(&event.ServerMonitor{
+		ServerHeartbeatStarted: func(e *event.ServerHeartbeatStartedEvent) { started++ },
+	})
+	monitor.ServerHeartbeatStarted(&event.ServerHeartbeatStartedEvent{ConnectionID: "db1:27017"})
+	assert.Equal(t, 1, started)
+
+	for i := 0; i < 2; i++ {
+		monitor.ServerHeartbeatFailed(&event.ServerHeartbeatFailedEvent{ConnectionID: "db1:27017", Failure: errors.New("timeout")})
+	}
+	assert.Equal(t, gobreaker.StateOpen, m.Breaker("db1:27017").State())
+	m.ServerMonitor(nil).ServerHeartbeatFailed(&event.ServerHeartbeatFailedEvent{ConnectionID: "db2:27017", Failure: errors.New("timeout")})
+	assert.Equal(t, uint32(1), m.Breaker("db2:27017").Counts().TotalFailures)
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, "db1:27017", address("db1:27017[-12]"))
+	assert.Equal(t, "db1:27017", address("db1:27017"))
+}
@@ -0,0 +1,72 @@
+// Package natsbreaker guards the NATS publishers with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on NATS.
+package natsbreaker
+
+import (
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sony/gobreaker"
+)
+
+// messageErrors are the errors caused by a message rather than by the connection.
+var messageErrors = []error{
+	nats.ErrMaxPayload,
+	nats.ErrBadSubject,
+	nats.ErrInvalidMsg,
+}
+
+// Classify classifies the error of a NATS publisher for Settings.Classify.
+// The errors caused by a message, e.g. nats.ErrMaxPayload or nats.ErrBadSubject,
+// count as successes, and the others, e.g. nats.ErrConnectionClosed or
+// nats.ErrReconnectBufExceeded, as failures.
+func Classify(err error) gobreaker.Outcome {
+	for _, e := range messageErrors {
+		if errors.Is(err, e) {
+			return gobreaker.OutcomeSuccess
+		}
+	}
+	return gobreaker.OutcomeFailure
+}
+
+// Publisher is the publishing part of *nats.Conn.
+type Publisher interface {
+	PublishMsg(msg *nats.Msg) error
+}
+
+// OverflowFunc handles a message that a Producer didn't publish because its breaker rejected it,
+// e.g. by writing it to a local spool to be published later. err is the rejection.
+// The error returned by OverflowFunc is returned to the caller of the Producer instead of err.
+type OverflowFunc func(msg *nats.Msg, err error) error
+
+// Producer publishes messages with a Publisher through a Breaker.
+type Producer struct {
+	pub      Publisher
+	cb       gobreaker.Breaker
+	overflow OverflowFunc
+}
+
+// NewProducer returns a Producer that publishes the messages with pub through cb.
+// cb should be created with Classify as Settings.Classify, so that invalid messages don't trip it.
+// While cb rejects the messages, they are passed to overflow, if not nil, and fail fast otherwise,
+// so that the producers don't fill the reconnect buffer of a disconnected connection.
+func NewProducer(pub Publisher, cb gobreaker.Breaker, overflow OverflowFunc) *Producer {
+	return &Producer{pub: pub, cb: cb, overflow: overflow}
+}
+
+// Publish publishes data to subject through the Breaker.
+func (p *Producer) Publish(subject string, data []byte) error {
+	return p.PublishMsg(&nats.Msg{Subject: subject, Data: data})
+}
+
+// PublishMsg publishes msg through the Breaker.
+func (p *Producer) PublishMsg(msg *nats.Msg) error {
+	_, err := p.cb.Execute(func() (interface{}, error) {
+		return nil, p.pub.PublishMsg(msg)
+	})
+	if gobreaker.IsRejection(err) && p.overflow != nil {
+		return p.overflow(msg, err)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package natsbreaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		outcome gobreaker.Outcome
+	}{
+		{nats.ErrConnectionClosed, gobreaker.OutcomeFailure},
+		{nats.ErrReconnectBufExceeded, gobreaker.OutcomeFailure},
+		{nats.ErrMaxPayload, gobreaker.OutcomeSuccess},
+		{fmt.Errorf("publish: %w", nats.ErrBadSubject), gobreaker.OutcomeSuccess},
+	} {
+		assert.Equal(t, test.outcome, Classify(test.err), test.err.Error())
+	}
+}
+
+type fakePublisher struct {
+	err       error
+	published []*nats.Msg
+}
+
+func (p *fakePublisher) PublishMsg(msg *nats.Msg) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, msg)
+	return nil
+}
+
+func TestProducer(t *testing.T) {
+	pub := &fakePublisher{}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Classify: Classify})
+	var overflow []*nats.Msg
+	p := NewProducer(pub, cb, func(msg *nats.Msg, err error) error {
+		assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+		overflow = append(overflow, msg)
+		return nil
+	})
+
+	assert.NoError(t, p.Publish("orders", []byte("1")))
+	assert.Equal(t, "orders", pub.published[0].Subject)
+
+	pub.err = nats.ErrMaxPayload
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, nats.ErrMaxPayload, p.Publish("orders", []byte("large")))
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	pub.err = nats.ErrConnectionClosed
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, nats.ErrConnectionClosed, p.Publish("orders", []byte("2")))
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	pub.err = nil
+	assert.NoError(t, p.PublishMsg(&nats.Msg{Subject: "orders", Data: []byte("3")}))
+	assert.Len(t, overflow, 1)
+	assert.Equal(t, "3", string(overflow[0].Data))
+	assert.Len(t, pub.published, 1)
+}
+
+func TestProducerWithoutOverflow(t *testing.T) {
+	pub := &fakePublisher{err: nats.ErrConnectionClosed}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	p := NewProducer(pub, cb, nil)
+
+	assert.Equal(t, nats.ErrConnectionClosed, p.Publish("orders", nil))
+	assert.True(t, errors.Is(p.Publish("orders", nil), gobreaker.ErrOpenState))
+}
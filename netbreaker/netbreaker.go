@@ -0,0 +1,86 @@
+// Package netbreaker guards the connections to the network with the circuit breakers of gobreaker,
+// one per destination address, so that the dials to a dead host fail fast instead of piling up
+// before any protocol, such as HTTP or gRPC, gets a chance to notice.
+package netbreaker
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/sony/gobreaker"
+)
+
+// DialFunc dials a connection, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Classifier reports whether a dial that failed with err counts as a failure of the address.
+type Classifier func(err error) bool
+
+// DefaultClassifier fails the dials that failed to resolve or to connect to the address,
+// including those that timed out. It doesn't fail the dials canceled by the caller,
+// nor those whose network or address is invalid, which say nothing about the health of the host.
+func DefaultClassifier(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var addrErr *net.AddrError
+	var parseErr *net.ParseError
+	var networkErr net.UnknownNetworkError
+	return !errors.As(err, &addrErr) && !errors.As(err, &parseErr) && !errors.As(err, &networkErr)
+}
+
+// Dialer dials through the CircuitBreaker of the destination address in Group.
+// A dial rejected by its CircuitBreaker fails with the rejection, e.g. a *gobreaker.RejectionError,
+// without dialing. A connection counts as a success once it is established: what happens
+// to it later is up to the protocol layer.
+//
+// Its DialContext can be set as the DialContext of an http.Transport, and the function
+// returned by ContextDialer passed to grpc.WithContextDialer.
+type Dialer struct {
+	// Dial dials the connections. If Dial is nil, the DialContext of a zero net.Dialer is used.
+	Dial DialFunc
+	// Group holds the CircuitBreakers of the addresses.
+	Group *gobreaker.BreakerGroup
+	// Classify reports whether a failed dial counts as a failure. If Classify is nil, DefaultClassifier
+	// is used. The dials that Classify doesn't fail are ignored, see gobreaker.CircuitBreaker.ExecuteClassified.
+	Classify Classifier
+}
+
+// NewDialer returns a Dialer that dials with dial through the CircuitBreakers of the addresses
+// in a BreakerGroup of size, created with settings, see gobreaker.NewBreakerGroup.
+func NewDialer(dial DialFunc, size int, settings func(address string) gobreaker.Settings) *Dialer {
+	return &Dialer{Dial: dial, Group: gobreaker.NewBreakerGroup(size, settings)}
+}
+
+// DialContext dials address on network if the CircuitBreaker of address allows it.
+// The addresses are used as is: "example.com:443" and "93.184.216.34:443" have different CircuitBreakers.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dial := d.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	classify := d.Classify
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	var conn net.Conn
+	err := d.Group.Get(address).ExecuteClassified(ctx, func(ctx context.Context) error {
+		var err error
+		conn, err = dial(ctx, network, address)
+		return err
+	}, classify)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ContextDialer returns a function that dials the addresses on network with DialContext,
+// such as the dialer of grpc.WithContextDialer with "tcp".
+func (d *Dialer) ContextDialer(network string) func(ctx context.Context, address string) (net.Conn, error) {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		return d.DialContext(ctx, network, address)
+	}
+}
@@ -0,0 +1,95 @@
+package netbreaker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDial fails the dials to the addresses in down and connects to the others with net.Pipe.
+type fakeDial struct {
+	down  map[string]error
+	dials map[string]int
+}
+
+func (f *fakeDial) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	f.dials[address]++
+	if err := f.down[address]; err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	conn, other := net.Pipe()
+	other.Close()
+	return conn, nil
+}
+
+func TestDialer(t *testing.T) {
+	f := &fakeDial{
+		down:  map[string]error{"dead:80": errors.New("connection refused")},
+		dials: make(map[string]int),
+	}
+	d := NewDialer(f.dial, 0, func(address string) gobreaker.Settings {
+		return gobreaker.Settings{ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 }}
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := d.DialContext(context.Background(), "tcp", "dead:80")
+		var opErr *net.OpError
+		assert.True(t, errors.As(err, &opErr))
+	}
+	_, err := d.DialContext(context.Background(), "tcp", "dead:80")
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.Equal(t, 2, f.dials["dead:80"])
+	assert.Equal(t, gobreaker.StateOpen, d.Group.Get("dead:80").State())
+
+	// the other addresses are not affected
+	conn, err := d.ContextDialer("tcp")(context.Background(), "alive:80")
+	assert.Nil(t, err)
+	conn.Close()
+	assert.Equal(t, uint32(1), d.Group.Get("alive:80").Counts().TotalSuccesses)
+}
+
+func TestDialerClassify(t *testing.T) {
+	f := &fakeDial{
+		down:  map[string]error{"host:80": context.Canceled},
+		dials: make(map[string]int),
+	}
+	d := NewDialer(f.dial, 0, nil)
+	_, err := d.DialContext(context.Background(), "tcp", "host:80")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, gobreaker.Counts{}, d.Group.Get("host:80").Counts())
+
+	// a canceled dial doesn't close a half-open breaker
+	f.down["host:80"] = errors.New("connection refused")
+	d = NewDialer(f.dial, 0, func(address string) gobreaker.Settings {
+		return gobreaker.Settings{
+			Timeout:     time.Millisecond,
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}
+	})
+	d.DialContext(context.Background(), "tcp", "host:80")
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, gobreaker.StateHalfOpen, d.Group.Get("host:80").State())
+	f.down["host:80"] = context.Canceled
+	_, err = d.DialContext(context.Background(), "tcp", "host:80")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, gobreaker.StateHalfOpen, d.Group.Get("host:80").State())
+
+	// the zero net.Dialer rejects an invalid address without counting a failure
+	d = NewDialer(nil, 0, nil)
+	_, err = d.DialContext(context.Background(), "tcp", "no-port")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint32(0), d.Group.Get("no-port").Counts().TotalFailures)
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	assert.True(t, DefaultClassifier(&net.DNSError{Err: "no such host", Name: "dead", IsNotFound: true}))
+	assert.True(t, DefaultClassifier(&net.OpError{Op: "dial", Err: context.DeadlineExceeded}))
+	assert.False(t, DefaultClassifier(&net.OpError{Op: "dial", Err: context.Canceled}))
+	assert.False(t, DefaultClassifier(&net.AddrError{Err: "missing port in address", Addr: "dead"}))
+	assert.False(t, DefaultClassifier(net.UnknownNetworkError("foo")))
+}
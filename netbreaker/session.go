@@ -0,0 +1,75 @@
+package netbreaker
+
+import (
+	"sync"
+
+	"github.com/sony/gobreaker"
+)
+
+// Session accounts the health of a long-lived connection, such as a WebSocket or a streaming RPC,
+// in the Breaker of its endpoint, where counting one request per call doesn't fit.
+//
+// The Breaker counts the establishment of the connection, the errors reported within it and
+// its abnormal closure, and decides whether new connections to the endpoint may be opened:
+//
+//	s, err := netbreaker.Connect(cb, func() error {
+//		conn, _, err = websocket.DefaultDialer.DialContext(ctx, url, nil)
+//		return err
+//	})
+//	if err != nil {
+//		return err // failed, or rejected by cb
+//	}
+//	for {
+//		_, msg, err := conn.ReadMessage()
+//		if err != nil {
+//			s.Close(err) // nil for a normal closure
+//			return err
+//		}
+//		s.Report(handle(msg))
+//	}
+//
+// The errors are counted with gobreaker.CircuitBreaker.Report.
+type Session struct {
+	cb gobreaker.Reporter
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// Connect establishes a connection with connect if cb allows it. connect counts as a request of cb,
+// which fails if connect returns an error. Connect returns the error of connect or the rejection of cb,
+// or a Session to report the health of the connection to cb.
+func Connect(cb gobreaker.Reporter, connect func() error) (*Session, error) {
+	_, err := cb.Execute(func() (interface{}, error) {
+		return nil, connect()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Session{cb: cb}, nil
+}
+
+// Report reports the outcome of an exchange within the connection, e.g. a message or a ping:
+// nil counts as a success, and an error as a failure. The reports after Close are dropped.
+func (s *Session) Report(err error) {
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+
+	if !closed {
+		s.cb.Report(err)
+	}
+}
+
+// Close reports the end of the connection. A nil err is a normal closure, which isn't counted,
+// and an error an abnormal closure, which counts as a failure. Only the first call counts.
+func (s *Session) Close(err error) {
+	s.mutex.Lock()
+	closed := s.closed
+	s.closed = true
+	s.mutex.Unlock()
+
+	if !closed && err != nil {
+		s.cb.Report(err)
+	}
+}
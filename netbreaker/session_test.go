@@ -0,0 +1,40 @@
+package netbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+
+	_, err := Connect(cb, func() error { return errors.New("handshake failed") })
+	assert.EqualError(t, err, "handshake failed")
+	assert.Equal(t, uint32(1), cb.Counts().ConsecutiveFailures)
+
+	s, err := Connect(cb, func() error { return nil })
+	assert.Nil(t, err)
+	s.Report(nil)
+	assert.Equal(t, uint32(2), cb.Counts().ConsecutiveSuccesses)
+
+	s.Report(errors.New("bad frame"))
+	s.Close(nil)
+	s.Report(errors.New("late"))
+	assert.Equal(t, uint32(1), cb.Counts().ConsecutiveFailures)
+
+	// an abnormal closure trips the breaker, which rejects new connections
+	s, err = Connect(cb, func() error { return nil })
+	assert.Nil(t, err)
+	s.Report(errors.New("bad frame"))
+	s.Close(errors.New("connection reset"))
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	connected := false
+	_, err = Connect(cb, func() error { connected = true; return nil })
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.False(t, connected)
+}
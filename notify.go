@@ -0,0 +1,73 @@
+package gobreaker
+
+import "sync"
+
+// notifier calls functions one at a time, in order, from its own goroutine.
+// The goroutine is started on demand and exits when there is nothing left to call.
+type notifier struct {
+	mutex   sync.Mutex
+	queue   []func()
+	running bool
+	idle    *sync.Cond
+}
+
+func (n *notifier) notify(f func()) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.queue = append(n.queue, f)
+	if !n.running {
+		n.running = true
+		go n.run()
+	}
+}
+
+func (n *notifier) run() {
+	for {
+		n.mutex.Lock()
+		if len(n.queue) == 0 {
+			n.running = false
+			if n.idle != nil {
+				n.idle.Broadcast()
+			}
+			n.mutex.Unlock()
+			return
+		}
+		f := n.queue[0]
+		n.queue[0] = nil
+		n.queue = n.queue[1:]
+		n.mutex.Unlock()
+
+		f()
+	}
+}
+
+// wait blocks until all queued functions have been called.
+func (n *notifier) wait() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.idle == nil {
+		n.idle = sync.NewCond(&n.mutex)
+	}
+	for n.running {
+		n.idle.Wait()
+	}
+}
+
+// notify calls f asynchronously if AsyncNotify is set, or immediately otherwise.
+func (cb *CircuitBreaker) notify(f func()) {
+	if cb.notifier == nil {
+		f()
+		return
+	}
+	cb.notifier.notify(f)
+}
+
+// WaitNotify blocks until the state-change callbacks queued by AsyncNotify have returned.
+// It returns immediately if AsyncNotify is not set.
+func (cb *CircuitBreaker) WaitNotify() {
+	if cb.notifier != nil {
+		cb.notifier.wait()
+	}
+}
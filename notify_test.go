@@ -0,0 +1,71 @@
+package gobreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncNotify(t *testing.T) {
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	var changes []stateChange2
+	cb := NewCircuitBreaker(Settings{
+		AsyncNotify: true,
+		OnStateChange: func(name string, from State, to State) {
+			<-release
+		},
+		OnStateChange2: func(name string, from State, to State, counts Counts, reason TripReason) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			changes = append(changes, stateChange2{from, to, counts, reason})
+		},
+	})
+
+	// the blocked callback doesn't stall the CircuitBreaker
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cb.Trip()
+		assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+		cb.Reset()
+		assert.Nil(t, succeed(cb))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the CircuitBreaker waited for the callback")
+	}
+
+	close(release)
+	cb.WaitNotify()
+	assert.Equal(t, []stateChange2{
+		{StateClosed, StateOpen, Counts{}, ReasonManual},
+		{StateOpen, StateClosed, Counts{}, ReasonManual},
+	}, changes)
+}
+
+func TestNotifierOrder(t *testing.T) {
+	n := new(notifier)
+	var got []int
+	for i := 0; i < 100; i++ {
+		i := i
+		n.notify(func() { got = append(got, i) })
+	}
+	n.wait()
+
+	assert.Len(t, got, 100)
+	for i, v := range got {
+		assert.Equal(t, i, v)
+	}
+	assert.False(t, n.running)
+}
+
+func TestWaitNotifySync(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	cb.WaitNotify()
+	assert.Nil(t, cb.notifier)
+}
@@ -0,0 +1,251 @@
+package gobreaker
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenMetricsHandler is an http.Handler that serves the metrics of the CircuitBreakers of a Registry
+// in the text format of Prometheus, or of OpenMetrics when the scraper accepts it,
+// without depending on the Prometheus client library.
+//
+// It serves the state, the generation and the Counts of the current generation of each CircuitBreaker
+// as gauges. OpenMetricsHandler is also a MetricsSink that counts the requests, the successes,
+// the failures, the rejections by state, the state changes by new state and the latencies of the
+// CircuitBreakers. It must be set as Settings.Metrics, possibly with MultiMetricsSink, for the
+// counters to be served; otherwise only the gauges are.
+type OpenMetricsHandler struct {
+	registry *Registry
+
+	mutex    sync.Mutex
+	counters map[string]*openMetricsCounters
+}
+
+type openMetricsCounters struct {
+	requests     uint64
+	successes    uint64
+	failures     uint64
+	rejects      map[State]uint64
+	stateChanges map[State]uint64 // by new state
+	latencySum   time.Duration
+	latencyCount uint64
+}
+
+// NewOpenMetricsHandler returns an OpenMetricsHandler for the CircuitBreakers in r,
+// including those added to r later.
+func NewOpenMetricsHandler(r *Registry) *OpenMetricsHandler {
+	return &OpenMetricsHandler{registry: r, counters: make(map[string]*openMetricsCounters)}
+}
+
+// counter returns the counters of name. It must be called with h.mutex held.
+func (h *OpenMetricsHandler) counter(name string) *openMetricsCounters {
+	c, ok := h.counters[name]
+	if !ok {
+		c = &openMetricsCounters{rejects: make(map[State]uint64), stateChanges: make(map[State]uint64)}
+		h.counters[name] = c
+	}
+	return c
+}
+
+// OnRequest implements MetricsSink.
+func (h *OpenMetricsHandler) OnRequest(name string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counter(name).requests++
+}
+
+// OnSuccess implements MetricsSink.
+func (h *OpenMetricsHandler) OnSuccess(name string, latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	c := h.counter(name)
+	c.successes++
+	c.latencySum += latency
+	c.latencyCount++
+}
+
+// OnFailure implements MetricsSink.
+func (h *OpenMetricsHandler) OnFailure(name string, latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	c := h.counter(name)
+	c.failures++
+	c.latencySum += latency
+	c.latencyCount++
+}
+
+// OnReject implements MetricsSink.
+func (h *OpenMetricsHandler) OnReject(name string, state State) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counter(name).rejects[state]++
+}
+
+// OnStateChange implements MetricsSink.
+func (h *OpenMetricsHandler) OnStateChange(name string, from State, to State, elapsed time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counter(name).stateChanges[to]++
+}
+
+// openMetricsStates are the states of the gobreaker_state gauge, in the order they are served.
+var openMetricsStates = []State{StateClosed, StateHalfOpen, StateOpen, StateForcedOpen, StateForcedClosed, StateDisabled}
+
+// openMetricsWriter writes the metric families in either text format.
+type openMetricsWriter struct {
+	w           *bufio.Writer
+	openMetrics bool
+}
+
+// family writes the metadata of a metric family. The samples of a counter are suffixed with _total,
+// which is part of the name of the family only in the format of Prometheus.
+func (w *openMetricsWriter) family(name, typ, help string) {
+	if typ == "counter" && !w.openMetrics {
+		name += "_total"
+	}
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample with labels given as alternating names and values.
+func (w *openMetricsWriter) sample(name string, value float64, labels ...string) {
+	w.w.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			w.w.WriteByte('{')
+		} else {
+			w.w.WriteByte(',')
+		}
+		w.w.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+	}
+	if len(labels) > 0 {
+		w.w.WriteByte('}')
+	}
+	w.w.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// write writes the metrics of all the CircuitBreakers.
+func (h *OpenMetricsHandler) write(w *openMetricsWriter) {
+	var snapshots []Snapshot
+	for _, cb := range h.registry.sorted() {
+		snapshots = append(snapshots, cb.Snapshot())
+	}
+
+	h.mutex.Lock()
+	counters := make(map[string]openMetricsCounters, len(h.counters))
+	names := make([]string, 0, len(h.counters))
+	for name, c := range h.counters {
+		copied := *c
+		copied.rejects = make(map[State]uint64, len(c.rejects))
+		for s, n := range c.rejects {
+			copied.rejects[s] = n
+		}
+		copied.stateChanges = make(map[State]uint64, len(c.stateChanges))
+		for s, n := range c.stateChanges {
+			copied.stateChanges[s] = n
+		}
+		counters[name] = copied
+		names = append(names, name)
+	}
+	h.mutex.Unlock()
+	sort.Strings(names)
+
+	w.family("gobreaker_state", "gauge", "Whether the circuit breaker is in the state.")
+	for _, s := range snapshots {
+		for _, state := range openMetricsStates {
+			value := 0.0
+			if s.State == state {
+				value = 1
+			}
+			w.sample("gobreaker_state", value, "name", s.Name, "state", state.String())
+		}
+	}
+	w.family("gobreaker_generation", "gauge", "Generation of the circuit breaker.")
+	for _, s := range snapshots {
+		w.sample("gobreaker_generation", float64(s.Generation), "name", s.Name)
+	}
+	gauges := []struct {
+		name, help string
+		value      func(c Counts) uint32
+	}{
+		{"gobreaker_generation_requests", "Requests in the current generation.", func(c Counts) uint32 { return c.Requests }},
+		{"gobreaker_generation_failures", "Failures in the current generation.", func(c Counts) uint32 { return c.TotalFailures }},
+		{"gobreaker_consecutive_successes", "Consecutive successes in the current generation.", func(c Counts) uint32 { return c.ConsecutiveSuccesses }},
+		{"gobreaker_consecutive_failures", "Consecutive failures in the current generation.", func(c Counts) uint32 { return c.ConsecutiveFailures }},
+	}
+	for _, g := range gauges {
+		w.family(g.name, "gauge", g.help)
+		for _, s := range snapshots {
+			w.sample(g.name, float64(g.value(s.Counts)), "name", s.Name)
+		}
+	}
+
+	totals := []struct {
+		name, help string
+		value      func(c openMetricsCounters) uint64
+	}{
+		{"gobreaker_requests", "Requests allowed by the circuit breaker.", func(c openMetricsCounters) uint64 { return c.requests }},
+		{"gobreaker_successes", "Successful requests.", func(c openMetricsCounters) uint64 { return c.successes }},
+		{"gobreaker_failures", "Failed requests.", func(c openMetricsCounters) uint64 { return c.failures }},
+	}
+	for _, t := range totals {
+		w.family(t.name, "counter", t.help)
+		for _, name := range names {
+			w.sample(t.name+"_total", float64(t.value(counters[name])), "name", name)
+		}
+	}
+	w.family("gobreaker_rejections", "counter", "Requests rejected by the circuit breaker, by state.")
+	for _, name := range names {
+		for _, state := range openMetricsStates {
+			if n, ok := counters[name].rejects[state]; ok {
+				w.sample("gobreaker_rejections_total", float64(n), "name", name, "state", state.String())
+			}
+		}
+	}
+	w.family("gobreaker_state_changes", "counter", "State changes of the circuit breaker, by new state.")
+	for _, name := range names {
+		for _, state := range openMetricsStates {
+			if n, ok := counters[name].stateChanges[state]; ok {
+				w.sample("gobreaker_state_changes_total", float64(n), "name", name, "state", state.String())
+			}
+		}
+	}
+	w.family("gobreaker_request_duration_seconds", "summary", "Latency of the requests allowed by the circuit breaker.")
+	for _, name := range names {
+		c := counters[name]
+		w.sample("gobreaker_request_duration_seconds_sum", c.latencySum.Seconds(), "name", name)
+		w.sample("gobreaker_request_duration_seconds_count", float64(c.latencyCount), "name", name)
+	}
+	if w.openMetrics {
+		w.w.WriteString("# EOF\n")
+	}
+}
+
+// ServeHTTP implements http.Handler. It serves the OpenMetrics format if the Accept header
+// of the request asks for application/openmetrics-text, and the format of Prometheus otherwise.
+func (h *OpenMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	om := &openMetricsWriter{w: bufio.NewWriter(w), openMetrics: strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")}
+	if om.openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+	h.write(om)
+	om.w.Flush()
+}
@@ -0,0 +1,65 @@
+package gobreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(h http.Handler, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOpenMetricsHandler(t *testing.T) {
+	r := NewRegistry()
+	h := NewOpenMetricsHandler(r)
+	cb := r.GetOrCreate(`pay"ments`, Settings{Metrics: h, MinimumRequests: 5})
+	r.GetOrCreate("idle", Settings{})
+	assert.Nil(t, succeed(cb))
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+
+	rec := scrape(h, "")
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE gobreaker_state gauge",
+		`gobreaker_state{name="idle",state="closed"} 1`,
+		`gobreaker_state{name="pay\"ments",state="open"} 1`,
+		`gobreaker_state{name="pay\"ments",state="closed"} 0`,
+		`gobreaker_generation{name="pay\"ments"} 2`,
+		`gobreaker_consecutive_failures{name="idle"} 0`,
+		"# TYPE gobreaker_requests_total counter",
+		`gobreaker_requests_total{name="pay\"ments"} 7`,
+		`gobreaker_successes_total{name="pay\"ments"} 1`,
+		`gobreaker_failures_total{name="pay\"ments"} 6`,
+		`gobreaker_rejections_total{name="pay\"ments",state="open"} 1`,
+		`gobreaker_state_changes_total{name="pay\"ments",state="open"} 1`,
+		"# TYPE gobreaker_request_duration_seconds summary",
+		`gobreaker_request_duration_seconds_count{name="pay\"ments"} 7`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "# EOF")
+	// the idle breaker has no counters
+	assert.NotContains(t, body, `gobreaker_requests_total{name="idle"}`)
+
+	rec = scrape(h, "application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	assert.Equal(t, "application/openmetrics-text; version=1.0.0; charset=utf-8", rec.Header().Get("Content-Type"))
+	body = rec.Body.String()
+	assert.Contains(t, body, "# TYPE gobreaker_requests counter\n")
+	assert.Contains(t, body, `gobreaker_requests_total{name="pay\"ments"} 7`+"\n")
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
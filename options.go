@@ -0,0 +1,406 @@
+package gobreaker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Option configures a CircuitBreaker created by New.
+type Option func(st *Settings) error
+
+// New returns a new CircuitBreaker with the given name configured by opts.
+// Unlike NewCircuitBreaker, New returns an error for invalid settings
+// instead of silently substituting the defaults, see Settings.Validate.
+// The settings that no Option sets keep their defaults.
+func New(name string, opts ...Option) (*CircuitBreaker, error) {
+	st := Settings{Name: name}
+	for _, opt := range opts {
+		if err := opt(&st); err != nil {
+			return nil, fmt.Errorf("gobreaker: %s: %v", name, err)
+		}
+	}
+	st.Name = name
+
+	if err := st.Validate(); err != nil {
+		return nil, fmt.Errorf("gobreaker: %s: %v", name, err)
+	}
+	return NewCircuitBreaker(st), nil
+}
+
+// Validate reports the first invalid field of st, such as a negative duration
+// or a policy that misses its required function.
+// The zero values, for which NewCircuitBreaker uses the defaults, are valid.
+func (st Settings) Validate() error {
+	if st.Interval < 0 {
+		return fmt.Errorf("negative Interval %v", st.Interval)
+	}
+	if st.Timeout < 0 {
+		return fmt.Errorf("negative Timeout %v", st.Timeout)
+	}
+	if st.MaxRequestsRatio < 0 {
+		return fmt.Errorf("negative MaxRequestsRatio %v", st.MaxRequestsRatio)
+	}
+	if st.MaxConcurrent < 0 {
+		return fmt.Errorf("negative MaxConcurrent %d", st.MaxConcurrent)
+	}
+	if st.Stripes < 0 {
+		return fmt.Errorf("negative Stripes %d", st.Stripes)
+	}
+	if st.Jitter < 0 || st.Jitter > MaxJitter {
+		return fmt.Errorf("Jitter %v out of [0, %v]", st.Jitter, MaxJitter)
+	}
+	if st.CrossGeneration < CrossGenerationDrop || st.CrossGeneration > CrossGenerationSlowFailure {
+		return fmt.Errorf("unknown CrossGeneration %d", st.CrossGeneration)
+	}
+
+	if b := st.Backoff; b != nil {
+		if b.Multiplier < 0 {
+			return fmt.Errorf("negative Backoff.Multiplier %v", b.Multiplier)
+		}
+		if b.MaxTimeout < 0 {
+			return fmt.Errorf("negative Backoff.MaxTimeout %v", b.MaxTimeout)
+		}
+		if b.Jitter < 0 || b.Jitter > MaxJitter {
+			return fmt.Errorf("Backoff.Jitter %v out of [0, %v]", b.Jitter, MaxJitter)
+		}
+	}
+
+	if r := st.HalfOpenRamp; r != nil {
+		if r.Period < 0 {
+			return fmt.Errorf("negative HalfOpenRamp.Period %v", r.Period)
+		}
+		for _, step := range r.Steps {
+			if step <= 0 || step > 1 {
+				return fmt.Errorf("HalfOpenRamp step %v out of (0, 1]", step)
+			}
+		}
+	}
+
+	if r := st.Recovery; r != nil && (r.SuccessRatio < 0 || r.SuccessRatio > 1) {
+		return fmt.Errorf("Recovery.SuccessRatio %v out of [0, 1]", r.SuccessRatio)
+	}
+
+	if e := st.EWMA; e != nil {
+		if e.HalfLife < 0 {
+			return fmt.Errorf("negative EWMA.HalfLife %v", e.HalfLife)
+		}
+		if e.FailureRate < 0 || e.FailureRate > 1 {
+			return fmt.Errorf("EWMA.FailureRate %v out of [0, 1]", e.FailureRate)
+		}
+		if e.Latency < 0 {
+			return fmt.Errorf("negative EWMA.Latency %v", e.Latency)
+		}
+	}
+
+	for i, w := range st.Windows {
+		if w.Length <= 0 {
+			return fmt.Errorf("Windows[%d]: non-positive Length %v", i, w.Length)
+		}
+		if w.Buckets < 0 {
+			return fmt.Errorf("Windows[%d]: negative Buckets %d", i, w.Buckets)
+		}
+		if w.ReadyToTrip == nil {
+			return fmt.Errorf("Windows[%d]: no ReadyToTrip", i)
+		}
+	}
+
+	if b := st.ErrorBudget; b != nil {
+		if b.Objective < 0 || b.Objective >= 1 {
+			return fmt.Errorf("ErrorBudget.Objective %v out of [0, 1)", b.Objective)
+		}
+		for i, w := range b.Windows {
+			if w.Long <= 0 || w.Short <= 0 {
+				return fmt.Errorf("ErrorBudget.Windows[%d]: non-positive window", i)
+			}
+			if w.BurnRate <= 0 {
+				return fmt.Errorf("ErrorBudget.Windows[%d]: non-positive BurnRate %v", i, w.BurnRate)
+			}
+		}
+	}
+
+	if q := st.HalfOpenQueue; q != nil {
+		if q.Size < 0 {
+			return fmt.Errorf("negative HalfOpenQueue.Size %d", q.Size)
+		}
+		if q.Timeout < 0 {
+			return fmt.Errorf("negative HalfOpenQueue.Timeout %v", q.Timeout)
+		}
+	}
+
+	if p := st.Probe; p != nil {
+		if p.Probe == nil {
+			return errors.New("Probe without a Probe function")
+		}
+		if p.Interval < 0 {
+			return fmt.Errorf("negative Probe.Interval %v", p.Interval)
+		}
+		if p.Timeout < 0 {
+			return fmt.Errorf("negative Probe.Timeout %v", p.Timeout)
+		}
+	}
+
+	if st.Shadow != nil {
+		if err := st.Shadow.Validate(); err != nil {
+			return fmt.Errorf("Shadow: %v", err)
+		}
+	}
+
+	for i, rule := range st.Schedule {
+		w := rule.Window
+		if w.Start < 0 || w.Start > 24*time.Hour || w.End < 0 || w.End > 24*time.Hour {
+			return fmt.Errorf("Schedule[%d]: window out of the day", i)
+		}
+		if rule.Policy.Interval < 0 || rule.Policy.Timeout < 0 {
+			return fmt.Errorf("Schedule[%d]: negative duration", i)
+		}
+	}
+	return nil
+}
+
+// WithSettings starts from a copy of st. Its Name is ignored.
+// Options after WithSettings override the fields of st.
+func WithSettings(st Settings) Option {
+	return func(s *Settings) error {
+		*s = st
+		return nil
+	}
+}
+
+// WithMaxRequests sets MaxRequests, which must be between 1 and math.MaxUint32.
+func WithMaxRequests(n int) Option {
+	return func(st *Settings) error {
+		if n < 1 || int64(n) > math.MaxUint32 {
+			return fmt.Errorf("MaxRequests %d out of [1, %d]", n, uint32(math.MaxUint32))
+		}
+		st.MaxRequests = uint32(n)
+		return nil
+	}
+}
+
+// WithMaxConcurrent sets MaxConcurrent, which must be positive.
+func WithMaxConcurrent(n int) Option {
+	return func(st *Settings) error {
+		if n <= 0 {
+			return fmt.Errorf("MaxConcurrent %d must be positive", n)
+		}
+		st.MaxConcurrent = n
+		return nil
+	}
+}
+
+// WithStripes sets Stripes, which must be positive.
+func WithStripes(n int) Option {
+	return func(st *Settings) error {
+		if n <= 0 {
+			return fmt.Errorf("Stripes %d must be positive", n)
+		}
+		st.Stripes = n
+		return nil
+	}
+}
+
+// WithInterval sets Interval, which must be positive.
+func WithInterval(d time.Duration) Option {
+	return func(st *Settings) error {
+		if d <= 0 {
+			return fmt.Errorf("Interval %v must be positive", d)
+		}
+		st.Interval = d
+		return nil
+	}
+}
+
+// WithTimeout sets Timeout, which must be positive.
+func WithTimeout(d time.Duration) Option {
+	return func(st *Settings) error {
+		if d <= 0 {
+			return fmt.Errorf("Timeout %v must be positive", d)
+		}
+		st.Timeout = d
+		return nil
+	}
+}
+
+// WithJitter sets Jitter, which must be between 0 and MaxJitter.
+func WithJitter(fraction float64) Option {
+	return func(st *Settings) error {
+		if fraction < 0 || fraction > MaxJitter {
+			return fmt.Errorf("Jitter %v out of [0, %v]", fraction, MaxJitter)
+		}
+		st.Jitter = fraction
+		return nil
+	}
+}
+
+// WithReadyToTrip sets ReadyToTrip, which must not be nil.
+func WithReadyToTrip(f func(counts Counts) bool) Option {
+	return func(st *Settings) error {
+		if f == nil {
+			return errors.New("nil ReadyToTrip")
+		}
+		st.ReadyToTrip = f
+		return nil
+	}
+}
+
+// WithMinimumRequests sets MinimumRequests, which must be between 0 and math.MaxUint32.
+func WithMinimumRequests(n int) Option {
+	return func(st *Settings) error {
+		if n < 0 || int64(n) > math.MaxUint32 {
+			return fmt.Errorf("MinimumRequests %d out of [0, %d]", n, uint32(math.MaxUint32))
+		}
+		st.MinimumRequests = uint32(n)
+		return nil
+	}
+}
+
+// WithIsSuccessful sets IsSuccessful, which must not be nil.
+func WithIsSuccessful(f func(err error) bool) Option {
+	return func(st *Settings) error {
+		if f == nil {
+			return errors.New("nil IsSuccessful")
+		}
+		st.IsSuccessful = f
+		return nil
+	}
+}
+
+// WithIsSuccessfulResult sets IsSuccessfulResult, which must not be nil.
+func WithIsSuccessfulResult(f func(result interface{}, err error) bool) Option {
+	return func(st *Settings) error {
+		if f == nil {
+			return errors.New("nil IsSuccessfulResult")
+		}
+		st.IsSuccessfulResult = f
+		return nil
+	}
+}
+
+// WithOnStateChange sets OnStateChange, which must not be nil.
+func WithOnStateChange(f func(name string, from State, to State)) Option {
+	return func(st *Settings) error {
+		if f == nil {
+			return errors.New("nil OnStateChange")
+		}
+		st.OnStateChange = f
+		return nil
+	}
+}
+
+// WithMetrics sets Metrics, which must not be nil.
+func WithMetrics(m MetricsSink) Option {
+	return func(st *Settings) error {
+		if m == nil {
+			return errors.New("nil Metrics")
+		}
+		st.Metrics = m
+		return nil
+	}
+}
+
+// WithClock sets Clock, which must not be nil.
+func WithClock(c Clock) Option {
+	return func(st *Settings) error {
+		if c == nil {
+			return errors.New("nil Clock")
+		}
+		st.Clock = c
+		return nil
+	}
+}
+
+// WithBackoff sets Backoff to a copy of p.
+func WithBackoff(p BackoffPolicy) Option {
+	return func(st *Settings) error {
+		st.Backoff = &p
+		return nil
+	}
+}
+
+// WithHalfOpenRamp sets HalfOpenRamp to a copy of p, whose Period must be positive.
+func WithHalfOpenRamp(p RampPolicy) Option {
+	return func(st *Settings) error {
+		if p.Period <= 0 {
+			return fmt.Errorf("HalfOpenRamp.Period %v must be positive", p.Period)
+		}
+		st.HalfOpenRamp = &p
+		return nil
+	}
+}
+
+// WithHalfOpenQueue sets HalfOpenQueue to a copy of p, whose Size must be positive.
+func WithHalfOpenQueue(p QueuePolicy) Option {
+	return func(st *Settings) error {
+		if p.Size <= 0 {
+			return fmt.Errorf("HalfOpenQueue.Size %d must be positive", p.Size)
+		}
+		st.HalfOpenQueue = &p
+		return nil
+	}
+}
+
+// WithRecovery sets Recovery to a copy of p.
+func WithRecovery(p RecoveryPolicy) Option {
+	return func(st *Settings) error {
+		st.Recovery = &p
+		return nil
+	}
+}
+
+// WithEWMA sets EWMA to a copy of p.
+func WithEWMA(p EWMAPolicy) Option {
+	return func(st *Settings) error {
+		st.EWMA = &p
+		return nil
+	}
+}
+
+// WithWindows appends windows to Windows.
+func WithWindows(windows ...DetectionWindow) Option {
+	return func(st *Settings) error {
+		st.Windows = append(st.Windows, windows...)
+		return nil
+	}
+}
+
+// WithErrorBudget sets ErrorBudget to a copy of p.
+func WithErrorBudget(p ErrorBudgetPolicy) Option {
+	return func(st *Settings) error {
+		st.ErrorBudget = &p
+		return nil
+	}
+}
+
+// WithShadow sets Shadow to a copy of candidate.
+func WithShadow(candidate Settings) Option {
+	return func(st *Settings) error {
+		st.Shadow = &candidate
+		return nil
+	}
+}
+
+// WithProbe sets Probe to a copy of p.
+func WithProbe(p ProbePolicy) Option {
+	return func(st *Settings) error {
+		st.Probe = &p
+		return nil
+	}
+}
+
+// WithSchedule sets Schedule.
+func WithSchedule(s Schedule) Option {
+	return func(st *Settings) error {
+		st.Schedule = s
+		return nil
+	}
+}
+
+// WithHistorySize sets HistorySize.
+func WithHistorySize(n int) Option {
+	return func(st *Settings) error {
+		st.HistorySize = n
+		return nil
+	}
+}
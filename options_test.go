@@ -0,0 +1,97 @@
+package gobreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	cb, err := New("new",
+		WithSettings(Settings{Name: "ignored", Interval: time.Minute}),
+		WithMaxRequests(3),
+		WithTimeout(5*time.Second),
+		WithJitter(0.1),
+		WithReadyToTrip(func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 }),
+		WithBackoff(BackoffPolicy{Multiplier: 2}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", cb.Name())
+	assert.Equal(t, uint32(3), cb.maxRequests)
+	assert.Equal(t, time.Minute, cb.interval)
+	assert.Equal(t, 5*time.Second, cb.timeout)
+	assert.Equal(t, 0.1, cb.jitter)
+	assert.Equal(t, 2.0, cb.backoff.Multiplier)
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	cb, err = New("defaults")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), cb.maxRequests)
+	assert.Equal(t, defaultTimeout, cb.timeout)
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, test := range []struct {
+		opt Option
+		err string
+	}{
+		{WithMaxRequests(0), "gobreaker: bad: MaxRequests 0 out of [1, 4294967295]"},
+		{WithMaxRequests(-1), "gobreaker: bad: MaxRequests -1 out of [1, 4294967295]"},
+		{WithMinimumRequests(-1), "gobreaker: bad: MinimumRequests -1 out of [0, 4294967295]"},
+		{WithMaxConcurrent(0), "gobreaker: bad: MaxConcurrent 0 must be positive"},
+		{WithStripes(0), "gobreaker: bad: Stripes 0 must be positive"},
+		{WithInterval(-time.Second), "gobreaker: bad: Interval -1s must be positive"},
+		{WithTimeout(0), "gobreaker: bad: Timeout 0s must be positive"},
+		{WithJitter(1.5), "gobreaker: bad: Jitter 1.5 out of [0, 0.5]"},
+		{WithReadyToTrip(nil), "gobreaker: bad: nil ReadyToTrip"},
+		{WithIsSuccessful(nil), "gobreaker: bad: nil IsSuccessful"},
+		{WithIsSuccessfulResult(nil), "gobreaker: bad: nil IsSuccessfulResult"},
+		{WithOnStateChange(nil), "gobreaker: bad: nil OnStateChange"},
+		{WithMetrics(nil), "gobreaker: bad: nil Metrics"},
+		{WithClock(nil), "gobreaker: bad: nil Clock"},
+		{WithHalfOpenRamp(RampPolicy{}), "gobreaker: bad: HalfOpenRamp.Period 0s must be positive"},
+		{WithHalfOpenQueue(QueuePolicy{}), "gobreaker: bad: HalfOpenQueue.Size 0 must be positive"},
+		{WithBackoff(BackoffPolicy{Jitter: 2}), "gobreaker: bad: Backoff.Jitter 2 out of [0, 0.5]"},
+		{WithProbe(ProbePolicy{Interval: time.Second}), "gobreaker: bad: Probe without a Probe function"},
+		{WithSettings(Settings{Timeout: -time.Second}), "gobreaker: bad: negative Timeout -1s"},
+	} {
+		cb, err := New("bad", test.opt)
+		assert.Nil(t, cb)
+		if assert.Error(t, err) {
+			assert.Equal(t, test.err, err.Error())
+		}
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	assert.Nil(t, Settings{}.Validate())
+	assert.Nil(t, Settings{HistorySize: -1}.Validate())
+	assert.Nil(t, Settings{ErrorBudget: &ErrorBudgetPolicy{}}.Validate())
+
+	probe := func(ctx context.Context) error { return nil }
+	assert.Nil(t, Settings{Probe: &ProbePolicy{Probe: probe}}.Validate())
+	assert.Error(t, Settings{Probe: &ProbePolicy{Probe: probe, Timeout: -time.Second}}.Validate())
+	assert.Error(t, Settings{Interval: -time.Second}.Validate())
+	assert.Error(t, Settings{MaxRequestsRatio: -0.1}.Validate())
+	assert.Error(t, Settings{Jitter: -0.1}.Validate())
+	assert.Error(t, Settings{Jitter: 1}.Validate())
+	assert.Error(t, Settings{CrossGeneration: CrossGenerationSlowFailure + 1}.Validate())
+	assert.Error(t, Settings{Backoff: &BackoffPolicy{Multiplier: -1}}.Validate())
+	assert.Error(t, Settings{HalfOpenRamp: &RampPolicy{Period: time.Second, Steps: []float64{0.5, 0}}}.Validate())
+	assert.Error(t, Settings{HalfOpenQueue: &QueuePolicy{Size: -1}}.Validate())
+	assert.Error(t, Settings{Recovery: &RecoveryPolicy{SuccessRatio: 1.5}}.Validate())
+	assert.Error(t, Settings{EWMA: &EWMAPolicy{FailureRate: 1.5}}.Validate())
+	assert.Error(t, Settings{EWMA: &EWMAPolicy{HalfLife: -time.Second}}.Validate())
+	assert.Error(t, Settings{Windows: []DetectionWindow{{ReadyToTrip: defaultReadyToTrip}}}.Validate())
+	assert.Error(t, Settings{Windows: []DetectionWindow{{Length: time.Second}}}.Validate())
+	assert.Error(t, Settings{ErrorBudget: &ErrorBudgetPolicy{Objective: 1}}.Validate())
+	assert.Error(t, Settings{ErrorBudget: &ErrorBudgetPolicy{Objective: -0.5}}.Validate())
+	assert.Error(t, Settings{Shadow: &Settings{Timeout: -time.Second}}.Validate())
+	assert.Error(t, Settings{ErrorBudget: &ErrorBudgetPolicy{Objective: 0.99, Windows: []BurnRateWindow{{Long: time.Hour}}}}.Validate())
+	assert.Error(t, Settings{Schedule: Schedule{{Window: Window{Start: 25 * time.Hour}}}}.Validate())
+	assert.Error(t, Settings{Schedule: Schedule{{Policy: Policy{Timeout: -1}}}}.Validate())
+}
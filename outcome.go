@@ -0,0 +1,181 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Outcome is the classification of the result of a request.
+type Outcome int
+
+const (
+	// OutcomeSuccess counts the request as a success.
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure counts the request as a failure.
+	OutcomeFailure
+	// OutcomeIgnore doesn't count the request at all, e.g. for a business error
+	// that says nothing about the health of the dependency.
+	OutcomeIgnore
+)
+
+// String implements stringer interface.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeIgnore:
+		return "ignore"
+	default:
+		return fmt.Sprintf("unknown outcome: %d", o)
+	}
+}
+
+func outcomeOf(success bool) Outcome {
+	if success {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
+
+// classify returns the Outcome of a request that returned result and err.
+func (cb *CircuitBreaker) classify(result interface{}, err error) Outcome {
+	if err != nil {
+		var ignored *ignoredError
+		if errors.As(err, &ignored) {
+			return OutcomeIgnore
+		}
+		if matchError(err, cb.failureErrors) {
+			return OutcomeFailure
+		}
+		if matchError(err, cb.ignoredErrors) {
+			return OutcomeIgnore
+		}
+		if cb.classifier != nil {
+			return cb.classifier(err)
+		}
+	}
+	if cb.isSuccessfulResult != nil {
+		return outcomeOf(cb.isSuccessfulResult(result, err))
+	}
+	return outcomeOf(cb.isSuccessful(err))
+}
+
+// ignoredError wraps an error of ExecuteClassified that doesn't count as a failure.
+type ignoredError struct {
+	err error
+}
+
+func (e *ignoredError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ignoredError) Unwrap() error {
+	return e.err
+}
+
+// ExecuteClassified runs req like ExecuteContext, for the adapters of clients that tell the failures
+// of a dependency from the other errors, e.g. a request canceled by its caller.
+// An error of req that isFailure reports as a failure is counted as such. The other errors
+// are ignored, see OutcomeIgnore: they count neither as a failure nor as a success,
+// so that they can't close a half-open CircuitBreaker. If isFailure is nil, all the errors are counted.
+// ExecuteClassified returns the error of req, or the rejection if req wasn't run.
+func (cb *CircuitBreaker) ExecuteClassified(ctx context.Context, req func(ctx context.Context) error, isFailure func(err error) bool) error {
+	ran := false
+	var reqErr error
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		ran = true
+		reqErr = req(ctx)
+		if reqErr != nil && isFailure != nil && !isFailure(reqErr) {
+			return nil, &ignoredError{err: reqErr}
+		}
+		return nil, reqErr
+	})
+	if ran && reqErr != nil {
+		return reqErr
+	}
+	return err
+}
+
+// errorType matches the errors of a type, see ErrorType.
+type errorType struct {
+	typ reflect.Type
+}
+
+// ErrorType returns an error for IgnoredErrors and FailureErrors that matches,
+// with errors.As, any error of the same type as example, e.g. ErrorType(&net.OpError{}).
+func ErrorType(example error) error {
+	return errorType{typ: reflect.TypeOf(example)}
+}
+
+func (e errorType) Error() string {
+	return fmt.Sprintf("error of type %v", e.typ)
+}
+
+func (e errorType) match(err error) bool {
+	if e.typ == nil {
+		return false
+	}
+	return errors.As(err, reflect.New(e.typ).Interface())
+}
+
+// matchError reports whether err matches one of targets with errors.Is,
+// or with errors.As for the targets returned by ErrorType.
+func matchError(err error, targets []error) bool {
+	for _, target := range targets {
+		if t, ok := target.(errorType); ok {
+			if t.match(err) {
+				return true
+			}
+		} else if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishRequest reports the outcome of a request admitted in the generation before.
+// f describes the request if it failed.
+func (cb *CircuitBreaker) finishRequest(ctx context.Context, before uint64, start time.Time, outcome Outcome, f failure) {
+	cb.observeShadow(ctx, start, outcome, f)
+	if outcome == OutcomeIgnore {
+		cb.ignoreRequest(before)
+		return
+	}
+	cb.afterRequest(ctx, before, start, outcome == OutcomeSuccess, f)
+}
+
+// finishResult reports the outcome of a request that returned result and err.
+func (cb *CircuitBreaker) finishResult(ctx context.Context, before uint64, start time.Time, result interface{}, err error) {
+	outcome := cb.classify(result, err)
+	cb.finishRequest(ctx, before, start, outcome, cb.failureOf(outcome, err))
+}
+
+// ignoreRequest releases a request admitted in the generation before without counting it,
+// giving back its half-open slot.
+func (cb *CircuitBreaker) ignoreRequest(before uint64) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.finishInflight()
+	now := cb.clock.Now()
+	state, generation := cb.currentState(now)
+	if generation != before || cb.counts.Requests == 0 {
+		return
+	}
+	switch state {
+	case StateClosed, StateForcedClosed:
+		cb.counts.Requests--
+	case StateHalfOpen:
+		cb.counts.Requests--
+		if cb.ramp == nil && cb.recovery != nil {
+			cb.judgeProbes(now)
+		} else {
+			cb.wakeWaiters()
+		}
+	}
+}
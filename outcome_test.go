@@ -0,0 +1,186 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errNotFound = errors.New("not found")
+
+func classifyNotFound(err error) Outcome {
+	if err == errNotFound {
+		return OutcomeIgnore
+	}
+	return OutcomeFailure
+}
+
+func notFound() (interface{}, error) {
+	return nil, errNotFound
+}
+
+func TestClassify(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Classify: classifyNotFound})
+
+	assert.Nil(t, succeed(cb))
+	_, err := cb.Execute(notFound)
+	assert.Equal(t, errNotFound, err)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+
+	done, err := (&TwoStepCircuitBreaker{cb}).AllowE()
+	assert.Nil(t, err)
+	done(errNotFound)
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+
+	child := cb.NewChild(ChildSettings{})
+	_, err = child.Execute(notFound)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+	assert.Equal(t, 0, cb.inflight)
+}
+
+func TestClassifyHalfOpen(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{Classify: classifyNotFound})
+
+	// an ignored probe gives its slot back
+	_, err := cb.Execute(notFound)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestExecuteClassified(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{})
+	isFailure := func(err error) bool { return !errors.Is(err, context.Canceled) }
+	run := func(err error) error {
+		return cb.ExecuteClassified(context.Background(), func(ctx context.Context) error { return err }, isFailure)
+	}
+
+	// an error that isn't a failure neither closes nor reopens the breaker
+	canceled := fmt.Errorf("dial: %w", context.Canceled)
+	assert.Equal(t, canceled, run(canceled))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	assert.Equal(t, errNotFound, run(errNotFound))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.True(t, IsRejection(run(nil)))
+}
+
+func TestOutcomeString(t *testing.T) {
+	assert.Equal(t, "success", OutcomeSuccess.String())
+	assert.Equal(t, "failure", OutcomeFailure.String())
+	assert.Equal(t, "ignore", OutcomeIgnore.String())
+	assert.Equal(t, "unknown outcome: 9", Outcome(9).String())
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+func TestErrorLists(t *testing.T) {
+	errFatal := errors.New("fatal")
+	cb := NewCircuitBreaker(Settings{
+		FailureErrors: []error{errFatal},
+		IgnoredErrors: []error{errNotFound, ErrorType(&statusError{})},
+		IsSuccessful:  func(err error) bool { return true },
+	})
+
+	assert.Equal(t, OutcomeFailure, cb.classify(nil, errFatal))
+	assert.Equal(t, OutcomeFailure, cb.classify(nil, fmt.Errorf("wrapped: %w", errFatal)))
+	assert.Equal(t, OutcomeIgnore, cb.classify(nil, errNotFound))
+	assert.Equal(t, OutcomeIgnore, cb.classify(nil, fmt.Errorf("wrapped: %w", &statusError{404})))
+	assert.Equal(t, OutcomeSuccess, cb.classify(nil, errors.New("other")))
+	assert.Equal(t, OutcomeSuccess, cb.classify(nil, nil))
+
+	// the lists come before Classify
+	cb = NewCircuitBreaker(Settings{
+		FailureErrors: []error{ErrorType(&statusError{})},
+		Classify:      func(err error) Outcome { return OutcomeIgnore },
+	})
+	assert.Equal(t, OutcomeFailure, cb.classify(nil, &statusError{500}))
+	assert.Equal(t, OutcomeIgnore, cb.classify(nil, errNotFound))
+
+	assert.Equal(t, "error of type *gobreaker.statusError", ErrorType(&statusError{}).Error())
+	assert.False(t, matchError(errNotFound, []error{ErrorType(nil)}))
+}
+
+func TestFailureWeight(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	var weighed []error
+	cb := NewCircuitBreaker(Settings{
+		FailureWeight: func(err error) uint32 {
+			weighed = append(weighed, err)
+			if err == errTimeout {
+				return 3
+			}
+			return 0
+		},
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailureWeight >= 5 },
+	})
+
+	timeout := func() (interface{}, error) { return nil, errTimeout }
+	_, err := cb.Execute(timeout)
+	assert.Equal(t, errTimeout, err)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, Counts{2, 0, 2, 0, 2, 4, 4, FailureCounts{2}}, cb.Counts())
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, Counts{3, 1, 2, 1, 0, 4, 0, FailureCounts{2}}, cb.Counts())
+	assert.Len(t, weighed, 2) // successes are not weighed
+
+	done, err := (&TwoStepCircuitBreaker{cb}).AllowE()
+	assert.Nil(t, err)
+	done(errTimeout)
+	assert.Equal(t, StateClosed, cb.State())
+	_, err = cb.Execute(timeout)
+	assert.Equal(t, errTimeout, err)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestIsSuccessfulResult(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		IsSuccessfulResult: func(result interface{}, err error) bool {
+			return err == nil && result != 500
+		},
+	})
+
+	status := func(code int) func() (interface{}, error) {
+		return func() (interface{}, error) { return code, nil }
+	}
+	result, err := cb.Execute(status(500))
+	assert.Equal(t, 500, result)
+	assert.Nil(t, err)
+	_, err = cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return status(500)()
+	})
+	assert.Nil(t, err)
+	_, err = cb.NewChild(ChildSettings{}).Execute(status(500))
+	assert.Nil(t, err)
+	assert.Equal(t, Counts{3, 0, 3, 0, 3, 3, 3, FailureCounts{3}}, cb.Counts())
+
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	_, err = cb.Execute(status(200))
+	assert.Nil(t, err)
+	assert.Equal(t, Counts{6, 2, 4, 1, 0, 4, 0, FailureCounts{4}}, cb.Counts())
+
+	// IsSuccessfulResult replaces IsSuccessful
+	cb = NewCircuitBreaker(Settings{
+		IsSuccessful:       func(err error) bool { return false },
+		IsSuccessfulResult: func(result interface{}, err error) bool { return true },
+	})
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+}
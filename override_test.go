@@ -0,0 +1,91 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdministrativeStateConstants(t *testing.T) {
+	assert.Equal(t, State(3), StateForcedOpen)
+	assert.Equal(t, State(4), StateForcedClosed)
+	assert.Equal(t, State(5), StateDisabled)
+
+	assert.Equal(t, "forced-open", StateForcedOpen.String())
+	assert.Equal(t, "forced-closed", StateForcedClosed.String())
+	assert.Equal(t, "disabled", StateDisabled.String())
+}
+
+func TestForceOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	cb.ForceOpen()
+	assert.Equal(t, StateForcedOpen, cb.State())
+	assert.True(t, cb.expiry.IsZero())
+	assert.True(t, errors.Is(succeed(cb), ErrOpenState))
+
+	pseudoSleep(cb, time.Duration(24)*time.Hour)
+	assert.Equal(t, StateForcedOpen, cb.State())
+
+	cb.ClearOverride()
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, succeed(cb))
+}
+
+func TestForceClose(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	cb.ForceClose()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateForcedClosed, cb.State())
+	assert.Equal(t, Counts{11, 1, 10, 1, 0, 10, 0, FailureCounts{10}}, cb.Counts())
+
+	cb.ClearOverride()
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.Counts())
+}
+
+func TestDisable(t *testing.T) {
+	sink := new(RecordingMetricsSink)
+	cb := NewCircuitBreaker(Settings{Metrics: sink})
+	cb.Disable()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateDisabled, cb.State())
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, cb.Counts())
+	assert.Equal(t, 0, sink.Requests())
+	assert.Equal(t, 0, sink.Failures())
+
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+	tscb.cb.Disable()
+	assert.Nil(t, fail2Step(tscb))
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, FailureCounts{}}, tscb.Counts())
+}
+
+func TestClearOverrideAutomaticState(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	cb.Trip()
+	cb.ClearOverride() // not an administrative state
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestSnapshotAdministrativeState(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	cb.ForceOpen()
+
+	data, err := json.Marshal(cb.Snapshot())
+	assert.Nil(t, err)
+
+	var s Snapshot
+	assert.Nil(t, json.Unmarshal(data, &s))
+	assert.Equal(t, StateForcedOpen, s.State)
+
+	restored := NewCircuitBreaker(Settings{})
+	assert.Nil(t, restored.Restore(s))
+	assert.Equal(t, StateForcedOpen, restored.State())
+}
@@ -0,0 +1,37 @@
+package gobreaker
+
+import "fmt"
+
+// PanicError is a panic recovered from a request, see RecoverPanics.
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RecoverPanics is a PanicHandler that returns the panic as a *PanicError.
+func RecoverPanics(v interface{}) error {
+	return &PanicError{Value: v}
+}
+
+// recoverPanic classifies a request that panicked with v.
+// It returns a nil error if the panic must be raised again, i.e. if there is no PanicHandler.
+// Otherwise it returns the error of the PanicHandler, which the request fails with.
+func (cb *CircuitBreaker) recoverPanic(v interface{}) (Outcome, failure, error) {
+	if cb.panicHandler == nil {
+		f := panicFailure
+		f.err = &PanicError{Value: v}
+		return OutcomeFailure, f, nil
+	}
+
+	err := cb.panicHandler(v)
+	if err == nil {
+		err = &PanicError{Value: v}
+	}
+	outcome := cb.classify(nil, err)
+	f := cb.failureOf(outcome, err)
+	f.kind = FailurePanic
+	return outcome, f, err
+}
@@ -0,0 +1,52 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errPanicked = errors.New("panicked")
+
+func TestPanicHandler(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{PanicHandler: RecoverPanics})
+
+	result, err := cb.Execute(func() (interface{}, error) { panic("oops") })
+	assert.Nil(t, result)
+	assert.Equal(t, &PanicError{Value: "oops"}, err)
+	assert.Equal(t, "panic: oops", err.Error())
+
+	_, err = cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) { panic("oops") })
+	assert.Error(t, err)
+	_, err = cb.ExecuteIdempotent("", func() (interface{}, error) { panic("oops") })
+	assert.Error(t, err)
+	_, err = cb.NewChild(ChildSettings{}).Execute(func() (interface{}, error) { panic("oops") })
+	assert.Error(t, err)
+	_, err = cb.ExecuteHedged(context.Background(), time.Hour, func(ctx context.Context) (interface{}, error) { panic("oops") })
+	assert.Error(t, err)
+	assert.Equal(t, Counts{5, 0, 5, 0, 5, 5, 5, FailureCounts{FailurePanic: 5}}, cb.Counts())
+	assert.Equal(t, 0, cb.inflight)
+}
+
+func TestPanicHandlerClassify(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		PanicHandler:  func(v interface{}) error { return errPanicked },
+		IgnoredErrors: []error{errPanicked},
+	})
+	_, err := cb.Execute(func() (interface{}, error) { panic("oops") })
+	assert.Equal(t, errPanicked, err)
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	// a nil error is replaced with a *PanicError
+	cb = NewCircuitBreaker(Settings{PanicHandler: func(v interface{}) error { return nil }})
+	_, err = cb.Execute(func() (interface{}, error) { panic("oops") })
+	assert.Equal(t, &PanicError{Value: "oops"}, err)
+	assert.Equal(t, FailureCounts{FailurePanic: 1}, cb.Counts().FailuresByKind)
+}
+
+func TestDefaultFailureKindPanic(t *testing.T) {
+	assert.Equal(t, FailurePanic, DefaultFailureKind(&PanicError{Value: "oops"}))
+}
@@ -0,0 +1,101 @@
+package gobreaker
+
+import "time"
+
+// Policy holds the thresholds of a CircuitBreaker that may change while it is running.
+// The fields have the same meaning and defaults as the fields of Settings with the same names.
+type Policy struct {
+	MaxRequests uint32
+	Interval    time.Duration
+	Timeout     time.Duration
+	ReadyToTrip func(counts Counts) bool
+
+	MinimumRequests uint32
+}
+
+func (st Settings) policy() Policy {
+	return Policy{
+		MaxRequests: st.MaxRequests,
+		Interval:    st.Interval,
+		Timeout:     st.Timeout,
+		ReadyToTrip: st.ReadyToTrip,
+
+		MinimumRequests: st.MinimumRequests,
+	}
+}
+
+// applyPolicy replaces the thresholds of the CircuitBreaker with p.
+// The new Interval and Timeout take effect from the next generation.
+func (cb *CircuitBreaker) applyPolicy(p Policy) {
+	if p.MaxRequests == 0 {
+		cb.maxRequests = 1
+	} else {
+		cb.maxRequests = p.MaxRequests
+	}
+
+	if p.Interval <= 0 {
+		cb.interval = defaultInterval
+	} else {
+		cb.interval = p.Interval
+	}
+
+	if p.Timeout <= 0 {
+		cb.timeout = defaultTimeout
+	} else {
+		cb.timeout = p.Timeout
+	}
+
+	if p.ReadyToTrip == nil {
+		cb.readyToTrip = defaultReadyToTrip
+	} else {
+		cb.readyToTrip = p.ReadyToTrip
+	}
+	cb.minimumRequests = p.MinimumRequests
+}
+
+// UpdateSettings replaces MaxRequests, Interval, Timeout, ReadyToTrip and MinimumRequests of the running CircuitBreaker
+// with those of st, without resetting its state and Counts. The other fields of st are ignored.
+// The new Interval and Timeout take effect from the next generation.
+// If a ScheduleRule is active, st replaces the thresholds used outside the Windows of the Schedule.
+func (cb *CircuitBreaker) UpdateSettings(st Settings) {
+	cb.UpdatePolicy(func(Policy) Policy { return st.policy() })
+}
+
+// Policy returns the thresholds of the CircuitBreaker set by Settings or UpdateSettings,
+// those used outside the Windows of the Schedule, if any.
+func (cb *CircuitBreaker) Policy() Policy {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.basePolicy
+}
+
+// UpdatePolicy replaces the thresholds of the running CircuitBreaker with the result of update
+// applied to its current Policy, like UpdateSettings. Since update is called while
+// the CircuitBreaker holds its internal lock, concurrent updates of different fields don't
+// overwrite each other, but update must not call the CircuitBreaker.
+func (cb *CircuitBreaker) UpdatePolicy(update func(p Policy) Policy) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	p := update(cb.basePolicy)
+	cb.basePolicy = p
+	if len(cb.schedule) == 0 || cb.activeRule < 0 {
+		cb.applyPolicy(p)
+	}
+}
+
+// UpdateSettings replaces the thresholds of the running TwoStepCircuitBreaker, like CircuitBreaker.UpdateSettings.
+func (tscb *TwoStepCircuitBreaker) UpdateSettings(st Settings) {
+	tscb.cb.UpdateSettings(st)
+}
+
+// Policy returns the thresholds of the TwoStepCircuitBreaker, like CircuitBreaker.Policy.
+func (tscb *TwoStepCircuitBreaker) Policy() Policy {
+	return tscb.cb.Policy()
+}
+
+// UpdatePolicy updates the thresholds of the running TwoStepCircuitBreaker, like CircuitBreaker.UpdatePolicy.
+func (tscb *TwoStepCircuitBreaker) UpdatePolicy(update func(p Policy) Policy) {
+	tscb.cb.UpdatePolicy(update)
+}
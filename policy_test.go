@@ -0,0 +1,90 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateSettings(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:    clockFunc(func() time.Time { return now }),
+		Interval: time.Minute,
+		Timeout:  time.Minute,
+	})
+
+	assert.Nil(t, fail(cb))
+	cb.UpdateSettings(Settings{
+		Name:        "ignored",
+		MaxRequests: 2,
+		Interval:    10 * time.Second,
+		Timeout:     5 * time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	assert.Equal(t, "", cb.Name())
+	assert.Equal(t, uint32(2), cb.maxRequests)
+
+	// the counts survive and the current generation keeps its interval
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+	assert.Equal(t, now.Add(time.Minute), cb.expiry)
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, 5*time.Second, cb.expiry.Sub(now))
+
+	now = cb.expiry.Add(time.Nanosecond)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, now.Add(10*time.Second), cb.expiry)
+
+	// zero values fall back to the defaults
+	(&TwoStepCircuitBreaker{cb}).UpdateSettings(Settings{})
+	assert.Equal(t, uint32(1), cb.maxRequests)
+	assert.Equal(t, defaultInterval, cb.interval)
+	assert.Equal(t, defaultTimeout, cb.timeout)
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	readyToTrip := func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 }
+	cb := NewCircuitBreaker(Settings{Interval: time.Minute, ReadyToTrip: readyToTrip})
+	assert.Equal(t, time.Minute, cb.Policy().Interval)
+
+	(&TwoStepCircuitBreaker{cb}).UpdatePolicy(func(p Policy) Policy {
+		p.MaxRequests = 3
+		return p
+	})
+	p := cb.Policy()
+	assert.Equal(t, uint32(3), p.MaxRequests)
+	assert.Equal(t, time.Minute, p.Interval)
+	assert.Equal(t, uint32(3), cb.maxRequests)
+	assert.Equal(t, time.Minute, cb.interval)
+
+	// the custom ReadyToTrip is kept
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestUpdateSettingsSchedule(t *testing.T) {
+	business := MustParseWindow("Mon-Fri 09:00-18:00")
+	business.Location = time.UTC
+
+	now := time.Date(2020, 1, 6, 12, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:    clockFunc(func() time.Time { return now }),
+		Timeout:  time.Minute,
+		Schedule: Schedule{{Window: business, Policy: Policy{Timeout: 10 * time.Second}}},
+	})
+	assert.Equal(t, 10*time.Second, cb.timeout)
+
+	// the active rule wins until its window ends
+	cb.UpdateSettings(Settings{Timeout: 30 * time.Second})
+	assert.Equal(t, 10*time.Second, cb.timeout)
+
+	now = time.Date(2020, 1, 6, 18, 0, 0, 0, time.UTC)
+	cb.State()
+	assert.Equal(t, 30*time.Second, cb.timeout)
+}
@@ -0,0 +1,86 @@
+// Package poolbreaker lets connection pools, such as the pool of database/sql or a custom one,
+// report their failures into the circuit breakers of gobreaker, and consult them before creating
+// new connections, so that a pool doesn't exhaust itself dialing a failing backend during an outage.
+package poolbreaker
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/sony/gobreaker"
+)
+
+// Hooks are the hooks of a connection pool into the Breaker of its backend.
+//
+// The pool creates its new connections with Dial, which counts them as requests of the Breaker,
+// and reports the failures to check out a connection and the errors of the pooled connections
+// with CheckoutFailed and ConnError, see gobreaker.CircuitBreaker.Report.
+// A pool that creates its connections on its own can ask Allow first.
+type Hooks struct {
+	// Breaker is the Breaker of the backend.
+	Breaker gobreaker.Reporter
+	// IsConnError reports whether an error of a pooled connection counts as a failure, e.g. only
+	// driver.ErrBadConn or the network errors, but not the errors of the queries.
+	// If IsConnError is nil, every error reported by ConnError counts.
+	IsConnError func(err error) bool
+}
+
+// New returns the Hooks of a pool into cb.
+func New(cb gobreaker.Reporter) *Hooks {
+	return &Hooks{Breaker: cb}
+}
+
+// Allow returns a *gobreaker.RejectionError if the Breaker is open, so that no new connection
+// should be created, and nil otherwise. Allow doesn't count as a request of the Breaker.
+func (h *Hooks) Allow() error {
+	return h.Breaker.Check()
+}
+
+// Dial creates a new connection with dial if the Breaker allows it. dial counts as a request
+// of the Breaker, which fails if dial returns an error. Dial returns the error of dial
+// or the rejection of the Breaker.
+func (h *Hooks) Dial(dial func() error) error {
+	_, err := h.Breaker.Execute(func() (interface{}, error) {
+		return nil, dial()
+	})
+	return err
+}
+
+// CheckoutFailed reports that the pool failed to hand out a connection, e.g. because
+// it timed out waiting for a free one, which counts as a failure of the Breaker.
+func (h *Hooks) CheckoutFailed(err error) {
+	if err != nil {
+		h.Breaker.Report(err)
+	}
+}
+
+// ConnError reports an error of a pooled connection, which counts as a failure of the Breaker
+// if IsConnError accepts it.
+func (h *Hooks) ConnError(err error) {
+	if err != nil && (h.IsConnError == nil || h.IsConnError(err)) {
+		h.Breaker.Report(err)
+	}
+}
+
+// Connector returns a driver.Connector that creates the connections of c through hooks,
+// so that the pool of a sql.DB opened with sql.OpenDB stops creating connections to a backend
+// whose Breaker is open, failing fast with the rejection instead.
+func Connector(c driver.Connector, hooks *Hooks) driver.Connector {
+	return &connector{Connector: c, hooks: hooks}
+}
+
+type connector struct {
+	driver.Connector
+	hooks *Hooks
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	err := c.hooks.Dial(func() error {
+		var err error
+		conn, err = c.Connector.Connect(ctx)
+		return err
+	})
+	return conn, err
+}
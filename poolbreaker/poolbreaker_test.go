@@ -0,0 +1,73 @@
+package poolbreaker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBreaker() *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "db",
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+}
+
+func TestHooks(t *testing.T) {
+	cb := newBreaker()
+	h := New(cb)
+	h.IsConnError = func(err error) bool { return err == driver.ErrBadConn }
+
+	assert.Nil(t, h.Allow())
+	assert.Nil(t, h.Dial(func() error { return nil }))
+	h.ConnError(errors.New("syntax error"))
+	h.ConnError(nil)
+	h.CheckoutFailed(nil)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+
+	h.ConnError(driver.ErrBadConn)
+	h.CheckoutFailed(context.DeadlineExceeded)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+	err := h.Allow()
+	var rejection *gobreaker.RejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.True(t, rejection.RetryAfter > 0)
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	dialed := false
+	err = h.Dial(func() error { dialed = true; return nil })
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.False(t, dialed)
+}
+
+type fakeConnector struct {
+	err   error
+	dials int
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.dials++
+	return nil, c.err
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestConnector(t *testing.T) {
+	c := &fakeConnector{err: errors.New("connection refused")}
+	db := sql.OpenDB(Connector(c, New(newBreaker())))
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		assert.EqualError(t, db.PingContext(context.Background()), "connection refused")
+	}
+	err := db.PingContext(context.Background())
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.Equal(t, 2, c.dials)
+}
@@ -0,0 +1,74 @@
+package gobreaker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Priority is the criticality of a request, carried by its context.
+// When a CircuitBreaker degrades, it sheds the requests of lower Priority first.
+type Priority int
+
+const (
+	// PrioritySheddable is for requests whose failure is acceptable, e.g. batch jobs and prefetches.
+	PrioritySheddable Priority = iota - 2
+	// PrioritySheddablePlus is for requests that may fail occasionally, e.g. retried background work.
+	PrioritySheddablePlus
+	// PriorityCritical is the Priority of the requests whose context carries none.
+	PriorityCritical
+	// PriorityCriticalPlus is for the requests that matter most, e.g. those serving users directly.
+	PriorityCriticalPlus
+)
+
+// String implements stringer interface.
+func (p Priority) String() string {
+	switch p {
+	case PrioritySheddable:
+		return "sheddable"
+	case PrioritySheddablePlus:
+		return "sheddable-plus"
+	case PriorityCritical:
+		return "critical"
+	case PriorityCriticalPlus:
+		return "critical-plus"
+	default:
+		return fmt.Sprintf("unknown priority: %d", p)
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that carries p, for ExecuteContext.
+//
+// In the half-open state, a request of lower Priority than PriorityCritical is rejected with
+// ErrTooManyRequests, so that the probes are spent on the critical requests, unless the
+// CircuitBreaker has been half-open for longer than its Timeout, so that a dependency used only
+// by sheddable requests still recovers.
+//
+// With a ThrottlePolicy, the K of a request is doubled for each step of Priority above
+// PriorityCritical and halved for each step below, so that the sheddable requests are throttled
+// as soon as the dependency degrades and the critical ones only when it degrades further.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the Priority carried by ctx, or PriorityCritical if it carries none.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityCritical
+}
+
+// shedHalfOpen reports whether the half-open CircuitBreaker sheds a request of ctx at now.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) shedHalfOpen(ctx context.Context, now time.Time) bool {
+	return PriorityFrom(ctx) < PriorityCritical && now.Sub(cb.stateSince) <= cb.timeout
+}
+
+// throttleK returns the K of a ThrottlePolicy for a request of Priority p.
+func throttleK(k float64, p Priority) float64 {
+	return k * math.Pow(2, float64(p-PriorityCritical))
+}
@@ -0,0 +1,65 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	assert.Equal(t, "sheddable", PrioritySheddable.String())
+	assert.Equal(t, "sheddable-plus", PrioritySheddablePlus.String())
+	assert.Equal(t, "critical", PriorityCritical.String())
+	assert.Equal(t, "critical-plus", PriorityCriticalPlus.String())
+	assert.Equal(t, "unknown priority: 5", Priority(5).String())
+
+	ctx := context.Background()
+	assert.Equal(t, PriorityCritical, PriorityFrom(ctx))
+	assert.Equal(t, PrioritySheddable, PriorityFrom(WithPriority(ctx, PrioritySheddable)))
+}
+
+func TestPriorityHalfOpen(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Clock: clock, MaxRequests: 2})
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	clock.Advance(61 * time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	sheddable := WithPriority(context.Background(), PrioritySheddablePlus)
+	run := func(ctx context.Context) error {
+		_, err := cb.ExecuteContext(ctx, func(context.Context) (interface{}, error) { return nil, nil })
+		return err
+	}
+	assert.True(t, errors.Is(run(sheddable), ErrTooManyRequests))
+	assert.Equal(t, uint32(0), cb.Counts().Requests)
+
+	assert.NoError(t, run(WithPriority(context.Background(), PriorityCriticalPlus)))
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+
+	// a dependency used only by sheddable requests still recovers
+	clock.Advance(61 * time.Second)
+	assert.NoError(t, run(sheddable))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.NoError(t, run(sheddable))
+}
+
+func TestPriorityThrottle(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	th := newThrottle(&ThrottlePolicy{K: 2, Window: 10 * time.Second})
+	for i := 0; i < 20; i++ {
+		th.allow(now, PriorityCritical)
+		if i < 6 {
+			th.onSuccess(now)
+		}
+	}
+	// 20 requests, 6 accepts
+	assert.Equal(t, 17.0/21, th.probability(now, PrioritySheddable))
+	assert.Equal(t, 14.0/21, th.probability(now, PrioritySheddablePlus))
+	assert.Equal(t, 8.0/21, th.probability(now, PriorityCritical))
+	assert.Equal(t, 0.0, th.probability(now, PriorityCriticalPlus))
+}
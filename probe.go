@@ -0,0 +1,98 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+const defaultProbeInterval = time.Second
+
+// ProbePolicy runs a health check periodically while the CircuitBreaker is open,
+// so that the recovery of the dependency is detected without sacrificing user requests.
+//
+// Probe checks the health of the dependency. A nil error means the dependency is healthy.
+//
+// Interval is the period between probes. The first probe runs Interval after the CircuitBreaker trips.
+// If Interval is less than or equal to 0, the probe runs every second.
+//
+// Timeout limits the duration of a probe through its context.
+// If Timeout is less than or equal to 0, Interval is used.
+//
+// Successes is the number of consecutive successful probes after which the CircuitBreaker
+// leaves the open state before its Timeout expires. If Successes is 0, one success is enough.
+//
+// If Close is true, the CircuitBreaker becomes closed after the successful probes.
+// Otherwise it becomes half-open and admits MaxRequests requests as usual.
+type ProbePolicy struct {
+	Probe     func(ctx context.Context) error
+	Interval  time.Duration
+	Timeout   time.Duration
+	Successes uint32
+	Close     bool
+}
+
+// startProbe starts probing the dependency while the CircuitBreaker stays in the
+// open state of the current generation. It must be called with cb.mutex held.
+func (cb *CircuitBreaker) startProbe() {
+	if cb.probe == nil || cb.state != StateOpen {
+		return
+	}
+	go cb.runProbe(cb.generation)
+}
+
+func (cb *CircuitBreaker) runProbe(generation uint64) {
+	p := cb.probe
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = interval
+	}
+	required := p.Successes
+	if required == 0 {
+		required = 1
+	}
+	next := StateHalfOpen
+	if p.Close {
+		next = StateClosed
+	}
+
+	var successes uint32
+	for {
+		<-cb.newTimer(interval).C()
+
+		cb.mutex.Lock()
+		open := cb.probing(generation)
+		cb.mutex.Unlock()
+		if !open {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := p.Probe(ctx)
+		cancel()
+		if err != nil {
+			successes = 0
+			continue
+		}
+
+		successes++
+		if successes >= required {
+			cb.mutex.Lock()
+			if cb.probing(generation) {
+				cb.setState(next, cb.clock.Now(), ReasonHealthCheck)
+			}
+			cb.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// probing reports whether the CircuitBreaker is still open in the given generation.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) probing(generation uint64) bool {
+	state, current := cb.currentState(cb.clock.Now())
+	return state == StateOpen && current == generation
+}
@@ -0,0 +1,107 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProbeCB(probe ProbePolicy) (*CircuitBreaker, <-chan State) {
+	changes := make(chan State, 10)
+	cb := NewCircuitBreaker(Settings{
+		Timeout: time.Hour,
+		Probe:   &probe,
+		OnStateChange: func(name string, from State, to State) {
+			changes <- to
+		},
+	})
+	return cb, changes
+}
+
+func nextState(t *testing.T, changes <-chan State) State {
+	select {
+	case state := <-changes:
+		return state
+	case <-time.After(time.Second):
+		t.Fatal("no state change")
+		return StateClosed
+	}
+}
+
+func TestProbeHalfOpen(t *testing.T) {
+	var calls int32
+	cb, changes := newProbeCB(ProbePolicy{
+		Interval:  time.Millisecond,
+		Successes: 3,
+		Probe: func(ctx context.Context) error {
+			// the second probe fails, so that three more successes are needed
+			if atomic.AddInt32(&calls, 1) == 2 {
+				return errors.New("still down")
+			}
+			return nil
+		},
+	})
+
+	cb.Trip()
+	assert.Equal(t, StateOpen, nextState(t, changes))
+	assert.Equal(t, StateHalfOpen, nextState(t, changes))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestProbeClose(t *testing.T) {
+	cb, changes := newProbeCB(ProbePolicy{
+		Interval: time.Millisecond,
+		Close:    true,
+		Probe: func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			if !ok {
+				return errors.New("no timeout")
+			}
+			return nil
+		},
+	})
+
+	cb.Trip()
+	assert.Equal(t, StateOpen, nextState(t, changes))
+	assert.Equal(t, StateClosed, nextState(t, changes))
+}
+
+func TestProbeStops(t *testing.T) {
+	var calls int32
+	cb, changes := newProbeCB(ProbePolicy{
+		Interval: time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return errors.New("down")
+		},
+	})
+
+	cb.Trip()
+	assert.Equal(t, StateOpen, nextState(t, changes))
+	time.Sleep(10 * time.Millisecond)
+	cb.Reset()
+	assert.Equal(t, StateClosed, nextState(t, changes))
+
+	// the probe stops once the CircuitBreaker leaves the open state
+	time.Sleep(5 * time.Millisecond)
+	n := atomic.LoadInt32(&calls)
+	assert.True(t, n > 0)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&calls))
+}
+
+func TestProbeNotForcedOpen(t *testing.T) {
+	cb, changes := newProbeCB(ProbePolicy{
+		Interval: time.Millisecond,
+		Probe:    func(ctx context.Context) error { return nil },
+	})
+
+	cb.ForceOpen()
+	assert.Equal(t, StateForcedOpen, nextState(t, changes))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StateForcedOpen, cb.State())
+}
@@ -0,0 +1,78 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// QueuePolicy lets requests that arrive while the CircuitBreaker is half-open and
+// has already admitted MaxRequests probes wait for the outcome of the probes.
+// When the CircuitBreaker becomes closed, the waiting requests proceed; when it becomes
+// open again, they are rejected with ErrOpenState. This avoids spurious errors for
+// idempotent, low-latency requests during recovery.
+//
+// Size is the maximum number of waiting requests.
+// Further requests are rejected immediately with ErrTooManyRequests.
+// If Size is less than or equal to 0, no request waits.
+//
+// Timeout is the maximum time a request waits before it is rejected with ErrTooManyRequests.
+// If Timeout is less than or equal to 0, a request waits until the state changes.
+//
+// A request passed to ExecuteContext also stops waiting when its context is done,
+// in which case the error of the context is returned.
+type QueuePolicy struct {
+	Size    int
+	Timeout time.Duration
+}
+
+// waitHalfOpen waits for the next state change of the half-open CircuitBreaker.
+// It must be called with cb.mutex held, which it releases while waiting.
+// The deadline of the request is set on its first wait and kept across waits.
+// waitHalfOpen returns nil if the state changed and the request should be admitted again.
+func (cb *CircuitBreaker) waitHalfOpen(ctx context.Context, deadline *time.Time) error {
+	if cb.queue == nil || cb.waiters >= cb.queue.Size {
+		return ErrTooManyRequests
+	}
+
+	var timeout <-chan time.Time
+	if cb.queue.Timeout > 0 {
+		if deadline.IsZero() {
+			*deadline = cb.clock.Now().Add(cb.queue.Timeout)
+		}
+		d := deadline.Sub(cb.clock.Now())
+		if d <= 0 {
+			return ErrTooManyRequests
+		}
+		timer := cb.newTimer(d)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	if cb.stateDone == nil {
+		cb.stateDone = make(chan struct{})
+	}
+	done := cb.stateDone
+	cb.waiters++
+	cb.mutex.Unlock()
+
+	var err error
+	select {
+	case <-done:
+	case <-timeout:
+		err = ErrTooManyRequests
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cb.mutex.Lock()
+	cb.waiters--
+	return err
+}
+
+// wakeWaiters wakes the requests waiting in the half-open queue.
+func (cb *CircuitBreaker) wakeWaiters() {
+	if cb.stateDone != nil {
+		close(cb.stateDone)
+		cb.stateDone = nil
+	}
+}
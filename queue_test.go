@@ -0,0 +1,129 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueueCB(queue QueuePolicy) *CircuitBreaker {
+	cb := NewCircuitBreaker(Settings{
+		MaxRequests:   1,
+		HalfOpenQueue: &queue,
+	})
+	cb.ForceOpen()
+	cb.mutex.Lock()
+	cb.setState(StateHalfOpen, cb.clock.Now(), ReasonManual)
+	cb.mutex.Unlock()
+	return cb
+}
+
+// waitForWaiters waits until n requests are waiting in the half-open queue.
+func waitForWaiters(t *testing.T, cb *CircuitBreaker, n int) {
+	for i := 0; i < 1000; i++ {
+		cb.mutex.Lock()
+		waiters := cb.waiters
+		cb.mutex.Unlock()
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d requests never started waiting", n)
+}
+
+func queued(cb *CircuitBreaker, n int) (chan error, *sync.WaitGroup) {
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- succeed(cb)
+		}()
+	}
+	return errs, &wg
+}
+
+func TestHalfOpenQueueClosed(t *testing.T) {
+	cb := newQueueCB(QueuePolicy{Size: 3})
+
+	probe, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+
+	errs, wg := queued(cb, 5)
+	waitForWaiters(t, cb, 3)
+
+	probe(true)
+	wg.Wait()
+	close(errs)
+
+	var ok, rejected int
+	for err := range errs {
+		if err == nil {
+			ok++
+		} else {
+			assert.True(t, errors.Is(err, ErrTooManyRequests))
+			rejected++
+		}
+	}
+	assert.Equal(t, 3, ok)
+	assert.Equal(t, 2, rejected)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestHalfOpenQueueOpen(t *testing.T) {
+	cb := newQueueCB(QueuePolicy{Size: 10})
+
+	probe, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+
+	errs, wg := queued(cb, 2)
+	waitForWaiters(t, cb, 2)
+
+	probe(false)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.True(t, errors.Is(err, ErrOpenState))
+	}
+}
+
+func TestHalfOpenQueueTimeout(t *testing.T) {
+	cb := newQueueCB(QueuePolicy{Size: 1, Timeout: 10 * time.Millisecond})
+
+	_, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+
+	start := time.Now()
+	assert.True(t, errors.Is(succeed(cb), ErrTooManyRequests))
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, 0, cb.waiters)
+}
+
+func TestHalfOpenQueueContext(t *testing.T) {
+	cb := newQueueCB(QueuePolicy{Size: 1})
+
+	_, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("unreachable")
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestHalfOpenQueueDisabled(t *testing.T) {
+	cb := newQueueCB(QueuePolicy{})
+	assert.Nil(t, cb.queue)
+
+	_, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(succeed(cb), ErrTooManyRequests))
+}
@@ -0,0 +1,62 @@
+package gobreaker
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultRampSteps are the fractions of traffic admitted by a RampPolicy without Steps.
+var DefaultRampSteps = []float64{0.01, 0.05, 0.25, 1}
+
+// RampPolicy admits a growing fraction of the traffic while the CircuitBreaker is half-open,
+// instead of a burst of MaxRequests requests that could overload a barely recovered dependency.
+//
+// Period is the length of the ramp. It is divided evenly among Steps, and during
+// each step the CircuitBreaker admits the given fraction, between 0 and 1, of the requests.
+// The other requests are rejected with ErrTooManyRequests.
+// If Steps is empty, DefaultRampSteps is used.
+//
+// Any failure during the ramp places the CircuitBreaker back into the open state.
+// The first success after Period places the CircuitBreaker into the closed state.
+type RampPolicy struct {
+	Period time.Duration
+	Steps  []float64
+}
+
+// fraction returns the fraction of traffic to admit after elapsed time in the half-open state.
+func (p *RampPolicy) fraction(elapsed time.Duration) float64 {
+	steps := p.Steps
+	if len(steps) == 0 {
+		steps = DefaultRampSteps
+	}
+	if elapsed >= p.Period {
+		return 1
+	}
+
+	i := int(elapsed * time.Duration(len(steps)) / p.Period)
+	if i < 0 {
+		i = 0
+	}
+	return steps[i]
+}
+
+// admitHalfOpen reports whether a request may pass through the half-open CircuitBreaker.
+func (cb *CircuitBreaker) admitHalfOpen(now time.Time) bool {
+	if cb.ramp == nil {
+		if cb.recovery != nil {
+			return cb.admitProbe()
+		}
+		return cb.counts.Requests < cb.halfOpenMax()
+	}
+
+	fraction := cb.ramp.fraction(now.Sub(cb.stateSince))
+	return fraction >= 1 || rand.Float64() < fraction
+}
+
+// readyToClose reports whether a success in the half-open state closes the CircuitBreaker.
+func (cb *CircuitBreaker) readyToClose(now time.Time) bool {
+	if cb.ramp == nil {
+		return cb.counts.ConsecutiveSuccesses >= cb.halfOpenMax()
+	}
+	return now.Sub(cb.stateSince) >= cb.ramp.Period
+}
@@ -0,0 +1,79 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRampPolicyFraction(t *testing.T) {
+	p := &RampPolicy{Period: 40 * time.Second}
+	assert.Equal(t, 0.01, p.fraction(0))
+	assert.Equal(t, 0.01, p.fraction(9*time.Second))
+	assert.Equal(t, 0.05, p.fraction(10*time.Second))
+	assert.Equal(t, 0.25, p.fraction(25*time.Second))
+	assert.Equal(t, 1.0, p.fraction(39*time.Second))
+	assert.Equal(t, 1.0, p.fraction(time.Hour))
+
+	p = &RampPolicy{Period: time.Second, Steps: []float64{0.5}}
+	assert.Equal(t, 0.5, p.fraction(500*time.Millisecond))
+	assert.Equal(t, 1.0, p.fraction(time.Second))
+}
+
+func newRampCB() (*CircuitBreaker, *time.Time) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:        clockFunc(func() time.Time { return now }),
+		MaxRequests:  1,
+		Timeout:      time.Second,
+		ReadyToTrip:  func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		HalfOpenRamp: &RampPolicy{Period: 20 * time.Second, Steps: []float64{0, 1}},
+	})
+	return cb, &now
+}
+
+func TestHalfOpenRamp(t *testing.T) {
+	cb, now := newRampCB()
+
+	assert.Nil(t, fail(cb))
+	*now = now.Add(2 * time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	// the first step admits nothing
+	assert.True(t, errors.Is(succeed(cb), ErrTooManyRequests))
+
+	// the second step admits everything, beyond MaxRequests, without closing yet
+	*now = now.Add(10 * time.Second)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	// the ramp is complete
+	*now = now.Add(10 * time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestHalfOpenRampFailure(t *testing.T) {
+	cb, now := newRampCB()
+
+	assert.Nil(t, fail(cb))
+	*now = now.Add(2 * time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	*now = now.Add(10 * time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the ramp starts over after the next timeout
+	*now = now.Add(2 * time.Second)
+	assert.True(t, errors.Is(succeed(cb), ErrTooManyRequests))
+}
+
+func TestHalfOpenRampDisabled(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{HalfOpenRamp: &RampPolicy{}})
+	assert.Nil(t, cb.ramp)
+}
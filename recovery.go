@@ -0,0 +1,96 @@
+package gobreaker
+
+import (
+	"math"
+	"time"
+)
+
+// RecoveryPolicy decides when a half-open CircuitBreaker closes, independently of MaxRequests.
+// With a RecoveryPolicy, MaxRequests is the maximum number of probes in flight at a time.
+//
+// Probes is the number of probes the decision is based on.
+// If Probes is 0, MaxRequests is used.
+//
+// SuccessRatio is the ratio of successes among the Probes required to close the CircuitBreaker.
+// The CircuitBreaker closes as soon as enough probes succeeded, and becomes open again
+// as soon as too many probes failed to reach SuccessRatio, not necessarily on the first failure.
+// If SuccessRatio is less than or equal to 0 or greater than or equal to 1, all the Probes
+// must succeed in a row, and the first failure opens the CircuitBreaker again.
+//
+// AwaitProbes delays the decision until the probes in flight complete, instead of deciding
+// on the first completions: once enough probes succeeded or failed, no more probes are admitted,
+// and the CircuitBreaker changes state when the last outstanding probe finishes.
+type RecoveryPolicy struct {
+	Probes       uint32
+	SuccessRatio float64
+	AwaitProbes  bool
+}
+
+// probes returns the number of probes judged by p.
+func (p *RecoveryPolicy) probes(maxRequests uint32) uint32 {
+	if p.Probes == 0 {
+		return maxRequests
+	}
+	return p.Probes
+}
+
+// successes returns the number of successful probes required to close the CircuitBreaker.
+func (p *RecoveryPolicy) successes(maxRequests uint32) uint32 {
+	probes := p.probes(maxRequests)
+	if p.SuccessRatio <= 0 || p.SuccessRatio >= 1 {
+		return probes
+	}
+	n := uint32(math.Ceil(p.SuccessRatio * float64(probes)))
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// admitProbe reports whether a request may pass through the half-open CircuitBreaker
+// with a RecoveryPolicy.
+func (cb *CircuitBreaker) admitProbe() bool {
+	c := cb.counts
+	max := cb.halfOpenMax()
+	if cb.recovery.AwaitProbes {
+		if _, _, decided := cb.probeVerdict(); decided {
+			return false
+		}
+	}
+	return c.Requests < cb.recovery.probes(max) && probesInFlight(c) < max
+}
+
+// probesInFlight returns the number of half-open probes in c that haven't finished yet.
+func probesInFlight(c Counts) uint32 {
+	return c.Requests - c.TotalSuccesses - c.TotalFailures
+}
+
+// probeVerdict returns the state the probes finished so far decide on, if they do.
+func (cb *CircuitBreaker) probeVerdict() (State, TripReason, bool) {
+	c := cb.counts
+	max := cb.halfOpenMax()
+	probes := cb.recovery.probes(max)
+	successes := cb.recovery.successes(max)
+	switch {
+	case c.TotalSuccesses >= successes:
+		return StateClosed, ReasonHalfOpenSuccess, true
+	case c.TotalFailures > probes-successes:
+		return StateOpen, ReasonHalfOpenFailure, true
+	default:
+		return StateHalfOpen, 0, false
+	}
+}
+
+// judgeProbes updates the half-open CircuitBreaker with a RecoveryPolicy after a probe finished.
+func (cb *CircuitBreaker) judgeProbes(now time.Time) {
+	state, reason, decided := cb.probeVerdict()
+	if !decided {
+		// a slot is free for the next probe
+		cb.wakeWaiters()
+		return
+	}
+	if cb.recovery.AwaitProbes && probesInFlight(cb.counts) > 0 {
+		return
+	}
+	cb.setState(state, now, reason)
+}
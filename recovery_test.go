@@ -0,0 +1,108 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverySuccesses(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{Recovery: &RecoveryPolicy{Probes: 3}})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	// MaxRequests limits the probes in flight
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+	done(true)
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	cb = newHalfOpenCB(t, Settings{Recovery: &RecoveryPolicy{Probes: 3}})
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestRecoverySuccessRatio(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{
+		MaxRequests: 4,
+		Recovery:    &RecoveryPolicy{SuccessRatio: 0.5},
+	})
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	cb = newHalfOpenCB(t, Settings{
+		MaxRequests: 4,
+		Recovery:    &RecoveryPolicy{SuccessRatio: 0.5},
+	})
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestRecoveryAwaitProbes(t *testing.T) {
+	cb := newHalfOpenCB(t, Settings{
+		MaxRequests: 3,
+		Recovery:    &RecoveryPolicy{Probes: 3, SuccessRatio: 0.6, AwaitProbes: true},
+	})
+	tscb := &TwoStepCircuitBreaker{cb}
+	var dones []func(bool)
+	for i := 0; i < 3; i++ {
+		done, err := tscb.Allow()
+		assert.Nil(t, err)
+		dones = append(dones, done)
+	}
+
+	// 2 failures decide to open, but the last probe is still in flight
+	dones[0](false)
+	dones[1](false)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, 1, cb.InFlight())
+	dones[2](true)
+	assert.Equal(t, StateOpen, cb.State())
+
+	// no more probes are admitted once decided
+	cb = newHalfOpenCB(t, Settings{
+		MaxRequests: 3,
+		Recovery:    &RecoveryPolicy{Probes: 4, SuccessRatio: 0.5, AwaitProbes: true},
+	})
+	tscb = &TwoStepCircuitBreaker{cb}
+	first, err := tscb.Allow()
+	assert.Nil(t, err)
+	second, err := tscb.Allow()
+	assert.Nil(t, err)
+	third, err := tscb.AllowE()
+	assert.Nil(t, err)
+	first(true)
+	second(true)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+
+	// an ignored probe completes the decision too
+	errIgnored := errors.New("ignored")
+	cb.ignoredErrors = []error{errIgnored}
+	third(errIgnored)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestRecoveryPolicySuccesses(t *testing.T) {
+	assert.Equal(t, uint32(5), (&RecoveryPolicy{}).successes(5))
+	assert.Equal(t, uint32(10), (&RecoveryPolicy{Probes: 10, SuccessRatio: 1}).successes(5))
+	assert.Equal(t, uint32(9), (&RecoveryPolicy{Probes: 10, SuccessRatio: 0.81}).successes(5))
+	assert.Equal(t, uint32(1), (&RecoveryPolicy{Probes: 10, SuccessRatio: 0.01}).successes(5))
+}
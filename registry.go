@@ -0,0 +1,117 @@
+package gobreaker
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry holds CircuitBreakers by name, so that the parts of an application
+// share one CircuitBreaker per dependency, and tooling can enumerate all of them.
+// A Registry is safe for concurrent use. The zero value is an empty Registry.
+type Registry struct {
+	mutex    sync.RWMutex
+	breakers map[string]*CircuitBreaker
+}
+
+// DefaultRegistry is the Registry used by the package-level GetOrCreate and Get.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{breakers: make(map[string]*CircuitBreaker)}
+}
+
+// GetOrCreate returns the CircuitBreaker registered under name.
+// If there is none, GetOrCreate creates one with st, named name, and registers it.
+// st is ignored if the CircuitBreaker already exists.
+func (r *Registry) GetOrCreate(name string, st Settings) *CircuitBreaker {
+	if cb, ok := r.Get(name); ok {
+		return cb
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if cb, ok := r.breakers[name]; ok {
+		return cb
+	}
+	st.Name = name
+	cb := NewCircuitBreaker(st)
+	r.set(cb)
+	return cb
+}
+
+// Get returns the CircuitBreaker registered under name, if any.
+func (r *Registry) Get(name string) (*CircuitBreaker, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Register adds cb to the Registry under its name, replacing any CircuitBreaker with the same name.
+func (r *Registry) Register(cb *CircuitBreaker) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.set(cb)
+}
+
+// Remove removes the CircuitBreaker registered under name, if any.
+func (r *Registry) Remove(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.breakers, name)
+}
+
+// Len returns the number of CircuitBreakers in the Registry.
+func (r *Registry) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.breakers)
+}
+
+// Range calls fn for each CircuitBreaker in the Registry in the order of their names.
+// If fn returns false, Range stops the iteration.
+// fn may use the Registry; the CircuitBreakers registered meanwhile may not be visited.
+func (r *Registry) Range(fn func(cb *CircuitBreaker) bool) {
+	for _, cb := range r.sorted() {
+		if !fn(cb) {
+			return
+		}
+	}
+}
+
+func (r *Registry) set(cb *CircuitBreaker) {
+	if r.breakers == nil {
+		r.breakers = make(map[string]*CircuitBreaker)
+	}
+	r.breakers[cb.Name()] = cb
+}
+
+// sorted returns the CircuitBreakers in the order of their names.
+func (r *Registry) sorted() []*CircuitBreaker {
+	r.mutex.RLock()
+	cbs := make([]*CircuitBreaker, 0, len(r.breakers))
+	for _, cb := range r.breakers {
+		cbs = append(cbs, cb)
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(cbs, func(i, j int) bool { return cbs[i].Name() < cbs[j].Name() })
+	return cbs
+}
+
+// GetOrCreate returns the CircuitBreaker registered under name in DefaultRegistry,
+// creating it with st if there is none.
+func GetOrCreate(name string, st Settings) *CircuitBreaker {
+	return DefaultRegistry.GetOrCreate(name, st)
+}
+
+// Get returns the CircuitBreaker registered under name in DefaultRegistry, if any.
+func Get(name string) (*CircuitBreaker, bool) {
+	return DefaultRegistry.Get(name)
+}
@@ -0,0 +1,96 @@
+package gobreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	a := r.GetOrCreate("a", Settings{Name: "ignored", Timeout: time.Second})
+	assert.Equal(t, "a", a.Name())
+	assert.Equal(t, time.Second, a.timeout)
+	assert.Equal(t, a, r.GetOrCreate("a", Settings{Timeout: time.Minute}))
+	assert.Equal(t, time.Second, a.timeout)
+
+	cb, ok := r.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, a, cb)
+	_, ok = r.Get("b")
+	assert.False(t, ok)
+
+	c := NewCircuitBreaker(Settings{Name: "c"})
+	r.Register(c)
+	b := r.GetOrCreate("b", Settings{})
+	assert.Equal(t, 3, r.Len())
+
+	var names []string
+	r.Range(func(cb *CircuitBreaker) bool {
+		names = append(names, cb.Name())
+		return true
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	names = nil
+	r.Range(func(cb *CircuitBreaker) bool {
+		names = append(names, cb.Name())
+		return cb != b
+	})
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	r.Remove("b")
+	_, ok = r.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, r.Len())
+
+	var zero Registry
+	zero.Register(a)
+	assert.Equal(t, 1, zero.Len())
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	cbs := make([]*CircuitBreaker, 10)
+	for i := range cbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cbs[i] = r.GetOrCreate("shared", Settings{})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, cb := range cbs {
+		assert.Equal(t, cbs[0], cb)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	cb := GetOrCreate("default", Settings{})
+	found, ok := Get("default")
+	assert.True(t, ok)
+	assert.Equal(t, cb, found)
+	DefaultRegistry.Remove("default")
+}
+
+func TestRegistryAdminHandler(t *testing.T) {
+	r := NewRegistry()
+	h := NewRegistryAdminHandler(r)
+	cb := r.GetOrCreate("later", Settings{})
+
+	rec := postAdmin(h, "later", "open")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, StateOpen, cb.State())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Contains(t, rec.Body.String(), `"later"`)
+}
@@ -0,0 +1,94 @@
+package gobreaker
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultReportSize is the number of breakers and transitions in a RegistryReport
+// if Report is called with n less than or equal to 0.
+const DefaultReportSize = 10
+
+// RegistryReport is an aggregated view of the CircuitBreakers of a Registry,
+// e.g. for periodic logging or an ops endpoint. It is encoded in JSON with the names
+// of the states.
+//
+// States is the number of CircuitBreakers in each state. TopFailing are the CircuitBreakers
+// with the highest failure rates in their current generation, the highest first, without
+// those that have no failure. Transitions are the latest state transitions of all the
+// CircuitBreakers, the latest first; they are only kept by the CircuitBreakers with a HistorySize.
+type RegistryReport struct {
+	Breakers    int                 `json:"breakers"`
+	States      map[State]int       `json:"states"`
+	TopFailing  []BreakerFailures   `json:"topFailing"`
+	Transitions []BreakerTransition `json:"transitions"`
+}
+
+// BreakerFailures is the failure rate of a CircuitBreaker in a RegistryReport.
+type BreakerFailures struct {
+	Name        string  `json:"name"`
+	State       State   `json:"state"`
+	Counts      Counts  `json:"counts"`
+	FailureRate float64 `json:"failureRate"`
+}
+
+// BreakerTransition is a state transition of a CircuitBreaker in a RegistryReport.
+type BreakerTransition struct {
+	Name   string     `json:"name"`
+	Time   time.Time  `json:"time"`
+	From   State      `json:"from"`
+	To     State      `json:"to"`
+	Reason TripReason `json:"reason"`
+}
+
+// Report returns a RegistryReport of the CircuitBreakers in r, with at most n of them
+// in TopFailing and at most n Transitions. If n is less than or equal to 0, DefaultReportSize is used.
+func (r *Registry) Report(n int) RegistryReport {
+	if n <= 0 {
+		n = DefaultReportSize
+	}
+
+	cbs := r.sorted()
+	report := RegistryReport{
+		Breakers:    len(cbs),
+		States:      make(map[State]int),
+		TopFailing:  []BreakerFailures{},
+		Transitions: []BreakerTransition{},
+	}
+	for _, cb := range cbs {
+		s := cb.StatusSnapshot()
+		report.States[s.State]++
+		if rate := s.Counts.FailureRate(); rate > 0 {
+			report.TopFailing = append(report.TopFailing, BreakerFailures{
+				Name:        s.Name,
+				State:       s.State,
+				Counts:      s.Counts,
+				FailureRate: rate,
+			})
+		}
+		for _, t := range cb.History() {
+			report.Transitions = append(report.Transitions, BreakerTransition{
+				Name:   s.Name,
+				Time:   t.Time,
+				From:   t.From,
+				To:     t.To,
+				Reason: t.Reason,
+			})
+		}
+	}
+
+	// the breakers are sorted by name, which breaks the ties
+	sort.SliceStable(report.TopFailing, func(i, j int) bool {
+		return report.TopFailing[i].FailureRate > report.TopFailing[j].FailureRate
+	})
+	if len(report.TopFailing) > n {
+		report.TopFailing = report.TopFailing[:n]
+	}
+	sort.SliceStable(report.Transitions, func(i, j int) bool {
+		return report.Transitions[i].Time.After(report.Transitions[j].Time)
+	})
+	if len(report.Transitions) > n {
+		report.Transitions = report.Transitions[:n]
+	}
+	return report
+}
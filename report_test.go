@@ -0,0 +1,50 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryReport(t *testing.T) {
+	clock := newFakeClock()
+	r := NewRegistry()
+	st := Settings{Clock: clock, HistorySize: 4, ReadyToTrip: tripAfter(2)}
+	a := r.GetOrCreate("a", st)
+	b := r.GetOrCreate("b", st)
+	c := r.GetOrCreate("c", st)
+	r.GetOrCreate("d", st)
+
+	assert.Nil(t, succeed(a))
+	assert.Nil(t, fail(a))
+	assert.Nil(t, fail(b))
+	assert.Nil(t, fail(b))
+	clock.Advance(time.Second)
+	c.Trip()
+
+	report := r.Report(1)
+	assert.Equal(t, 4, report.Breakers)
+	assert.Equal(t, map[State]int{StateClosed: 2, StateOpen: 2}, report.States)
+	assert.Equal(t, []BreakerFailures{{Name: "a", State: StateClosed, Counts: a.Counts(), FailureRate: 0.5}}, report.TopFailing)
+	assert.Equal(t, []BreakerTransition{
+		{Name: "c", Time: clock.Now(), From: StateClosed, To: StateOpen, Reason: ReasonManual},
+	}, report.Transitions)
+
+	report = r.Report(0)
+	assert.Len(t, report.TopFailing, 1)
+	assert.Len(t, report.Transitions, 2)
+	assert.Equal(t, "b", report.Transitions[1].Name)
+	assert.Equal(t, ReasonReadyToTrip, report.Transitions[1].Reason)
+
+	data, err := json.Marshal(r.Report(1))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"breakers":4,"states":{"closed":2,"open":2},"topFailing":[{"name":"a","state":"closed",`+
+		`"counts":{"requests":2,"totalSuccesses":1,"totalFailures":1,"consecutiveSuccesses":0,"consecutiveFailures":1,`+
+		`"totalFailureWeight":1,"consecutiveFailureWeight":1,"failuresByKind":{"other":1}},"failureRate":0.5}],`+
+		`"transitions":[{"name":"c","time":"2020-01-01T00:00:01Z","from":"closed","to":"open","reason":"manual"}]}`, string(data))
+
+	report = NewRegistry().Report(0)
+	assert.Equal(t, RegistryReport{States: map[State]int{}, TopFailing: []BreakerFailures{}, Transitions: []BreakerTransition{}}, report)
+}
@@ -0,0 +1,92 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// Reporter is a Breaker that also takes the results observed outside of its requests,
+// e.g. the errors of the pooled connections of a client or the heartbeats of a driver,
+// for the adapters of the clients that don't run each request through Execute.
+type Reporter interface {
+	Breaker
+	// Check returns a *RejectionError if the Breaker is open, and nil otherwise.
+	Check() error
+	// Report counts the result of an operation that didn't go through the Breaker.
+	Report(err error)
+}
+
+var (
+	_ Reporter = (*CircuitBreaker)(nil)
+	_ Reporter = (*TwoStepCircuitBreaker)(nil)
+)
+
+// Check returns a *RejectionError with ErrOpenState and the RetryAfter of the CircuitBreaker
+// if it is open or forced open, and nil otherwise. Check doesn't count as a request,
+// nor as a rejection: a client can ask it before starting an operation it can't run through Execute.
+func (cb *CircuitBreaker) Check() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	state, _ := cb.currentState(now)
+	if state == StateOpen || state == StateForcedOpen {
+		return cb.rejection(state, now, ErrOpenState)
+	}
+	return nil
+}
+
+// Report counts the result of an operation that didn't go through the CircuitBreaker as a request
+// that returned err, classified like the errors of the requests, e.g. with IsSuccessful and Classify.
+//
+// The reports are dropped while the CircuitBreaker is open. While it is half-open, a report
+// takes a free slot of the probes, like a request that finished at once, or is dropped if there is none.
+// A report can thus close a half-open CircuitBreaker fed only by reports, e.g. one following
+// the events of a driver, whose operations never go through Execute. The slot is released
+// as soon as the report is counted, so that a report never holds a slot that a probe is waiting for,
+// but a report counted first decides the probes of MaxRequests 1 in place of the next request.
+func (cb *CircuitBreaker) Report(err error) {
+	outcome := cb.classify(nil, err)
+	if outcome == OutcomeIgnore {
+		return
+	}
+	generation, now, ok := cb.admitReport()
+	if !ok {
+		return
+	}
+	cb.afterRequest(context.Background(), generation, now, outcome == OutcomeSuccess, cb.failureOf(outcome, err))
+}
+
+// admitReport counts a report as a request if the CircuitBreaker admits it, and returns its generation and time.
+func (cb *CircuitBreaker) admitReport() (uint64, time.Time, bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	state, generation := cb.currentState(now)
+	switch state {
+	case StateClosed, StateForcedClosed:
+	case StateHalfOpen:
+		if !cb.admitHalfOpen(now) {
+			return generation, now, false
+		}
+	default:
+		return generation, now, false
+	}
+	cb.touch(now)
+	cb.inflight++
+	cb.counts.onRequest()
+	cb.metrics.OnRequest(cb.name)
+	return generation, now, true
+}
+
+// Check returns the rejection of the TwoStepCircuitBreaker if it is open, like CircuitBreaker.Check.
+func (tscb *TwoStepCircuitBreaker) Check() error {
+	return tscb.cb.Check()
+}
+
+// Report counts the result of an operation that didn't go through the TwoStepCircuitBreaker,
+// like CircuitBreaker.Report.
+func (tscb *TwoStepCircuitBreaker) Report(err error) {
+	tscb.cb.Report(err)
+}
@@ -0,0 +1,109 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Name:        "check",
+		Clock:       clock,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	assert.Nil(t, cb.Check())
+
+	assert.Nil(t, fail(cb))
+	clock.Advance(time.Second)
+	err := cb.Check()
+	var rejection *RejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "check", rejection.Name)
+	assert.Equal(t, StateOpen, rejection.State)
+	assert.Equal(t, defaultTimeout-time.Second, rejection.RetryAfter)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	clock.Advance(defaultTimeout)
+	assert.Nil(t, (&TwoStepCircuitBreaker{cb}).Check())
+}
+
+func TestReport(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock:         clock,
+		Timeout:       time.Minute,
+		ReadyToTrip:   func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		IgnoredErrors: []error{errNotFound},
+	})
+
+	cb.Report(nil)
+	cb.Report(errNotFound)
+	cb.Report(errors.New("reset by peer"))
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+	assert.Equal(t, 0, cb.InFlight())
+
+	(&TwoStepCircuitBreaker{cb}).Report(errors.New("reset by peer"))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the reports are dropped while open
+	cb.Report(nil)
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	// a report takes the slot of a probe
+	clock.Advance(time.Minute + time.Second)
+	done, err := (&TwoStepCircuitBreaker{cb}).Allow()
+	assert.Nil(t, err)
+	cb.Report(nil)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+	done(true)
+	assert.Equal(t, StateClosed, cb.State())
+
+	cb.Report(errors.New("reset by peer"))
+	cb.Report(errors.New("reset by peer"))
+	clock.Advance(time.Minute + time.Second)
+	cb.Report(nil)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestReportHalfOpen(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Clock:       clock,
+		MaxRequests: 1,
+		Timeout:     time.Minute,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	tscb := &TwoStepCircuitBreaker{cb}
+	halfOpen := func() {
+		cb.Report(errors.New("reset by peer"))
+		clock.Advance(time.Minute + time.Second)
+		assert.Equal(t, StateHalfOpen, cb.State())
+	}
+
+	// a report while the probe is running is dropped: the probe decides
+	halfOpen()
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	cb.Report(errors.New("reset by peer"))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	done(true)
+	assert.Equal(t, StateClosed, cb.State())
+
+	// a failed report reopens the breaker like a failed probe
+	halfOpen()
+	cb.Report(errors.New("reset by peer"))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// a successful report releases its slot as it closes the breaker
+	clock.Advance(time.Minute + time.Second)
+	cb.Report(nil)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, 0, cb.InFlight())
+	_, err = tscb.Allow()
+	assert.Nil(t, err)
+}
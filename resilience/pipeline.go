@@ -0,0 +1,135 @@
+// Package resilience composes a CircuitBreaker with other resilience stages,
+// such as retry, timeout, bulkhead and rate limit, into a single Pipeline.
+//
+// Stages are applied in the order they are declared: the first stage is the
+// outermost one and sees every call, the last stage is the closest to the request.
+//
+//	p := resilience.New().
+//		Retry(resilience.RetryPolicy{MaxAttempts: 3, Backoff: 100 * time.Millisecond}).
+//		Breaker(cb).
+//		Timeout(time.Second).
+//		Build()
+//
+//	result, err := p.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+//		return fetch(ctx)
+//	})
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+// Func is a request executed by a Pipeline.
+type Func func(ctx context.Context) (interface{}, error)
+
+// Stage is a single step of a Pipeline.
+// Wrap returns a Func that applies the stage around next and reports what happens through emit.
+type Stage interface {
+	Name() string
+	Wrap(next Func, emit func(Event)) Func
+}
+
+// EventType is a type of Event.
+type EventType int
+
+// These constants are types of Event.
+const (
+	EventSuccess EventType = iota
+	EventFailure
+	EventRejected
+	EventRetry
+	EventTimeout
+)
+
+// String implements stringer interface.
+func (t EventType) String() string {
+	switch t {
+	case EventSuccess:
+		return "success"
+	case EventFailure:
+		return "failure"
+	case EventRejected:
+		return "rejected"
+	case EventRetry:
+		return "retry"
+	case EventTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is emitted by the stages of a Pipeline and delivered to the OnEvent callbacks.
+// Stage is the name of the stage that emitted the Event, or "pipeline" for the outcome
+// of Pipeline.Execute itself.
+type Event struct {
+	Stage   string
+	Type    EventType
+	Err     error
+	Latency time.Duration
+	Attempt int
+}
+
+// Builder declares the stages of a Pipeline.
+type Builder struct {
+	stages   []Stage
+	handlers []func(Event)
+}
+
+// New returns a Builder without any stage.
+func New() *Builder {
+	return new(Builder)
+}
+
+// Use appends a custom stage.
+func (b *Builder) Use(s Stage) *Builder {
+	b.stages = append(b.stages, s)
+	return b
+}
+
+// OnEvent registers fn to receive the events of all stages.
+// fn is called synchronously by the goroutine running the stage.
+func (b *Builder) OnEvent(fn func(Event)) *Builder {
+	b.handlers = append(b.handlers, fn)
+	return b
+}
+
+// Build returns a Pipeline with the stages declared so far.
+func (b *Builder) Build() *Pipeline {
+	return &Pipeline{
+		stages:   append([]Stage(nil), b.stages...),
+		handlers: append(make([]func(Event), 0, len(b.handlers)), b.handlers...),
+	}
+}
+
+// Pipeline executes requests through a fixed chain of stages.
+// A Pipeline is safe for concurrent use.
+type Pipeline struct {
+	stages   []Stage
+	handlers []func(Event)
+}
+
+// Execute runs fn through all the stages of the Pipeline.
+func (p *Pipeline) Execute(ctx context.Context, fn Func) (interface{}, error) {
+	run := fn
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		run = p.stages[i].Wrap(run, p.emit)
+	}
+
+	start := time.Now()
+	result, err := run(ctx)
+
+	e := Event{Stage: "pipeline", Type: EventSuccess, Err: err, Latency: time.Since(start)}
+	if err != nil {
+		e.Type = EventFailure
+	}
+	p.emit(e)
+	return result, err
+}
+
+func (p *Pipeline) emit(e Event) {
+	for _, fn := range p.handlers {
+		fn(e)
+	}
+}
@@ -0,0 +1,156 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sony/gobreaker"
+)
+
+type eventLog struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) add(e Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) types(stage string) []EventType {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var types []EventType
+	for _, e := range l.events {
+		if e.Stage == stage {
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+func failing(n int) (Func, *int) {
+	calls := 0
+	return func(ctx context.Context) (interface{}, error) {
+		calls++
+		if calls <= n {
+			return nil, errors.New("fail")
+		}
+		return calls, nil
+	}, &calls
+}
+
+func TestPipelineRetryBreaker(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{})
+	log := new(eventLog)
+	p := New().
+		Retry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}).
+		Breaker(cb).
+		OnEvent(log.add).
+		Build()
+
+	fn, calls := failing(2)
+	result, err := p.Execute(context.Background(), fn)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, gobreaker.Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, ConsecutiveSuccesses: 1, TotalFailureWeight: 2, FailuresByKind: gobreaker.FailureCounts{2}}, cb.Counts())
+	assert.Equal(t, []EventType{EventRetry, EventRetry}, log.types("retry"))
+	assert.Equal(t, []EventType{EventFailure, EventFailure, EventSuccess}, log.types("breaker"))
+	assert.Equal(t, []EventType{EventSuccess}, log.types("pipeline"))
+}
+
+func TestPipelineRetryStopsWhenOpen(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	log := new(eventLog)
+	p := New().Retry(RetryPolicy{MaxAttempts: 5}).Breaker(cb).OnEvent(log.add).Build()
+
+	fn, calls := failing(10)
+	_, err := p.Execute(context.Background(), fn)
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, []EventType{EventFailure, EventFailure, EventRejected}, log.types("breaker"))
+}
+
+func TestPipelineTimeout(t *testing.T) {
+	log := new(eventLog)
+	p := New().Timeout(10 * time.Millisecond).OnEvent(log.add).Build()
+
+	_, err := p.Execute(context.Background(), func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	})
+	assert.Equal(t, ErrTimeout, err)
+	assert.Equal(t, []EventType{EventTimeout}, log.types("timeout"))
+	assert.Equal(t, []EventType{EventFailure}, log.types("pipeline"))
+
+	result, err := p.Execute(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return "fast", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fast", result)
+}
+
+func TestPipelineBulkhead(t *testing.T) {
+	p := New().Bulkhead(1).Build()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go p.Execute(context.Background(), func(ctx context.Context) (interface{}, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	})
+	<-entered
+
+	_, err := p.Execute(context.Background(), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrBulkheadFull, err)
+
+	close(release)
+	for i := 0; i < 1000 && err != nil; i++ {
+		time.Sleep(time.Millisecond)
+		_, err = p.Execute(context.Background(), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	}
+	assert.Nil(t, err)
+}
+
+func TestPipelineRateLimit(t *testing.T) {
+	stage := &rateLimitStage{rate: 10, burst: 2, tokens: 2}
+	now := time.Now()
+
+	assert.True(t, stage.allow(now))
+	assert.True(t, stage.allow(now))
+	assert.False(t, stage.allow(now))
+	assert.False(t, stage.allow(now.Add(50*time.Millisecond)))
+	assert.True(t, stage.allow(now.Add(150*time.Millisecond)))
+
+	p := New().RateLimit(1, 1).Build()
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	_, err := p.Execute(context.Background(), noop)
+	assert.Nil(t, err)
+	_, err = p.Execute(context.Background(), noop)
+	assert.Equal(t, ErrRateLimited, err)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, Multiplier: 2, MaxBackoff: 300 * time.Millisecond}
+	half := func() float64 { return 0.5 }
+	assert.Equal(t, 100*time.Millisecond, p.delay(0, half))
+	assert.Equal(t, 200*time.Millisecond, p.delay(1, half))
+	assert.Equal(t, 300*time.Millisecond, p.delay(2, half))
+
+	p.Jitter = 0.5
+	assert.Equal(t, 150*time.Millisecond, p.delay(0, func() float64 { return 1 }))
+	assert.Equal(t, 50*time.Millisecond, p.delay(0, func() float64 { return 0 }))
+}
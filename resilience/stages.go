@@ -0,0 +1,257 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+var (
+	// ErrBulkheadFull is returned when the bulkhead stage has no free slot.
+	ErrBulkheadFull = errors.New("bulkhead is full")
+	// ErrRateLimited is returned when the rate limit stage has no token left.
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrTimeout is returned when the timeout stage gives up waiting for the request.
+	ErrTimeout = errors.New("request timed out")
+)
+
+// Breaker appends a stage that runs the request through cb.
+func (b *Builder) Breaker(cb gobreaker.Breaker) *Builder {
+	return b.Use(breakerStage{cb})
+}
+
+type breakerStage struct {
+	cb gobreaker.Breaker
+}
+
+func (s breakerStage) Name() string {
+	return "breaker"
+}
+
+func (s breakerStage) Wrap(next Func, emit func(Event)) Func {
+	return func(ctx context.Context) (interface{}, error) {
+		start := time.Now()
+		result, err := s.cb.Execute(func() (interface{}, error) {
+			return next(ctx)
+		})
+
+		e := Event{Stage: s.Name(), Type: EventSuccess, Err: err, Latency: time.Since(start)}
+		if gobreaker.IsRejection(err) {
+			e.Type = EventRejected
+		} else if err != nil {
+			e.Type = EventFailure
+		}
+		emit(e)
+		return result, err
+	}
+}
+
+// RetryPolicy configures the retry stage.
+//
+// MaxAttempts is the maximum number of attempts including the first one.
+// If MaxAttempts is less than or equal to 1, the request is not retried.
+//
+// Backoff is the delay before the first retry. The delay is multiplied by Multiplier
+// after every retry and is capped by MaxBackoff if MaxBackoff is greater than 0.
+// If Multiplier is less than 1, the delay stays the same.
+//
+// Jitter randomizes each delay by up to the given fraction of it, in either direction.
+//
+// RetryIf reports whether a failed attempt should be retried.
+// If RetryIf is nil, every error is retried except the rejections of a CircuitBreaker
+// and the errors of a cancelled context.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Multiplier  float64
+	Jitter      float64
+	RetryIf     func(err error) bool
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.RetryIf != nil {
+		return p.RetryIf(err)
+	}
+	return !gobreaker.IsRejection(err) &&
+		err != context.Canceled && err != context.DeadlineExceeded
+}
+
+func (p RetryPolicy) delay(retry int, rnd func() float64) time.Duration {
+	d := float64(p.Backoff)
+	if p.Multiplier > 1 {
+		d *= math.Pow(p.Multiplier, float64(retry))
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rnd() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Retry appends a stage that retries failed requests according to policy.
+func (b *Builder) Retry(policy RetryPolicy) *Builder {
+	return b.Use(&retryStage{policy: policy, rnd: rand.New(rand.NewSource(time.Now().UnixNano()))})
+}
+
+type retryStage struct {
+	policy RetryPolicy
+
+	mutex sync.Mutex
+	rnd   *rand.Rand
+}
+
+func (s *retryStage) Name() string {
+	return "retry"
+}
+
+func (s *retryStage) random() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rnd.Float64()
+}
+
+func (s *retryStage) Wrap(next Func, emit func(Event)) Func {
+	return func(ctx context.Context) (interface{}, error) {
+		for attempt := 1; ; attempt++ {
+			result, err := next(ctx)
+			if err == nil || attempt >= s.policy.MaxAttempts || !s.policy.retryable(err) {
+				return result, err
+			}
+
+			emit(Event{Stage: s.Name(), Type: EventRetry, Err: err, Attempt: attempt})
+
+			timer := time.NewTimer(s.policy.delay(attempt-1, s.random))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// Timeout appends a stage that cancels the context of the request after d and
+// returns ErrTimeout without waiting for the request to return.
+func (b *Builder) Timeout(d time.Duration) *Builder {
+	return b.Use(timeoutStage{d})
+}
+
+type timeoutStage struct {
+	timeout time.Duration
+}
+
+func (s timeoutStage) Name() string {
+	return "timeout"
+}
+
+type result struct {
+	value interface{}
+	err   error
+}
+
+func (s timeoutStage) Wrap(next Func, emit func(Event)) Func {
+	return func(ctx context.Context) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+
+		ch := make(chan result, 1)
+		go func() {
+			v, err := next(ctx)
+			ch <- result{v, err}
+		}()
+
+		select {
+		case r := <-ch:
+			return r.value, r.err
+		case <-ctx.Done():
+			if ctx.Err() != context.DeadlineExceeded {
+				return nil, ctx.Err()
+			}
+			emit(Event{Stage: s.Name(), Type: EventTimeout, Err: ErrTimeout, Latency: s.timeout})
+			return nil, ErrTimeout
+		}
+	}
+}
+
+// Bulkhead appends a stage that allows at most maxConcurrent requests in flight
+// and rejects the others with ErrBulkheadFull.
+func (b *Builder) Bulkhead(maxConcurrent int) *Builder {
+	return b.Use(bulkheadStage{make(chan struct{}, maxConcurrent)})
+}
+
+type bulkheadStage struct {
+	slots chan struct{}
+}
+
+func (s bulkheadStage) Name() string {
+	return "bulkhead"
+}
+
+func (s bulkheadStage) Wrap(next Func, emit func(Event)) Func {
+	return func(ctx context.Context) (interface{}, error) {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			emit(Event{Stage: s.Name(), Type: EventRejected, Err: ErrBulkheadFull})
+			return nil, ErrBulkheadFull
+		}
+		defer func() { <-s.slots }()
+
+		return next(ctx)
+	}
+}
+
+// RateLimit appends a stage that allows requests at rate per second with bursts of up to burst
+// requests, and rejects the others with ErrRateLimited.
+func (b *Builder) RateLimit(rate float64, burst int) *Builder {
+	return b.Use(&rateLimitStage{rate: rate, burst: float64(burst), tokens: float64(burst)})
+}
+
+type rateLimitStage struct {
+	rate  float64
+	burst float64
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (s *rateLimitStage) Name() string {
+	return "ratelimit"
+}
+
+func (s *rateLimitStage) allow(now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.last.IsZero() {
+		s.tokens = math.Min(s.burst, s.tokens+now.Sub(s.last).Seconds()*s.rate)
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *rateLimitStage) Wrap(next Func, emit func(Event)) Func {
+	return func(ctx context.Context) (interface{}, error) {
+		if !s.allow(time.Now()) {
+			emit(Event{Stage: s.Name(), Type: EventRejected, Err: ErrRateLimited})
+			return nil, ErrRateLimited
+		}
+		return next(ctx)
+	}
+}
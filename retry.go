@@ -0,0 +1,75 @@
+package gobreaker
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures ExecuteWithRetry.
+//
+// MaxAttempts is the maximum number of attempts including the first one.
+// If MaxAttempts is less than or equal to 1, the request is not retried.
+//
+// Backoff is the delay before the first retry. The delay is multiplied by Multiplier
+// after every retry and is capped by MaxBackoff if MaxBackoff is greater than 0.
+// If Multiplier is less than 1, the delay stays the same.
+//
+// Jitter randomizes each delay by up to the given fraction of it, in either direction.
+//
+// RetryIf reports whether a failed attempt should be retried.
+// If RetryIf is nil, every error that the CircuitBreaker counts as a failure is retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Multiplier  float64
+	Jitter      float64
+	RetryIf     func(err error) bool
+}
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := float64(p.Backoff)
+	if p.Multiplier > 1 {
+		d *= math.Pow(p.Multiplier, float64(retry))
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// ExecuteWithRetry runs req like Execute and retries it according to p while it fails.
+// Every attempt is a request of its own in the Counts of the CircuitBreaker.
+// The retries stop as soon as the CircuitBreaker no longer admits requests, e.g. because
+// an attempt tripped it; ExecuteWithRetry then returns the result of the last attempt.
+// If the first attempt is rejected, ExecuteWithRetry returns the rejection.
+func (cb *CircuitBreaker) ExecuteWithRetry(req func() (interface{}, error), p RetryPolicy) (interface{}, error) {
+	retryIf := p.RetryIf
+	if retryIf == nil {
+		retryIf = func(err error) bool { return cb.classify(nil, err) == OutcomeFailure }
+	}
+
+	var result interface{}
+	var err error
+	for attempt := 1; ; attempt++ {
+		r, e := cb.Execute(req)
+		if attempt > 1 && IsRejection(e) {
+			return result, err
+		}
+		result, err = r, e
+		if err == nil || attempt >= p.MaxAttempts || IsRejection(err) || !retryIf(err) {
+			return result, err
+		}
+
+		if state := cb.State(); state == StateOpen || state == StateForcedOpen {
+			return result, err
+		}
+		if d := p.delay(attempt - 1); d > 0 {
+			<-cb.newTimer(d).C()
+		}
+	}
+}
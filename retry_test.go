@@ -0,0 +1,100 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteWithRetry(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	errFail := errors.New("fail")
+
+	attempts := 0
+	result, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errFail
+		}
+		return "ok", nil
+	}, RetryPolicy{MaxAttempts: 5})
+	assert.Equal(t, "ok", result)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, Counts{3, 1, 2, 1, 0, 2, 0, FailureCounts{2}}, cb.Counts())
+
+	attempts = 0
+	_, err = cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errFail
+	}, RetryPolicy{MaxAttempts: 2})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 2, attempts)
+
+	// errors that RetryIf rejects are not retried
+	attempts = 0
+	_, err = cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errFail
+	}, RetryPolicy{MaxAttempts: 3, RetryIf: func(err error) bool { return false }})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestExecuteWithRetryTrip(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	})
+	errFail := errors.New("fail")
+
+	attempts := 0
+	_, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errFail
+	}, RetryPolicy{MaxAttempts: 5})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, StateOpen, cb.State())
+
+	_, err = cb.ExecuteWithRetry(func() (interface{}, error) { return nil, nil }, RetryPolicy{MaxAttempts: 5})
+	assert.True(t, errors.Is(err, ErrOpenState))
+}
+
+func TestExecuteWithRetryBackoff(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Clock: clock})
+
+	done := make(chan error)
+	go func() {
+		_, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+			return nil, errors.New("fail")
+		}, RetryPolicy{MaxAttempts: 3, Backoff: time.Second, Multiplier: 2})
+		done <- err
+	}()
+
+	for _, d := range []time.Duration{time.Second, 2 * time.Second} {
+		for clock.Timers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(d - time.Nanosecond)
+		assert.Equal(t, 1, clock.Timers())
+		clock.Advance(time.Nanosecond)
+	}
+	assert.Error(t, <-done)
+	assert.Equal(t, uint32(3), cb.Counts().TotalFailures)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, Multiplier: 3, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(0))
+	assert.Equal(t, 3*time.Second, p.delay(1))
+	assert.Equal(t, 5*time.Second, p.delay(2))
+
+	p = RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 10; i++ {
+		d := p.delay(0)
+		assert.True(t, d >= 500*time.Millisecond && d <= 1500*time.Millisecond, "%v", d)
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/saramabreaker
+
+go 1.13
+
+require (
+	github.com/Shopify/sarama v1.27.2
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.6.1
+)
+
+replace github.com/sony/gobreaker => ../
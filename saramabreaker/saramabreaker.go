@@ -0,0 +1,111 @@
+// Package saramabreaker guards the Kafka producers of sarama with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on sarama.
+package saramabreaker
+
+import (
+	"errors"
+
+	"github.com/Shopify/sarama"
+	"github.com/sony/gobreaker"
+)
+
+// messageErrors are the errors caused by a message or the configuration of the producer
+// rather than by the unavailability of the brokers.
+var messageErrors = map[sarama.KError]bool{
+	sarama.ErrInvalidMessage:              true,
+	sarama.ErrUnknownTopicOrPartition:     true,
+	sarama.ErrInvalidMessageSize:          true,
+	sarama.ErrMessageSizeTooLarge:         true,
+	sarama.ErrInvalidTopic:                true,
+	sarama.ErrMessageSetSizeTooLarge:      true,
+	sarama.ErrInvalidRequiredAcks:         true,
+	sarama.ErrTopicAuthorizationFailed:    true,
+	sarama.ErrUnsupportedForMessageFormat: true,
+	sarama.ErrPolicyViolation:             true,
+	sarama.ErrInvalidRecord:               true,
+}
+
+// Classify classifies the error of a sarama producer for Settings.Classify.
+// The errors caused by a message, e.g. sarama.ErrMessageSizeTooLarge or sarama.ErrInvalidTopic,
+// count as successes, and the others, e.g. sarama.ErrOutOfBrokers or sarama.ErrNotLeaderForPartition,
+// as failures. sarama.ProducerErrors counts as a failure if any of its errors does.
+func Classify(err error) gobreaker.Outcome {
+	var errs sarama.ProducerErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			if Classify(e.Err) == gobreaker.OutcomeFailure {
+				return gobreaker.OutcomeFailure
+			}
+		}
+		return gobreaker.OutcomeSuccess
+	}
+
+	var kerr sarama.KError
+	if errors.As(err, &kerr) && messageErrors[kerr] {
+		return gobreaker.OutcomeSuccess
+	}
+	return gobreaker.OutcomeFailure
+}
+
+// OverflowFunc handles a message that a producer didn't send because its breaker rejected it,
+// e.g. by writing it to a local spool to be sent later. err is the rejection.
+// The error returned by OverflowFunc is returned to the caller of the producer instead of err.
+type OverflowFunc func(msg *sarama.ProducerMessage, err error) error
+
+// SyncProducer is a sarama.SyncProducer that sends the messages through a Breaker.
+type SyncProducer struct {
+	sarama.SyncProducer
+	cb       gobreaker.Breaker
+	overflow OverflowFunc
+}
+
+// NewSyncProducer returns a SyncProducer that sends the messages with p through cb.
+// cb should be created with Classify as Settings.Classify, so that invalid messages don't trip it.
+// While cb rejects the messages, they are passed to overflow, if not nil, and fail fast otherwise,
+// so that the producers don't block on unavailable brokers.
+func NewSyncProducer(p sarama.SyncProducer, cb gobreaker.Breaker, overflow OverflowFunc) *SyncProducer {
+	return &SyncProducer{SyncProducer: p, cb: cb, overflow: overflow}
+}
+
+// SendMessage sends msg through the Breaker.
+func (p *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	_, err = p.cb.Execute(func() (interface{}, error) {
+		var err error
+		partition, offset, err = p.SyncProducer.SendMessage(msg)
+		return nil, err
+	})
+	if gobreaker.IsRejection(err) {
+		return -1, -1, p.reject(msg, err)
+	}
+	return partition, offset, err
+}
+
+// SendMessages sends msgs through the Breaker as a single request.
+// If the Breaker rejects them, the error is a sarama.ProducerErrors of the messages that failed to overflow.
+func (p *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	_, err := p.cb.Execute(func() (interface{}, error) {
+		return nil, p.SyncProducer.SendMessages(msgs)
+	})
+	if !gobreaker.IsRejection(err) {
+		return err
+	}
+
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if err := p.reject(msg, err); err != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *SyncProducer) reject(msg *sarama.ProducerMessage, err error) error {
+	if p.overflow == nil {
+		return err
+	}
+	return p.overflow(msg, err)
+}
@@ -0,0 +1,117 @@
+package saramabreaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	msg := &sarama.ProducerMessage{Topic: "orders"}
+	for _, test := range []struct {
+		err     error
+		outcome gobreaker.Outcome
+	}{
+		{sarama.ErrOutOfBrokers, gobreaker.OutcomeFailure},
+		{sarama.ErrNotLeaderForPartition, gobreaker.OutcomeFailure},
+		{sarama.ErrRequestTimedOut, gobreaker.OutcomeFailure},
+		{sarama.ErrMessageSizeTooLarge, gobreaker.OutcomeSuccess},
+		{fmt.Errorf("send: %w", sarama.ErrInvalidTopic), gobreaker.OutcomeSuccess},
+		{sarama.ProducerErrors{
+			{Msg: msg, Err: sarama.ErrMessageSizeTooLarge},
+			{Msg: msg, Err: sarama.ErrInvalidMessage},
+		}, gobreaker.OutcomeSuccess},
+		{sarama.ProducerErrors{
+			{Msg: msg, Err: sarama.ErrMessageSizeTooLarge},
+			{Msg: msg, Err: sarama.ErrBrokerNotAvailable},
+		}, gobreaker.OutcomeFailure},
+	} {
+		assert.Equal(t, test.outcome, Classify(test.err), test.err.Error())
+	}
+}
+
+type fakeProducer struct {
+	sarama.SyncProducer
+	err  error
+	sent []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.err != nil {
+		return -1, -1, p.err
+	}
+	p.sent = append(p.sent, msg)
+	return 0, int64(len(p.sent) - 1), nil
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent = append(p.sent, msgs...)
+	return nil
+}
+
+func TestSyncProducer(t *testing.T) {
+	fake := &fakeProducer{}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Classify: Classify})
+	var overflow []*sarama.ProducerMessage
+	p := NewSyncProducer(fake, cb, func(msg *sarama.ProducerMessage, err error) error {
+		assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+		overflow = append(overflow, msg)
+		return nil
+	})
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("1")}
+
+	_, offset, err := p.SendMessage(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), offset)
+
+	fake.err = sarama.ErrMessageSizeTooLarge
+	for i := 0; i < 10; i++ {
+		_, _, err = p.SendMessage(msg)
+		assert.Equal(t, sarama.ErrMessageSizeTooLarge, err)
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	fake.err = sarama.ErrOutOfBrokers
+	for i := 0; i < 6; i++ {
+		_, _, err = p.SendMessage(msg)
+		assert.Equal(t, sarama.ErrOutOfBrokers, err)
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	fake.err = nil
+	partition, offset, err := p.SendMessage(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(-1), partition)
+	assert.Equal(t, int64(-1), offset)
+	assert.NoError(t, p.SendMessages([]*sarama.ProducerMessage{msg, msg}))
+	assert.Len(t, overflow, 3)
+	assert.Len(t, fake.sent, 1)
+}
+
+func TestSyncProducerWithoutOverflow(t *testing.T) {
+	fake := &fakeProducer{err: sarama.ErrOutOfBrokers}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	var p sarama.SyncProducer = NewSyncProducer(fake, cb, nil)
+	msgs := []*sarama.ProducerMessage{{Topic: "orders"}, {Topic: "invoices"}}
+
+	assert.Equal(t, sarama.ErrOutOfBrokers, p.SendMessages(msgs))
+
+	_, _, err := p.SendMessage(msgs[0])
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	err = p.SendMessages(msgs)
+	errs, ok := err.(sarama.ProducerErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, msgs[1], errs[1].Msg)
+	assert.True(t, errors.Is(errs[1].Err, gobreaker.ErrOpenState))
+}
@@ -0,0 +1,306 @@
+package gobreaker
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The scenario tests drive a CircuitBreaker with a fake clock through the steps of
+// testdata/scenarios/*.txt and compare the resulting timeline with the .golden file
+// next to it. Run "go test -run TestScenarios -update" to rewrite the golden files.
+//
+// A scenario file consists of one step per line. Empty lines and lines starting with #
+// are ignored. The first step may configure the CircuitBreaker:
+//
+//	settings maxRequests=3 interval=30s timeout=90s trip=3
+//
+// where trip=N trips the CircuitBreaker after N consecutive failures.
+// Every other step starts with the time since the start of the scenario:
+//
+//	@10s success         a request that succeeds
+//	@10s fail            a request that fails
+//	@10s state           observes the state
+//	@10s begin a         a request named a is allowed but doesn't finish yet
+//	@20s end a success   the request named a finishes with the given outcome
+//	@20s parallel 50 fail  50 concurrent requests with the given outcome
+//	@20s trip / reset    Trip and Reset
+
+var update = flag.Bool("update", false, "update golden files")
+
+type scenarioClock struct {
+	mutex sync.Mutex
+	start time.Time
+	now   time.Time
+}
+
+func (c *scenarioClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *scenarioClock) set(offset time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.start.Add(offset)
+}
+
+type scenario struct {
+	t        *testing.T
+	clock    *scenarioClock
+	tscb     *TwoStepCircuitBreaker
+	pending  map[string]func(bool)
+	offset   time.Duration
+	timeline []string
+}
+
+func newScenario(t *testing.T, st Settings) *scenario {
+	clock := &scenarioClock{start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	clock.now = clock.start
+
+	s := &scenario{t: t, clock: clock, pending: make(map[string]func(bool))}
+	st.OnStateChange = func(name string, from State, to State) {
+		s.logf("transition %s -> %s", from, to)
+	}
+
+	st.Clock = clock
+	s.tscb = NewTwoStepCircuitBreaker(st)
+	return s
+}
+
+func (s *scenario) logf(format string, args ...interface{}) {
+	s.timeline = append(s.timeline, fmt.Sprintf("@%v ", s.offset)+fmt.Sprintf(format, args...))
+}
+
+func (s *scenario) result(step string, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = err.Error()
+	}
+
+	cb := s.tscb.cb
+	cb.mutex.Lock()
+	c := cb.counts
+	state := cb.state
+	cb.mutex.Unlock()
+
+	s.logf("%s => %s [%s %d/%d/%d/%d/%d]", step, outcome, state,
+		c.Requests, c.TotalSuccesses, c.TotalFailures, c.ConsecutiveSuccesses, c.ConsecutiveFailures)
+}
+
+func parseOutcome(s string) (bool, error) {
+	switch s {
+	case "success":
+		return true, nil
+	case "fail":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown outcome %q", s)
+	}
+}
+
+func (s *scenario) request(success bool) error {
+	done, err := s.tscb.Allow()
+	if err != nil {
+		return err
+	}
+	done(success)
+	return nil
+}
+
+func (s *scenario) step(fields []string) error {
+	switch fields[0] {
+	case "success", "fail":
+		success, _ := parseOutcome(fields[0])
+		s.result(fields[0], s.request(success))
+	case "state":
+		s.tscb.State()
+		s.result("state", nil)
+	case "begin":
+		if len(fields) != 2 {
+			return errors.New("usage: begin NAME")
+		}
+		done, err := s.tscb.Allow()
+		if err == nil {
+			s.pending[fields[1]] = done
+		}
+		s.result(strings.Join(fields, " "), err)
+	case "end":
+		if len(fields) != 3 {
+			return errors.New("usage: end NAME OUTCOME")
+		}
+		done, ok := s.pending[fields[1]]
+		if !ok {
+			return fmt.Errorf("unknown request %q", fields[1])
+		}
+		success, err := parseOutcome(fields[2])
+		if err != nil {
+			return err
+		}
+		delete(s.pending, fields[1])
+		done(success)
+		s.result(strings.Join(fields, " "), nil)
+	case "parallel":
+		if len(fields) != 3 {
+			return errors.New("usage: parallel N OUTCOME")
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		success, err := parseOutcome(fields[2])
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.request(success)
+			}()
+		}
+		wg.Wait()
+		s.result(strings.Join(fields, " "), nil)
+	case "trip":
+		s.tscb.Trip()
+		s.result("trip", nil)
+	case "reset":
+		s.tscb.Reset()
+		s.result("reset", nil)
+	default:
+		return fmt.Errorf("unknown step %q", fields[0])
+	}
+	return nil
+}
+
+func parseSettings(fields []string) (Settings, error) {
+	var st Settings
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return st, fmt.Errorf("invalid setting %q", field)
+		}
+
+		var err error
+		switch kv[0] {
+		case "maxRequests":
+			var n uint64
+			n, err = strconv.ParseUint(kv[1], 10, 32)
+			st.MaxRequests = uint32(n)
+		case "interval":
+			st.Interval, err = time.ParseDuration(kv[1])
+		case "timeout":
+			st.Timeout, err = time.ParseDuration(kv[1])
+		case "trip":
+			var n uint64
+			n, err = strconv.ParseUint(kv[1], 10, 32)
+			st.ReadyToTrip = func(counts Counts) bool {
+				return counts.ConsecutiveFailures >= uint32(n)
+			}
+		default:
+			err = fmt.Errorf("unknown setting %q", kv[0])
+		}
+		if err != nil {
+			return st, err
+		}
+	}
+	return st, nil
+}
+
+func runScenario(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var s *scenario
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "settings" {
+			if s != nil {
+				t.Fatalf("%s:%d: settings must come first", path, line)
+			}
+			st, err := parseSettings(fields[1:])
+			if err != nil {
+				t.Fatalf("%s:%d: %v", path, line, err)
+			}
+			s = newScenario(t, st)
+			continue
+		}
+		if s == nil {
+			s = newScenario(t, Settings{})
+		}
+
+		if !strings.HasPrefix(fields[0], "@") || len(fields) < 2 {
+			t.Fatalf("%s:%d: a step must look like @OFFSET STEP", path, line)
+		}
+		offset, err := time.ParseDuration(fields[0][1:])
+		if err != nil {
+			t.Fatalf("%s:%d: %v", path, line, err)
+		}
+		s.offset = offset
+		s.clock.set(offset)
+
+		if err := s.step(fields[1:]); err != nil {
+			t.Fatalf("%s:%d: %v", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if s == nil {
+		t.Fatalf("%s: empty scenario", path)
+	}
+	return strings.Join(s.timeline, "\n") + "\n"
+}
+
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		path := path
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		t.Run(name, func(t *testing.T) {
+			got := runScenario(t, path)
+
+			golden := strings.TrimSuffix(path, ".txt") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(want), got)
+		})
+	}
+}
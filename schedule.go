@@ -0,0 +1,200 @@
+package gobreaker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule switches the Policy of a CircuitBreaker by the time of day,
+// e.g. to stricter thresholds during business hours and lenient ones during nightly batch jobs.
+//
+// The CircuitBreaker uses the Policy of the first ScheduleRule whose Window contains
+// the current time, or the thresholds of its Settings if there is none.
+// The Schedule is evaluated at most once per minute. A new Policy is applied atomically
+// with respect to the requests; its Interval and Timeout take effect from the next generation.
+type Schedule []ScheduleRule
+
+// ScheduleRule applies Policy during Window.
+type ScheduleRule struct {
+	Window Window
+	Policy Policy
+}
+
+// Window is a recurring period of time in a week.
+//
+// Days are the days of the week on which the Window starts. If Days is empty, the Window starts every day.
+// Start and End are the offsets since midnight at which the Window starts and ends.
+// If End is not after Start, the Window ends on the following day.
+// If both Start and End are 0, the Window lasts the whole day.
+// Location is the time zone of the Window. If Location is nil, time.Local is used.
+type Window struct {
+	Days     []time.Weekday
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWindow parses a Window in the local time zone from a crontab-like specification
+// of days and hours, each of which may be "*" or omitted:
+//
+//	Mon-Fri 09:00-18:00   business hours
+//	Sat,Sun               the whole weekend
+//	22:00-06:00           every night
+func ParseWindow(spec string) (Window, error) {
+	var w Window
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("gobreaker: invalid window %q", spec)
+	}
+
+	if fields[0] != "*" && !strings.Contains(fields[0], ":") {
+		days, err := parseDays(fields[0])
+		if err != nil {
+			return w, fmt.Errorf("gobreaker: invalid window %q: %v", spec, err)
+		}
+		w.Days = days
+		fields = fields[1:]
+	} else if fields[0] == "*" {
+		fields = fields[1:]
+	}
+
+	if len(fields) == 1 && fields[0] != "*" {
+		hours := strings.SplitN(fields[0], "-", 2)
+		if len(hours) != 2 {
+			return w, fmt.Errorf("gobreaker: invalid window %q: hours must look like 09:00-18:00", spec)
+		}
+		var err error
+		if w.Start, err = parseTimeOfDay(hours[0]); err != nil {
+			return w, fmt.Errorf("gobreaker: invalid window %q: %v", spec, err)
+		}
+		if w.End, err = parseTimeOfDay(hours[1]); err != nil {
+			return w, fmt.Errorf("gobreaker: invalid window %q: %v", spec, err)
+		}
+		if w.Start == w.End {
+			return w, fmt.Errorf("gobreaker: invalid window %q: empty hours", spec)
+		}
+	} else if len(fields) > 1 {
+		return w, fmt.Errorf("gobreaker: invalid window %q", spec)
+	}
+	return w, nil
+}
+
+// MustParseWindow is like ParseWindow but panics if spec is invalid.
+func MustParseWindow(spec string) Window {
+	w, err := ParseWindow(spec)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func parseDays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, ok := weekdays[strings.ToLower(bounds[0])]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[strings.ToLower(bounds[1])]; !ok {
+				return nil, fmt.Errorf("unknown day %q", bounds[1])
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func (w Window) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t is within the Window.
+func (w Window) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	day := t.Weekday()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+
+	if w.Start == 0 && (w.End == 0 || w.End >= 24*time.Hour) {
+		return w.startsOn(day)
+	}
+	if w.Start < w.End {
+		return w.startsOn(day) && offset >= w.Start && offset < w.End
+	}
+	return (w.startsOn(day) && offset >= w.Start) || (w.startsOn((day+6)%7) && offset < w.End)
+}
+
+// applySchedule applies the Policy that the Schedule selects for now.
+func (cb *CircuitBreaker) applySchedule(now time.Time) {
+	if len(cb.schedule) == 0 || now.Before(cb.nextCheck) {
+		return
+	}
+	cb.nextCheck = now.Truncate(time.Minute).Add(time.Minute)
+	cb.storeFast()
+
+	active := -1
+	for i, rule := range cb.schedule {
+		if rule.Window.Contains(now) {
+			active = i
+			break
+		}
+	}
+	if active == cb.activeRule {
+		return
+	}
+
+	cb.activeRule = active
+	if active < 0 {
+		cb.applyPolicy(cb.basePolicy)
+	} else {
+		cb.applyPolicy(cb.schedule[active].Policy)
+	}
+}
@@ -0,0 +1,108 @@
+package gobreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow("Mon-Fri 09:00-18:30")
+	assert.Nil(t, err)
+	assert.Equal(t, Window{
+		Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start: 9 * time.Hour,
+		End:   18*time.Hour + 30*time.Minute,
+	}, w)
+
+	w, err = ParseWindow("sat,Sun")
+	assert.Nil(t, err)
+	assert.Equal(t, Window{Days: []time.Weekday{time.Saturday, time.Sunday}}, w)
+
+	w, err = ParseWindow("Fri-Mon *")
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}, w.Days)
+
+	w, err = ParseWindow("* 22:00-06:00")
+	assert.Nil(t, err)
+	assert.Equal(t, Window{Start: 22 * time.Hour, End: 6 * time.Hour}, w)
+
+	for _, spec := range []string{"", "Mon 09:00-18:00 x", "Someday", "09:00", "09:00-09:00", "25:00-26:00", "Mon 9-18"} {
+		_, err := ParseWindow(spec)
+		assert.NotNil(t, err, spec)
+	}
+	assert.Panics(t, func() { MustParseWindow("Someday") })
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2020-01-06 is a Monday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2020, 1, day, hour, min, 0, 0, time.UTC)
+	}
+
+	business := MustParseWindow("Mon-Fri 09:00-18:00")
+	business.Location = time.UTC
+	assert.False(t, business.Contains(at(6, 8, 59)))
+	assert.True(t, business.Contains(at(6, 9, 0)))
+	assert.True(t, business.Contains(at(10, 17, 59)))
+	assert.False(t, business.Contains(at(10, 18, 0)))
+	assert.False(t, business.Contains(at(11, 12, 0)))
+
+	night := MustParseWindow("Fri 22:00-06:00")
+	night.Location = time.UTC
+	assert.False(t, night.Contains(at(10, 21, 59)))
+	assert.True(t, night.Contains(at(10, 22, 0)))
+	assert.True(t, night.Contains(at(11, 5, 59)))
+	assert.False(t, night.Contains(at(11, 6, 0)))
+	assert.False(t, night.Contains(at(11, 23, 0)))
+	assert.False(t, night.Contains(at(10, 3, 0)))
+
+	weekend := MustParseWindow("Sat,Sun")
+	weekend.Location = time.UTC
+	assert.False(t, weekend.Contains(at(10, 23, 59)))
+	assert.True(t, weekend.Contains(at(11, 0, 0)))
+	assert.True(t, weekend.Contains(at(12, 23, 59)))
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err == nil {
+		business.Location = tokyo
+		assert.True(t, business.Contains(at(6, 0, 0)))
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	strict := func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 }
+	business := MustParseWindow("Mon-Fri 09:00-18:00")
+	business.Location = time.UTC
+
+	now := time.Date(2020, 1, 6, 8, 59, 30, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:   clockFunc(func() time.Time { return now }),
+		Timeout: time.Minute,
+		Schedule: Schedule{
+			{Window: business, Policy: Policy{Timeout: 10 * time.Second, ReadyToTrip: strict}},
+		},
+	})
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, time.Minute, cb.timeout)
+
+	// the policy is checked again after the minute boundary
+	now = now.Add(29 * time.Second)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	now = now.Add(time.Second)
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, 10*time.Second, cb.expiry.Sub(now))
+
+	// the base policy applies again after business hours
+	now = time.Date(2020, 1, 6, 18, 0, 0, 0, time.UTC)
+	cb.Reset()
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, time.Minute, cb.timeout)
+	assert.Equal(t, -1, cb.activeRule)
+}
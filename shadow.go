@@ -0,0 +1,50 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// newShadow returns the CircuitBreaker that evaluates the candidate Settings st
+// alongside cb, see Settings.Shadow.
+func (cb *CircuitBreaker) newShadow(st Settings) *CircuitBreaker {
+	if st.Name == "" {
+		st.Name = cb.name + ".shadow"
+	}
+	if st.Metrics == nil {
+		st.Metrics = cb.metrics
+	}
+	st.Clock = cb.clock
+	st.Shadow = nil
+	// the shadow never waits nor probes, as it admits no real request
+	st.HalfOpenQueue = nil
+	st.Probe = nil
+	st.DoneTimeout = 0
+	return NewCircuitBreaker(st)
+}
+
+// observeShadow feeds the outcome of a request that started at start to the shadow CircuitBreaker,
+// as if the request had been made through it when it finished. If the shadow would have
+// rejected the request, the rejection is reported to its Metrics and the outcome is discarded.
+func (cb *CircuitBreaker) observeShadow(ctx context.Context, start time.Time, outcome Outcome, f failure) {
+	if cb.shadow == nil || outcome == OutcomeIgnore {
+		return
+	}
+	generation, _, err := cb.shadow.beforeRequest(ctx)
+	if err != nil {
+		return
+	}
+	cb.shadow.afterRequest(ctx, generation, start, outcome == OutcomeSuccess, f)
+}
+
+// Shadow returns the CircuitBreaker that evaluates Settings.Shadow alongside cb,
+// or nil if there is none. Its State, Counts and History are what they would be
+// if it had replaced cb. Making requests through it affects nothing but itself.
+func (cb *CircuitBreaker) Shadow() *CircuitBreaker {
+	return cb.shadow
+}
+
+// Shadow returns the CircuitBreaker that evaluates Settings.Shadow alongside tscb, like CircuitBreaker.Shadow.
+func (tscb *TwoStepCircuitBreaker) Shadow() *CircuitBreaker {
+	return tscb.cb.shadow
+}
@@ -0,0 +1,43 @@
+package gobreaker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadow(t *testing.T) {
+	live := new(RecordingMetricsSink)
+	candidate := new(RecordingMetricsSink)
+	cb := NewCircuitBreaker(Settings{
+		Name:    "live",
+		Metrics: live,
+		Shadow: &Settings{
+			Metrics:     candidate,
+			ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		},
+	})
+	shadow := cb.Shadow()
+	assert.Equal(t, "live.shadow", shadow.Name())
+	assert.Nil(t, shadow.Shadow())
+
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, StateOpen, shadow.State())
+
+	// the shadow would have rejected the request, but the CircuitBreaker admits it
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, 4, live.Requests())
+	assert.Equal(t, 3, candidate.Requests())
+	assert.Equal(t, 1, candidate.Rejects(StateOpen))
+	assert.Len(t, live.StateChanges(), 0)
+	changes := candidate.StateChanges()
+	assert.Len(t, changes, 1)
+	assert.Equal(t, StateChangeRecord{"live.shadow", StateClosed, StateOpen, changes[0].Elapsed}, changes[0])
+
+	tscb := NewTwoStepCircuitBreaker(Settings{Shadow: &Settings{Name: "candidate"}})
+	assert.Equal(t, "candidate", tscb.Shadow().Name())
+	assert.Nil(t, NewCircuitBreaker(Settings{}).Shadow())
+}
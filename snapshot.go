@@ -0,0 +1,170 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format produced by this package.
+//
+// The format follows these compatibility rules so that a snapshot persisted by one
+// binary can be restored by the next one (or the previous one) during a rolling upgrade:
+//
+// New fields are only ever added, never renamed or reinterpreted, and adding a field
+// doesn't change SnapshotVersion. Decoders ignore unknown fields and give missing
+// fields their zero value, so older and newer binaries can read each other's snapshots.
+//
+// SnapshotVersion is incremented only for incompatible changes. A decoder rejects
+// snapshots whose version is greater than its own SnapshotVersion with ErrSnapshotVersion.
+// A snapshot without a version is treated as version 1.
+const SnapshotVersion = 1
+
+var (
+	// ErrSnapshotVersion is returned when a snapshot was written in a newer, incompatible format.
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrSnapshotState is returned when a snapshot holds a state that can't be restored.
+	ErrSnapshotState = errors.New("invalid snapshot state")
+)
+
+// Snapshot is the persistable state of a CircuitBreaker.
+// Snapshot can be encoded with encoding/json in the versioned format described by SnapshotVersion.
+type Snapshot struct {
+	Version    int
+	Name       string
+	State      State
+	Generation uint64
+	Counts     Counts
+	Expiry     time.Time
+}
+
+type snapshotJSON struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	Generation uint64     `json:"generation"`
+	Counts     Counts     `json:"counts"`
+	Expiry     *time.Time `json:"expiry,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	v := snapshotJSON{
+		Version:    s.Version,
+		Name:       s.Name,
+		State:      s.State.String(),
+		Generation: s.Generation,
+		Counts:     s.Counts,
+	}
+	if v.Version == 0 {
+		v.Version = SnapshotVersion
+	}
+	if !s.Expiry.IsZero() {
+		expiry := s.Expiry.UTC()
+		v.Expiry = &expiry
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Unknown fields are ignored and missing fields are left zero.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var v snapshotJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Version == 0 {
+		v.Version = 1
+	}
+	if v.Version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, v.Version)
+	}
+
+	state, err := parseState(v.State)
+	if err != nil {
+		return err
+	}
+
+	*s = Snapshot{
+		Version:    v.Version,
+		Name:       v.Name,
+		State:      state,
+		Generation: v.Generation,
+		Counts:     v.Counts,
+	}
+	if v.Expiry != nil {
+		s.Expiry = *v.Expiry
+	}
+	return nil
+}
+
+func parseState(s string) (State, error) {
+	switch s {
+	case "", StateClosed.String():
+		return StateClosed, nil
+	case StateHalfOpen.String():
+		return StateHalfOpen, nil
+	case StateOpen.String():
+		return StateOpen, nil
+	case StateForcedOpen.String():
+		return StateForcedOpen, nil
+	case StateForcedClosed.String():
+		return StateForcedClosed, nil
+	case StateDisabled.String():
+		return StateDisabled, nil
+	default:
+		return StateClosed, fmt.Errorf("%w: %q", ErrSnapshotState, s)
+	}
+}
+
+// Snapshot returns the current state of the CircuitBreaker in a form that can be persisted
+// and later passed to Restore, possibly by a different version of the binary.
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, generation := cb.currentState(cb.clock.Now())
+	return Snapshot{
+		Version:    SnapshotVersion,
+		Name:       cb.name,
+		State:      state,
+		Generation: generation,
+		Counts:     cb.counts,
+		Expiry:     cb.expiry,
+	}
+}
+
+// Restore puts the CircuitBreaker into the state recorded by s.
+// The results of the requests sent before Restore are ignored, and OnStateChange is not called.
+// An open snapshot without expiry is given a fresh timeout.
+func (cb *CircuitBreaker) Restore(s Snapshot) error {
+	if s.Version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, s.Version)
+	}
+	switch s.State {
+	case StateClosed, StateHalfOpen, StateOpen, StateForcedOpen, StateForcedClosed, StateDisabled:
+	default:
+		return fmt.Errorf("%w: %v", ErrSnapshotState, s.State)
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	cb.state = s.State
+	cb.stateSince = now
+	cb.toNewGeneration(now)
+	if s.Generation > cb.generation {
+		cb.generation = s.Generation
+	}
+	cb.counts = s.Counts
+	if !s.Expiry.IsZero() && (s.State == StateClosed || s.State == StateOpen) {
+		cb.expiry = s.Expiry
+	}
+	cb.storeFast()
+	cb.wakeWaiters()
+	cb.startProbe()
+	return nil
+}
@@ -0,0 +1,78 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "snap"})
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	s := cb.Snapshot()
+	assert.Equal(t, SnapshotVersion, s.Version)
+	assert.Equal(t, "snap", s.Name)
+	assert.Equal(t, StateOpen, s.State)
+	assert.False(t, s.Expiry.IsZero())
+
+	data, err := json.Marshal(s)
+	assert.Nil(t, err)
+
+	var decoded Snapshot
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.True(t, s.Expiry.Equal(decoded.Expiry))
+	decoded.Expiry = s.Expiry
+	assert.Equal(t, s, decoded)
+
+	restored := NewCircuitBreaker(Settings{Name: "snap"})
+	assert.Nil(t, restored.Restore(decoded))
+	assert.Equal(t, StateOpen, restored.State())
+	assert.True(t, s.Expiry.Equal(restored.expiry))
+	assert.True(t, restored.generation >= s.Generation)
+
+	pseudoSleep(restored, time.Duration(60)*time.Second)
+	assert.Equal(t, StateHalfOpen, restored.State())
+}
+
+func TestSnapshotRestoreCounts(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.Nil(t, cb.Restore(Snapshot{State: StateClosed, Counts: Counts{5, 0, 5, 0, 5, 5, 5, FailureCounts{5}}}))
+	assert.Equal(t, Counts{5, 0, 5, 0, 5, 5, 5, FailureCounts{5}}, cb.Counts())
+
+	assert.Nil(t, fail(cb)) // 6 consecutive failures
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestSnapshotCompatibility(t *testing.T) {
+	var s Snapshot
+
+	// written by a newer binary with additional fields
+	data := `{"version":1,"name":"cb","state":"half-open","generation":7,` +
+		`"counts":{"requests":1,"totalSuccesses":1,"consecutiveSuccesses":1,"latencyP99":12},` +
+		`"lastTrip":{"reason":"ready-to-trip"}}`
+	assert.Nil(t, json.Unmarshal([]byte(data), &s))
+	assert.Equal(t, Snapshot{Version: 1, Name: "cb", State: StateHalfOpen, Generation: 7, Counts: Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}}, s)
+
+	// written without a version
+	assert.Nil(t, json.Unmarshal([]byte(`{"state":"open"}`), &s))
+	assert.Equal(t, 1, s.Version)
+	assert.Equal(t, StateOpen, s.State)
+
+	// written in an incompatible format
+	err := json.Unmarshal([]byte(`{"version":2,"state":"open"}`), &s)
+	assert.True(t, errors.Is(err, ErrSnapshotVersion))
+
+	err = json.Unmarshal([]byte(`{"version":1,"state":"ajar"}`), &s)
+	assert.True(t, errors.Is(err, ErrSnapshotState))
+
+	cb := NewCircuitBreaker(Settings{})
+	assert.True(t, errors.Is(cb.Restore(Snapshot{Version: 2}), ErrSnapshotVersion))
+	assert.True(t, errors.Is(cb.Restore(Snapshot{State: State(100)}), ErrSnapshotState))
+}
@@ -0,0 +1,196 @@
+// Package sqlbreaker guards the queries of database/sql with the circuit breakers of gobreaker,
+// split by kind of statement, e.g. the reads apart from the writes, so that a failing primary
+// doesn't break the reads served by the replicas.
+package sqlbreaker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/sony/gobreaker"
+)
+
+// The keys returned by ReadWriteKey.
+const (
+	KeyRead  = "read"
+	KeyWrite = "write"
+)
+
+// KeyFunc returns the key of the CircuitBreaker of a query in a BreakerGroup.
+type KeyFunc func(query string) string
+
+// ReadWriteKey is the KeyFunc that returns KeyRead for the queries that start with SELECT, SHOW,
+// EXPLAIN, DESCRIBE or WITH, after any blanks and opening parentheses, and KeyWrite for the others.
+// A data-modifying WITH, as allowed by PostgreSQL, is taken for a read.
+func ReadWriteKey(query string) string {
+	query = strings.TrimLeftFunc(query, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })
+	end := strings.IndexFunc(query, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(query)
+	}
+	switch strings.ToUpper(query[:end]) {
+	case "SELECT", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "WITH":
+		return KeyRead
+	}
+	return KeyWrite
+}
+
+// Classifier reports whether a query that failed with err counts as a failure of its CircuitBreaker.
+// sql.ErrNoRows is not passed to a Classifier: the database answered the query,
+// so that it always counts as a success.
+type Classifier func(err error) bool
+
+// DefaultClassifier fails the queries on any error but context.Canceled,
+// which says nothing about the health of the database.
+func DefaultClassifier(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// DB runs the queries of a *sql.DB through the CircuitBreaker of their key in Group.
+// A query rejected by its CircuitBreaker fails with the rejection, e.g. a *gobreaker.RejectionError,
+// without reaching the database.
+//
+// To send the reads to the replicas, a DB can be used per *sql.DB, with the keys of the queries
+// the DB doesn't serve never used.
+type DB struct {
+	// DB runs the queries.
+	DB *sql.DB
+	// Group holds the CircuitBreakers of the keys.
+	Group *gobreaker.BreakerGroup
+	// Key returns the key of a query. If Key is nil, ReadWriteKey is used,
+	// while e.g. the table or the name of the statement can be used instead.
+	Key KeyFunc
+	// Classify reports whether a query failed. If Classify is nil, DefaultClassifier is used.
+	// The queries that Classify doesn't fail are ignored, see gobreaker.CircuitBreaker.ExecuteClassified.
+	Classify Classifier
+}
+
+// New returns a DB that runs the queries of db through the CircuitBreakers of the keys
+// in a BreakerGroup created with settings, see gobreaker.NewBreakerGroup.
+func New(db *sql.DB, settings func(key string) gobreaker.Settings) *DB {
+	return &DB{DB: db, Group: gobreaker.NewBreakerGroup(0, settings)}
+}
+
+func (db *DB) key(query string) string {
+	if db.Key == nil {
+		return ReadWriteKey(query)
+	}
+	return db.Key(query)
+}
+
+// execute runs query through the CircuitBreaker of key.
+func (db *DB) execute(ctx context.Context, key string, query func(ctx context.Context) error) error {
+	classify := db.Classify
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	var noRows error
+	err := db.Group.Get(key).ExecuteClassified(ctx, func(ctx context.Context) error {
+		err := query(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			noRows = err
+			return nil
+		}
+		return err
+	}, classify)
+	if err != nil {
+		return err
+	}
+	return noRows
+}
+
+// ExecContext runs sql.DB.ExecContext through the CircuitBreaker of the key of query.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.execute(ctx, db.key(query), func(ctx context.Context) error {
+		var err error
+		result, err = db.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs sql.DB.QueryContext through the CircuitBreaker of the key of query.
+// Only the error of QueryContext is counted, not those met while reading the rows.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := db.execute(ctx, db.key(query), func(ctx context.Context) error {
+		var err error
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowScan runs sql.DB.QueryRowContext through the CircuitBreaker of the key of query,
+// and scans the row into dest. Unlike QueryRowContext, it can return the rejection of the CircuitBreaker.
+func (db *DB) QueryRowScan(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	return db.execute(ctx, db.key(query), func(ctx context.Context) error {
+		return db.DB.QueryRowContext(ctx, query, args...).Scan(dest...)
+	})
+}
+
+// PrepareContext prepares query through the CircuitBreaker of its key, and returns a Stmt
+// whose executions go through the same CircuitBreaker.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	return db.PrepareNamed(ctx, db.key(query), query)
+}
+
+// PrepareNamed is like PrepareContext, but the CircuitBreaker of the Stmt is that of name,
+// so that each prepared statement can get its own CircuitBreaker.
+func (db *DB) PrepareNamed(ctx context.Context, name string, query string) (*Stmt, error) {
+	var stmt *sql.Stmt
+	err := db.execute(ctx, name, func(ctx context.Context) error {
+		var err error
+		stmt, err = db.DB.PrepareContext(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: stmt, db: db, key: name}, nil
+}
+
+// Stmt is a prepared statement whose executions go through the CircuitBreaker of its key.
+type Stmt struct {
+	// Stmt runs the executions.
+	Stmt *sql.Stmt
+	db   *DB
+	key  string
+}
+
+// Key returns the key of the CircuitBreaker of the Stmt.
+func (s *Stmt) Key() string {
+	return s.key
+}
+
+// ExecContext runs sql.Stmt.ExecContext through the CircuitBreaker of the Stmt.
+func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := s.db.execute(ctx, s.key, func(ctx context.Context) error {
+		var err error
+		result, err = s.Stmt.ExecContext(ctx, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs sql.Stmt.QueryContext through the CircuitBreaker of the Stmt.
+func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := s.db.execute(ctx, s.key, func(ctx context.Context) error {
+		var err error
+		rows, err = s.Stmt.QueryContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+// Close closes the statement.
+func (s *Stmt) Close() error {
+	return s.Stmt.Close()
+}
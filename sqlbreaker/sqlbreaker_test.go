@@ -0,0 +1,177 @@
+package sqlbreaker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDB is a database whose primary is down: the queries that don't start with SELECT fail.
+type fakeDB struct {
+	queries int
+}
+
+func (d *fakeDB) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{d}, nil }
+func (d *fakeDB) Driver() driver.Driver                            { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+func (c *fakeConn) run(query string) error {
+	c.db.queries++
+	if !strings.HasPrefix(query, "SELECT") {
+		return errors.New("primary down")
+	}
+	return nil
+}
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.c.run(s.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.c.run(s.query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: strings.Count(s.query, "1")}, nil
+}
+
+type fakeRows struct{ rows int }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.rows == 0 {
+		return io.EOF
+	}
+	r.rows--
+	dest[0] = int64(1)
+	return nil
+}
+
+func newDB() (*DB, *fakeDB) {
+	fake := &fakeDB{}
+	db := New(sql.OpenDB(fake), func(key string) gobreaker.Settings {
+		return gobreaker.Settings{ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 }}
+	})
+	return db, fake
+}
+
+func TestReadWriteKey(t *testing.T) {
+	assert.Equal(t, KeyRead, ReadWriteKey("SELECT 1"))
+	assert.Equal(t, KeyRead, ReadWriteKey("  (select 1) union (select 2)"))
+	assert.Equal(t, KeyRead, ReadWriteKey("with t as (select 1) select * from t"))
+	assert.Equal(t, KeyRead, ReadWriteKey("SHOW TABLES"))
+	assert.Equal(t, KeyWrite, ReadWriteKey("INSERT INTO t VALUES (1)"))
+	assert.Equal(t, KeyWrite, ReadWriteKey("selected"))
+	assert.Equal(t, KeyWrite, ReadWriteKey(""))
+}
+
+func TestDB(t *testing.T) {
+	db, fake := newDB()
+	defer db.DB.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := db.ExecContext(ctx, "UPDATE t SET n = 1")
+		assert.EqualError(t, err, "primary down")
+	}
+	_, err := db.ExecContext(ctx, "UPDATE t SET n = 1")
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+	assert.Equal(t, 2, fake.queries)
+
+	// the reads are not affected
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	assert.Nil(t, err)
+	rows.Close()
+	var n int
+	assert.Nil(t, db.QueryRowScan(ctx, "SELECT 1", nil, &n))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, gobreaker.StateClosed, db.Group.Get(KeyRead).State())
+
+	// no row is a success
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, sql.ErrNoRows, db.QueryRowScan(ctx, "SELECT none", nil, &n))
+	}
+	assert.Equal(t, gobreaker.StateClosed, db.Group.Get(KeyRead).State())
+	assert.Equal(t, uint32(4), db.Group.Get(KeyRead).Counts().TotalSuccesses)
+}
+
+func TestDBCanceled(t *testing.T) {
+	db := New(sql.OpenDB(&fakeDB{}), func(key string) gobreaker.Settings {
+		return gobreaker.Settings{
+			Timeout:     time.Millisecond,
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}
+	})
+	defer db.DB.Close()
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "UPDATE t SET n = 1")
+	assert.EqualError(t, err, "primary down")
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, gobreaker.StateHalfOpen, db.Group.Get(KeyWrite).State())
+
+	// a query canceled by its caller doesn't close the half-open breaker
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = db.ExecContext(canceled, "UPDATE t SET n = 1")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, gobreaker.StateHalfOpen, db.Group.Get(KeyWrite).State())
+	assert.Equal(t, gobreaker.Counts{}, db.Group.Get(KeyWrite).Counts())
+}
+
+func TestStmt(t *testing.T) {
+	db, _ := newDB()
+	defer db.DB.Close()
+	ctx := context.Background()
+
+	insert, err := db.PrepareNamed(ctx, "insert-order", "INSERT INTO orders VALUES (?)")
+	assert.Nil(t, err)
+	defer insert.Close()
+	assert.Equal(t, "insert-order", insert.Key())
+	for i := 0; i < 2; i++ {
+		_, err = insert.ExecContext(ctx, 1)
+		assert.EqualError(t, err, "primary down")
+	}
+	_, err = insert.ExecContext(ctx, 1)
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+
+	// the other statements have their own CircuitBreaker
+	update, err := db.PrepareContext(ctx, "UPDATE t SET n = 1")
+	assert.Nil(t, err)
+	defer update.Close()
+	assert.Equal(t, KeyWrite, update.Key())
+	_, err = update.ExecContext(ctx)
+	assert.EqualError(t, err, "primary down")
+
+	sel, err := db.PrepareContext(ctx, "SELECT 1")
+	assert.Nil(t, err)
+	defer sel.Close()
+	rows, err := sel.QueryContext(ctx)
+	assert.Nil(t, err)
+	rows.Close()
+}
@@ -0,0 +1,140 @@
+package gobreaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stripe is a share of the Counts of a generation, padded to its own cache line.
+type stripe struct {
+	requests  uint32
+	successes uint32
+	_         [56]byte
+}
+
+// inflightStripe is a share of the number of requests in flight.
+type inflightStripe struct {
+	n int32
+	_ [60]byte
+}
+
+// stripes spreads the counting of the requests admitted in the closed state
+// over several cache lines, so that they don't contend on the mutex.
+// The requests and their successes are counted in the stripes of the generation
+// they belong to, and folded into Counts whenever the CircuitBreaker takes a decision.
+// Failures are always counted under the mutex, as each of them may trip the CircuitBreaker.
+type stripes struct {
+	inflight []inflightStripe
+	hints    sync.Pool // *int, the stripe index of the P that gets it
+	next     uint32
+}
+
+func newStripes(n int) *stripes {
+	s := &stripes{inflight: make([]inflightStripe, n)}
+	s.hints.New = func() interface{} {
+		i := int(atomic.AddUint32(&s.next, 1)-1) % n
+		return &i
+	}
+	return s
+}
+
+// index returns the stripe of the calling goroutine.
+// sync.Pool keeps the hints per P, so that concurrent goroutines mostly use different stripes.
+func (s *stripes) index() int {
+	hint := s.hints.Get().(*int)
+	i := *hint
+	s.hints.Put(hint)
+	return i
+}
+
+func (s *stripes) inflightCount() int {
+	n := 0
+	for i := range s.inflight {
+		n += int(atomic.LoadInt32(&s.inflight[i].n))
+	}
+	return n
+}
+
+// newGeneration returns the empty stripes of a new generation.
+func (s *stripes) newGeneration() []stripe {
+	return make([]stripe, len(s.inflight))
+}
+
+// beforeRequestStriped admits a request in the closed state without the mutex.
+// It returns false if the request must go through beforeRequest.
+func (cb *CircuitBreaker) beforeRequestStriped(now time.Time) (uint64, bool) {
+	f := cb.loadFast()
+	if f.counts == nil || f.state != StateClosed || !f.stable(now) ||
+		atomic.LoadInt32(&cb.fast.listeners) > 0 {
+		return 0, false
+	}
+
+	i := cb.stripes.index()
+	atomic.AddInt32(&cb.stripes.inflight[i].n, 1)
+	atomic.AddUint32(&f.counts[i].requests, 1)
+	cb.touch(now)
+	cb.metrics.OnRequest(cb.name)
+	return f.generation, true
+}
+
+// afterSuccessStriped counts a success in the closed state without the mutex.
+// It returns false if the success must go through afterRequest.
+func (cb *CircuitBreaker) afterSuccessStriped(before uint64, start time.Time, now time.Time) bool {
+	f := cb.loadFast()
+	if f.counts == nil || f.state != StateClosed || !f.stable(now) || f.generation != before ||
+		atomic.LoadInt32(&cb.fast.listeners) > 0 {
+		return false
+	}
+	cb.countSuccessStriped(f, start, now)
+	return true
+}
+
+// countSuccessStriped counts a success in the stripes of f. As Drain may have started
+// since f was loaded, it wakes Drain under the mutex if Drain is waiting.
+func (cb *CircuitBreaker) countSuccessStriped(f fastState, start time.Time, now time.Time) {
+	i := cb.stripes.index()
+	atomic.AddInt32(&cb.stripes.inflight[i].n, -1)
+	atomic.AddUint32(&f.counts[i].successes, 1)
+	cb.metrics.OnSuccess(cb.name, now.Sub(start))
+
+	if atomic.LoadInt32(&cb.fast.draining) != 0 {
+		cb.mutex.Lock()
+		cb.wakeDrain()
+		cb.mutex.Unlock()
+	}
+}
+
+// foldStripes moves the requests and successes counted in the stripes into Counts.
+// It must be called with cb.mutex held. The successes are folded before the requests,
+// so that a success is never folded without the request it belongs to.
+func (cb *CircuitBreaker) foldStripes() {
+	if cb.counted == nil {
+		return
+	}
+
+	var requests, successes uint32
+	for i := range cb.counted {
+		successes += atomic.SwapUint32(&cb.counted[i].successes, 0)
+	}
+	for i := range cb.counted {
+		requests += atomic.SwapUint32(&cb.counted[i].requests, 0)
+	}
+
+	cb.counts.Requests += requests
+	if successes > 0 {
+		cb.counts.TotalSuccesses += successes
+		cb.counts.ConsecutiveSuccesses += successes
+		cb.counts.ConsecutiveFailures = 0
+		cb.counts.ConsecutiveFailureWeight = 0
+	}
+}
+
+// inflightCount returns the number of requests admitted and not finished yet.
+// It must be called with cb.mutex held.
+func (cb *CircuitBreaker) inflightCount() int {
+	if cb.stripes == nil {
+		return cb.inflight
+	}
+	return cb.inflight + cb.stripes.inflightCount()
+}
@@ -0,0 +1,132 @@
+package gobreaker
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripes(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Stripes: 4})
+
+	// closed-state successes don't take the mutex
+	cb.mutex.Lock()
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	cb.mutex.Unlock()
+	assert.Equal(t, Counts{2, 2, 0, 2, 0, 0, 0, FailureCounts{}}, cb.Counts())
+
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, Counts{3, 2, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, Counts{4, 3, 1, 1, 0, 1, 0, FailureCounts{1}}, cb.Counts())
+	assert.Equal(t, 0, cb.inflightCount())
+
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Nil(t, cb.loadFast().counts)
+}
+
+func TestStripesParallel(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		Stripes:     4,
+		ReadyToTrip: func(counts Counts) bool { return false },
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if j%100 == i {
+					fail(cb)
+				} else {
+					succeed(cb)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	counts := cb.Counts()
+	assert.Equal(t, uint32(8000), counts.Requests)
+	assert.Equal(t, uint32(7920), counts.TotalSuccesses)
+	assert.Equal(t, uint32(80), counts.TotalFailures)
+	assert.Equal(t, 0, cb.inflightCount())
+}
+
+func TestStripesGeneration(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Stripes: 2, Interval: time.Minute, Clock: clock})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	clock.Advance(time.Minute + time.Nanosecond)
+
+	// the success of a request of the previous generation doesn't count
+	done(true)
+	assert.Equal(t, Counts{}, cb.Counts())
+	assert.Equal(t, 0, cb.inflightCount())
+}
+
+func TestStripesDisabled(t *testing.T) {
+	assert.NotNil(t, NewCircuitBreaker(Settings{Stripes: 1}).stripes)
+	assert.Nil(t, NewCircuitBreaker(Settings{Stripes: 1, MaxConcurrent: 1}).stripes)
+	assert.Nil(t, NewCircuitBreaker(Settings{Stripes: 1, Throttle: &ThrottlePolicy{}}).stripes)
+
+	cb := NewCircuitBreaker(Settings{Stripes: 1})
+	events, unsubscribe := cb.Subscribe()
+	defer unsubscribe()
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, EventSuccess, (<-events).Type)
+}
+
+func TestStripesDrain(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Stripes: 2})
+	start := cb.clock.Now()
+	_, ok := cb.beforeRequestStriped(start)
+	assert.True(t, ok)
+
+	// the request loads the closed state, then Drain forces the CircuitBreaker open
+	// and waits for it before the request leaves its stripe
+	f := cb.loadFast()
+	drained := make(chan error)
+	go func() { drained <- cb.Drain(context.Background()) }()
+	for waiting := false; !waiting; runtime.Gosched() {
+		cb.mutex.Lock()
+		waiting = cb.drained != nil
+		cb.mutex.Unlock()
+	}
+	cb.countSuccessStriped(f, start, cb.clock.Now())
+	select {
+	case err := <-drained:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Drain didn't wake up")
+	}
+
+	cb = NewCircuitBreaker(Settings{Stripes: 4})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				succeed(cb)
+			}
+		}()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, cb.Drain(ctx))
+	wg.Wait()
+	assert.Equal(t, 0, cb.InFlight())
+}
@@ -0,0 +1,86 @@
+package gobreaker
+
+import (
+	"context"
+	"fmt"
+)
+
+// TenantRejectionError is returned when TenantBreakers rejects a request of Tenant.
+// Global is true if the CircuitBreaker shared by all tenants rejected it, i.e. the whole
+// dependency is unavailable, and false if the CircuitBreaker of the tenant did, i.e. only
+// the tenant is throttled. Err is the RejectionError of the CircuitBreaker, so IsRejection
+// and RetryAfterHeader apply to a TenantRejectionError too.
+type TenantRejectionError struct {
+	Tenant string
+	Global bool
+	Err    error
+}
+
+// Error implements error.
+func (e *TenantRejectionError) Error() string {
+	if e.Global {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("tenant %s: %v", e.Tenant, e.Err)
+}
+
+// Unwrap returns the RejectionError.
+func (e *TenantRejectionError) Unwrap() error {
+	return e.Err
+}
+
+// TenantBreakers isolates the tenants of a multi-tenant service from each other: each tenant
+// gets its own CircuitBreaker, so that a noisy tenant trips only its own, in front of
+// a global CircuitBreaker shared by all tenants, which trips when the dependency itself fails.
+// A request must be admitted by both, and its outcome is reported to both, like with Chain.
+// The tenant's CircuitBreaker is asked first, so a tenant whose CircuitBreaker is open
+// doesn't reach the global one.
+//
+// The CircuitBreakers of the tenants are held by a BreakerGroup.
+// A TenantBreakers is safe for concurrent use.
+type TenantBreakers struct {
+	tenants *BreakerGroup
+	global  *CircuitBreaker
+}
+
+// NewTenantBreakers returns a new TenantBreakers that holds the CircuitBreakers of at most
+// size tenants, like NewBreakerGroup. settings returns the Settings for the CircuitBreaker
+// of a tenant, and global is the Settings of the shared CircuitBreaker.
+func NewTenantBreakers(size int, settings func(tenant string) Settings, global Settings) *TenantBreakers {
+	return &TenantBreakers{
+		tenants: NewBreakerGroup(size, settings),
+		global:  NewCircuitBreaker(global),
+	}
+}
+
+// Tenant returns the CircuitBreaker of tenant, creating it if needed.
+func (t *TenantBreakers) Tenant(tenant string) *CircuitBreaker {
+	return t.tenants.Get(tenant)
+}
+
+// Global returns the CircuitBreaker shared by all tenants.
+func (t *TenantBreakers) Global() *CircuitBreaker {
+	return t.global
+}
+
+// Remove removes the CircuitBreaker of tenant, if any.
+func (t *TenantBreakers) Remove(tenant string) {
+	t.tenants.Remove(tenant)
+}
+
+// Execute runs req if both the CircuitBreaker of tenant and the global one admit it.
+// Otherwise it returns a TenantRejectionError instantly.
+func (t *TenantBreakers) Execute(tenant string, req func() (interface{}, error)) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), tenant, func(context.Context) (interface{}, error) {
+		return req()
+	})
+}
+
+// ExecuteContext is like Execute but passes ctx to the request and to the CircuitBreakers.
+func (t *TenantBreakers) ExecuteContext(ctx context.Context, tenant string, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	result, rejectedBy, err := Chain(t.Tenant(tenant), t.global).execute(ctx, req)
+	if rejectedBy >= 0 && IsRejection(err) {
+		err = &TenantRejectionError{Tenant: tenant, Global: rejectedBy == 1, Err: err}
+	}
+	return result, err
+}
@@ -0,0 +1,54 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantBreakers(t *testing.T) {
+	tb := NewTenantBreakers(0, func(tenant string) Settings {
+		return Settings{Timeout: time.Minute, ReadyToTrip: tripAfter(1)}
+	}, Settings{Name: "dependency", Timeout: time.Minute, ReadyToTrip: tripAfter(3)})
+	failTenant := func(tenant string) error {
+		_, err := tb.Execute(tenant, func() (interface{}, error) { return nil, errors.New("fail") })
+		return err
+	}
+
+	assert.EqualError(t, failTenant("noisy"), "fail")
+	assert.Equal(t, StateOpen, tb.Tenant("noisy").State())
+	assert.Equal(t, "noisy", tb.Tenant("noisy").Name())
+
+	// the noisy tenant is rejected without reaching the global breaker
+	err := failTenant("noisy")
+	var rejection *TenantRejectionError
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "noisy", rejection.Tenant)
+	assert.False(t, rejection.Global)
+	assert.True(t, errors.Is(err, ErrOpenState))
+	assert.Equal(t, "60", RetryAfterHeader(err))
+	assert.Equal(t, "tenant noisy: noisy: circuit breaker is open", err.Error())
+	assert.Equal(t, uint32(1), tb.Global().Counts().Requests)
+
+	// other tenants are unaffected
+	_, err = tb.Execute("quiet", succeedFunc)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, failTenant("a"), "fail")
+	assert.EqualError(t, failTenant("b"), "fail")
+	assert.EqualError(t, failTenant("c"), "fail")
+	assert.Equal(t, StateOpen, tb.Global().State())
+
+	_, err = tb.Execute("quiet", succeedFunc)
+	assert.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "quiet", rejection.Tenant)
+	assert.True(t, rejection.Global)
+	assert.Equal(t, "dependency: circuit breaker is open", err.Error())
+	assert.True(t, IsRejection(err))
+	assert.Equal(t, uint32(1), tb.Tenant("quiet").Counts().Requests)
+
+	tb.Remove("noisy")
+	assert.Equal(t, StateClosed, tb.Tenant("noisy").State())
+}
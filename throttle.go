@@ -0,0 +1,99 @@
+package gobreaker
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultThrottleK      = 2
+	defaultThrottleWindow = 2 * time.Minute
+	throttleBuckets       = 10
+)
+
+// ThrottlePolicy switches the CircuitBreaker to the adaptive client-side throttling
+// described in the Google SRE book. Instead of opening and rejecting all requests,
+// the CircuitBreaker stays closed and rejects each request with the probability
+//
+//	max(0, (requests - K*accepts) / (requests + 1))
+//
+// where requests is the number of requests attempted in the last Window, including the
+// throttled ones, and accepts is the number of them that succeeded. The throttled requests
+// are rejected with ErrThrottled. ReadyToTrip is not called in this mode.
+//
+// K is the multiplier of accepts. A lower K throttles more aggressively.
+// If K is less than or equal to 0, 2 is used.
+//
+// Window is the period over which requests and accepts are counted.
+// If Window is less than or equal to 0, 2 minutes is used.
+type ThrottlePolicy struct {
+	K      float64
+	Window time.Duration
+}
+
+// throttle counts the requests and accepts of a ThrottlePolicy in a sliding window of buckets.
+type throttle struct {
+	k        float64
+	bucket   time.Duration
+	requests [throttleBuckets]uint64
+	accepts  [throttleBuckets]uint64
+	last     int64 // index of the latest bucket since the zero time
+	rand     func() float64
+}
+
+func newThrottle(p *ThrottlePolicy) *throttle {
+	t := &throttle{k: p.K, bucket: p.Window / throttleBuckets, rand: rand.Float64}
+	if t.k <= 0 {
+		t.k = defaultThrottleK
+	}
+	if p.Window <= 0 {
+		t.bucket = defaultThrottleWindow / throttleBuckets
+	} else if t.bucket <= 0 {
+		t.bucket = 1
+	}
+	return t
+}
+
+// advance moves the window to now, clearing the buckets that fell out of it.
+func (t *throttle) advance(now time.Time) int {
+	current := now.UnixNano() / int64(t.bucket)
+	for i := t.last + 1; i <= current && i <= t.last+throttleBuckets; i++ {
+		t.requests[i%throttleBuckets] = 0
+		t.accepts[i%throttleBuckets] = 0
+	}
+	if current > t.last {
+		t.last = current
+	}
+	return int(t.last % throttleBuckets)
+}
+
+func (t *throttle) sums() (requests, accepts uint64) {
+	for i := 0; i < throttleBuckets; i++ {
+		requests += t.requests[i]
+		accepts += t.accepts[i]
+	}
+	return requests, accepts
+}
+
+// probability returns the probability to reject the next request of the given Priority.
+func (t *throttle) probability(now time.Time, priority Priority) float64 {
+	t.advance(now)
+	requests, accepts := t.sums()
+	p := (float64(requests) - throttleK(t.k, priority)*float64(accepts)) / float64(requests+1)
+	if p < 0 {
+		return 0
+	}
+	return p
+}
+
+// allow counts a new request of the given Priority and reports whether it may proceed.
+func (t *throttle) allow(now time.Time, priority Priority) bool {
+	p := t.probability(now, priority)
+	t.requests[t.advance(now)]++
+	return p == 0 || t.rand() >= p
+}
+
+// onSuccess counts an accepted request.
+func (t *throttle) onSuccess(now time.Time) {
+	t.accepts[t.advance(now)]++
+}
@@ -0,0 +1,83 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleProbability(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	th := newThrottle(&ThrottlePolicy{K: 2, Window: 10 * time.Second})
+	th.rand = func() float64 { return 0.5 }
+
+	assert.Equal(t, 0.0, th.probability(now, PriorityCritical))
+	for i := 0; i < 10; i++ {
+		assert.True(t, th.allow(now, PriorityCritical))
+		if i < 5 {
+			th.onSuccess(now)
+		}
+	}
+	// 10 requests, 5 accepts
+	assert.Equal(t, 0.0, th.probability(now, PriorityCritical))
+
+	for i := 0; i < 10; i++ {
+		th.allow(now, PriorityCritical)
+	}
+	// 20 requests, 5 accepts
+	assert.Equal(t, 10.0/21, th.probability(now, PriorityCritical))
+	assert.True(t, th.allow(now, PriorityCritical))
+	th.rand = func() float64 { return 0.4 }
+	assert.False(t, th.allow(now, PriorityCritical))
+
+	// the buckets expire with the window
+	now = now.Add(5 * time.Second)
+	th.allow(now, PriorityCritical)
+	requests, accepts := th.sums()
+	assert.Equal(t, uint64(23), requests)
+	assert.Equal(t, uint64(5), accepts)
+	now = now.Add(5 * time.Second)
+	assert.Equal(t, 0.5, th.probability(now, PriorityCritical))
+	requests, accepts = th.sums()
+	assert.Equal(t, uint64(1), requests)
+	assert.Equal(t, uint64(0), accepts)
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, 0.0, th.probability(now, PriorityCritical))
+	requests, _ = th.sums()
+	assert.Equal(t, uint64(0), requests)
+}
+
+func TestThrottlePolicy(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(Settings{
+		Clock:    clockFunc(func() time.Time { return now }),
+		Throttle: &ThrottlePolicy{},
+	})
+	assert.Equal(t, 2.0, cb.throttle.k)
+	assert.Equal(t, 12*time.Second, cb.throttle.bucket)
+	cb.throttle.rand = func() float64 { return 0.99 }
+
+	// failures never trip the CircuitBreaker but throttle it
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, uint32(10), cb.Counts().Requests)
+
+	cb.throttle.rand = func() float64 { return 0 }
+	err := succeed(cb)
+	assert.True(t, errors.Is(err, ErrThrottled))
+	assert.True(t, IsRejection(err))
+	assert.Equal(t, uint32(10), cb.Counts().Requests)
+
+	// successes lift the throttle
+	cb.throttle.rand = func() float64 { return 0.99 }
+	for i := 0; i < 11; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	cb.throttle.rand = func() float64 { return 0 }
+	assert.Nil(t, succeed(cb))
+}
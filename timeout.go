@@ -0,0 +1,52 @@
+package gobreaker
+
+import (
+	"context"
+	"time"
+)
+
+// ExecuteTimeout runs req like ExecuteContext with a context that times out after d.
+// If req hasn't returned by then, ExecuteTimeout returns context.DeadlineExceeded
+// without waiting for it. The error is classified like the error of any request, e.g. with
+// IgnoredErrors or Classify, so by default the request counts as a failure of kind FailureTimeout,
+// and a hung dependency trips the CircuitBreaker instead of blocking its callers forever.
+//
+// req keeps running in its goroutine until it notices that its context is done. Its late result
+// is discarded, since the request has already been counted, and so is a late panic.
+func (cb *CircuitBreaker) ExecuteTimeout(d time.Duration, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan hedgedResult, 1)
+	go func() {
+		var r hedgedResult
+		defer func() {
+			r.panic = recover()
+			results <- r
+		}()
+		r.value, r.err = req(ctx)
+	}()
+
+	select {
+	case r := <-results:
+		if r.panic != nil {
+			outcome, f, perr := cb.recoverPanic(r.panic)
+			cb.finishRequest(ctx, generation, start, outcome, f)
+			if perr == nil {
+				panic(r.panic)
+			}
+			return nil, perr
+		}
+		cb.finishResult(ctx, generation, start, r.value, r.err)
+		return r.value, r.err
+	case <-ctx.Done():
+		err := ctx.Err()
+		cb.finishResult(ctx, generation, start, nil, err)
+		return nil, err
+	}
+}
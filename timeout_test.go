@@ -0,0 +1,81 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteTimeout(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: tripAfter(2)})
+
+	result, err := cb.ExecuteTimeout(time.Second, func(ctx context.Context) (interface{}, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result)
+
+	late := make(chan struct{})
+	returned := make(chan struct{})
+	result, err = cb.ExecuteTimeout(10*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		defer close(returned)
+		<-late
+		return "late", nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, result)
+	counts := cb.Counts()
+	assert.Equal(t, uint32(2), counts.Requests)
+	assert.Equal(t, uint32(1), counts.TotalFailures)
+	assert.Equal(t, uint32(1), counts.FailuresByKind[FailureTimeout])
+
+	// the late result is discarded
+	close(late)
+	<-returned
+	assert.Equal(t, counts, cb.Counts())
+
+	_, err = cb.ExecuteTimeout(10*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, errors.New("late")
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, StateOpen, cb.State())
+
+	_, err = cb.ExecuteTimeout(time.Second, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.True(t, errors.Is(err, ErrOpenState))
+}
+
+func TestExecuteTimeoutClassified(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{IgnoredErrors: []error{context.DeadlineExceeded}})
+	_, err := cb.ExecuteTimeout(time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	cb = NewCircuitBreaker(Settings{Classify: func(err error) Outcome { return OutcomeSuccess }})
+	cb.ExecuteTimeout(time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+}
+
+func TestExecuteTimeoutPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	assert.PanicsWithValue(t, "oops", func() {
+		cb.ExecuteTimeout(time.Second, func(ctx context.Context) (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, uint32(1), cb.Counts().FailuresByKind[FailurePanic])
+
+	cb = NewCircuitBreaker(Settings{PanicHandler: RecoverPanics})
+	_, err := cb.ExecuteTimeout(time.Second, func(ctx context.Context) (interface{}, error) { panic("oops") })
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+}
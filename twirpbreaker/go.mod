@@ -0,0 +1,11 @@
+module github.com/sony/gobreaker/twirpbreaker
+
+go 1.13
+
+require (
+	github.com/sony/gobreaker v0.6.0
+	github.com/stretchr/testify v1.3.0
+	github.com/twitchtv/twirp v7.1.0+incompatible
+)
+
+replace github.com/sony/gobreaker => ../
@@ -0,0 +1,98 @@
+// Package twirpbreaker guards the calls of Twirp clients with the circuit breakers of gobreaker.
+//
+// It lives in its own module so that gobreaker doesn't depend on Twirp.
+package twirpbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sony/gobreaker"
+	"github.com/twitchtv/twirp"
+)
+
+// failureCodes are the error codes that say the service is unhealthy.
+// The other codes, e.g. invalid_argument or not_found, are answers of a healthy service.
+var failureCodes = map[twirp.ErrorCode]bool{
+	twirp.Unknown:           true,
+	twirp.DeadlineExceeded:  true,
+	twirp.ResourceExhausted: true,
+	twirp.Internal:          true,
+	twirp.Unavailable:       true,
+	twirp.DataLoss:          true,
+}
+
+// Classify classifies the error of a Twirp call for Settings.Classify.
+// The codes unavailable, deadline_exceeded, resource_exhausted, internal, unknown
+// and data_loss count as failures, canceled is ignored, as the caller gave up on the call,
+// and the other codes count as successes. An error that is not a twirp.Error counts as a failure.
+func Classify(err error) gobreaker.Outcome {
+	var twerr twirp.Error
+	if !errors.As(err, &twerr) {
+		return gobreaker.OutcomeFailure
+	}
+	switch code := twerr.Code(); {
+	case code == twirp.Canceled:
+		return gobreaker.OutcomeIgnore
+	case failureCodes[code]:
+		return gobreaker.OutcomeFailure
+	default:
+		return gobreaker.OutcomeSuccess
+	}
+}
+
+// Interceptor returns a twirp.Interceptor that runs the calls through cb, for
+// twirp.WithClientInterceptors. cb should be created with Classify as Settings.Classify,
+// so that the errors of invalid requests don't trip it. A call rejected by cb returns its error,
+// e.g. a *gobreaker.RejectionError, without reaching the service.
+func Interceptor(cb gobreaker.Breaker) twirp.Interceptor {
+	return func(next twirp.Method) twirp.Method {
+		if c, ok := cb.(gobreaker.ContextBreaker); ok {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				return c.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+					return next(ctx, request)
+				})
+			}
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return cb.Execute(func() (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
+
+// PerMethod returns a twirp.Interceptor like Interceptor that runs each method through its own
+// CircuitBreaker, registered in r under the name of the method, e.g. "example.Haberdasher/MakeHat",
+// and created with st if it doesn't exist yet. If st.Classify is nil, Classify is used.
+// The calls whose context doesn't name the method are not guarded.
+func PerMethod(r *gobreaker.Registry, st gobreaker.Settings) twirp.Interceptor {
+	if st.Classify == nil {
+		st.Classify = Classify
+	}
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			name, ok := methodName(ctx)
+			if !ok {
+				return next(ctx, request)
+			}
+			return Interceptor(r.GetOrCreate(name, st))(next)(ctx, request)
+		}
+	}
+}
+
+// methodName returns the fully qualified name of the method called with ctx.
+func methodName(ctx context.Context) (string, bool) {
+	service, ok := twirp.ServiceName(ctx)
+	if !ok {
+		return "", false
+	}
+	method, ok := twirp.MethodName(ctx)
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := twirp.PackageName(ctx); ok && pkg != "" {
+		service = pkg + "." + service
+	}
+	return service + "/" + method, true
+}
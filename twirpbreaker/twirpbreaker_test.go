@@ -0,0 +1,97 @@
+package twirpbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		outcome gobreaker.Outcome
+	}{
+		{twirp.NewError(twirp.Unavailable, "down"), gobreaker.OutcomeFailure},
+		{twirp.NewError(twirp.DeadlineExceeded, "slow"), gobreaker.OutcomeFailure},
+		{twirp.NewError(twirp.Internal, "bug"), gobreaker.OutcomeFailure},
+		{twirp.NewError(twirp.InvalidArgument, "bad"), gobreaker.OutcomeSuccess},
+		{twirp.NewError(twirp.NotFound, "missing"), gobreaker.OutcomeSuccess},
+		{twirp.NewError(twirp.Canceled, "gave up"), gobreaker.OutcomeIgnore},
+		{fmt.Errorf("call: %w", twirp.NewError(twirp.Unavailable, "down")), gobreaker.OutcomeFailure},
+		{errors.New("connection refused"), gobreaker.OutcomeFailure},
+	} {
+		assert.Equal(t, test.outcome, Classify(test.err), test.err.Error())
+	}
+}
+
+func respond(err error) twirp.Method {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{Classify: Classify})
+	intercept := Interceptor(cb)
+	ctx := context.Background()
+
+	resp, err := intercept(respond(nil))(ctx, "hat")
+	assert.NoError(t, err)
+	assert.Equal(t, "hat", resp)
+
+	for i := 0; i < 10; i++ {
+		_, err = intercept(respond(twirp.NewError(twirp.InvalidArgument, "bad size")))(ctx, "hat")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+
+	for i := 0; i < 6; i++ {
+		_, err = intercept(respond(twirp.NewError(twirp.Unavailable, "down")))(ctx, "hat")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+
+	called := false
+	_, err = intercept(func(ctx context.Context, request interface{}) (interface{}, error) {
+		called = true
+		return request, nil
+	})(ctx, "hat")
+	assert.False(t, called)
+	assert.True(t, errors.Is(err, gobreaker.ErrOpenState))
+}
+
+func TestPerMethod(t *testing.T) {
+	registry := gobreaker.NewRegistry()
+	intercept := PerMethod(registry, gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	call := func(method string, err error) error {
+		ctx := ctxsetters.WithPackageName(context.Background(), "example")
+		ctx = ctxsetters.WithServiceName(ctx, "Haberdasher")
+		ctx = ctxsetters.WithMethodName(ctx, method)
+		_, err = intercept(respond(err))(ctx, "hat")
+		return err
+	}
+
+	assert.Error(t, call("MakeHat", twirp.NewError(twirp.InvalidArgument, "bad size")))
+	assert.Error(t, call("MakeHat", twirp.NewError(twirp.Unavailable, "down")))
+	assert.True(t, errors.Is(call("MakeHat", nil), gobreaker.ErrOpenState))
+	assert.NoError(t, call("ListHats", nil))
+
+	_, err := intercept(respond(nil))(context.Background(), "hat")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, registry.Len())
+	cb, ok := registry.Get("example.Haberdasher/MakeHat")
+	assert.True(t, ok)
+	assert.Equal(t, gobreaker.StateOpen, cb.State())
+}
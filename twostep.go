@@ -0,0 +1,175 @@
+package gobreaker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// twoStepRequest is a request admitted by a TwoStepCircuitBreaker whose outcome is reported later.
+type twoStepRequest struct {
+	cb         *CircuitBreaker
+	ctx        context.Context
+	generation uint64
+	start      time.Time
+	called     uint32        // set by the first call of done
+	finished   chan struct{} // closed by the first call of done if the request is watched
+}
+
+// allow admits a new two-step request, and watches it if DoneTimeout is set or ctx can be cancelled.
+func (cb *CircuitBreaker) allow(ctx context.Context) (*twoStepRequest, error) {
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &twoStepRequest{cb: cb, ctx: ctx, generation: generation, start: start}
+	if cb.doneTimeout > 0 || ctx.Done() != nil {
+		var timer Timer
+		if cb.doneTimeout > 0 {
+			timer = cb.newTimer(cb.doneTimeout)
+		}
+		r.finished = make(chan struct{})
+		go r.watch(timer)
+	}
+	return r, nil
+}
+
+// done reports the outcome of the request if it is the first call.
+// It reports whether the outcome was counted.
+func (r *twoStepRequest) done(outcome Outcome, f failure) bool {
+	if !atomic.CompareAndSwapUint32(&r.called, 0, 1) {
+		return false
+	}
+	if r.finished != nil {
+		close(r.finished)
+	}
+	r.cb.finishRequest(r.ctx, r.generation, r.start, outcome, f)
+	return true
+}
+
+// watch counts the request as a failure if done isn't called before timer fires,
+// and handles the cancellation of the context of the request.
+// timer is nil if DoneTimeout is not set.
+func (r *twoStepRequest) watch(timer Timer) {
+	var timeout <-chan time.Time
+	if timer != nil {
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	select {
+	case <-timeout:
+		if r.done(OutcomeFailure, failure{kind: FailureTimeout, weight: 1}) {
+			if r.cb.logger != nil {
+				r.cb.logger.Warn("done callback not called, counted as a failure",
+					"name", r.cb.name, "doneTimeout", r.cb.doneTimeout.String())
+			}
+		}
+	case <-r.ctx.Done():
+		if r.cb.ignoreCanceled {
+			r.done(OutcomeIgnore, failure{})
+		} else {
+			r.done(OutcomeFailure, r.cb.failureOf(OutcomeFailure, r.ctx.Err()))
+		}
+	case <-r.finished:
+	}
+}
+
+// AllowContext is like Allow, but binds the request to ctx. If ctx is done before
+// the callback is called, the request is counted as a failure, or released without
+// being counted if IgnoreCanceled is set. The later call of the callback is ignored.
+func (tscb *TwoStepCircuitBreaker) AllowContext(ctx context.Context) (done func(success bool), err error) {
+	r, err := tscb.cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) { r.done(outcomeOf(success), plainFailure) }, nil
+}
+
+// flagBlock holds the flags of the callbacks of Allow, so that the flag of a callback
+// doesn't take an allocation of its own besides the closure.
+type flagBlock struct {
+	next  uint32
+	flags [64]uint32
+}
+
+// calledFlag returns a new flag for a callback of Allow, taken from the current flagBlock.
+func (cb *CircuitBreaker) calledFlag() *uint32 {
+	for {
+		b, _ := cb.fast.flags.Load().(*flagBlock)
+		if b != nil {
+			if i := atomic.AddUint32(&b.next, 1) - 1; i < uint32(len(b.flags)) {
+				return &b.flags[i]
+			}
+		}
+		// a concurrent caller may replace the new block too; its flags are then just left unused
+		cb.fast.flags.Store(&flagBlock{})
+	}
+}
+
+// Done reports the outcome of a request admitted by TwoStepCircuitBreaker.AllowDone.
+// Unlike the callback returned by Allow, Done is a small value that doesn't need to be
+// allocated, for very high request rates. Exactly one of its methods must be called once,
+// unless DoneTimeout is set, in which case only the first call counts as with Allow.
+// The zero Done does nothing.
+type Done struct {
+	cb         *CircuitBreaker
+	generation uint64
+	start      time.Time
+	r          *twoStepRequest // set if the request is watched
+	called     *uint32         // set if only the first call counts, for the callbacks of Allow
+}
+
+// AllowDone is like Allow, but returns a Done instead of a callback,
+// so that admitting a request doesn't allocate unless DoneTimeout is set.
+func (tscb *TwoStepCircuitBreaker) AllowDone() (Done, error) {
+	return tscb.cb.allowDone(false)
+}
+
+// allowDone admits a request like AllowDone. If once is true, only the first call
+// of the Done counts even if DoneTimeout is not set, as with the callbacks of Allow.
+func (cb *CircuitBreaker) allowDone(once bool) (Done, error) {
+	ctx := context.Background()
+	if cb.doneTimeout > 0 {
+		r, err := cb.allow(ctx)
+		if err != nil {
+			return Done{}, err
+		}
+		return Done{cb: cb, r: r}, nil
+	}
+
+	generation, start, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return Done{}, err
+	}
+	d := Done{cb: cb, generation: generation, start: start}
+	if once {
+		d.called = cb.calledFlag()
+	}
+	return d, nil
+}
+
+// Report reports the success or failure of the request, like the callback returned by Allow.
+func (d Done) Report(success bool) {
+	d.finish(outcomeOf(success), plainFailure)
+}
+
+// ReportError reports the error of the request, like the callback returned by AllowE.
+func (d Done) ReportError(err error) {
+	if d.cb == nil {
+		return
+	}
+	outcome := d.cb.classify(nil, err)
+	d.finish(outcome, d.cb.failureOf(outcome, err))
+}
+
+func (d Done) finish(outcome Outcome, f failure) {
+	switch {
+	case d.r != nil:
+		d.r.done(outcome, f)
+	case d.called != nil && !atomic.CompareAndSwapUint32(d.called, 0, 1):
+	case d.cb != nil:
+		d.cb.finishRequest(context.Background(), d.generation, d.start, outcome, f)
+	}
+}
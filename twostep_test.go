@@ -0,0 +1,170 @@
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoStepDoneIdempotent(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	done(false)
+	done(true)
+	done(false)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+
+	doneE, err := tscb.AllowE()
+	assert.Nil(t, err)
+	doneE(nil)
+	doneE(nil)
+	assert.Equal(t, Counts{2, 1, 1, 1, 0, 1, 0, FailureCounts{1}}, cb.Counts())
+
+	child := cb.NewChild(ChildSettings{})
+	done, err = child.Allow()
+	assert.Nil(t, err)
+	done(true)
+	done(true)
+	assert.Equal(t, Counts{3, 2, 1, 2, 0, 1, 0, FailureCounts{1}}, cb.Counts())
+}
+
+func TestTwoStepDoneTimeout(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{
+		Name:        "leak",
+		Clock:       clock,
+		DoneTimeout: time.Second,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	// a callback in time stops the watch
+	done, err := tscb.Allow()
+	assert.Nil(t, err)
+	done(true)
+	clock.Advance(time.Second)
+
+	// a forgotten callback counts as a failure
+	_, err = tscb.Allow()
+	assert.Nil(t, err)
+	clock.Advance(time.Second)
+	for cb.State() != StateOpen {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, uint32(1), cb.LastTrip().Counts.FailuresByKind[FailureTimeout])
+}
+
+func TestTwoStepAllowContext(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{})
+	tscb := &TwoStepCircuitBreaker{cb}
+
+	// a cancelled request counts as a failure once
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := tscb.AllowContext(ctx)
+	assert.Nil(t, err)
+	cancel()
+	for cb.Counts().TotalFailures == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done(true)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1, 1, 1, FailureCounts{1}}, cb.Counts())
+
+	// done before the cancellation counts as usual
+	ctx, cancel = context.WithCancel(context.Background())
+	done, err = tscb.AllowContext(ctx)
+	assert.Nil(t, err)
+	done(true)
+	cancel()
+	assert.Equal(t, Counts{2, 1, 1, 1, 0, 1, 0, FailureCounts{1}}, cb.Counts())
+}
+
+func TestTwoStepAllowContextIgnoreCanceled(t *testing.T) {
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(Settings{Clock: clock, Timeout: time.Second, IgnoreCanceled: true})
+	tscb := &TwoStepCircuitBreaker{cb}
+	cb.Trip()
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := tscb.AllowContext(ctx)
+	assert.Nil(t, err)
+	_, err = tscb.Allow()
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+
+	// the cancellation gives the half-open slot back without a failure
+	cancel()
+	for cb.Counts().Requests != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done(false)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	done, err = tscb.AllowContext(context.Background())
+	assert.Nil(t, err)
+	done(true)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestTwoStepAllowDone(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+
+	done, err := tscb.AllowDone()
+	assert.Nil(t, err)
+	done.Report(true)
+	done, err = tscb.AllowDone()
+	assert.Nil(t, err)
+	done.ReportError(errors.New("fail"))
+	assert.Equal(t, Counts{2, 1, 1, 0, 1, 1, 1, FailureCounts{1}}, tscb.Counts())
+
+	Done{}.Report(true)
+	Done{}.ReportError(nil)
+
+	tscb.cb.Trip()
+	_, err = tscb.AllowDone()
+	assert.True(t, errors.Is(err, ErrOpenState))
+}
+
+func TestTwoStepAllowDoneTimeout(t *testing.T) {
+	clock := newFakeClock()
+	tscb := NewTwoStepCircuitBreaker(Settings{DoneTimeout: time.Second, Clock: clock})
+
+	done, err := tscb.AllowDone()
+	assert.Nil(t, err)
+	done.Report(true)
+	done.Report(false)
+	assert.Equal(t, Counts{1, 1, 0, 1, 0, 0, 0, FailureCounts{}}, tscb.Counts())
+}
+
+func TestTwoStepAllowDoneAllocs(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{})
+	allocs := testing.AllocsPerRun(100, func() {
+		done, _ := tscb.AllowDone()
+		done.Report(true)
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	// the callback of Allow only allocates its closure, once the caller keeps it
+	allocs = testing.AllocsPerRun(100, func() {
+		keptDone, _ = tscb.Allow()
+		keptDone(true)
+	})
+	assert.Equal(t, 1.0, allocs)
+	allocs = testing.AllocsPerRun(100, func() {
+		keptDoneE, _ = tscb.AllowE()
+		keptDoneE(nil)
+	})
+	assert.Equal(t, 1.0, allocs)
+}
+
+var (
+	keptDone  func(success bool)
+	keptDoneE func(err error)
+)